@@ -0,0 +1,616 @@
+// Package app wires the indexer: fullnode client, event listener, background
+// workers and HTTP routes. cmd/server runs it on its own; the verifi-services
+// binary runs it next to the sync service on one pool and one HTTP server.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/alert"
+	"github.com/verifi-protocol/indexer-service/internal/archive"
+	"github.com/verifi-protocol/indexer-service/internal/bus"
+	"github.com/verifi-protocol/indexer-service/internal/config"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/deliverylog"
+	"github.com/verifi-protocol/indexer-service/internal/errreport"
+	"github.com/verifi-protocol/indexer-service/internal/health"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/indexer-service/internal/logbuffer"
+	"github.com/verifi-protocol/indexer-service/internal/logstore"
+	"github.com/verifi-protocol/indexer-service/internal/notify"
+	"github.com/verifi-protocol/indexer-service/internal/subscriptions"
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+	"github.com/verifi-protocol/indexer-service/migrations"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/registry"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// Service is the name the indexer reports in logs, /health and the registry
+const Service = "verifi-indexer-service"
+
+// EnvFiles are tried in order by LoadEnv and by config reloads; the first
+// one that loads wins
+var EnvFiles = []string{"../.env", "../.env.local"}
+
+// App is a wired indexer. Build it with New, mount its routes, then Start it.
+type App struct {
+	cfg       *config.Config
+	database  *db.DB
+	client    *aptos.Client
+	rotator   *aptos.APIKeyRotator
+	listener  *indexer.EventListener
+	checker   *health.Checker
+	subs      *subscriptions.Registry
+	replayer  *indexer.Replayer
+	scaling   indexer.ScalingTargets
+	buildInfo buildinfo.Info
+
+	// Every configured network, the first being client/listener/replayer
+	networks []*networkIndexer
+
+	publishers []*bus.Publisher
+	archiver   *archive.Archiver
+
+	// Cancelled by Close; stops the listener, workers and background replays
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// networkIndexer is the fullnode client, listener and replayer of one of
+// the configured networks
+type networkIndexer struct {
+	name     string
+	client   *aptos.Client
+	listener *indexer.EventListener
+	replayer *indexer.Replayer
+}
+
+// LoadEnv reads the first of EnvFiles that exists with load
+func LoadEnv(load func(filenames ...string) error) error {
+	var err error
+	for _, file := range EnvFiles {
+		if err = load(file); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// LogWriter returns the log sink behind GET /logs and, when LOG_DB is on,
+// the log_entries table. Pass it to logging.Setup.
+func LogWriter() zerolog.LevelWriter {
+	logbuffer.Init(logbuffer.FromEnv())
+	return &logBufferWriter{}
+}
+
+// Migrate applies the indexer's migrations
+func Migrate(ctx context.Context, database *store.DB) error {
+	log.Info().Msg("🔄 Running migrations...")
+
+	if err := database.Migrate(ctx, migrations.Service, migrations.FS); err != nil {
+		return err
+	}
+
+	log.Info().Msg("✅ Migrations complete")
+	return nil
+}
+
+// Load reads the indexer configuration from the environment and wires the
+// indexer on database
+func Load(database *store.DB) (*App, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, &db.DB{DB: database})
+}
+
+// New wires the indexer. Migrations must have run; Close releases what New
+// starts.
+func New(cfg *config.Config, database *db.DB) (*App, error) {
+	a := &App{cfg: cfg, database: database}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	// Persist warn+ log entries for post-mortems (optional). Stopped before
+	// the pool closes so queued entries are written.
+	if cfg.LogDB {
+		logstore.Start(database, store.InstanceID("indexer-service"), cfg.LogDBRetention)
+		log.Info().Dur("retention", cfg.LogDBRetention).Msg("✅ Persisting warn+ log entries to log_entries")
+	}
+
+	// Record every webhook attempt for GET /webhooks/deliveries
+	deliverylog.Start(database, cfg.WebhookAuditRetention)
+	webhook.SetObserver(deliverylog.Record)
+
+	// Initialize Aptos client
+	if _, ok := aptos.NetworkRPCURL(cfg.AptosNetwork); !ok && cfg.AptosRPCURL == "" && len(cfg.RPCEndpoints) == 0 {
+		a.Close()
+		return nil, fmt.Errorf("unknown network %q, set APTOS_RPC_URL", cfg.AptosNetwork)
+	}
+	a.client = NewAptosClient(cfg)
+	log.Info().
+		Str("network", cfg.AptosNetwork).
+		Str("rpc", a.client.RPCURL()).
+		Int("endpoints", len(a.client.Endpoints())).
+		Float64("rps_limit", cfg.RPCRateLimit).
+		Msg("✅ Aptos client initialized")
+
+	// Initialize API key rotator if keys are provided
+	if len(cfg.AptosAPIKeys) > 0 || len(cfg.NoditAPIKeys) > 0 {
+		a.rotator = aptos.NewAPIKeyRotator(cfg.AptosAPIKeys, cfg.NoditAPIKeys)
+		a.client.SetAPIRotator(a.rotator)
+		log.Info().
+			Int("aptos_keys", len(cfg.AptosAPIKeys)).
+			Int("nodit_keys", len(cfg.NoditAPIKeys)).
+			Msg("✅ API key rotation enabled")
+	}
+
+	if rules := cfg.EventMappings.Rules(); len(rules) > 0 {
+		log.Info().Int("rules", len(rules)).Msg("✅ Event mappings loaded for older contract versions")
+	}
+
+	// Message bus and Redis publishers, validated by config.Load
+	for _, busCfg := range []bus.Config{cfg.EventBus, cfg.Redis} {
+		if publisher, _ := bus.New(busCfg); publisher != nil {
+			a.publishers = append(a.publishers, publisher)
+		}
+	}
+
+	// Object storage archive, validated by config.Load
+	if archiver, _ := archive.New(cfg.Archive); archiver != nil {
+		a.archiver = archiver
+	}
+
+	// Rows written before rows carried their network belong to the first
+	// one. Done before any listener writes.
+	if labelled, err := database.LabelRows(context.Background(), cfg.AptosNetwork); err != nil {
+		log.Warn().Err(err).Msg("Failed to label existing rows with their network, retrying on the next start")
+	} else if labelled {
+		log.Info().Str("network", cfg.AptosNetwork).Msg("🏷️ Existing rows labelled with their network")
+	}
+
+	// One event listener per network. The others get their own client,
+	// each with the APTOS_RPC_RPS budget, and share the API keys.
+	for i, network := range cfg.Networks {
+		client := a.client
+		if i > 0 {
+			var err error
+			if client, err = networkClient(cfg, network); err != nil {
+				a.Close()
+				return nil, err
+			}
+			if a.rotator != nil {
+				client.SetAPIRotator(a.rotator)
+			}
+			log.Info().
+				Str("network", network.Name).
+				Str("rpc", client.RPCURL()).
+				Msg("✅ Aptos client initialized")
+		}
+		a.networks = append(a.networks, &networkIndexer{
+			name:     network.Name,
+			client:   client,
+			listener: a.newListener(client, network, i == 0),
+		})
+	}
+	a.listener = a.networks[0].listener
+	listener := a.listener
+
+	// A wrong RPC URL or module address otherwise polls forever without
+	// finding an event. An unreachable fullnode is left to the listener's
+	// restarts.
+	if cfg.StartupValidation != "off" {
+		for _, n := range a.networks {
+			ctx, cancel := context.WithTimeout(a.ctx, 15*time.Second)
+			err := n.listener.VerifyIdentity(ctx, cfg.RequiredModules)
+			cancel()
+			switch {
+			case err == nil:
+			case !errors.Is(err, indexer.ErrMisconfigured):
+				log.Warn().Err(err).Str("network", n.name).Msg("Could not verify the network and module at startup")
+			case cfg.StartupValidation == "strict":
+				a.Close()
+				return nil, fmt.Errorf("network %s: %w (STARTUP_VALIDATION=warn starts anyway)", n.name, err)
+			default:
+				log.Error().Err(err).Str("network", n.name).Msg("🚨 Network or module mismatch, the listener may never find an event")
+			}
+		}
+	}
+
+	// Nodit indexer for fast catch-up over large backlogs. Nodit only
+	// indexes the public networks.
+	noditEnabled := len(cfg.NoditAPIKeys) > 0 && noditNetwork(cfg.AptosNetwork)
+	for _, n := range a.networks {
+		if len(cfg.NoditAPIKeys) > 0 && noditNetwork(n.name) {
+			n.listener.SetNoditClient(aptos.NewNoditClient(n.name, a.rotator))
+			log.Info().Str("network", n.name).Msg("✅ Nodit indexer enabled for catch-up")
+		}
+	}
+
+	// Build metadata for /version and incident triage
+	a.buildInfo = buildinfo.Get(Service)
+	a.buildInfo.SchemaVersion, _ = database.SchemaVersion(context.Background(), migrations.Service)
+	a.buildInfo.Features["webhook"] = cfg.WebhookURL != "" || len(cfg.WebhookTargets) > 0
+	a.buildInfo.Features["api_key_rotation"] = a.rotator != nil
+	a.buildInfo.Features["nodit_catchup"] = noditEnabled
+	a.buildInfo.Features["error_reporting"] = cfg.SentryDSN != ""
+	a.buildInfo.Features["event_bus"] = cfg.EventBus.Kind != ""
+	a.buildInfo.Features["redis_pubsub"] = cfg.Redis.Kind != ""
+	a.buildInfo.Features["archive"] = cfg.Archive.URL != ""
+	a.buildInfo.Features["chat_notifications"] = cfg.Notify.DiscordWebhookURL != "" || cfg.Notify.TelegramBotToken != ""
+
+	log.Info().
+		Str("version", a.buildInfo.Version).
+		Str("commit", a.buildInfo.Commit).
+		Str("build_time", a.buildInfo.BuildTime).
+		Str("go", a.buildInfo.GoVersion).
+		Str("schema", a.buildInfo.SchemaVersion).
+		Interface("features", a.buildInfo.Features).
+		Msg("📦 Build info")
+
+	// Send handler failures, poll errors and panics to Sentry (optional)
+	if err := errreport.Init(cfg.SentryDSN, cfg.SentryEnv, a.buildInfo.Version); err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to set up error reporting: %w", err)
+	}
+
+	// Health check - database, fullnode and indexer lag
+	a.checker = &health.Checker{
+		Service:       Service,
+		DB:            database,
+		Client:        a.client,
+		Listener:      listener,
+		MaxLag:        cfg.HealthMaxLag,
+		ListenerGrace: cfg.ListenerGrace,
+	}
+
+	// Autoscaling signal (KEDA metrics-api / HPA external metrics)
+	a.scaling = indexer.ScalingTargets{
+		LagPerReplica:   cfg.ScalingLagPerReplica,
+		QueuePerReplica: cfg.ScalingQueuePerReplica,
+		MaxReplicas:     cfg.ScalingMaxReplicas,
+	}
+
+	// User subscriptions: matching events are delivered to a callback URL or
+	// Telegram chat
+	a.subs = subscriptions.New(database, cfg.Notify.TelegramBotToken)
+	if err := a.subs.Load(a.ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load subscriptions, retrying in the background")
+	}
+	for _, n := range a.networks {
+		n.listener.SetSubscriptions(a.subs)
+
+		// Replay a version range through the handlers without moving the
+		// checkpoint
+		n.replayer = indexer.NewReplayer(a.ctx, n.listener)
+	}
+	a.replayer = a.networks[0].replayer
+
+	return a, nil
+}
+
+// newListener builds the event listener of network. Every network delivers
+// to the same webhooks and buses; chat announcements and the archive only
+// cover the first.
+func (a *App) newListener(client *aptos.Client, network config.Network, first bool) *indexer.EventListener {
+	cfg := a.cfg
+	listener := indexer.NewEventListener(client, a.database, network.ModuleAddress, cfg.WebhookURL)
+	listener.SetNetwork(network.Name, first)
+	listener.SetDisplayPolicy(cfg.DisplayPolicy)
+	listener.SetMarketView(cfg.MarketViewFunction)
+	listener.SetSenderFilter(cfg.Senders)
+	listener.SetEventMapper(cfg.EventMappings)
+	listener.SetReorgCheckDepth(cfg.ReorgCheckDepth)
+	listener.SetConfirmationDepth(cfg.Confirmations)
+	listener.SetPipeline(cfg.PipelineDepth, cfg.PipelineWorkers)
+	listener.SetWriteQueueStall(cfg.WriteStall)
+	listener.SetPollInterval(cfg.PollInterval)
+	listener.SetMaxPollInterval(cfg.MaxPollInterval)
+	for _, target := range cfg.WebhookTargets {
+		listener.AddWebhookTarget(target)
+	}
+	for _, publisher := range a.publishers {
+		listener.AddPublisher(publisher)
+	}
+	if cfg.Outbox {
+		listener.SetOutbox(cfg.OutboxMaxAttempts)
+	}
+	if first && a.archiver != nil {
+		listener.SetArchiver(a.archiver)
+	}
+	return listener
+}
+
+// network is the indexer of the named network, or of the first network
+// when name is empty
+func (a *App) network(name string) (*networkIndexer, error) {
+	if name == "" {
+		return a.networks[0], nil
+	}
+	for _, n := range a.networks {
+		if n.name == name {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown network %q", name)
+}
+
+func noditNetwork(network string) bool {
+	return network == "mainnet" || network == "testnet"
+}
+
+// Port is the configured INDEXER_PORT
+func (a *App) Port() string {
+	return a.cfg.Port
+}
+
+// BuildInfo is what GET /version returns
+func (a *App) BuildInfo() buildinfo.Info {
+	return a.buildInfo
+}
+
+// Auth is the route authentication for the indexer's routes: the configured
+// tokens, the built-in policies and AUTH_ROUTES
+func (a *App) Auth() auth.Config {
+	return auth.Config{
+		APITokens:  a.cfg.APITokens,
+		AdminToken: a.cfg.AdminToken,
+		Rules:      append(append([]auth.Rule{}, defaultAuthRules...), a.cfg.AuthRules...),
+	}
+}
+
+// RateLimit is the per-client rate limiting of the indexer's routes: the configured
+// budgets, the built-in classes and RATE_LIMIT_ROUTES. Clients sending one of
+// the configured tokens are limited per token.
+func (a *App) RateLimit() ratelimit.Config {
+	cfg := a.cfg.RateLimit
+	cfg.Rules = append(append([]ratelimit.Rule{}, defaultRateLimitRules...), a.cfg.RateLimit.Rules...)
+	cfg.Tokens = append([]string{}, a.cfg.APITokens...)
+	if a.cfg.AdminToken != "" {
+		cfg.Tokens = append(cfg.Tokens, a.cfg.AdminToken)
+	}
+	return cfg
+}
+
+// DebugEndpoints reports whether pprof should be served
+func (a *App) DebugEndpoints() bool {
+	return a.cfg.DebugEndpoints
+}
+
+// Start runs the event listener and the background workers until Close
+func (a *App) Start() {
+	ctx := a.ctx
+
+	// Announce market events in Discord/Telegram (optional)
+	if notifier := notify.New(a.cfg.Notify); notifier != nil {
+		a.listener.SetNotifier(notifier)
+		go notifier.Run(ctx)
+	}
+
+	go a.subs.Run(ctx)
+
+	// Publish processed events to NATS, Kafka or Redis (optional)
+	for _, publisher := range a.publishers {
+		go publisher.Run(ctx)
+	}
+
+	// Archive processed transactions to object storage (optional)
+	if a.archiver != nil {
+		go a.archiver.Run(ctx)
+	}
+
+	// Deliver webhooks and bus events recorded in the outbox. The outbox
+	// is shared, so one dispatcher delivers every network's rows.
+	if a.cfg.Outbox {
+		go a.listener.RunOutbox(ctx)
+	}
+
+	for i, n := range a.networks {
+		// Start event listener in goroutine, restarted with backoff if it
+		// fails
+		go n.listener.Supervise(ctx)
+
+		// Alert on lag and stalled polling (optional)
+		if a.cfg.AlertWebhookURL != "" {
+			service := Service
+			if i > 0 {
+				service += " (" + n.name + ")"
+			}
+			monitor := alert.NewMonitor(alert.Config{
+				URL:        a.cfg.AlertWebhookURL,
+				RoutingKey: a.cfg.AlertRoutingKey,
+				MaxLag:     a.cfg.AlertMaxLag,
+				MaxPollAge: a.cfg.AlertMaxPollAge,
+				For:        a.cfg.AlertFor,
+			}, service, n.listener)
+			go monitor.Run(ctx)
+		}
+
+		// Probe fullnode endpoints so failed ones rejoin (and the primary
+		// takes over again) once they recover
+		go n.client.RunHealthChecks(ctx, 30*time.Second)
+	}
+}
+
+// Announce publishes this instance to the configured service registry and
+// returns the function that deregisters it. Without a registry it does
+// nothing; an unknown SERVICE_REGISTRY is an error.
+func (a *App) Announce() (func(), error) {
+	if a.cfg.Registry == "" {
+		return func() {}, nil
+	}
+
+	reg, err := registry.New(a.cfg.Registry, a.database.DB, a.cfg.ConsulAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVICE_REGISTRY: %w", err)
+	}
+
+	registration := registry.Registration{
+		InstanceID: store.InstanceID("indexer-service"),
+		Service:    a.buildInfo.Service,
+		Version:    a.buildInfo.Version,
+		Commit:     a.buildInfo.Commit,
+		URL:        a.cfg.AdvertiseURL,
+		Endpoints: map[string]string{
+			"health":  "/health",
+			"status":  "/status",
+			"version": "/version",
+			"logs":    "/logs",
+		},
+	}
+
+	stop, err := registry.Start(a.ctx, reg, registration)
+	if err != nil {
+		log.Warn().Err(err).Str("registry", a.cfg.Registry).Msg("Service registration failed")
+		return func() {}, nil
+	}
+
+	log.Info().
+		Str("registry", a.cfg.Registry).
+		Str("instance", registration.InstanceID).
+		Str("url", registration.URL).
+		Msg("📇 Service registered")
+	return stop, nil
+}
+
+// Close stops the listener and workers and flushes queued log entries,
+// webhook audit records and error reports. It doesn't close the pool.
+func (a *App) Close() {
+	a.cancel()
+	deliverylog.Stop()
+	if a.cfg.LogDB {
+		logstore.Stop()
+	}
+	errreport.Flush(5 * time.Second)
+}
+
+// ReloadResult is the settings a reload applied. Key counts are set when the
+// rotator took the new keys; RestartRequired lists the settings that changed
+// but need a restart.
+type ReloadResult struct {
+	Status          string       `json:"status"`
+	WebhookURL      string       `json:"webhook_url"`
+	WebhookTargets  int          `json:"webhook_targets"`
+	PollInterval    string       `json:"poll_interval"`
+	MaxPollInterval string       `json:"max_poll_interval"`
+	SenderFilter    SenderFilter `json:"sender_filter"`
+	AptosKeys       *int         `json:"aptos_keys,omitempty"`
+	NoditKeys       *int         `json:"nodit_keys,omitempty"`
+	RestartRequired []string     `json:"restart_required,omitempty"`
+}
+
+// Reload re-reads the env file and environment and hands the settings that
+// can change at runtime to the listener and key rotator. Everything else in
+// the config still needs a restart.
+func (a *App) Reload() (*ReloadResult, error) {
+	// Overload so edited values replace the ones loaded at startup
+	if err := LoadEnv(godotenv.Overload); err != nil {
+		log.Warn().Msg("No .env file found in parent directory, reloading from system environment variables")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range a.networks {
+		n.listener.Reload(indexer.Settings{
+			WebhookURL:      cfg.WebhookURL,
+			WebhookTargets:  cfg.WebhookTargets,
+			Senders:         cfg.Senders,
+			PollInterval:    cfg.PollInterval,
+			MaxPollInterval: cfg.MaxPollInterval,
+		})
+	}
+
+	result := &ReloadResult{
+		Status:          "reloaded",
+		WebhookURL:      cfg.WebhookURL,
+		WebhookTargets:  len(cfg.WebhookTargets),
+		PollInterval:    cfg.PollInterval.String(),
+		MaxPollInterval: cfg.MaxPollInterval.String(),
+		SenderFilter: SenderFilter{
+			Allow: cfg.Senders.Allowed(),
+			Deny:  cfg.Senders.Denied(),
+		},
+	}
+
+	switch {
+	case a.rotator != nil:
+		a.rotator.SetKeys(cfg.AptosAPIKeys, cfg.NoditAPIKeys)
+		aptosKeys, noditKeys := len(cfg.AptosAPIKeys), len(cfg.NoditAPIKeys)
+		result.AptosKeys, result.NoditKeys = &aptosKeys, &noditKeys
+	case len(cfg.AptosAPIKeys) > 0 || len(cfg.NoditAPIKeys) > 0:
+		// The rotator is wired into the clients at startup
+		result.RestartRequired = []string{"APTOS_API_KEYS", "NODIT_API_KEYS"}
+	}
+
+	log.Info().Interface("result", result).Msg("🔁 Configuration reloaded")
+	return result, nil
+}
+
+// NewAptosClient uses the configured RPC endpoints in priority order, then
+// APTOS_RPC_URL (a self-hosted fullnode or localnet), and finally the
+// network's public fullnode. APTOS_RPC_RPS caps its request rate.
+func NewAptosClient(cfg *config.Config) *aptos.Client {
+	var client *aptos.Client
+	switch {
+	case len(cfg.RPCEndpoints) > 0:
+		client = aptos.NewClientWithEndpoints(cfg.RPCEndpoints)
+	case cfg.AptosRPCURL != "":
+		client = aptos.NewClientWithEndpoints([]string{cfg.AptosRPCURL})
+	default:
+		client = aptos.NewClient(cfg.AptosNetwork)
+	}
+
+	if cfg.RPCRateLimit > 0 {
+		client.SetRateLimiter(aptos.NewRateLimiter(cfg.RPCRateLimit, cfg.RPCBurst))
+	}
+	return client
+}
+
+// networkClient is the fullnode client of a network after the first: its
+// RPC URL from INDEXER_NETWORKS, or the network's public fullnode
+func networkClient(cfg *config.Config, network config.Network) (*aptos.Client, error) {
+	var client *aptos.Client
+	if network.RPCURL != "" {
+		client = aptos.NewClientWithEndpoints([]string{network.RPCURL})
+	} else {
+		if _, ok := aptos.NetworkRPCURL(network.Name); !ok {
+			return nil, fmt.Errorf("unknown network %q, give its RPC URL in INDEXER_NETWORKS", network.Name)
+		}
+		client = aptos.NewClient(network.Name)
+	}
+
+	if cfg.RPCRateLimit > 0 {
+		client.SetRateLimiter(aptos.NewRateLimiter(cfg.RPCRateLimit, cfg.RPCBurst))
+	}
+	return client, nil
+}
+
+// Custom writer to capture logs into buffer, and warn+ entries into the
+// database when LOG_DB is on. zerolog hands it the JSON line in either log
+// format; the buffer takes the level from the line itself.
+type logBufferWriter struct{}
+
+func (w *logBufferWriter) Write(p []byte) (n int, err error) {
+	logbuffer.Add("", p)
+	return len(p), nil
+}
+
+func (w *logBufferWriter) WriteLevel(level zerolog.Level, p []byte) (n int, err error) {
+	logbuffer.Add(level.String(), p)
+	logstore.Add(level, p)
+	return len(p), nil
+}
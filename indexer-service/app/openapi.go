@@ -0,0 +1,116 @@
+package app
+
+import (
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/diagnose"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/indexer-service/internal/runtimestats"
+	"github.com/verifi-protocol/indexer-service/internal/selftest"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/openapi"
+)
+
+// Query parameters shared by the list endpoints
+var (
+	limitParam  = openapi.Param{Name: "limit", Type: "integer", Description: "Page size"}
+	cursorParam = openapi.Param{Name: "cursor", Description: "next_cursor of the previous page"}
+	sinceParam  = openapi.Param{Name: "since", Description: "RFC 3339 or epoch timestamp"}
+
+	// networkParam picks one of INDEXER_NETWORKS on the admin routes
+	networkParam = openapi.Param{Name: "network", Description: "Network to act on (default: the first configured)"}
+)
+
+// ProbeOperations documents the routes Probes mounts
+func (a *App) ProbeOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: "GET", Path: "/health", Tag: "service", Summary: "Database, fullnode and lag checks; 503 when degraded", Response: HealthReport{}},
+		{Method: "GET", Path: "/healthz", Tag: "service", Summary: "Liveness", Response: Liveness{}},
+		{Method: "GET", Path: "/readyz", Tag: "service", Summary: "Readiness; 503 while the database or listener is down", Response: HealthReport{}},
+		{Method: "GET", Path: "/version", Tag: "service", Summary: "Build metadata", Response: buildinfo.Info{}},
+		{Method: "GET", Path: "/status", Tag: "service", Summary: "Listener progress, counters and freshness", Response: StatusReport{}},
+	}
+}
+
+// Operations documents the routes Routes mounts, in the same order
+func (a *App) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: "GET", Path: "/scaling", Tag: "service", Summary: "Autoscaling signal", Response: indexer.ScalingSignal{}},
+		{Method: "GET", Path: "/admin/checkpoints", Tag: "admin", Summary: "Checkpoints and their advance history",
+			Query: []openapi.Param{
+				limitParam,
+				{Name: "since", Description: "History since, RFC 3339 or epoch timestamp (default 24h ago)"},
+				{Name: "network", Description: "Only this network's history (default: all)"},
+			},
+			Response: CheckpointReport{}},
+		{Method: "GET", Path: "/admin/gaps", Tag: "admin", Summary: "Skipped version ranges and their repair progress",
+			Query: []openapi.Param{
+				limitParam,
+				{Name: "network", Description: "Only this network's gaps (default: all)"},
+				{Name: "all", Description: "Include repaired gaps (default false)"},
+			},
+			Response: VersionGapList{}},
+		{Method: "GET", Path: "/admin/unknown-events", Tag: "admin", Summary: "Module events with no schema or handler",
+			Query:    []openapi.Param{{Name: "network", Description: "Only this network's events (default: all)"}},
+			Response: UnknownEventList{}},
+		{Method: "GET", Path: "/rotator/stats", Tag: "service", Summary: "Per-key API rotator counters and quarantines",
+			Response: map[string]any{}},
+		{Method: "GET", Path: "/debug/runtime", Tag: "debug", Summary: "Goroutines, heap and GC pauses", Response: runtimestats.Stats{}},
+		{Method: "GET", Path: "/logs", Tag: "logs", Summary: "Recent in-memory log entries, newest last",
+			Query: []openapi.Param{
+				{Name: "level", Description: "Minimum level"},
+				sinceParam,
+				{Name: "q", Description: "Text search"},
+				{Name: "event", Description: "Handler event type"},
+				limitParam,
+			},
+			Response: LogList{}},
+		{Method: "GET", Path: "/logs/errors", Tag: "logs", Summary: "Persisted warn+ log entries, newest first",
+			Query: []openapi.Param{
+				{Name: "level", Description: "Minimum level: warn (default), error, fatal or panic"},
+				sinceParam,
+				{Name: "until", Description: "RFC 3339 or epoch timestamp"},
+				{Name: "q", Description: "Text search"},
+				{Name: "event", Description: "Handler event type"},
+				{Name: "instance", Description: "Only this instance"},
+				limitParam, cursorParam,
+			},
+			Response: LogRecordList{}},
+		{Method: "GET", Path: "/webhooks/deliveries", Tag: "webhooks", Summary: "Webhook delivery attempts, newest first",
+			Query: []openapi.Param{
+				{Name: "status", Description: "delivered or failed"},
+				{Name: "target", Description: "Only this target URL"},
+				{Name: "event", Description: "Only this event type"},
+				sinceParam,
+				limitParam, cursorParam,
+			},
+			Response: DeliveryList{}},
+		{Method: "GET", Path: "/webhooks/deliveries/:id", Tag: "webhooks", Summary: "Get a delivery attempt with its body",
+			Response: db.WebhookDelivery{}},
+		{Method: "POST", Path: "/webhooks/deliveries/:id/redeliver", Tag: "webhooks", Summary: "Send a recorded body to its target again",
+			Response: Redelivery{}},
+		{Method: "POST", Path: "/subscriptions", Tag: "subscriptions", Summary: "Subscribe a callback URL or Telegram chat to events",
+			Body: db.Subscription{}, Response: db.Subscription{}, Status: 201},
+		{Method: "GET", Path: "/subscriptions", Tag: "subscriptions", Summary: "List subscriptions",
+			Query:    []openapi.Param{{Name: "wallet", Description: "Only this wallet's subscriptions"}},
+			Response: SubscriptionList{}},
+		{Method: "DELETE", Path: "/subscriptions/:id", Tag: "subscriptions", Summary: "Delete a subscription", Status: 204},
+		{Method: "POST", Path: "/admin/selftest", Tag: "admin", Summary: "Run the startup self-test; 503 when it fails",
+			Response: selftest.Report{}},
+		{Method: "POST", Path: "/admin/diagnose", Tag: "admin", Summary: "Run the runbook checks, most severe first",
+			Response: diagnose.Report{}},
+		{Method: "POST", Path: "/admin/reload", Tag: "admin", Summary: "Reload webhook targets, sender filter, API keys and poll interval",
+			Response: ReloadResult{}},
+		{Method: "POST", Path: "/admin/pause", Tag: "admin", Summary: "Pause the listener",
+			Query: []openapi.Param{networkParam}, Response: ListenerState{}},
+		{Method: "POST", Path: "/admin/resume", Tag: "admin", Summary: "Resume the listener",
+			Query: []openapi.Param{networkParam}, Response: ListenerState{}},
+		{Method: "POST", Path: "/admin/set-version", Tag: "admin", Summary: "Move the checkpoint of a paused listener",
+			Query: []openapi.Param{networkParam}, Body: VersionRequest{}, Response: ListenerState{}},
+		{Method: "POST", Path: "/admin/replay", Tag: "admin", Summary: "Replay a version range without moving the checkpoint",
+			Query: []openapi.Param{networkParam}, Body: ReplayRequest{}, Response: indexer.ReplayStatus{}, Status: 202},
+		{Method: "GET", Path: "/admin/replay", Tag: "admin", Summary: "Progress of the last replay",
+			Query: []openapi.Param{networkParam}, Response: indexer.ReplayStatus{}},
+		{Method: "POST", Path: "/debug/verbose", Tag: "debug", Summary: "Toggle verbose event logging",
+			Body: VerboseRequest{}, Response: VerboseState{}},
+	}
+}
@@ -0,0 +1,780 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/archive"
+	"github.com/verifi-protocol/indexer-service/internal/bus"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/diagnose"
+	"github.com/verifi-protocol/indexer-service/internal/errreport"
+	"github.com/verifi-protocol/indexer-service/internal/health"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/indexer-service/internal/logbuffer"
+	"github.com/verifi-protocol/indexer-service/internal/runtimestats"
+	"github.com/verifi-protocol/indexer-service/internal/selftest"
+	"github.com/verifi-protocol/indexer-service/internal/subscriptions"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/page"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Built-in route policies; AUTH_ROUTES entries override them. Everything
+// else needs an API token once API_TOKENS or ADMIN_TOKEN is set.
+var defaultAuthRules = []auth.Rule{
+	{Prefix: "/health", Policy: auth.Public},
+	{Prefix: "/healthz", Policy: auth.Public},
+	{Prefix: "/readyz", Policy: auth.Public},
+	{Prefix: "/openapi.json", Policy: auth.Public},
+	{Prefix: "/admin", Policy: auth.Admin},
+	{Method: "GET", Prefix: "/admin/replay", Policy: auth.Token},
+	{Prefix: "/debug", Policy: auth.Admin},
+	{Prefix: "/webhooks", Policy: auth.Admin},
+}
+
+// Built-in rate limit classes; RATE_LIMIT_ROUTES entries override them.
+// Everything else is a read.
+var defaultRateLimitRules = []ratelimit.Rule{
+	{Prefix: "/health", Class: ratelimit.Unlimited},
+	{Prefix: "/healthz", Class: ratelimit.Unlimited},
+	{Prefix: "/readyz", Class: ratelimit.Unlimited},
+	{Method: "POST", Prefix: "/admin/replay", Class: ratelimit.Expensive},
+	{Prefix: "/admin/diagnose", Class: ratelimit.Expensive},
+	{Prefix: "/admin/selftest", Class: ratelimit.Expensive},
+}
+
+// Recover turns handler panics into 500s and reports them to Sentry
+func Recover() fiber.Handler {
+	return recover.New(recover.Config{
+		EnableStackTrace: true,
+		StackTraceHandler: func(c *fiber.Ctx, e interface{}) {
+			stack := debug.Stack()
+			log.Error().
+				Interface("panic", e).
+				Str("method", c.Method()).
+				Str("path", c.Path()).
+				Msg("💥 Recovered panic in request handler")
+			errreport.CapturePanic(e, stack, errreport.Tags{
+				"source": "http",
+				"method": c.Method(),
+				"path":   c.Path(),
+			})
+		},
+	})
+}
+
+// HealthReport is the body of /health and /readyz
+type HealthReport = health.Report
+
+// Health checks the database, fullnode and indexer lag
+func (a *App) Health(ctx context.Context) health.Report {
+	return a.checker.Check(ctx)
+}
+
+// Ready fails while the database is unreachable or the listener isn't
+// running
+func (a *App) Ready(ctx context.Context) health.Report {
+	return a.checker.Ready(ctx)
+}
+
+// SenderFilter is the sender allow and deny lists in effect
+type SenderFilter struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// StatusReport is the body of GET /status. The optional sections are left
+// out when their feature is off.
+type StatusReport struct {
+	Status         string                  `json:"status"`
+	LastVersion    uint64                  `json:"last_version"`
+	LedgerVersion  uint64                  `json:"ledger_version"`
+	SafeVersion    uint64                  `json:"safe_version"`
+	Confirmations  uint64                  `json:"confirmation_depth"`
+	Lag            uint64                  `json:"lag"`
+	LagSeconds     float64                 `json:"lag_seconds"`
+	ProcessingRate float64                 `json:"processing_rate"`
+	Network        string                  `json:"network"`
+	KnownMarkets   int                     `json:"known_markets"`
+	RPCEndpoint    string                  `json:"rpc_endpoint"`
+	RPCEndpoints   []aptos.EndpointStatus  `json:"rpc_endpoints"`
+	Paused         bool                    `json:"paused"`
+	PollInterval   string                  `json:"poll_interval"`
+	SenderFilter   SenderFilter            `json:"sender_filter"`
+	WriteQueue     indexer.WriteQueueStats `json:"write_queue"`
+	Listener       indexer.SupervisorStats `json:"listener"`
+	indexer.Stats
+	Role         string                `json:"role"` // leader or standby
+	LeaderSince  string                `json:"leader_since,omitempty"`
+	RPCRateLimit *aptos.RateLimitStats `json:"rpc_rate_limit,omitempty"`
+	EventBus     []bus.Stats           `json:"event_bus,omitempty"`
+	Archive      *archive.Stats        `json:"archive,omitempty"`
+	Outbox       *db.OutboxStats       `json:"outbox,omitempty"`
+	Networks     []NetworkStatus       `json:"networks,omitempty"`
+	aptos.Freshness
+}
+
+// NetworkStatus is the progress of one network on GET /status, listed when
+// INDEXER_NETWORKS has more than one
+type NetworkStatus struct {
+	Network       string  `json:"network"`
+	ModuleAddress string  `json:"module_address"`
+	LastVersion   uint64  `json:"last_version"`
+	LedgerVersion uint64  `json:"ledger_version"`
+	SafeVersion   uint64  `json:"safe_version"`
+	Lag           uint64  `json:"lag"`
+	LagSeconds    float64 `json:"lag_seconds"`
+	Paused        bool    `json:"paused"`
+	Role          string  `json:"role"`
+	RPCEndpoint   string  `json:"rpc_endpoint"`
+	Restarts      int64   `json:"restarts"`
+}
+
+// listenerStatus is running, or starting and restarting while Supervise
+// waits to (re)run Start
+func listenerStatus(l *indexer.EventListener) string {
+	switch {
+	case l.Running():
+		return "running"
+	case l.EverStarted():
+		return "restarting"
+	}
+	return "starting"
+}
+
+// Status is the body of GET /status
+func (a *App) Status(ctx context.Context) StatusReport {
+	listener := a.listener
+	progress := listener.Scaling(indexer.ScalingTargets{})
+	status := StatusReport{
+		Status:         listenerStatus(listener),
+		LastVersion:    listener.GetLastVersion(),
+		LedgerVersion:  progress.LedgerVersion,
+		SafeVersion:    progress.SafeVersion,
+		Confirmations:  listener.ConfirmationDepth(),
+		Lag:            progress.Lag,
+		LagSeconds:     progress.LagSeconds,
+		ProcessingRate: progress.ProcessingRate,
+		Network:        a.cfg.AptosNetwork,
+		KnownMarkets:   listener.Markets().Len(),
+		RPCEndpoint:    a.client.ActiveEndpoint(),
+		RPCEndpoints:   a.client.Endpoints(),
+		Paused:         listener.Paused(),
+		PollInterval:   listener.PollInterval().String(),
+		SenderFilter: SenderFilter{
+			Allow: listener.SenderFilter().Allowed(),
+			Deny:  listener.SenderFilter().Denied(),
+		},
+		WriteQueue: listener.WriteQueue(),
+		Listener:   listener.SupervisorStats(),
+		Stats:      listener.Stats(),
+		Role:       "standby",
+		Freshness:  a.client.Freshness(),
+	}
+	if leader, since := listener.Leader(); leader {
+		status.Role, status.LeaderSince = "leader", timeconv.Format(since)
+	}
+	if limiter := a.client.RateLimiter(); limiter != nil {
+		stats := limiter.Stats()
+		status.RPCRateLimit = &stats
+	}
+	for _, publisher := range a.publishers {
+		status.EventBus = append(status.EventBus, publisher.Stats())
+	}
+	if a.archiver != nil {
+		stats := a.archiver.Stats()
+		status.Archive = &stats
+	}
+	if a.cfg.Outbox {
+		if outbox, err := a.database.OutboxStats(ctx); err == nil {
+			status.Outbox = &outbox
+		}
+	}
+	if len(a.networks) > 1 {
+		for _, n := range a.networks {
+			progress := n.listener.Scaling(indexer.ScalingTargets{})
+			network := NetworkStatus{
+				Network:       n.name,
+				ModuleAddress: n.listener.ModuleAddress(),
+				LastVersion:   n.listener.GetLastVersion(),
+				LedgerVersion: progress.LedgerVersion,
+				SafeVersion:   progress.SafeVersion,
+				Lag:           progress.Lag,
+				LagSeconds:    progress.LagSeconds,
+				Paused:        n.listener.Paused(),
+				Role:          "standby",
+				RPCEndpoint:   n.client.ActiveEndpoint(),
+				Restarts:      n.listener.SupervisorStats().Restarts,
+			}
+			if leader, _ := n.listener.Leader(); leader {
+				network.Role = "leader"
+			}
+			status.Networks = append(status.Networks, network)
+		}
+	}
+	return status
+}
+
+// Probes mounts /health, /healthz, /readyz, /version and /status. The
+// unified binary serves its own combined versions instead.
+func (a *App) Probes(r fiber.Router) {
+	// Health check - database, fullnode and indexer lag, 503 when degraded
+	r.Get("/health", func(c *fiber.Ctx) error {
+		report := a.Health(c.Context())
+		if !report.Healthy() {
+			return c.Status(503).JSON(report)
+		}
+		return c.JSON(report)
+	})
+
+	// Kubernetes probes. /healthz only says the process is up and serving
+	// HTTP; /readyz fails while the database is unreachable or the listener
+	// isn't running, so traffic stops once its goroutine has died.
+	r.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(Liveness{Status: "healthy", Service: Service, Time: time.Now().Unix()})
+	})
+	r.Get("/readyz", func(c *fiber.Ctx) error {
+		report := a.Ready(c.Context())
+		if !report.Healthy() {
+			return c.Status(503).JSON(report)
+		}
+		return c.JSON(report)
+	})
+
+	// Version endpoint
+	r.Get("/version", func(c *fiber.Ctx) error {
+		return c.JSON(a.buildInfo)
+	})
+
+	// Status endpoint
+	r.Get("/status", func(c *fiber.Ctx) error {
+		return c.JSON(a.Status(c.Context()))
+	})
+}
+
+// List endpoint paging. The persisted lists are keyset paginated on
+// (timestamp, id); checkpoint history, version gaps and the in-memory log
+// buffer take a limit only.
+var (
+	checkpointPage = page.Options{DefaultLimit: 100, MaxLimit: 1000}
+	gapPage        = page.Options{DefaultLimit: 100, MaxLimit: 1000}
+	logPage        = page.Options{DefaultLimit: 100, MaxLimit: 500}
+	logEntryPage   = page.Options{DefaultLimit: 100, MaxLimit: 1000, Keys: 2}
+	deliveryPage   = page.Options{DefaultLimit: 100, MaxLimit: 1000, Keys: 2}
+)
+
+// Liveness is the body of GET /healthz
+type Liveness struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+	Time    int64  `json:"time"` // epoch seconds
+}
+
+// CheckpointReport is the body of GET /admin/checkpoints
+type CheckpointReport struct {
+	Checkpoints []db.Checkpoint       `json:"checkpoints"`
+	History     []db.CheckpointSample `json:"history"`
+	aptos.Freshness
+}
+
+// VersionGapList is the body of GET /admin/gaps
+type VersionGapList struct {
+	Gaps  []db.VersionGap `json:"gaps"`
+	Count int             `json:"count"`
+}
+
+// UnknownEventList is the body of GET /admin/unknown-events
+type UnknownEventList struct {
+	Events []db.UnknownEvent `json:"events"`
+	Count  int               `json:"count"`
+}
+
+// LogList is the body of GET /logs
+type LogList struct {
+	Logs   []logbuffer.LogEntry `json:"logs"`
+	Count  int                  `json:"count"`
+	Buffer logbuffer.Stats      `json:"buffer"`
+}
+
+// LogRecordList is the body of GET /logs/errors. Enabled is false when
+// LOG_DB is off, so the list is always empty.
+type LogRecordList struct {
+	Entries    []db.LogRecord `json:"entries"`
+	Count      int            `json:"count"`
+	NextCursor *string        `json:"next_cursor"`
+	Enabled    bool           `json:"enabled"`
+}
+
+// DeliveryList is the body of GET /webhooks/deliveries
+type DeliveryList struct {
+	Deliveries []db.WebhookDelivery `json:"deliveries"`
+	Count      int                  `json:"count"`
+	NextCursor *string              `json:"next_cursor"`
+}
+
+// Redelivery is the outcome of POST /webhooks/deliveries/:id/redeliver
+type Redelivery struct {
+	Target    string `json:"target"`
+	Attempt   int    `json:"attempt"`
+	Delivered bool   `json:"delivered"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SubscriptionList is the body of GET /subscriptions
+type SubscriptionList struct {
+	Subscriptions []db.Subscription `json:"subscriptions"`
+	Count         int               `json:"count"`
+}
+
+// ListenerState is the body of the pause, resume and set-version routes.
+// PreviousVersion is only set by set-version.
+type ListenerState struct {
+	Network         string  `json:"network"`
+	Status          string  `json:"status"`
+	PreviousVersion *uint64 `json:"previous_version,omitempty"`
+	LastVersion     uint64  `json:"last_version"`
+}
+
+// VersionRequest is the body of POST /admin/set-version
+type VersionRequest struct {
+	Version *uint64 `json:"version"`
+}
+
+// ReplayRequest is the body of POST /admin/replay. Handlers limits the
+// replay to those handlers; empty replays through all of them.
+type ReplayRequest struct {
+	FromVersion *uint64  `json:"from_version"`
+	ToVersion   *uint64  `json:"to_version"`
+	Handlers    []string `json:"handlers"`
+}
+
+// VerboseRequest is the body of POST /debug/verbose
+type VerboseRequest struct {
+	Passkey string `json:"passkey"`
+	Enable  bool   `json:"enable"`
+}
+
+// VerboseState is the outcome of POST /debug/verbose
+type VerboseState struct {
+	Status  string `json:"status"`
+	Verbose bool   `json:"verbose"`
+}
+
+// Routes mounts the indexer's API and admin routes
+func (a *App) Routes(r fiber.Router) {
+	database := a.database
+	listener := a.listener
+
+	r.Get("/scaling", func(c *fiber.Ctx) error {
+		return c.JSON(listener.Scaling(a.scaling))
+	})
+
+	// Checkpoints and their sampled advance history
+	r.Get("/admin/checkpoints", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, checkpointPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		since, err := page.Time(c, "since")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if since.IsZero() {
+			since = time.Now().Add(-24 * time.Hour)
+		}
+
+		checkpoints, err := database.Checkpoints(c.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load checkpoints")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load checkpoints"})
+		}
+		history, err := database.CheckpointHistory(c.Context(), c.Query("network"), since, p.Limit)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load checkpoint history")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load checkpoint history"})
+		}
+
+		return c.JSON(CheckpointReport{
+			Checkpoints: checkpoints,
+			History:     history,
+			Freshness:   a.client.Freshness(),
+		})
+	})
+
+	// Version ranges skipped by the checkpoint and their repair progress
+	r.Get("/admin/gaps", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, gapPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		gaps, err := database.VersionGaps(c.Context(), c.Query("network"), c.QueryBool("all"), p.Limit)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load version gaps")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load version gaps"})
+		}
+		return c.JSON(VersionGapList{Gaps: gaps, Count: len(gaps)})
+	})
+
+	// Module events with neither a schema nor a handler, e.g. from a
+	// contract upgrade the indexer hasn't caught up with
+	r.Get("/admin/unknown-events", func(c *fiber.Ctx) error {
+		events, err := database.UnknownEvents(c.Context(), c.Query("network"))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load unknown events")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load unknown events"})
+		}
+		return c.JSON(UnknownEventList{Events: events, Count: len(events)})
+	})
+
+	// API key rotation health - per-key success/429/error counts and quarantines
+	r.Get("/rotator/stats", func(c *fiber.Ctx) error {
+		if a.rotator == nil {
+			return c.JSON(fiber.Map{"enabled": false})
+		}
+		stats := a.rotator.GetStats()
+		stats["enabled"] = true
+		return c.JSON(stats)
+	})
+
+	// Goroutines, heap and GC pauses, admin only
+	r.Get("/debug/runtime", func(c *fiber.Ctx) error {
+		return c.JSON(runtimestats.Read())
+	})
+
+	// Recent log entries, newest last. Filters: level (minimum), since
+	// (RFC 3339 or epoch), q (text search), event (handler event type).
+	r.Get("/logs", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, logPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		since, err := page.Time(c, "since")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		query := logbuffer.Query{
+			Level:  strings.ToLower(c.Query("level")),
+			Search: c.Query("q"),
+			Event:  c.Query("event"),
+			Since:  since,
+			Limit:  p.Limit,
+		}
+		if query.Level != "" {
+			if _, err := zerolog.ParseLevel(query.Level); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "level must be one of trace, debug, info, warn, error, fatal, panic"})
+			}
+		}
+		logs := logbuffer.Find(query)
+		return c.JSON(LogList{Logs: logs, Count: len(logs), Buffer: logbuffer.GetStats()})
+	})
+
+	// Persisted warn+ entries from log_entries, newest first. Filters: level
+	// (minimum, default warn), since/until, q, event, instance.
+	r.Get("/logs/errors", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, logEntryPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter := db.LogFilter{
+			Search:   c.Query("q"),
+			Event:    c.Query("event"),
+			Instance: c.Query("instance"),
+			Page:     p,
+		}
+
+		minLevel, err := zerolog.ParseLevel(strings.ToLower(c.Query("level", "warn")))
+		if err != nil || minLevel < zerolog.WarnLevel || minLevel >= zerolog.NoLevel {
+			return c.Status(400).JSON(fiber.Map{"error": "level must be one of warn, error, fatal, panic"})
+		}
+		for level := minLevel; level <= zerolog.PanicLevel; level++ {
+			filter.Levels = append(filter.Levels, level.String())
+		}
+
+		if filter.Since, err = page.Time(c, "since"); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if filter.Until, err = page.Time(c, "until"); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		entries, err := database.LogEntries(c.Context(), filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		var next *string
+		if n := len(entries); n > 0 {
+			next = p.Next(n, page.TimeKey(entries[n-1].LoggedAt), strconv.FormatInt(entries[n-1].ID, 10))
+		}
+		return c.JSON(LogRecordList{
+			Entries:    entries,
+			Count:      len(entries),
+			NextCursor: next,
+			Enabled:    a.cfg.LogDB,
+		})
+	})
+
+	// Webhook delivery attempts, newest first. Filters: status
+	// (delivered|failed), target, event, since.
+	r.Get("/webhooks/deliveries", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, deliveryPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter := db.DeliveryFilter{
+			Target:    c.Query("target"),
+			EventType: c.Query("event"),
+			Page:      p,
+		}
+
+		status, err := page.OneOf(c, "status", "delivered", "failed")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if status != "" {
+			delivered := status == "delivered"
+			filter.Delivered = &delivered
+		}
+		if filter.Since, err = page.Time(c, "since"); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		deliveries, err := database.WebhookDeliveries(c.Context(), filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		var next *string
+		if n := len(deliveries); n > 0 {
+			next = p.Next(n, page.TimeKey(deliveries[n-1].AttemptedAt), strconv.FormatInt(deliveries[n-1].ID, 10))
+		}
+		return c.JSON(DeliveryList{Deliveries: deliveries, Count: len(deliveries), NextCursor: next})
+	})
+	r.Get("/webhooks/deliveries/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "id must be an integer"})
+		}
+		delivery, err := database.WebhookDelivery(c.Context(), int64(id))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if delivery == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "delivery not found"})
+		}
+		return c.JSON(delivery)
+	})
+
+	// Send a recorded body to its target again, now, exactly as it was
+	// first sent (admin token)
+	r.Post("/webhooks/deliveries/:id/redeliver", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "id must be an integer"})
+		}
+		delivery, err := database.WebhookDelivery(c.Context(), int64(id))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if delivery == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "delivery not found"})
+		}
+
+		client := listener.WebhookClient(delivery.Target)
+		if client == nil {
+			return c.Status(409).JSON(fiber.Map{"error": "target is no longer a configured webhook target"})
+		}
+		attempt, err := database.MaxDeliveryAttempt(c.Context(), delivery.Target, delivery.PayloadHash)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		result := Redelivery{Target: delivery.Target, Attempt: attempt + 1, Delivered: true}
+		if err := client.Resend(delivery.Payload, delivery.EventType, attempt+1); err != nil {
+			result.Delivered, result.Error = false, err.Error()
+		}
+		return c.JSON(result)
+	})
+
+	// User subscriptions: matching events are delivered to a callback URL or
+	// Telegram chat
+	subs := a.subs
+	r.Post("/subscriptions", func(c *fiber.Ctx) error {
+		var sub db.Subscription
+		if err := c.BodyParser(&sub); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "body must be {\"callback_url\" or \"telegram_chat_id\", \"market_address\"?, \"wallet_address\"?, \"event_types\"?}"})
+		}
+
+		err := subs.Create(c.Context(), &sub)
+		var invalid *subscriptions.InvalidError
+		if errors.As(err, &invalid) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(201).JSON(sub)
+	})
+	r.Get("/subscriptions", func(c *fiber.Ctx) error {
+		list := subs.List(c.Query("wallet"))
+		return c.JSON(SubscriptionList{Subscriptions: list, Count: len(list)})
+	})
+	r.Delete("/subscriptions/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "id must be an integer"})
+		}
+
+		deleted, err := subs.Delete(c.Context(), int64(id))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !deleted {
+			return c.Status(404).JSON(fiber.Map{"error": "subscription not found"})
+		}
+		return c.SendStatus(204)
+	})
+
+	// Self-test endpoint - same checks as --selftest against the live process
+	r.Post("/admin/selftest", func(c *fiber.Ctx) error {
+		runner := &selftest.Runner{Config: a.cfg, DB: database, Client: a.client}
+		report := runner.Run(c.Context())
+		if !report.Passed {
+			return c.Status(503).JSON(report)
+		}
+		return c.JSON(report)
+	})
+
+	// Diagnose endpoint - runbook checks against live state, most severe first
+	r.Post("/admin/diagnose", func(c *fiber.Ctx) error {
+		runner := &diagnose.Runner{
+			DB:       database,
+			Client:   a.client,
+			Listener: listener,
+			Rotator:  a.rotator,
+			Scaling:  a.scaling,
+		}
+		return c.JSON(runner.Run(c.Context()))
+	})
+
+	// Reload webhook targets, sender filter, API keys and poll interval
+	r.Post("/admin/reload", func(c *fiber.Ctx) error {
+		result, err := a.Reload()
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(result)
+	})
+
+	// Pause, resume and move the checkpoint of the ?network= network, the
+	// first one by default (admin token)
+	r.Post("/admin/pause", func(c *fiber.Ctx) error {
+		n, err := a.network(c.Query("network"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		n.listener.Pause()
+		return c.JSON(ListenerState{Network: n.name, Status: "paused", LastVersion: n.listener.GetLastVersion()})
+	})
+	r.Post("/admin/resume", func(c *fiber.Ctx) error {
+		n, err := a.network(c.Query("network"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		n.listener.Resume()
+		return c.JSON(ListenerState{Network: n.name, Status: "running", LastVersion: n.listener.GetLastVersion()})
+	})
+	r.Post("/admin/set-version", func(c *fiber.Ctx) error {
+		n, err := a.network(c.Query("network"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		var req VersionRequest
+		if err := c.BodyParser(&req); err != nil || req.Version == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "body must be {\"version\": <uint64>}"})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		previous, err := n.listener.SetVersion(ctx, *req.Version)
+		if errors.Is(err, indexer.ErrNotPaused) {
+			return c.Status(409).JSON(fiber.Map{"error": "pause the indexer first (POST /admin/pause)"})
+		}
+		if err != nil {
+			log.Error().Err(err).Uint64("version", *req.Version).Msg("Failed to set version")
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(ListenerState{
+			Network:         n.name,
+			Status:          "paused",
+			PreviousVersion: &previous,
+			LastVersion:     n.listener.GetLastVersion(),
+		})
+	})
+
+	// Replay a version range through the handlers without moving the
+	// checkpoint (admin token), on the ?network= network
+	r.Post("/admin/replay", func(c *fiber.Ctx) error {
+		n, err := a.network(c.Query("network"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		replayer := n.replayer
+		var req ReplayRequest
+		if err := c.BodyParser(&req); err != nil || req.FromVersion == nil || req.ToVersion == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "body must be {\"from_version\", \"to_version\", \"handlers\"?}"})
+		}
+
+		status, err := replayer.Start(*req.FromVersion, *req.ToVersion, req.Handlers)
+		if errors.Is(err, indexer.ErrReplayRunning) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error(), "replay": replayer.Status()})
+		}
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(202).JSON(status)
+	})
+	r.Get("/admin/replay", func(c *fiber.Ctx) error {
+		n, err := a.network(c.Query("network"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(n.replayer.Status())
+	})
+
+	// Debug verbose toggle endpoint
+	r.Post("/debug/verbose", func(c *fiber.Ctx) error {
+		var req VerboseRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+		}
+
+		// Check passkey from environment
+		debugPasskey := os.Getenv("DEBUG_PASSKEY")
+		if debugPasskey == "" {
+			debugPasskey = "default-debug-key" // fallback
+		}
+
+		if req.Passkey != debugPasskey {
+			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		// Toggle verbose mode
+		listener.SetVerboseMode(req.Enable)
+
+		return c.JSON(VerboseState{Status: "success", Verbose: req.Enable})
+	})
+}
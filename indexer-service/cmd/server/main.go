@@ -2,48 +2,66 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/joho/godotenv"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/verifi-protocol/indexer-service/app"
 	"github.com/verifi-protocol/indexer-service/internal/config"
-	"github.com/verifi-protocol/indexer-service/internal/db"
-	"github.com/verifi-protocol/indexer-service/internal/indexer"
-	"github.com/verifi-protocol/indexer-service/internal/logbuffer"
+	"github.com/verifi-protocol/indexer-service/internal/importer"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/logging"
+	"github.com/verifi-protocol/pkg/openapi"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/store"
 )
 
 func main() {
-	// Load environment variables from main project
-	if err := godotenv.Load("../.env"); err != nil {
-		if err := godotenv.Load("../.env.local"); err != nil {
-			log.Warn().Msg("No .env file found in parent directory, using system environment variables")
+	// The first argument names the command; without one (or when it is a
+	// flag, as in older deploy scripts) the server runs
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		printUsage()
+		if name == "help" {
+			return
 		}
+		os.Exit(2)
 	}
 
-	// Setup logger
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-
-	// Initialize log buffer for HTTP endpoint
-	logbuffer.Init(500) // Keep last 500 log entries
-
-	// Create multi-writer: console + log buffer
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr}
-	multiWriter := zerolog.MultiLevelWriter(
-		consoleWriter,
-		&logBufferWriter{},
-	)
-	log.Logger = log.Output(multiWriter)
-
-	log.Info().Msg("🎧 VeriFi Event Indexer Starting...")
+	// Load environment variables from main project (LOG_FORMAT may be set
+	// there, so report a missing file once the logger is set up)
+	envErr := app.LoadEnv(godotenv.Load)
+
+	// Setup logger: stderr (console or JSON) + log buffer. LOG_FORMAT is read
+	// here rather than in config so config errors come out in the same format.
+	logFormat = os.Getenv("LOG_FORMAT")
+	err := logging.Setup(logFormat, logging.Meta{
+		Service:  app.Service,
+		Instance: store.InstanceID("indexer-service"),
+		Version:  buildinfo.Version,
+	}, app.LogWriter())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid log format")
+	}
+	if envErr != nil {
+		log.Warn().Msg("No .env file found in parent directory, using system environment variables")
+	}
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -51,132 +69,115 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
-	// Initialize database
-	database, err := db.New(cfg.DatabaseURL)
+	if err := cmd.run(cfg, args); err != nil {
+		if errors.Is(err, errCheckFailed) {
+			os.Exit(1)
+		}
+		log.Fatal().Err(err).Str("command", cmd.name).Msg("Command failed")
+	}
+}
+
+// serve runs the indexer with its HTTP API until SIGINT or SIGTERM
+func serve(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	// Older spellings of the selftest, import and repair-timestamps commands
+	selftestMode := flags.Bool("selftest", false, "Same as the selftest command")
+	importPath := flags.String("import", "", "Same as the import command")
+	importFormat := flags.String("import-format", "", "Dump format for --import: csv or ndjson (default: from file extension)")
+	importFrom := flags.Uint64("import-from", 0, "Skip dump rows below this transaction version")
+	importTimeout := flags.Duration("import-timeout", 0, "Stop the import cleanly after this long, e.g. 30m (0 = no limit)")
+	repairTimestamps := flags.Bool("repair-timestamps", false, "Same as the repair-timestamps command")
+	flags.Parse(args)
+
+	switch {
+	case *selftestMode:
+		return runSelftest(cfg)
+	case *importPath != "":
+		return runImport(cfg, importer.Options{
+			Path:        *importPath,
+			Format:      *importFormat,
+			FromVersion: *importFrom,
+			Timeout:     *importTimeout,
+		})
+	case *repairTimestamps:
+		return repairTimestampsCmd(cfg, nil)
+	}
+
+	log.Info().Msg("🎧 VeriFi Event Indexer Starting...")
+
+	database, err := openDB(cfg)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to database")
+		return err
 	}
 	defer database.Close()
 
-	log.Info().Msg("✅ Database connected")
-
 	// Run migrations
-	if err := runMigrations(database); err != nil {
-		log.Fatal().Err(err).Msg("Failed to run migrations")
+	if err := app.Migrate(context.Background(), database.DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	// Initialize Aptos client
-	aptosClient := indexer.NewClient(cfg.AptosNetwork)
-	log.Info().Str("network", cfg.AptosNetwork).Msg("✅ Aptos client initialized")
-
-	// Initialize API key rotator if keys are provided
-	if len(cfg.AptosAPIKeys) > 0 || len(cfg.NoditAPIKeys) > 0 {
-		rotator := indexer.NewAPIKeyRotator(cfg.AptosAPIKeys, cfg.NoditAPIKeys)
-		aptosClient.SetAPIRotator(rotator)
-		log.Info().
-			Int("aptos_keys", len(cfg.AptosAPIKeys)).
-			Int("nodit_keys", len(cfg.NoditAPIKeys)).
-			Msg("✅ API key rotation enabled")
+	indexerApp, err := app.New(cfg, database)
+	if err != nil {
+		return fmt.Errorf("failed to start indexer: %w", err)
 	}
 
-	// Initialize event listener
-	listener := indexer.NewEventListener(aptosClient, database, cfg.ModuleAddress, cfg.WebhookURL)
-
 	// Setup Fiber app
-	app := fiber.New(fiber.Config{
+	server := fiber.New(fiber.Config{
 		AppName:      "VeriFi Event Indexer",
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	})
 
 	// Middleware
-	app.Use(recover.New())
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
+	server.Use(app.Recover())
+	server.Use(logging.Requests(logFormat))
+	server.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST",
 	}))
+	server.Use(ratelimit.New(indexerApp.RateLimit()))
+	server.Use(auth.New(indexerApp.Auth()))
+
+	// Profiling for the deployed binary; fetch a profile with the admin token
+	// and open it with go tool pprof
+	if indexerApp.DebugEndpoints() {
+		server.Use(pprof.New())
+		log.Warn().Msg("🔬 pprof enabled under /debug/pprof")
+	}
 
-	// Health check
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "healthy",
-			"service": "verifi-indexer-service",
-			"time":    time.Now().Unix(),
-		})
-	})
-
-	// Status endpoint
-	app.Get("/status", func(c *fiber.Ctx) error {
-		version := listener.GetLastVersion()
-		return c.JSON(fiber.Map{
-			"status":       "running",
-			"last_version": version,
-			"network":      cfg.AptosNetwork,
-		})
-	})
-
-	// Logs endpoint - returns recent logs
-	app.Get("/logs", func(c *fiber.Ctx) error {
-		// Get limit from query param, default 100
-		limit := c.QueryInt("limit", 100)
-		if limit > 500 {
-			limit = 500
-		}
-
-		logs := logbuffer.GetRecent(limit)
-		return c.JSON(fiber.Map{
-			"logs":  logs,
-			"count": len(logs),
-		})
-	})
-
-	// Debug verbose toggle endpoint
-	app.Post("/debug/verbose", func(c *fiber.Ctx) error {
-		type VerboseRequest struct {
-			Passkey string `json:"passkey"`
-			Enable  bool   `json:"enable"`
-		}
-
-		var req VerboseRequest
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
-		}
-
-		// Check passkey from environment
-		debugPasskey := os.Getenv("DEBUG_PASSKEY")
-		if debugPasskey == "" {
-			debugPasskey = "default-debug-key" // fallback
-		}
-
-		if req.Passkey != debugPasskey {
-			return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
-		}
+	indexerApp.Probes(server)
+	indexerApp.Routes(server)
 
-		// Toggle verbose mode
-		listener.SetVerboseMode(req.Enable)
-
-		return c.JSON(fiber.Map{
-			"status":  "success",
-			"verbose": req.Enable,
-		})
-	})
+	// OpenAPI document of every route, for generated clients
+	ops := append(indexerApp.ProbeOperations(), indexerApp.Operations()...)
+	ops = append(ops, openapi.Operation{Method: "GET", Path: "/openapi.json", Tag: "service", Summary: "This document"})
+	server.Get("/openapi.json", openapi.Handler(openapi.Build(app.Service, buildinfo.Version, ops, indexerApp.Auth().Policy)))
 
 	// Start server in goroutine
 	go func() {
 		log.Info().Msgf("🌐 Server listening on :%s", cfg.Port)
-		if err := app.Listen(":" + cfg.Port); err != nil {
+		if err := server.Listen(":" + cfg.Port); err != nil {
 			log.Fatal().Err(err).Msg("Failed to start server")
 		}
 	}()
 
-	// Start event listener in goroutine
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Listener, webhooks, bus publishers, alerting and fullnode probes
+	indexerApp.Start()
+
+	// Announce this instance to the service registry (optional)
+	deregister, err := indexerApp.Announce()
+	if err != nil {
+		return fmt.Errorf("invalid SERVICE_REGISTRY: %w", err)
+	}
 
+	// SIGHUP reloads the same settings as POST /admin/reload
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		if err := listener.Start(ctx); err != nil {
-			log.Error().Err(err).Msg("Event listener error")
+		for range hup {
+			if _, err := indexerApp.Reload(); err != nil {
+				log.Error().Err(err).Msg("Config reload failed, keeping current settings")
+			}
 		}
 	}()
 
@@ -186,48 +187,13 @@ func main() {
 	<-quit
 
 	log.Info().Msg("🛑 Shutting down indexer...")
-	cancel() // Stop event listener
+	deregister()
 
-	if err := app.Shutdown(); err != nil {
+	if err := server.Shutdown(); err != nil {
 		log.Error().Err(err).Msg("Server shutdown error")
 	}
+	indexerApp.Close() // Stop event listener, flush logs and reports
 
 	log.Info().Msg("✅ Indexer stopped")
-}
-
-func runMigrations(database *db.DB) error {
-	log.Info().Msg("🔄 Running migrations...")
-
-	migration := `
-	CREATE TABLE IF NOT EXISTS sync_state (
-		key VARCHAR(255) PRIMARY KEY,
-		value TEXT NOT NULL,
-		updated_at TIMESTAMP DEFAULT NOW()
-	);
-
-	INSERT INTO sync_state (key, value, updated_at)
-	VALUES ('last_indexed_version', '0', NOW())
-	ON CONFLICT (key) DO NOTHING;
-	`
-
-	_, err := database.Pool().Exec(context.Background(), migration)
-	if err != nil {
-		return err
-	}
-
-	log.Info().Msg("✅ Migrations complete")
 	return nil
 }
-
-// Custom writer to capture logs into buffer
-type logBufferWriter struct{}
-
-func (w *logBufferWriter) Write(p []byte) (n int, err error) {
-	logbuffer.Add("INFO", string(p))
-	return len(p), nil
-}
-
-func (w *logBufferWriter) WriteLevel(level zerolog.Level, p []byte) (n int, err error) {
-	logbuffer.Add(level.String(), string(p))
-	return len(p), nil
-}
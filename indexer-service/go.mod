@@ -7,6 +7,7 @@ require (
 	github.com/jackc/pgx/v5 v5.5.1
 	github.com/joho/godotenv v1.5.1
 	github.com/rs/zerolog v1.31.0
+	github.com/verifi-protocol/pkg v0.0.0
 )
 
 require (
@@ -28,3 +29,5 @@ require (
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
+
+replace github.com/verifi-protocol/pkg => ../pkg
@@ -0,0 +1,221 @@
+// Package alert watches indexer lag and poll health and posts to an alert
+// webhook when a threshold has been exceeded for a while, and again once it
+// recovers. Slack, Discord and PagerDuty URLs get their native payload; any
+// other URL gets the same JSON body the sync service's alerts use.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Conditions are evaluated this often
+const checkInterval = 30 * time.Second
+
+const (
+	FormatGeneric   = "generic"
+	FormatSlack     = "slack"
+	FormatDiscord   = "discord"
+	FormatPagerDuty = "pagerduty"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Config holds the alert thresholds. A zero MaxLag or MaxPollAge disables
+// that check.
+type Config struct {
+	URL        string
+	RoutingKey string        // PagerDuty integration key
+	MaxLag     uint64        // versions behind the ledger head
+	MaxPollAge time.Duration // since the last successful poll
+	For        time.Duration // how long a condition must hold before firing
+}
+
+// Format picks the payload format from the URL
+func (c Config) Format() string {
+	switch {
+	case strings.Contains(c.URL, "hooks.slack.com"):
+		return FormatSlack
+	case strings.Contains(c.URL, "discord.com/api/webhooks"), strings.Contains(c.URL, "discordapp.com/api/webhooks"):
+		return FormatDiscord
+	case strings.HasPrefix(c.URL, pagerDutyEventsURL):
+		return FormatPagerDuty
+	default:
+		return FormatGeneric
+	}
+}
+
+// condition is one watched threshold
+type condition struct {
+	name    string
+	since   time.Time // when it started failing, zero while fine
+	firing  bool
+	summary string
+}
+
+type Monitor struct {
+	cfg      Config
+	format   string
+	service  string
+	listener *indexer.EventListener
+	client   *http.Client
+
+	lag   condition
+	stall condition
+}
+
+func NewMonitor(cfg Config, service string, listener *indexer.EventListener) *Monitor {
+	return &Monitor{
+		cfg:      cfg,
+		format:   cfg.Format(),
+		service:  service,
+		listener: listener,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		lag:      condition{name: "indexer_lag"},
+		stall:    condition{name: "poll_stalled"},
+	}
+}
+
+// Run checks the conditions until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	log.Info().
+		Str("format", m.format).
+		Uint64("max_lag", m.cfg.MaxLag).
+		Dur("max_poll_age", m.cfg.MaxPollAge).
+		Dur("for", m.cfg.For).
+		Msg("🚨 Lag alerting enabled")
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	now := time.Now()
+
+	// A paused listener isn't expected to advance. One that has stopped is:
+	// its last poll keeps aging, so a dead listener raises poll_stalled.
+	if m.listener.Paused() || m.listener.LastPoll().IsZero() {
+		return
+	}
+	signal := m.listener.Scaling(indexer.ScalingTargets{})
+
+	// A standby instance doesn't track the ledger head, its lag is stale
+	lagging := m.cfg.MaxLag > 0 && !signal.Standby && signal.Lag > m.cfg.MaxLag
+	m.update(ctx, &m.lag, now, lagging, fmt.Sprintf(
+		"Indexer is %d versions behind the ledger head (threshold %d, last version %d)",
+		signal.Lag, m.cfg.MaxLag, signal.LastVersion))
+
+	pollAge := now.Sub(m.listener.LastPoll())
+	stalled := m.cfg.MaxPollAge > 0 && pollAge > m.cfg.MaxPollAge
+	m.update(ctx, &m.stall, now, stalled, fmt.Sprintf(
+		"No successful poll for %s (threshold %s)",
+		pollAge.Round(time.Second), m.cfg.MaxPollAge))
+}
+
+// update fires c once failing has held for cfg.For and resolves it on the
+// first check where it doesn't
+func (m *Monitor) update(ctx context.Context, c *condition, now time.Time, failing bool, summary string) {
+	if !failing {
+		if c.firing {
+			c.firing = false
+			m.send(ctx, c, false, c.summary)
+		}
+		c.since = time.Time{}
+		return
+	}
+
+	if c.since.IsZero() {
+		c.since = now
+	}
+	c.summary = summary
+	if !c.firing && now.Sub(c.since) >= m.cfg.For {
+		c.firing = true
+		m.send(ctx, c, true, summary)
+	}
+}
+
+func (m *Monitor) send(ctx context.Context, c *condition, firing bool, summary string) {
+	if firing {
+		log.Warn().Str("alert", c.name).Msg("🚨 Alert firing: " + summary)
+	} else {
+		log.Info().Str("alert", c.name).Msg("✅ Alert resolved: " + summary)
+	}
+
+	body, err := json.Marshal(m.payload(c, firing, summary))
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", m.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create alert request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Alert delivery failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Int("status", resp.StatusCode).Msg("Alert delivery failed")
+	}
+}
+
+func (m *Monitor) payload(c *condition, firing bool, summary string) interface{} {
+	status := "resolved"
+	if firing {
+		status = "firing"
+	}
+	text := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(status), m.service, summary)
+
+	switch m.format {
+	case FormatSlack:
+		return map[string]interface{}{"text": text}
+	case FormatDiscord:
+		return map[string]interface{}{"content": text}
+	case FormatPagerDuty:
+		action := "resolve"
+		if firing {
+			action = "trigger"
+		}
+		return map[string]interface{}{
+			"routing_key":  m.cfg.RoutingKey,
+			"event_action": action,
+			"dedup_key":    m.service + ":" + c.name,
+			"payload": map[string]interface{}{
+				"summary":  summary,
+				"source":   m.service,
+				"severity": "error",
+			},
+		}
+	default:
+		return map[string]interface{}{
+			"service": m.service,
+			"alert":   c.name,
+			"status":  status,
+			"details": map[string]interface{}{"summary": summary, "since": timeconv.Format(c.since)},
+			"time":    timeconv.Now(),
+		}
+	}
+}
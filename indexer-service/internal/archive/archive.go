@@ -0,0 +1,324 @@
+// Package archive writes the transactions the indexer processed to object
+// storage as gzipped newline-delimited JSON, one object per contiguous
+// version range, partitioned by UTC date. The objects form a replayable data
+// lake that doesn't depend on Postgres. S3 and S3-compatible stores (GCS
+// through its XML API with HMAC keys, MinIO) are spoken natively with
+// Signature V4; a local directory is supported for development.
+//
+// Like the event bus, archiving is at most once: batches are buffered in
+// memory and uploaded in the background, and a range whose upload keeps
+// failing is logged and skipped. The range is archived again when it is
+// re-indexed.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+const (
+	KindS3   = "s3"
+	KindGCS  = "gs"
+	KindFile = "file"
+)
+
+const (
+	// Batches waiting to be archived; more are dropped
+	queueSize = 100
+
+	uploadAttempts = 3
+	uploadTimeout  = time.Minute
+)
+
+// Config selects the archive. An empty URL disables it.
+type Config struct {
+	// s3://bucket/prefix, gs://bucket/prefix or file:///path/to/dir
+	URL string
+	// S3 API base URL; defaults to AWS for s3:// and
+	// https://storage.googleapis.com for gs://
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// An object is written once it holds this many transactions, after
+	// FlushInterval, or when the UTC date changes, whichever comes first
+	FlushTransactions int
+	FlushInterval     time.Duration
+}
+
+// Batch is one committed range of versions and the transactions in it that
+// emitted module events
+type Batch struct {
+	Start, End   uint64
+	Transactions []aptos.TransactionEvent
+}
+
+// Stats are archiving totals since startup
+type Stats struct {
+	URL          string `json:"url"`
+	Objects      int64  `json:"objects"`
+	Transactions int64  `json:"transactions"`
+	Failed       int64  `json:"failed"`  // objects whose upload failed
+	Dropped      int64  `json:"dropped"` // batches, queue full
+	Queued       int    `json:"queued"`
+	LastVersion  uint64 `json:"last_version"` // end of the last archived range
+}
+
+// store puts one object
+type store interface {
+	put(ctx context.Context, key string, body []byte) error
+}
+
+type Archiver struct {
+	cfg    Config
+	prefix string
+	store  store
+	queue  chan Batch
+
+	objects      atomic.Int64
+	transactions atomic.Int64
+	failed       atomic.Int64
+	dropped      atomic.Int64
+	lastVersion  atomic.Uint64
+}
+
+// Validate checks cfg without connecting
+func (c Config) Validate() error {
+	if c.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	switch u.Scheme {
+	case KindS3, KindGCS:
+		if u.Host == "" {
+			return fmt.Errorf("URL must name a bucket, e.g. %s://bucket/prefix", u.Scheme)
+		}
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("access key ID and secret are required for %s://", u.Scheme)
+		}
+		if c.Endpoint != "" {
+			if e, err := url.Parse(c.Endpoint); err != nil || e.Host == "" || (e.Scheme != "http" && e.Scheme != "https") {
+				return fmt.Errorf("endpoint must be an http(s) URL")
+			}
+		}
+	case KindFile:
+		if u.Path == "" {
+			return fmt.Errorf("file URL must look like file:///path/to/dir")
+		}
+	default:
+		return fmt.Errorf("URL must start with s3://, gs:// or file://")
+	}
+	if c.FlushTransactions <= 0 || c.FlushInterval <= 0 {
+		return fmt.Errorf("flush size and interval must be positive")
+	}
+	return nil
+}
+
+// New returns an archiver for cfg, or nil when archiving is disabled
+func New(cfg Config) (*Archiver, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	u, _ := url.Parse(cfg.URL) // checked by Validate
+	a := &Archiver{
+		cfg:    cfg,
+		prefix: strings.Trim(u.Path, "/"),
+		queue:  make(chan Batch, queueSize),
+	}
+	switch u.Scheme {
+	case KindS3, KindGCS:
+		endpoint, region := cfg.Endpoint, cfg.Region
+		if u.Scheme == KindGCS {
+			if endpoint == "" {
+				endpoint = "https://storage.googleapis.com"
+			}
+			if region == "" {
+				region = "auto"
+			}
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		a.store = newS3(endpoint, u.Host, region, cfg.AccessKeyID, cfg.SecretAccessKey)
+	case KindFile:
+		a.prefix = ""
+		a.store = newFileStore(u.Path)
+	}
+	return a, nil
+}
+
+// Archive queues a committed batch. A nil archiver drops it.
+func (a *Archiver) Archive(batch Batch) {
+	if a == nil {
+		return
+	}
+
+	select {
+	case a.queue <- batch:
+	default:
+		if a.dropped.Add(1) == 1 {
+			log.Warn().Uint64("start", batch.Start).Uint64("end", batch.End).Msg("Archive queue full, dropping batches")
+		}
+	}
+}
+
+// Run collects queued batches into objects and uploads them until ctx is
+// cancelled
+func (a *Archiver) Run(ctx context.Context) {
+	log.Info().Str("archive", a.cfg.URL).Msg("🗄️  Archiving processed transactions")
+
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []Batch
+	count := 0
+	flush := func(ctx context.Context) {
+		if len(pending) > 0 {
+			a.upload(ctx, pending)
+		}
+		pending, count = nil, 0
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Upload what is in hand; the queue itself is dropped on shutdown
+			flush(context.Background())
+			return
+		case batch := <-a.queue:
+			// An object covers one contiguous range on one date
+			if len(pending) > 0 {
+				last := pending[len(pending)-1]
+				if batch.Start != last.End+1 || batchDate(batch) != batchDate(pending[0]) {
+					flush(ctx)
+				}
+			}
+			pending = append(pending, batch)
+			count += len(batch.Transactions)
+			if count < a.cfg.FlushTransactions {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		flush(ctx)
+	}
+}
+
+// upload writes batches as one object, retrying with backoff
+func (a *Archiver) upload(ctx context.Context, batches []Batch) {
+	start, end := batches[0].Start, batches[len(batches)-1].End
+	key := a.key(batchDate(batches[0]), start, end)
+
+	body, n, err := encode(batches)
+	if err != nil {
+		a.failed.Add(1)
+		log.Error().Err(err).Uint64("start", start).Uint64("end", end).Msg("Failed to encode archive object")
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		putCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
+		err = a.store.put(putCtx, key, body)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt == uploadAttempts || ctx.Err() != nil {
+			a.failed.Add(1)
+			log.Error().
+				Err(err).
+				Str("key", key).
+				Uint64("start", start).
+				Uint64("end", end).
+				Msg("❌ Archive upload failed, range not archived")
+			return
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+		}
+	}
+
+	a.objects.Add(1)
+	a.transactions.Add(int64(n))
+	a.lastVersion.Store(end)
+	log.Debug().Str("key", key).Int("transactions", n).Msg("Archive object written")
+}
+
+// key is prefix/dt=YYYY-MM-DD/<start>-<end>.ndjson.gz, with versions zero
+// padded so keys sort by version
+func (a *Archiver) key(date string, start, end uint64) string {
+	name := fmt.Sprintf("dt=%s/%020d-%020d.ndjson.gz", date, start, end)
+	if a.prefix == "" {
+		return name
+	}
+	return a.prefix + "/" + name
+}
+
+// Stats returns archiving totals
+func (a *Archiver) Stats() Stats {
+	return Stats{
+		URL:          a.cfg.URL,
+		Objects:      a.objects.Load(),
+		Transactions: a.transactions.Load(),
+		Failed:       a.failed.Load(),
+		Dropped:      a.dropped.Load(),
+		Queued:       len(a.queue),
+		LastVersion:  a.lastVersion.Load(),
+	}
+}
+
+// encode gzips one JSON transaction per line
+func encode(batches []Batch) ([]byte, int, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	n := 0
+	for _, batch := range batches {
+		for _, tx := range batch.Transactions {
+			if err := enc.Encode(tx); err != nil {
+				return nil, 0, err
+			}
+			n++
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), n, nil
+}
+
+// batchDate is the UTC date of a batch's first transaction, or today when it
+// has none
+func batchDate(batch Batch) string {
+	t := time.Now()
+	if len(batch.Transactions) > 0 {
+		if parsed, err := timeconv.Parse(batch.Transactions[0].Timestamp); err == nil {
+			t = parsed
+		}
+	}
+	return t.UTC().Format(time.DateOnly)
+}
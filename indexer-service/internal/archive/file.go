@@ -0,0 +1,31 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// fileStore writes objects under a local directory, for development and for
+// stores mounted as a filesystem
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(dir string) *fileStore {
+	return &fileStore{dir: dir}
+}
+
+// put writes to a temporary file and renames it, so readers never see a
+// partial object
+func (f *fileStore) put(_ context.Context, key string, body []byte) error {
+	path := filepath.Join(f.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
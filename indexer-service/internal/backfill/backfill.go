@@ -0,0 +1,236 @@
+// Package backfill rebuilds long version ranges with several workers at
+// once. A job splits the range into shards; worker processes claim shards
+// with a lease and keep a checkpoint per shard, and once every shard is done
+// a merge applies the events a shard had to defer.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+const (
+	// Transactions fetched and committed per shard batch
+	batchSize = 100
+
+	// A shard is renewed with every batch; one whose worker stopped
+	// renewing is claimed by another worker after this long
+	shardLease = 2 * time.Minute
+)
+
+var errShardLost = errors.New("shard lease lost to another worker")
+
+// Plan creates a job splitting from..to into shards of shardSize versions
+func Plan(ctx context.Context, database *db.DB, from, to, shardSize uint64) (db.BackfillJob, error) {
+	if from > to {
+		return db.BackfillJob{}, fmt.Errorf("from_version must not be above to_version")
+	}
+	if shardSize == 0 {
+		return db.BackfillJob{}, fmt.Errorf("shard size must be positive")
+	}
+	return database.CreateBackfillJob(ctx, from, to, shardSize)
+}
+
+// Result is what one worker did
+type Result struct {
+	Shards       int  `json:"shards"`       // shards finished by this worker
+	Transactions int  `json:"transactions"` // fetched, with or without module events
+	Merged       bool `json:"merged"`       // this worker ran the merge
+	Deferred     int  `json:"deferred"`     // versions the merge applied
+}
+
+// NewListener builds a listener configured like the live one (sender
+// filter, market view, no webhooks). Every call must return a new listener.
+type NewListener func() *indexer.EventListener
+
+// Worker claims shards of a job and runs them through its listener
+type Worker struct {
+	db          *db.DB
+	client      *aptos.Client
+	newListener NewListener
+	listener    *indexer.EventListener
+	owner       string
+}
+
+// NewWorker creates a worker that identifies itself as owner in the shard
+// leases. Its listener defers events of markets not indexed yet, and its
+// batches are fenced by the shard lease rather than the writer leases.
+func NewWorker(database *db.DB, client *aptos.Client, newListener NewListener, owner string) *Worker {
+	listener := newListener()
+	listener.SetDeferUnknownMarkets(true)
+	listener.SetUnfenced(true)
+	return &Worker{db: database, client: client, newListener: newListener, listener: listener, owner: owner}
+}
+
+// Run processes shards of the job until none is left to claim, then runs
+// the merge if every shard is done and no other worker started it
+func (w *Worker) Run(ctx context.Context, jobID int64) (Result, error) {
+	var result Result
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		shard, err := w.db.ClaimBackfillShard(ctx, jobID, w.owner, shardLease)
+		if err != nil {
+			return result, fmt.Errorf("failed to claim shard: %w", err)
+		}
+		if shard == nil {
+			break
+		}
+
+		txs, err := w.runShard(ctx, *shard)
+		result.Transactions += txs
+		if err != nil {
+			release, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := w.db.ReleaseBackfillShard(release, jobID, shard.Shard, w.owner, err.Error()); err != nil {
+				log.Warn().Err(err).Int("shard", shard.Shard).Msg("Failed to release backfill shard")
+			}
+			cancel()
+			return result, fmt.Errorf("shard %d: %w", shard.Shard, err)
+		}
+		result.Shards++
+	}
+
+	started, err := w.db.StartBackfillMerge(ctx, jobID, false)
+	if err != nil || !started {
+		return result, err
+	}
+	result.Merged = true
+	result.Deferred, err = merge(ctx, w.db, w.client, w.newListener(), jobID)
+	return result, err
+}
+
+// runShard processes the shard from its checkpoint to its end, moving the
+// checkpoint with every batch
+func (w *Worker) runShard(ctx context.Context, shard db.BackfillShard) (int, error) {
+	log.Info().
+		Int64("job", shard.JobID).
+		Int("shard", shard.Shard).
+		Uint64("from", shard.Next()).
+		Uint64("to", shard.ToVersion).
+		Msg("🧩 Backfill shard claimed")
+
+	processed := 0
+	for start := shard.Next(); start <= shard.ToVersion; {
+		if err := ctx.Err(); err != nil {
+			return processed, err
+		}
+
+		limit := min(uint64(batchSize), shard.ToVersion-start+1)
+		txs, err := w.client.GetTransactionsByVersionRange(ctx, start, limit)
+		if err != nil {
+			return processed, fmt.Errorf("failed to fetch transactions from %d: %w", start, err)
+		}
+
+		// Versions the fullnode left out are recorded as gaps, so moving
+		// the checkpoint past them doesn't lose them
+		checkpoint := start + limit - 1
+		err = w.listener.ProcessRangeWith(ctx, start, checkpoint, txs, func(ctx context.Context, q pgx.Tx) error {
+			held, err := db.SaveShardCheckpoint(ctx, q, shard.JobID, shard.Shard, w.owner, checkpoint, shardLease)
+			if err != nil {
+				return err
+			}
+			if !held {
+				return errShardLost
+			}
+			return nil
+		})
+		if err != nil {
+			return processed, err
+		}
+
+		processed += len(txs)
+		start += limit
+	}
+
+	log.Info().
+		Int64("job", shard.JobID).
+		Int("shard", shard.Shard).
+		Int("transactions", processed).
+		Msg("✅ Backfill shard done")
+	return processed, nil
+}
+
+// Merge applies the job's deferred events once every shard is done. force
+// takes over a merge another worker left unfinished.
+func Merge(ctx context.Context, database *db.DB, client *aptos.Client, newListener NewListener, jobID int64, force bool) (int, error) {
+	started, err := database.StartBackfillMerge(ctx, jobID, force)
+	if err != nil {
+		return 0, err
+	}
+	if !started {
+		return 0, fmt.Errorf("job %d has open shards, is already merging or is complete", jobID)
+	}
+	return merge(ctx, database, client, newListener(), jobID)
+}
+
+// merge re-runs every version with deferred events, lowest first, through
+// a listener that quarantines as usual: by now each shard has written its
+// markets, so only markets that never existed are left unknown. Events of
+// those versions that were applied already are skipped by their claims.
+// The job's merge claim, not the writer leases, guards its batches.
+func merge(ctx context.Context, database *db.DB, client *aptos.Client, listener *indexer.EventListener, jobID int64) (applied int, err error) {
+	listener.SetUnfenced(true)
+	defer func() {
+		finish, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if finishErr := database.FinishBackfillMerge(finish, jobID, err == nil); finishErr != nil && err == nil {
+			err = finishErr
+		}
+	}()
+
+	job, err := database.GetBackfillJob(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	if job == nil {
+		return 0, fmt.Errorf("job %d not found", jobID)
+	}
+
+	versions, err := database.DeferredVersions(ctx, listener.Network(), job.FromVersion, job.ToVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load deferred events: %w", err)
+	}
+
+	log.Info().
+		Int64("job", jobID).
+		Int("versions", len(versions)).
+		Msg("🔗 Merging backfill: applying deferred events")
+
+	for start := 0; start < len(versions); start += batchSize {
+		batch := versions[start:min(start+batchSize, len(versions))]
+
+		txs := make([]aptos.TransactionEvent, 0, len(batch))
+		for _, version := range batch {
+			tx, err := client.GetTransactionByVersion(ctx, version)
+			if err != nil {
+				return applied, fmt.Errorf("failed to fetch transaction %d: %w", version, err)
+			}
+			txs = append(txs, *tx)
+		}
+
+		err := listener.ProcessTransactionsWith(ctx, txs, func(ctx context.Context, q pgx.Tx) error {
+			return db.DeleteDeferredEvents(ctx, q, listener.Network(), batch)
+		})
+		if err != nil {
+			return applied, err
+		}
+		applied += len(batch)
+	}
+
+	log.Info().
+		Int64("job", jobID).
+		Uint64("from", job.FromVersion).
+		Uint64("to", job.ToVersion).
+		Msg("✅ Backfill complete")
+	return applied, nil
+}
@@ -0,0 +1,212 @@
+// Package bus publishes every processed module event to a message bus, so
+// downstream consumers get a streaming feed without reading Postgres. NATS
+// and Redis pub/sub are spoken natively; Kafka is reached through a Kafka
+// REST Proxy. Events
+// are queued and published in the background, at most once: a full queue
+// or a failed publish drops them after logging.
+package bus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	KindNATS  = "nats"
+	KindKafka = "kafka"
+	KindRedis = "redis"
+)
+
+const (
+	// Messages waiting to be published; more are dropped
+	queueSize = 10000
+
+	batchSize      = 100
+	flushInterval  = 200 * time.Millisecond
+	publishTimeout = 10 * time.Second
+)
+
+// Config selects the bus. An empty Kind disables publishing.
+type Config struct {
+	Kind  string // nats, kafka or redis
+	URL   string // nats://[user:pass@]host:4222, the Kafka REST Proxy base URL, or redis[s]://[user:pass@]host:6379
+	Topic string // Kafka topic, NATS subject prefix (the event name is appended) or Redis channel prefix (the market address is appended)
+}
+
+// Message is one processed event as published
+type Message struct {
+	Event      string                 `json:"event"` // e.g. SharesMintedEvent
+	Type       string                 `json:"type"`  // fully qualified Move type
+	Market     string                 `json:"market_address,omitempty"`
+	TxHash     string                 `json:"tx_hash"`
+	Version    string                 `json:"version"`
+	EventIndex int                    `json:"event_index"`
+	Sender     string                 `json:"sender"`
+	Timestamp  string                 `json:"timestamp"` // RFC 3339
+	Data       map[string]interface{} `json:"data"`
+	Network    string                 `json:"network,omitempty"`
+}
+
+// Stats are publishing totals since startup
+type Stats struct {
+	Kind      string `json:"kind"`
+	Topic     string `json:"topic"`
+	Published int64  `json:"published"`
+	Failed    int64  `json:"failed"`
+	Dropped   int64  `json:"dropped"` // queue full
+	Queued    int    `json:"queued"`
+}
+
+// transport sends one batch of messages
+type transport interface {
+	publish(ctx context.Context, msgs []Message) error
+	close()
+}
+
+type Publisher struct {
+	cfg       Config
+	transport transport
+	queue     chan Message
+
+	published atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+}
+
+// Validate checks cfg without connecting
+func (c Config) Validate() error {
+	switch c.Kind {
+	case "":
+		return nil
+	case KindNATS, KindKafka, KindRedis:
+	default:
+		return fmt.Errorf("must be nats or kafka")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("URL is required for %s", c.Kind)
+	}
+	if c.Kind == KindNATS && u.Scheme != "nats" {
+		return fmt.Errorf("NATS URL must look like nats://host:4222")
+	}
+	if c.Kind == KindKafka && u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Kafka URL must be the http(s) address of a Kafka REST Proxy")
+	}
+	if c.Kind == KindRedis && u.Scheme != "redis" && u.Scheme != "rediss" {
+		return fmt.Errorf("Redis URL must look like redis://host:6379")
+	}
+	if strings.TrimSpace(c.Topic) == "" {
+		return fmt.Errorf("topic is required")
+	}
+	return nil
+}
+
+// New returns a publisher for cfg, or nil when no bus is configured
+func New(cfg Config) (*Publisher, error) {
+	if cfg.Kind == "" {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{cfg: cfg, queue: make(chan Message, queueSize)}
+	switch cfg.Kind {
+	case KindNATS:
+		p.transport = newNATS(cfg.URL, cfg.Topic)
+	case KindKafka:
+		p.transport = newKafka(cfg.URL, cfg.Topic)
+	case KindRedis:
+		p.transport = newRedis(cfg.URL, cfg.Topic)
+	}
+	return p, nil
+}
+
+// Publish queues msg. A nil publisher drops it.
+func (p *Publisher) Publish(msg Message) {
+	if p == nil {
+		return
+	}
+
+	select {
+	case p.queue <- msg:
+	default:
+		if p.dropped.Add(1) == 1 {
+			log.Warn().Str("bus", p.cfg.Kind).Msg("Event bus queue full, dropping events")
+		}
+	}
+}
+
+// Run publishes queued messages in batches until ctx is cancelled
+func (p *Publisher) Run(ctx context.Context) {
+	log.Info().Str("bus", p.cfg.Kind).Str("topic", p.cfg.Topic).Msg("📡 Publishing events to the message bus")
+	defer p.transport.close()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Message, 0, batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// Publish what is in hand; the queue itself is dropped on shutdown
+			p.flush(context.Background(), batch)
+			return
+		case msg := <-p.queue:
+			batch = append(batch, msg)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		p.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// Name identifies the publisher in the outbox
+func (p *Publisher) Name() string {
+	return p.cfg.Kind + ":" + p.cfg.Topic
+}
+
+// Send publishes msgs right away, for callers that retry failures (the
+// outbox dispatcher) instead of going through the queue
+func (p *Publisher) Send(ctx context.Context, msgs []Message) error {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	if err := p.transport.publish(ctx, msgs); err != nil {
+		p.failed.Add(int64(len(msgs)))
+		return err
+	}
+	p.published.Add(int64(len(msgs)))
+	return nil
+}
+
+func (p *Publisher) flush(ctx context.Context, batch []Message) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := p.Send(ctx, batch); err != nil {
+		log.Warn().Err(err).Str("bus", p.cfg.Kind).Int("events", len(batch)).Msg("Event bus publish failed")
+	}
+}
+
+// Stats returns publishing totals
+func (p *Publisher) Stats() Stats {
+	return Stats{
+		Kind:      p.cfg.Kind,
+		Topic:     p.cfg.Topic,
+		Published: p.published.Load(),
+		Failed:    p.failed.Load(),
+		Dropped:   p.dropped.Load(),
+		Queued:    len(p.queue),
+	}
+}
@@ -0,0 +1,75 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// kafkaTransport produces to a topic through the Kafka REST Proxy v2 API,
+// one request per batch. Records are keyed by market address so a market's
+// events stay in one partition, in order.
+type kafkaTransport struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newKafka(baseURL, topic string) *kafkaTransport {
+	return &kafkaTransport{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: publishTimeout},
+	}
+}
+
+func (k *kafkaTransport) publish(ctx context.Context, msgs []Message) error {
+	type record struct {
+		Key   string  `json:"key,omitempty"`
+		Value Message `json:"value"`
+	}
+	records := make([]record, len(msgs))
+	for i, msg := range msgs {
+		records[i] = record{Key: msg.Market, Value: msg}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("REST proxy returned status %d", resp.StatusCode)
+	}
+
+	// The proxy reports per-record failures in a 200 response
+	var result struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
+		for _, offset := range result.Offsets {
+			if offset.Error != "" {
+				return fmt.Errorf("record rejected: %s", offset.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (k *kafkaTransport) close() {}
@@ -0,0 +1,158 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// natsTransport speaks the NATS client protocol over a plain TCP connection:
+// CONNECT, then one PUB per message. The connection is opened on first use
+// and reopened after an error. A reader answers the server's PINGs.
+type natsTransport struct {
+	url    *url.URL
+	prefix string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	writer *bufio.Writer
+}
+
+func newNATS(rawURL, prefix string) *natsTransport {
+	u, _ := url.Parse(rawURL) // checked by Config.Validate
+	return &natsTransport{url: u, prefix: prefix}
+}
+
+func (n *natsTransport) publish(ctx context.Context, msgs []Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		n.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, msg := range msgs {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(n.writer, "PUB %s.%s %d\r\n", n.prefix, msg.Event, len(payload))
+		n.writer.Write(payload)
+		n.writer.WriteString("\r\n")
+	}
+	if err := n.writer.Flush(); err != nil {
+		n.reset()
+		return err
+	}
+	return nil
+}
+
+// connect dials the server and sends CONNECT. Must hold n.mu.
+func (n *natsTransport) connect(ctx context.Context) error {
+	host := n.url.Host
+	if n.url.Port() == "" {
+		host = net.JoinHostPort(host, "4222")
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("no INFO from NATS server at %s", host)
+	}
+	if strings.Contains(info, `"tls_required":true`) {
+		conn.Close()
+		return fmt.Errorf("NATS server at %s requires TLS, which isn't supported", host)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "verifi-indexer-service",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if user := n.url.User; user != nil {
+		if pass, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), pass
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+
+	writer := bufio.NewWriter(conn)
+	fmt.Fprintf(writer, "CONNECT %s\r\n", connect)
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.conn, n.writer = conn, writer
+	go n.read(conn, reader)
+	return nil
+}
+
+// read answers PINGs and drops the connection on -ERR or a read error
+func (n *natsTransport) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			n.mu.Lock()
+			if n.conn == conn {
+				n.writer.WriteString("PONG\r\n")
+				n.writer.Flush()
+			}
+			n.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Warn().Str("error", strings.TrimSpace(line)).Msg("NATS server error")
+		}
+	}
+
+	n.mu.Lock()
+	if n.conn == conn {
+		n.reset()
+	}
+	n.mu.Unlock()
+}
+
+// reset closes the connection so the next publish reconnects. Must hold n.mu.
+func (n *natsTransport) reset() {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	n.conn, n.writer = nil, nil
+}
+
+func (n *natsTransport) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.writer != nil {
+		n.writer.Flush()
+	}
+	n.reset()
+}
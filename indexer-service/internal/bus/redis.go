@@ -0,0 +1,162 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTransport PUBLISHes each message to <prefix>:<market address> over
+// RESP, pipelining a batch in one round trip. The connection is opened on
+// first use and reopened after an error.
+type redisTransport struct {
+	url    *url.URL
+	prefix string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func newRedis(rawURL, prefix string) *redisTransport {
+	u, _ := url.Parse(rawURL) // checked by Config.Validate
+	return &redisTransport{url: u, prefix: prefix}
+}
+
+// channel is where msg is published; events without a market go to the
+// prefix itself
+func (r *redisTransport) channel(msg Message) string {
+	if msg.Market == "" {
+		return r.prefix
+	}
+	return r.prefix + ":" + strings.ToLower(msg.Market)
+}
+
+func (r *redisTransport) publish(ctx context.Context, msgs []Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		r.conn.SetDeadline(deadline)
+	}
+
+	sent := 0
+	for _, msg := range msgs {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		r.command("PUBLISH", r.channel(msg), string(payload))
+		sent++
+	}
+	if err := r.writer.Flush(); err != nil {
+		r.reset()
+		return err
+	}
+	for i := 0; i < sent; i++ {
+		if _, err := r.reply(); err != nil {
+			r.reset()
+			return err
+		}
+	}
+	return nil
+}
+
+// connect dials the server and authenticates. Must hold r.mu.
+func (r *redisTransport) connect(ctx context.Context) error {
+	host := r.url.Host
+	if r.url.Port() == "" {
+		host = net.JoinHostPort(host, "6379")
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if r.url.Scheme == "rediss" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: r.url.Hostname()}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return err
+	}
+	r.conn, r.reader, r.writer = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	if user := r.url.User; user != nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		if pass, ok := user.Password(); ok && user.Username() != "" {
+			r.command("AUTH", user.Username(), pass)
+		} else if ok {
+			r.command("AUTH", pass)
+		} else {
+			r.command("AUTH", user.Username())
+		}
+		if err := r.writer.Flush(); err != nil {
+			r.reset()
+			return err
+		}
+		if _, err := r.reply(); err != nil {
+			r.reset()
+			return fmt.Errorf("redis AUTH: %w", err)
+		}
+	}
+	return nil
+}
+
+// command writes one RESP array of bulk strings. Must hold r.mu.
+func (r *redisTransport) command(args ...string) {
+	fmt.Fprintf(r.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(r.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// reply reads one simple reply (status, error or integer). Must hold r.mu.
+func (r *redisTransport) reply() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("%s", line[1:])
+	default:
+		return "", fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// reset closes the connection so the next publish reconnects. Must hold r.mu.
+func (r *redisTransport) reset() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.conn, r.reader, r.writer = nil, nil, nil
+}
+
+func (r *redisTransport) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+}
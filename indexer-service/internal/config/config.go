@@ -3,17 +3,115 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/verifi-protocol/indexer-service/internal/archive"
+	"github.com/verifi-protocol/indexer-service/internal/bus"
+	"github.com/verifi-protocol/indexer-service/internal/notify"
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+	"github.com/verifi-protocol/pkg/amount"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/eventmap"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/senders"
 )
 
+// Network is one deployment the indexer follows. RPCURL is optional: the
+// first network's only applies when APTOS_RPC_ENDPOINTS and APTOS_RPC_URL
+// are unset, the others fall back to the network's public fullnode.
+type Network struct {
+	Name          string
+	ModuleAddress string
+	RPCURL        string
+}
+
 type Config struct {
-	DatabaseURL    string
-	AptosNetwork   string
-	ModuleAddress  string
-	Port           string
-	WebhookURL     string
-	AptosAPIKeys   []string
-	NoditAPIKeys   []string
+	DatabaseURL     string
+	AptosNetwork    string
+	ModuleAddress   string
+	Port            string
+	WebhookURL      string
+	AptosAPIKeys    []string
+	NoditAPIKeys    []string
+	DisplayPolicy   amount.Policy
+	RPCEndpoints    []string
+	AptosRPCURL     string
+	WebhookTargets  []webhook.Target
+	RPCRateLimit    float64
+	RPCBurst        int
+	Senders         *senders.Filter
+	Registry        string
+	ConsulAddr      string
+	AdvertiseURL    string
+	ReorgCheckDepth int
+	Confirmations   uint64
+	PipelineDepth   int
+	PipelineWorkers int
+	WriteStall      time.Duration
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	HealthMaxLag    uint64
+	ListenerGrace   time.Duration
+	AdminToken      string
+	APITokens       []string
+	AuthRules       []auth.Rule
+	RateLimit       ratelimit.Config
+	DebugEndpoints  bool
+	SentryDSN       string
+	LogDB           bool
+	LogDBRetention  time.Duration
+
+	// Lag alerting, enabled by AlertWebhookURL
+	AlertWebhookURL string
+	AlertRoutingKey string
+	AlertMaxLag     uint64
+	AlertMaxPollAge time.Duration
+	AlertFor        time.Duration
+	SentryEnv       string
+
+	// Discord/Telegram announcements of market events, enabled per channel
+	Notify notify.Config
+
+	// Message bus for processed events, enabled by EventBus.Kind
+	EventBus bus.Config
+
+	// Redis pub/sub fan-out per market, enabled by REDIS_URL
+	Redis bus.Config
+
+	// Object storage archive of processed transactions, enabled by ARCHIVE_URL
+	Archive archive.Config
+
+	// Webhook and bus deliveries go through the outbox table
+	Outbox            bool
+	OutboxMaxAttempts int
+
+	// Webhook delivery attempts are kept this long (0 keeps them)
+	WebhookAuditRetention time.Duration
+
+	// Autoscaling signal targets, per replica
+	ScalingLagPerReplica   uint64
+	ScalingQueuePerReplica int
+	ScalingMaxReplicas     int
+
+	// View function (without the module address) returning a market's
+	// creator, description and resolution timestamp, for markets that
+	// resolve without a Market row
+	MarketViewFunction string
+
+	// Startup checks that each network's fullnode serves the expected chain
+	// and RequiredModules are published at its module address: strict
+	// refuses to start on a mismatch, warn only logs it, off skips them
+	StartupValidation string
+	RequiredModules   []string
+
+	// Deployments indexed side by side, AptosNetwork/ModuleAddress first
+	Networks []Network
+
+	// Renames of older contract versions' events and fields
+	EventMappings *eventmap.Mapper
 }
 
 func Load() (*Config, error) {
@@ -27,9 +125,20 @@ func Load() (*Config, error) {
 		network = "testnet"
 	}
 
+	// Several deployments in one instance (optional); the first takes the
+	// place of NEXT_PUBLIC_APTOS_NETWORK and the publisher address
+	networks, err := parseNetworks(os.Getenv("INDEXER_NETWORKS"))
+	if err != nil {
+		return nil, fmt.Errorf("INDEXER_NETWORKS: %w", err)
+	}
 	moduleAddr := os.Getenv("NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS")
-	if moduleAddr == "" {
+	switch {
+	case len(networks) > 0:
+		network, moduleAddr = networks[0].Name, networks[0].ModuleAddress
+	case moduleAddr == "":
 		return nil, fmt.Errorf("NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS is required")
+	default:
+		networks = []Network{{Name: network, ModuleAddress: moduleAddr}}
 	}
 
 	port := os.Getenv("INDEXER_PORT")
@@ -40,6 +149,12 @@ func Load() (*Config, error) {
 	// Load webhook URL (optional)
 	webhookURL := os.Getenv("WEBHOOK_URL")
 
+	// Extra webhook targets, each in events or digest mode
+	webhookTargets, err := webhook.ParseTargets(os.Getenv("WEBHOOK_TARGETS"))
+	if err != nil {
+		return nil, fmt.Errorf("WEBHOOK_TARGETS: %w", err)
+	}
+
 	// Load API keys (comma-separated)
 	aptosKeys := []string{}
 	if aptosKeysStr := os.Getenv("APTOS_API_KEYS"); aptosKeysStr != "" {
@@ -59,13 +174,524 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Self-hosted fullnode or localnet, or the first network's RPC URL
+	aptosRPCURL := os.Getenv("APTOS_RPC_URL")
+	if aptosRPCURL == "" {
+		aptosRPCURL = networks[0].RPCURL
+	}
+
+	// Fullnode endpoints in priority order (comma-separated); the client
+	// fails over to the next one when an endpoint errors or times out
+	rpcEndpoints := []string{}
+	if endpointsStr := os.Getenv("APTOS_RPC_ENDPOINTS"); endpointsStr != "" {
+		for _, endpoint := range strings.Split(endpointsStr, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				rpcEndpoints = append(rpcEndpoints, endpoint)
+			}
+		}
+	}
+
+	// Client-side fullnode request budget shared by polling, catch-up,
+	// backfills and view calls (0 = unlimited)
+	rpcRateLimit := 0.0
+	if rps := os.Getenv("APTOS_RPC_RPS"); rps != "" {
+		n, err := strconv.ParseFloat(rps, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("APTOS_RPC_RPS must be a non-negative number")
+		}
+		rpcRateLimit = n
+	}
+	rpcBurst := 10
+	if burst := os.Getenv("APTOS_RPC_BURST"); burst != "" {
+		n, err := strconv.Atoi(burst)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("APTOS_RPC_BURST must be a positive integer")
+		}
+		rpcBurst = n
+	}
+
+	// Display rounding for API responses and webhook payloads
+	displayPolicy := amount.DefaultPolicy()
+	if mode := os.Getenv("DISPLAY_ROUNDING"); mode != "" {
+		parsed, err := amount.ParseMode(mode)
+		if err != nil {
+			return nil, fmt.Errorf("DISPLAY_ROUNDING: %w", err)
+		}
+		displayPolicy.Mode = parsed
+	}
+	if places := os.Getenv("DISPLAY_APT_DECIMALS"); places != "" {
+		n, err := strconv.Atoi(places)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("DISPLAY_APT_DECIMALS must be a non-negative integer")
+		}
+		displayPolicy.APTPlaces = n
+	}
+	if places := os.Getenv("DISPLAY_SHARE_DECIMALS"); places != "" {
+		n, err := strconv.Atoi(places)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("DISPLAY_SHARE_DECIMALS must be a non-negative integer")
+		}
+		displayPolicy.SharePlaces = n
+	}
+
+	// Recent checkpoints re-verified against the fullnode each poll
+	reorgCheckDepth := 5
+	if depth := os.Getenv("REORG_CHECK_DEPTH"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("REORG_CHECK_DEPTH must be a non-negative integer")
+		}
+		reorgCheckDepth = n
+	}
+
+	// Versions left between the ledger head and what is processed
+	var confirmations uint64
+	if depth := os.Getenv("CONFIRMATION_DEPTH"); depth != "" {
+		n, err := strconv.ParseUint(depth, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("CONFIRMATION_DEPTH must be a non-negative integer")
+		}
+		confirmations = n
+	}
+
+	// Batches fetched and prepared ahead of the one committing (1 = none),
+	// and the lookups each batch's preparation runs at once
+	pipelineDepth := 2
+	if depth := os.Getenv("PIPELINE_DEPTH"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("PIPELINE_DEPTH must be a positive integer")
+		}
+		pipelineDepth = n
+	}
+	pipelineWorkers := 4
+	if workers := os.Getenv("PIPELINE_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("PIPELINE_WORKERS must be a positive integer")
+		}
+		pipelineWorkers = n
+	}
+
+	// How often the listener polls the fullnode for new versions
+	pollInterval := 5 * time.Second
+	if interval := os.Getenv("POLL_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("POLL_INTERVAL must be a positive duration, e.g. 5s")
+		}
+		pollInterval = d
+	}
+	// How long fetching waits on a full write queue before the poll is cut
+	// short (0 waits as long as the commits take)
+	writeStall := 30 * time.Second
+	if stall := os.Getenv("WRITE_QUEUE_STALL"); stall != "" {
+		d, err := time.ParseDuration(stall)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("WRITE_QUEUE_STALL must be a non-negative duration, e.g. 30s")
+		}
+		writeStall = d
+	}
+	// /readyz keeps passing while a listener that has run is restarted, for
+	// up to this long
+	listenerGrace := 2 * time.Minute
+	if grace := os.Getenv("LISTENER_DOWN_GRACE"); grace != "" {
+		d, err := time.ParseDuration(grace)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("LISTENER_DOWN_GRACE must be a non-negative duration, e.g. 2m")
+		}
+		listenerGrace = d
+	}
+	// While idle the interval backs off up to this (at most POLL_INTERVAL
+	// disables backoff)
+	maxPollInterval := 30 * time.Second
+	if interval := os.Getenv("POLL_MAX_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("POLL_MAX_INTERVAL must be a positive duration, e.g. 30s")
+		}
+		maxPollInterval = d
+	}
+
+	// Lag beyond which /health reports the indexer degraded (0 = never)
+	healthMaxLag := uint64(100000)
+	if lag := os.Getenv("HEALTH_MAX_LAG"); lag != "" {
+		n, err := strconv.ParseUint(lag, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("HEALTH_MAX_LAG must be a non-negative integer")
+		}
+		healthMaxLag = n
+	}
+
+	// Work one replica should absorb, used for desired_replicas on /scaling
+	scalingLag := uint64(10000)
+	if lag := os.Getenv("SCALING_LAG_PER_REPLICA"); lag != "" {
+		n, err := strconv.ParseUint(lag, 10, 64)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("SCALING_LAG_PER_REPLICA must be a positive integer")
+		}
+		scalingLag = n
+	}
+	scalingQueue := 100
+	if queue := os.Getenv("SCALING_QUEUE_PER_REPLICA"); queue != "" {
+		n, err := strconv.Atoi(queue)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("SCALING_QUEUE_PER_REPLICA must be a positive integer")
+		}
+		scalingQueue = n
+	}
+	scalingMax := 0
+	if maxReplicas := os.Getenv("SCALING_MAX_REPLICAS"); maxReplicas != "" {
+		n, err := strconv.Atoi(maxReplicas)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("SCALING_MAX_REPLICAS must be a non-negative integer")
+		}
+		scalingMax = n
+	}
+
+	// Route authentication: tokens and per-route policy overrides
+	apiTokens := []string{}
+	for _, token := range strings.Split(os.Getenv("API_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			apiTokens = append(apiTokens, token)
+		}
+	}
+	authRules, err := auth.ParseRules(os.Getenv("AUTH_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("AUTH_ROUTES: %w", err)
+	}
+	rateLimit, err := ratelimit.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	eventMappings, err := eventmap.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// net/http/pprof under /debug/pprof, admin token required
+	debugEndpoints := false
+	if enabled := os.Getenv("DEBUG_ENDPOINTS"); enabled != "" {
+		b, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("DEBUG_ENDPOINTS must be true or false")
+		}
+		debugEndpoints = b
+	}
+
+	// Persist warn+ log entries to log_entries (optional), pruned after the
+	// retention (0 keeps them)
+	logDB := false
+	if enabled := os.Getenv("LOG_DB"); enabled != "" {
+		b, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_DB must be true or false")
+		}
+		logDB = b
+	}
+	logDBRetention := 7 * 24 * time.Hour
+	if retention := os.Getenv("LOG_DB_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("LOG_DB_RETENTION must be a non-negative duration, e.g. 168h")
+		}
+		logDBRetention = d
+	}
+
+	// Lag alerting (optional): thresholds and how long they must be exceeded
+	alertMaxLag := uint64(10000)
+	if lag := os.Getenv("ALERT_MAX_LAG"); lag != "" {
+		n, err := strconv.ParseUint(lag, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_MAX_LAG must be a non-negative integer")
+		}
+		alertMaxLag = n
+	}
+	alertMaxPollAge := 5 * time.Minute
+	if age := os.Getenv("ALERT_MAX_POLL_AGE"); age != "" {
+		d, err := time.ParseDuration(age)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("ALERT_MAX_POLL_AGE must be a non-negative duration, e.g. 5m")
+		}
+		alertMaxPollAge = d
+	}
+	alertFor := 2 * time.Minute
+	if hold := os.Getenv("ALERT_FOR"); hold != "" {
+		d, err := time.ParseDuration(hold)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("ALERT_FOR must be a non-negative duration, e.g. 2m")
+		}
+		alertFor = d
+	}
+	alertRoutingKey := os.Getenv("ALERT_PAGERDUTY_ROUTING_KEY")
+	alertURL := os.Getenv("ALERT_WEBHOOK_URL")
+	if strings.HasPrefix(alertURL, "https://events.pagerduty.com/") && alertRoutingKey == "" {
+		return nil, fmt.Errorf("ALERT_PAGERDUTY_ROUTING_KEY is required for a PagerDuty ALERT_WEBHOOK_URL")
+	}
+
+	// Error reporting (optional); events are tagged with the network unless
+	// SENTRY_ENVIRONMENT says otherwise
+	sentryEnv := os.Getenv("SENTRY_ENVIRONMENT")
+	if sentryEnv == "" {
+		sentryEnv = network
+	}
+
+	// Chat announcements of market events (optional), per channel
+	discordEvents, err := notifyEvents("NOTIFY_DISCORD_EVENTS")
+	if err != nil {
+		return nil, err
+	}
+	telegramEvents, err := notifyEvents("NOTIFY_TELEGRAM_EVENTS")
+	if err != nil {
+		return nil, err
+	}
+	telegramToken, telegramChat := os.Getenv("NOTIFY_TELEGRAM_BOT_TOKEN"), os.Getenv("NOTIFY_TELEGRAM_CHAT_ID")
+	if (telegramToken == "") != (telegramChat == "") {
+		return nil, fmt.Errorf("NOTIFY_TELEGRAM_BOT_TOKEN and NOTIFY_TELEGRAM_CHAT_ID must be set together")
+	}
+	notifyMaxAge := time.Hour
+	if age := os.Getenv("NOTIFY_MAX_AGE"); age != "" {
+		d, err := time.ParseDuration(age)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("NOTIFY_MAX_AGE must be a non-negative duration, e.g. 1h")
+		}
+		notifyMaxAge = d
+	}
+
+	// Publish processed events to NATS or Kafka (optional)
+	eventBus := bus.Config{
+		Kind:  strings.ToLower(os.Getenv("EVENT_BUS")),
+		URL:   os.Getenv("EVENT_BUS_URL"),
+		Topic: os.Getenv("EVENT_BUS_TOPIC"),
+	}
+	if eventBus.Topic == "" {
+		eventBus.Topic = "verifi.events"
+	}
+	if err := eventBus.Validate(); err != nil {
+		return nil, fmt.Errorf("EVENT_BUS: %w", err)
+	}
+
+	// Per-market Redis pub/sub channels for realtime consumers (optional)
+	var redis bus.Config
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		redis = bus.Config{Kind: bus.KindRedis, URL: redisURL, Topic: os.Getenv("REDIS_CHANNEL_PREFIX")}
+		if redis.Topic == "" {
+			redis.Topic = "verifi:events"
+		}
+		if err := redis.Validate(); err != nil {
+			return nil, fmt.Errorf("REDIS_URL: %w", err)
+		}
+	}
+
+	// Archive processed transactions to S3, GCS or a directory (optional)
+	archiveCfg := archive.Config{
+		URL:               os.Getenv("ARCHIVE_URL"),
+		Endpoint:          os.Getenv("ARCHIVE_ENDPOINT"),
+		Region:            os.Getenv("ARCHIVE_REGION"),
+		AccessKeyID:       os.Getenv("ARCHIVE_ACCESS_KEY_ID"),
+		SecretAccessKey:   os.Getenv("ARCHIVE_SECRET_ACCESS_KEY"),
+		FlushTransactions: 1000,
+		FlushInterval:     5 * time.Minute,
+	}
+	if n := os.Getenv("ARCHIVE_FLUSH_TRANSACTIONS"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("ARCHIVE_FLUSH_TRANSACTIONS must be a positive integer")
+		}
+		archiveCfg.FlushTransactions = v
+	}
+	if interval := os.Getenv("ARCHIVE_FLUSH_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("ARCHIVE_FLUSH_INTERVAL must be a positive duration, e.g. 5m")
+		}
+		archiveCfg.FlushInterval = d
+	}
+	if err := archiveCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("ARCHIVE_URL: %w", err)
+	}
+
+	// Transactional outbox for webhook and bus deliveries (default on)
+	outbox := true
+	if enabled := os.Getenv("OUTBOX"); enabled != "" {
+		b, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("OUTBOX must be true or false")
+		}
+		outbox = b
+	}
+	outboxMaxAttempts := 10
+	if attempts := os.Getenv("OUTBOX_MAX_ATTEMPTS"); attempts != "" {
+		n, err := strconv.Atoi(attempts)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("OUTBOX_MAX_ATTEMPTS must be a positive integer")
+		}
+		outboxMaxAttempts = n
+	}
+
+	// Webhook delivery audit log retention
+	webhookAuditRetention := 7 * 24 * time.Hour
+	if retention := os.Getenv("WEBHOOK_AUDIT_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("WEBHOOK_AUDIT_RETENTION must be a non-negative duration, e.g. 168h")
+		}
+		webhookAuditRetention = d
+	}
+
+	// Looks up markets that resolve without a Market row
+	marketViewFunction := os.Getenv("MARKET_VIEW_FUNCTION")
+	if marketViewFunction == "" {
+		marketViewFunction = "market::get_market_info"
+	}
+
+	// Modules that must be published at the module address, by default the
+	// market view function's
+	requiredModules := []string{}
+	if modules := os.Getenv("REQUIRED_MODULES"); modules != "" {
+		for _, module := range strings.Split(modules, ",") {
+			if module = strings.TrimSpace(module); module != "" {
+				requiredModules = append(requiredModules, module)
+			}
+		}
+	} else if module, _, ok := strings.Cut(marketViewFunction, "::"); ok {
+		requiredModules = append(requiredModules, module)
+	}
+	startupValidation := os.Getenv("STARTUP_VALIDATION")
+	switch startupValidation {
+	case "":
+		startupValidation = "strict"
+	case "strict", "warn", "off":
+	default:
+		return nil, fmt.Errorf("STARTUP_VALIDATION must be strict, warn or off")
+	}
+
+	// Self-registration for service discovery (optional)
+	consulAddr := os.Getenv("CONSUL_HTTP_ADDR")
+	if consulAddr == "" {
+		consulAddr = "http://127.0.0.1:8500"
+	}
+	advertiseURL := os.Getenv("SERVICE_ADVERTISE_URL")
+	if advertiseURL == "" {
+		host, _ := os.Hostname()
+		advertiseURL = fmt.Sprintf("http://%s:%s", host, port)
+	}
+
 	return &Config{
-		DatabaseURL:   dbURL,
-		AptosNetwork:  network,
-		ModuleAddress: moduleAddr,
-		Port:          port,
-		WebhookURL:    webhookURL,
-		AptosAPIKeys:  aptosKeys,
-		NoditAPIKeys:  noditKeys,
+		DatabaseURL:     dbURL,
+		AptosNetwork:    network,
+		ModuleAddress:   moduleAddr,
+		Port:            port,
+		WebhookURL:      webhookURL,
+		AptosAPIKeys:    aptosKeys,
+		NoditAPIKeys:    noditKeys,
+		DisplayPolicy:   displayPolicy,
+		RPCEndpoints:    rpcEndpoints,
+		AptosRPCURL:     aptosRPCURL,
+		WebhookTargets:  webhookTargets,
+		RPCRateLimit:    rpcRateLimit,
+		RPCBurst:        rpcBurst,
+		Senders:         senders.FromEnv(),
+		Registry:        os.Getenv("SERVICE_REGISTRY"),
+		ConsulAddr:      consulAddr,
+		AdvertiseURL:    advertiseURL,
+		ReorgCheckDepth: reorgCheckDepth,
+		Confirmations:   confirmations,
+		PipelineDepth:   pipelineDepth,
+		PipelineWorkers: pipelineWorkers,
+		WriteStall:      writeStall,
+		ListenerGrace:   listenerGrace,
+		PollInterval:    pollInterval,
+		MaxPollInterval: maxPollInterval,
+		HealthMaxLag:    healthMaxLag,
+		AdminToken:      os.Getenv("ADMIN_TOKEN"),
+		APITokens:       apiTokens,
+		AuthRules:       authRules,
+		RateLimit:       rateLimit,
+		DebugEndpoints:  debugEndpoints,
+		SentryDSN:       os.Getenv("SENTRY_DSN"),
+		LogDB:           logDB,
+		LogDBRetention:  logDBRetention,
+
+		AlertWebhookURL: alertURL,
+		AlertRoutingKey: alertRoutingKey,
+		AlertMaxLag:     alertMaxLag,
+		AlertMaxPollAge: alertMaxPollAge,
+		AlertFor:        alertFor,
+		SentryEnv:       sentryEnv,
+
+		Notify: notify.Config{
+			DiscordWebhookURL: os.Getenv("NOTIFY_DISCORD_WEBHOOK_URL"),
+			DiscordEvents:     discordEvents,
+			TelegramBotToken:  telegramToken,
+			TelegramChatID:    telegramChat,
+			TelegramEvents:    telegramEvents,
+			MarketURL:         os.Getenv("NOTIFY_MARKET_URL"),
+			MaxAge:            notifyMaxAge,
+		},
+		EventBus: eventBus,
+		Redis:    redis,
+		Archive:  archiveCfg,
+
+		Outbox:            outbox,
+		OutboxMaxAttempts: outboxMaxAttempts,
+
+		WebhookAuditRetention: webhookAuditRetention,
+
+		ScalingLagPerReplica:   scalingLag,
+		ScalingQueuePerReplica: scalingQueue,
+		ScalingMaxReplicas:     scalingMax,
+
+		MarketViewFunction: marketViewFunction,
+		StartupValidation:  startupValidation,
+		RequiredModules:    requiredModules,
+
+		Networks:      networks,
+		EventMappings: eventMappings,
 	}, nil
 }
+
+// parseNetworks reads a comma-separated list of network=module_address
+// pairs, each optionally followed by @rpc_url, e.g.
+// testnet=0xabc,mainnet=0xdef@https://fullnode.example.com/v1
+func parseNetworks(s string) ([]Network, error) {
+	var networks []Network
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q must be network=module_address[@rpc_url]", entry)
+		}
+		address, rpcURL, _ := strings.Cut(rest, "@")
+		address, rpcURL = strings.TrimSpace(address), strings.TrimSpace(rpcURL)
+		if address == "" {
+			return nil, fmt.Errorf("%q has no module address", entry)
+		}
+		if slices.ContainsFunc(networks, func(n Network) bool { return n.Name == name }) {
+			return nil, fmt.Errorf("network %q is listed twice", name)
+		}
+		networks = append(networks, Network{Name: name, ModuleAddress: address, RPCURL: rpcURL})
+	}
+	return networks, nil
+}
+
+// notifyEvents reads a comma-separated list of event types a chat channel
+// announces. Empty means the notify defaults.
+func notifyEvents(name string) ([]string, error) {
+	var events []string
+	for _, event := range strings.Split(os.Getenv(name), ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !slices.Contains(notify.DefaultEvents, event) {
+			return nil, fmt.Errorf("%s must list %s", name, strings.Join(notify.DefaultEvents, " or "))
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Checkpoint is one row of sync_state
+type Checkpoint struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointSample is one row of checkpoint_history
+type CheckpointSample struct {
+	Network    string    `json:"network"`
+	Version    uint64    `json:"version"`
+	Advanced   uint64    `json:"advanced"`
+	Polls      int       `json:"polls"`
+	ElapsedMs  int64     `json:"elapsed_ms"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Checkpoints returns every sync_state entry
+func (db *DB) Checkpoints(ctx context.Context) ([]Checkpoint, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT key, value, COALESCE(updated_at, 'epoch'::timestamp)
+		FROM sync_state
+		ORDER BY key
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []Checkpoint{}
+	for rows.Next() {
+		var c Checkpoint
+		if err := rows.Scan(&c.Key, &c.Value, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	return checkpoints, rows.Err()
+}
+
+// CheckpointHistory returns up to limit samples recorded at or after since,
+// newest first, of network or of every network when it is empty
+func (db *DB) CheckpointHistory(ctx context.Context, network string, since time.Time, limit int) ([]CheckpointSample, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT network, version, advanced, polls, elapsed_ms, recorded_at
+		FROM checkpoint_history
+		WHERE recorded_at >= $1 AND ($3 = '' OR network = $3)
+		ORDER BY recorded_at DESC
+		LIMIT $2
+	`, since, limit, network)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []CheckpointSample{}
+	for rows.Next() {
+		var s CheckpointSample
+		var version, advanced int64
+		if err := rows.Scan(&s.Network, &version, &advanced, &s.Polls, &s.ElapsedMs, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		s.Version, s.Advanced = uint64(version), uint64(advanced)
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// RecordCheckpointSample appends a sample to checkpoint_history
+func (db *DB) RecordCheckpointSample(ctx context.Context, s CheckpointSample) error {
+	_, err := db.Pool().Exec(ctx, `
+		INSERT INTO checkpoint_history (version, advanced, polls, elapsed_ms, network)
+		VALUES ($1, $2, $3, $4, $5)
+	`, int64(s.Version), int64(s.Advanced), s.Polls, s.ElapsedMs, s.Network)
+	return err
+}
@@ -1,39 +1,18 @@
 package db
 
 import (
-	"context"
-	"fmt"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/verifi-protocol/pkg/store"
 )
 
+// DB adds the indexer's own queries to the shared store
 type DB struct {
-	pool *pgxpool.Pool
+	*store.DB
 }
 
 func New(databaseURL string) (*DB, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse database URL: %w", err)
-	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	database, err := store.New(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create connection pool: %w", err)
-	}
-
-	// Test connection
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, err
 	}
-
-	return &DB{pool: pool}, nil
-}
-
-func (db *DB) Pool() *pgxpool.Pool {
-	return db.pool
-}
-
-func (db *DB) Close() {
-	db.pool.Close()
+	return &DB{DB: database}, nil
 }
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/pkg/page"
+)
+
+// WebhookDelivery is one row of webhook_deliveries
+type WebhookDelivery struct {
+	ID          int64           `json:"id"`
+	Target      string          `json:"target"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	PayloadHash string          `json:"payload_hash"`
+	Attempt     int             `json:"attempt"`
+	StatusCode  *int            `json:"status_code"`
+	Error       string          `json:"error,omitempty"`
+	LatencyMs   int             `json:"latency_ms"`
+	Delivered   bool            `json:"delivered"`
+	AttemptedAt time.Time       `json:"attempted_at"`
+}
+
+// DeliveryFilter narrows WebhookDeliveries. Zero values match everything.
+type DeliveryFilter struct {
+	Delivered *bool
+	Target    string
+	EventType string
+	Since     time.Time
+	Page      page.Params
+}
+
+// InsertWebhookDeliveries writes records in one round trip
+func (db *DB) InsertWebhookDeliveries(ctx context.Context, records []WebhookDelivery) error {
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		batch.Queue(`
+			INSERT INTO webhook_deliveries
+				(target, event_type, payload, payload_hash, attempt, status_code, error, latency_ms, delivered, attempted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		`, r.Target, r.EventType, r.Payload, r.PayloadHash, r.Attempt, r.StatusCode, r.Error, r.LatencyMs, r.Delivered, r.AttemptedAt)
+	}
+	return db.Pool().SendBatch(ctx, batch).Close()
+}
+
+// WebhookDeliveries returns a page of matching attempts, newest
+// first, without their payloads
+func (db *DB) WebhookDeliveries(ctx context.Context, filter DeliveryFilter) ([]WebhookDelivery, error) {
+	where, args := []string{"TRUE"}, []any{}
+	add := func(clause string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Delivered != nil {
+		add("delivered = $%d", *filter.Delivered)
+	}
+	if filter.Target != "" {
+		add("target = $%d", filter.Target)
+	}
+	if filter.EventType != "" {
+		add("event_type = $%d", filter.EventType)
+	}
+	if !filter.Since.IsZero() {
+		add("attempted_at >= $%d", filter.Since)
+	}
+	columns := []page.Column{{Expr: "attempted_at", Type: "timestamptz"}, {Expr: "id", Type: "bigint"}}
+	after, afterArgs := filter.Page.Keyset(columns, len(args)+1)
+	where = append(where, after)
+	args = append(append(args, afterArgs...), filter.Page.Limit)
+
+	rows, err := db.Pool().Query(ctx, fmt.Sprintf(`
+		SELECT id, target, event_type, NULL::json, payload_hash, attempt, status_code,
+		       COALESCE(error, ''), latency_ms, delivered, attempted_at
+		FROM webhook_deliveries
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, strings.Join(where, " AND "), filter.Page.Order(columns), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanDelivery)
+}
+
+// WebhookDelivery returns one attempt with its payload, or nil when there
+// is no such attempt
+func (db *DB) WebhookDelivery(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT id, target, event_type, payload, payload_hash, attempt, status_code,
+		       COALESCE(error, ''), latency_ms, delivered, attempted_at
+		FROM webhook_deliveries
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// MaxDeliveryAttempt returns the highest attempt recorded for this payload
+// to target
+func (db *DB) MaxDeliveryAttempt(ctx context.Context, target, payloadHash string) (int, error) {
+	var attempt int
+	err := db.Pool().QueryRow(ctx, `
+		SELECT COALESCE(MAX(attempt), 0) FROM webhook_deliveries
+		WHERE target = $1 AND payload_hash = $2
+	`, target, payloadHash).Scan(&attempt)
+	return attempt, err
+}
+
+// PruneWebhookDeliveries deletes attempts older than cutoff
+func (db *DB) PruneWebhookDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := db.Pool().Exec(ctx, `DELETE FROM webhook_deliveries WHERE attempted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanDelivery(row pgx.CollectableRow) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(&d.ID, &d.Target, &d.EventType, &d.Payload, &d.PayloadHash, &d.Attempt, &d.StatusCode,
+		&d.Error, &d.LatencyMs, &d.Delivered, &d.AttemptedAt)
+	return d, err
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// VersionGap is one row of version_gaps
+type VersionGap struct {
+	ID            int64      `json:"id"`
+	Network       string     `json:"network"`
+	StartVersion  uint64     `json:"start_version"`
+	EndVersion    uint64     `json:"end_version"`
+	Reason        string     `json:"reason"`
+	Attempts      int        `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	DetectedAt    time.Time  `json:"detected_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	RepairedAt    *time.Time `json:"repaired_at"`
+}
+
+// VersionGaps returns up to limit gaps of network, or of every network when
+// it is empty, newest first. Repaired gaps are included when all is set.
+func (db *DB) VersionGaps(ctx context.Context, network string, all bool, limit int) ([]VersionGap, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT id, network, start_version, end_version, reason, attempts, last_error,
+			detected_at, last_attempt_at, repaired_at
+		FROM version_gaps
+		WHERE ($1 = '' OR network = $1) AND ($2 OR repaired_at IS NULL)
+		ORDER BY start_version DESC
+		LIMIT $3
+	`, network, all, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gaps := []VersionGap{}
+	for rows.Next() {
+		var g VersionGap
+		var start, end int64
+		if err := rows.Scan(&g.ID, &g.Network, &start, &end, &g.Reason, &g.Attempts, &g.LastError,
+			&g.DetectedAt, &g.LastAttemptAt, &g.RepairedAt); err != nil {
+			return nil, err
+		}
+		g.StartVersion, g.EndVersion = uint64(start), uint64(end)
+		gaps = append(gaps, g)
+	}
+	return gaps, rows.Err()
+}
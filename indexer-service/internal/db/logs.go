@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/pkg/page"
+)
+
+// LogRecord is one row of log_entries
+type LogRecord struct {
+	ID       int64          `json:"id"`
+	LoggedAt time.Time      `json:"logged_at"`
+	Level    string         `json:"level"`
+	Message  string         `json:"message"`
+	Fields   map[string]any `json:"fields,omitempty"`
+	Instance string         `json:"instance"`
+}
+
+// LogFilter narrows LogEntries. Zero values match everything.
+type LogFilter struct {
+	Levels   []string // any of these levels
+	Since    time.Time
+	Until    time.Time
+	Search   string // case-insensitive, in the message and fields
+	Event    string // the "event" field
+	Instance string
+	Page     page.Params
+}
+
+// InsertLogEntries writes records in one round trip
+func (db *DB) InsertLogEntries(ctx context.Context, records []LogRecord) error {
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		batch.Queue(`
+			INSERT INTO log_entries (logged_at, level, message, fields, instance)
+			VALUES ($1, $2, $3, $4, $5)
+		`, r.LoggedAt, r.Level, r.Message, r.Fields, r.Instance)
+	}
+	return db.Pool().SendBatch(ctx, batch).Close()
+}
+
+// LogEntries returns a page of matching records, newest first
+func (db *DB) LogEntries(ctx context.Context, filter LogFilter) ([]LogRecord, error) {
+	where, args := []string{"TRUE"}, []any{}
+	add := func(clause string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if len(filter.Levels) > 0 {
+		add("level = ANY($%d)", filter.Levels)
+	}
+	if !filter.Since.IsZero() {
+		add("logged_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("logged_at < $%d", filter.Until)
+	}
+	if filter.Search != "" {
+		add("strpos(lower(message || ' ' || COALESCE(fields::text, '')), lower($%d)) > 0", filter.Search)
+	}
+	if filter.Event != "" {
+		add("fields->>'event' = $%d", filter.Event)
+	}
+	if filter.Instance != "" {
+		add("instance = $%d", filter.Instance)
+	}
+	columns := []page.Column{{Expr: "logged_at", Type: "timestamptz"}, {Expr: "id", Type: "bigint"}}
+	after, afterArgs := filter.Page.Keyset(columns, len(args)+1)
+	where = append(where, after)
+	args = append(append(args, afterArgs...), filter.Page.Limit)
+
+	rows, err := db.Pool().Query(ctx, fmt.Sprintf(`
+		SELECT id, logged_at, level, message, fields, instance
+		FROM log_entries
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, strings.Join(where, " AND "), filter.Page.Order(columns), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []LogRecord{}
+	for rows.Next() {
+		var r LogRecord
+		if err := rows.Scan(&r.ID, &r.LoggedAt, &r.Level, &r.Message, &r.Fields, &r.Instance); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// PruneLogEntries deletes records logged before cutoff and returns how many
+func (db *DB) PruneLogEntries(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := db.Pool().Exec(ctx, `DELETE FROM log_entries WHERE logged_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// networkTables have the network column added by migration 021
+var networkTables = []string{
+	`"Activity"`, `"Market"`, `"LpActivity"`, `"LpPosition"`, `"ProtocolFee"`, `"ResolutionHistory"`,
+	"quarantined_events", "checkpoint_history", "raw_events", "processed_events",
+	"deferred_events", "indexed_transactions", "checkpoint_hashes",
+}
+
+// LabelRows assigns network to the rows written before rows carried their
+// network. It runs once per database: later calls find network_labelled in
+// sync_state and return false.
+func (db *DB) LabelRows(ctx context.Context, network string) (bool, error) {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var labelled string
+	err = tx.QueryRow(ctx, `SELECT value FROM sync_state WHERE key = 'network_labelled'`).Scan(&labelled)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	// Instances starting together run the same updates; whichever
+	// inserts the key second rolls back
+	for _, table := range networkTables {
+		if _, err := tx.Exec(ctx, `UPDATE `+table+` SET network = $1 WHERE network = ''`, network); err != nil {
+			return false, err
+		}
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO sync_state (key, value, updated_at)
+		VALUES ('network_labelled', $1, NOW())
+		ON CONFLICT (key) DO NOTHING
+	`, network)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	OutboxWebhook = "webhook"
+	OutboxBus     = "bus"
+)
+
+// OutboxRecord is one row of outbox
+type OutboxRecord struct {
+	ID       int64
+	Kind     string // OutboxWebhook or OutboxBus
+	Target   string
+	Payload  json.RawMessage
+	Attempts int
+}
+
+// OutboxStats counts undelivered rows
+type OutboxStats struct {
+	Pending         int64      `json:"pending"`
+	Failed          int64      `json:"failed"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at,omitempty"`
+}
+
+// InsertOutbox writes records as part of tx
+func InsertOutbox(ctx context.Context, tx pgx.Tx, records []OutboxRecord) error {
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		batch.Queue(`INSERT INTO outbox (kind, target, payload) VALUES ($1, $2, $3)`, r.Kind, r.Target, r.Payload)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+// ClaimOutbox takes up to limit due pending rows, oldest first, and pushes
+// their next attempt lease into the future so no other dispatcher picks
+// them up meanwhile. A row whose delivery is never marked is retried once
+// the lease expires.
+func (db *DB) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxRecord, error) {
+	rows, err := db.Pool().Query(ctx, `
+		UPDATE outbox SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, target, payload, attempts
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []OutboxRecord
+	for rows.Next() {
+		var r OutboxRecord
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Target, &r.Payload, &r.Attempts); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// MarkOutboxDelivered records a successful delivery
+func (db *DB) MarkOutboxDelivered(ctx context.Context, id int64) error {
+	_, err := db.Pool().Exec(ctx, `
+		UPDATE outbox SET status = 'delivered', attempts = attempts + 1, delivered_at = NOW(), last_error = NULL
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// MarkOutboxFailed records a failed attempt. The row is retried at retryAt,
+// or given up on (status failed) when retryAt is zero.
+func (db *DB) MarkOutboxFailed(ctx context.Context, id int64, failure string, retryAt time.Time) error {
+	status, next := "pending", retryAt
+	if retryAt.IsZero() {
+		status, next = "failed", time.Now()
+	}
+	_, err := db.Pool().Exec(ctx, `
+		UPDATE outbox SET status = $2, attempts = attempts + 1, last_error = $3, next_attempt_at = $4
+		WHERE id = $1
+	`, id, status, failure, next)
+	return err
+}
+
+// PruneOutbox deletes delivered and failed rows created before cutoff
+func (db *DB) PruneOutbox(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := db.Pool().Exec(ctx, `DELETE FROM outbox WHERE status <> 'pending' AND created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// OutboxStats counts pending and failed rows
+func (db *DB) OutboxStats(ctx context.Context) (OutboxStats, error) {
+	var stats OutboxStats
+	err := db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'pending'),
+		       COUNT(*) FILTER (WHERE status = 'failed'),
+		       MIN(created_at) FILTER (WHERE status = 'pending')
+		FROM outbox
+		WHERE status <> 'delivered'
+	`).Scan(&stats.Pending, &stats.Failed, &stats.OldestPendingAt)
+	return stats, err
+}
@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BackfillJob is one row of backfill_jobs with its shards
+type BackfillJob struct {
+	ID          int64           `json:"id"`
+	FromVersion uint64          `json:"from_version"`
+	ToVersion   uint64          `json:"to_version"`
+	ShardSize   uint64          `json:"shard_size"`
+	Status      string          `json:"status"` // running, merging or complete
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Shards      []BackfillShard `json:"shards,omitempty"`
+}
+
+// BackfillShard is one row of backfill_shards
+type BackfillShard struct {
+	JobID          int64      `json:"-"`
+	Shard          int        `json:"shard"`
+	FromVersion    uint64     `json:"from_version"`
+	ToVersion      uint64     `json:"to_version"`
+	Checkpoint     *uint64    `json:"checkpoint,omitempty"` // last version committed
+	Owner          string     `json:"owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	Attempts       int        `json:"attempts"`
+	Done           bool       `json:"done"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// Next is the first version the shard still has to process
+func (s BackfillShard) Next() uint64 {
+	if s.Checkpoint == nil {
+		return s.FromVersion
+	}
+	return *s.Checkpoint + 1
+}
+
+// CreateBackfillJob splits from..to into shards of shardSize versions
+func (db *DB) CreateBackfillJob(ctx context.Context, from, to, shardSize uint64) (BackfillJob, error) {
+	tx, err := db.Pool().Begin(ctx)
+	if err != nil {
+		return BackfillJob{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	job := BackfillJob{FromVersion: from, ToVersion: to, ShardSize: shardSize, Status: "running"}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO backfill_jobs (from_version, to_version, shard_size)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, int64(from), int64(to), int64(shardSize)).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return BackfillJob{}, fmt.Errorf("failed to create backfill job: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for start, shard := from, 0; start <= to; shard++ {
+		end := min(start+shardSize-1, to)
+		batch.Queue(`
+			INSERT INTO backfill_shards (job_id, shard, from_version, to_version)
+			VALUES ($1, $2, $3, $4)
+		`, job.ID, shard, int64(start), int64(end))
+		job.Shards = append(job.Shards, BackfillShard{JobID: job.ID, Shard: shard, FromVersion: start, ToVersion: end})
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return BackfillJob{}, fmt.Errorf("failed to create backfill shards: %w", err)
+	}
+
+	return job, tx.Commit(ctx)
+}
+
+// GetBackfillJob returns the job with its shards, or nil when there is none
+func (db *DB) GetBackfillJob(ctx context.Context, id int64) (*BackfillJob, error) {
+	var job BackfillJob
+	var from, to, size int64
+	err := db.Pool().QueryRow(ctx, `
+		SELECT id, from_version, to_version, shard_size, status, created_at, completed_at
+		FROM backfill_jobs WHERE id = $1
+	`, id).Scan(&job.ID, &from, &to, &size, &job.Status, &job.CreatedAt, &job.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.FromVersion, job.ToVersion, job.ShardSize = uint64(from), uint64(to), uint64(size)
+
+	rows, err := db.Pool().Query(ctx, `
+		SELECT job_id, shard, from_version, to_version, checkpoint, COALESCE(owner, ''),
+		       lease_expires_at, attempts, done, COALESCE(last_error, '')
+		FROM backfill_shards WHERE job_id = $1
+		ORDER BY shard
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		shard, err := scanShard(rows)
+		if err != nil {
+			return nil, err
+		}
+		job.Shards = append(job.Shards, shard)
+	}
+	return &job, rows.Err()
+}
+
+// ClaimBackfillShard leases the lowest open shard of the job whose lease is
+// free or expired to owner, or returns nil when none is left to claim
+func (db *DB) ClaimBackfillShard(ctx context.Context, jobID int64, owner string, lease time.Duration) (*BackfillShard, error) {
+	rows, err := db.Pool().Query(ctx, `
+		UPDATE backfill_shards
+		SET owner = $2, lease_expires_at = NOW() + $3 * INTERVAL '1 millisecond',
+		    attempts = attempts + 1, updated_at = NOW()
+		WHERE (job_id, shard) = (
+			SELECT job_id, shard FROM backfill_shards
+			WHERE job_id = $1 AND NOT done
+			  AND (owner IS NULL OR lease_expires_at < NOW())
+			ORDER BY shard
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING job_id, shard, from_version, to_version, checkpoint, COALESCE(owner, ''),
+		          lease_expires_at, attempts, done, COALESCE(last_error, '')
+	`, jobID, owner, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	shard, err := scanShard(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &shard, nil
+}
+
+// SaveShardCheckpoint moves the shard's checkpoint to version and renews
+// owner's lease as part of tx, marking the shard done at its last version.
+// It returns false when owner no longer holds the shard, in which case the
+// caller must roll tx back.
+func SaveShardCheckpoint(ctx context.Context, tx pgx.Tx, jobID int64, shard int, owner string, version uint64, lease time.Duration) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		UPDATE backfill_shards
+		SET checkpoint = $4, done = ($4 = to_version), last_error = NULL,
+		    lease_expires_at = NOW() + $5 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE job_id = $1 AND shard = $2 AND owner = $3 AND NOT done
+	`, jobID, shard, owner, int64(version), lease.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to save shard checkpoint: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseBackfillShard gives up owner's lease on the shard, recording why
+// when reason is not empty, so another worker can claim it right away
+func (db *DB) ReleaseBackfillShard(ctx context.Context, jobID int64, shard int, owner, reason string) error {
+	_, err := db.Pool().Exec(ctx, `
+		UPDATE backfill_shards
+		SET owner = NULL, lease_expires_at = NULL, last_error = NULLIF($4, ''), updated_at = NOW()
+		WHERE job_id = $1 AND shard = $2 AND owner = $3
+	`, jobID, shard, owner, reason)
+	return err
+}
+
+// StartBackfillMerge moves a job whose shards are all done to merging. It
+// returns false when shards are still open or another worker got there
+// first; force also takes over a merge that was left unfinished.
+func (db *DB) StartBackfillMerge(ctx context.Context, jobID int64, force bool) (bool, error) {
+	tag, err := db.Pool().Exec(ctx, `
+		UPDATE backfill_jobs SET status = 'merging'
+		WHERE id = $1
+		  AND (status = 'running' OR ($2 AND status = 'merging'))
+		  AND NOT EXISTS (SELECT 1 FROM backfill_shards WHERE job_id = $1 AND NOT done)
+	`, jobID, force)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FinishBackfillMerge marks the job complete, or back to running when the
+// merge failed so it can be retried
+func (db *DB) FinishBackfillMerge(ctx context.Context, jobID int64, ok bool) error {
+	query := `UPDATE backfill_jobs SET status = 'complete', completed_at = NOW() WHERE id = $1`
+	if !ok {
+		query = `UPDATE backfill_jobs SET status = 'running' WHERE id = $1`
+	}
+	_, err := db.Pool().Exec(ctx, query, jobID)
+	return err
+}
+
+// DeferredVersions returns the versions of network between from and to
+// with deferred events, lowest first
+func (db *DB) DeferredVersions(ctx context.Context, network string, from, to uint64) ([]uint64, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT DISTINCT transaction_version FROM deferred_events
+		WHERE network = $3 AND transaction_version BETWEEN $1 AND $2
+		ORDER BY transaction_version
+	`, int64(from), int64(to), network)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []uint64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, uint64(version))
+	}
+	return versions, rows.Err()
+}
+
+// DeleteDeferredEvents drops the deferred events of network's versions as
+// part of tx, once they were applied (or quarantined for good)
+func DeleteDeferredEvents(ctx context.Context, tx pgx.Tx, network string, versions []uint64) error {
+	ids := make([]int64, len(versions))
+	for i, version := range versions {
+		ids[i] = int64(version)
+	}
+	_, err := tx.Exec(ctx, `DELETE FROM deferred_events WHERE network = $2 AND transaction_version = ANY($1)`, ids, network)
+	return err
+}
+
+func scanShard(rows pgx.Rows) (BackfillShard, error) {
+	var s BackfillShard
+	var from, to int64
+	var checkpoint *int64
+	if err := rows.Scan(&s.JobID, &s.Shard, &from, &to, &checkpoint, &s.Owner,
+		&s.LeaseExpiresAt, &s.Attempts, &s.Done, &s.LastError); err != nil {
+		return BackfillShard{}, err
+	}
+	s.FromVersion, s.ToVersion = uint64(from), uint64(to)
+	if checkpoint != nil {
+		v := uint64(*checkpoint)
+		s.Checkpoint = &v
+	}
+	return s, nil
+}
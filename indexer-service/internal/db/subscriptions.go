@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Subscription is one row of subscriptions. Empty filters match everything.
+type Subscription struct {
+	ID             int64     `json:"id"`
+	CallbackURL    string    `json:"callback_url,omitempty"`
+	TelegramChatID string    `json:"telegram_chat_id,omitempty"`
+	MarketAddress  string    `json:"market_address,omitempty"`
+	WalletAddress  string    `json:"wallet_address,omitempty"`
+	EventTypes     []string  `json:"event_types,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateSubscription inserts s, filling in its ID and CreatedAt
+func (db *DB) CreateSubscription(ctx context.Context, s *Subscription) error {
+	return db.Pool().QueryRow(ctx, `
+		INSERT INTO subscriptions (callback_url, telegram_chat_id, market_address, wallet_address, event_types)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5)
+		RETURNING id, created_at
+	`, s.CallbackURL, s.TelegramChatID, s.MarketAddress, s.WalletAddress, s.EventTypes).Scan(&s.ID, &s.CreatedAt)
+}
+
+// Subscriptions returns every subscription, oldest first
+func (db *DB) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT id, COALESCE(callback_url, ''), COALESCE(telegram_chat_id, ''),
+		       COALESCE(market_address, ''), COALESCE(wallet_address, ''),
+		       event_types, created_at
+		FROM subscriptions
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var s Subscription
+		if err := rows.Scan(&s.ID, &s.CallbackURL, &s.TelegramChatID, &s.MarketAddress, &s.WalletAddress, &s.EventTypes, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// DeleteSubscription removes the subscription, reporting whether it existed
+func (db *DB) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool().Exec(ctx, `DELETE FROM subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
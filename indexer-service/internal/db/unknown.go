@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// UnknownEvent is one row of unknown_events: a module event type the
+// indexer has neither a schema nor a handler for
+type UnknownEvent struct {
+	Network      string          `json:"network"`
+	EventType    string          `json:"event_type"`
+	EventName    string          `json:"event_name"`
+	Transactions int64           `json:"transactions"`
+	FirstVersion uint64          `json:"first_version"`
+	LastVersion  uint64          `json:"last_version"`
+	LastTxHash   string          `json:"last_tx_hash"`
+	Sample       json.RawMessage `json:"sample"`
+	FirstSeenAt  time.Time       `json:"first_seen_at"`
+	LastSeenAt   time.Time       `json:"last_seen_at"`
+}
+
+// UnknownEvents returns the unknown event types of network, or of every
+// network when it is empty, most recently seen first
+func (db *DB) UnknownEvents(ctx context.Context, network string) ([]UnknownEvent, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT network, event_type, event_name, transactions, first_version, last_version,
+			last_tx_hash, sample, first_seen_at, last_seen_at
+		FROM unknown_events
+		WHERE $1 = '' OR network = $1
+		ORDER BY last_seen_at DESC
+	`, network)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []UnknownEvent{}
+	for rows.Next() {
+		var e UnknownEvent
+		var first, last int64
+		var sample []byte
+		if err := rows.Scan(&e.Network, &e.EventType, &e.EventName, &e.Transactions, &first, &last,
+			&e.LastTxHash, &sample, &e.FirstSeenAt, &e.LastSeenAt); err != nil {
+			return nil, err
+		}
+		e.FirstVersion, e.LastVersion, e.Sample = uint64(first), uint64(last), sample
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
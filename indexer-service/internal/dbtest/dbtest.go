@@ -0,0 +1,33 @@
+// Package dbtest opens the database integration tests run against. Tests
+// that need one call Open and are skipped unless TEST_DATABASE_URL is set.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/migrations"
+)
+
+// Open connects to TEST_DATABASE_URL and runs the migrations, skipping the
+// test when it isn't set. The connection is closed when the test ends.
+func Open(t testing.TB) *db.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.New(url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(database.Close)
+
+	if err := database.Migrate(context.Background(), migrations.Service, migrations.FS); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return database
+}
@@ -0,0 +1,183 @@
+// Package deliverylog persists every webhook delivery attempt to
+// webhook_deliveries, so a missed notification can be traced to its target,
+// status code and latency, and redelivered. Attempts are queued and written
+// in batches off the delivery path. Until Start is called Record is a no-op.
+package deliverylog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+)
+
+const (
+	// Attempts waiting to be written; more are dropped while the database
+	// is slow or down
+	queueSize = 1000
+
+	batchSize     = 100
+	flushInterval = 2 * time.Second
+	pruneInterval = time.Hour
+	writeTimeout  = 5 * time.Second
+)
+
+type store struct {
+	db        *db.DB
+	retention time.Duration
+
+	queue  chan db.WebhookDelivery
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	global *store
+	mu     sync.Mutex
+)
+
+// Start records attempts from now on, deleting those older than retention
+// (0 keeps everything)
+func Start(database *db.DB, retention time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &store{
+		db:        database,
+		retention: retention,
+		queue:     make(chan db.WebhookDelivery, queueSize),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go s.run(ctx)
+
+	mu.Lock()
+	global = s
+	mu.Unlock()
+}
+
+// Stop writes what is still queued, waiting up to writeTimeout
+func Stop() {
+	mu.Lock()
+	s := global
+	global = nil
+	mu.Unlock()
+
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Hash is the payload hash recorded for body
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record queues one attempt; it is the webhook observer
+func Record(a webhook.Attempt) {
+	mu.Lock()
+	s := global
+	mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	record := db.WebhookDelivery{
+		Target:      a.Target,
+		EventType:   a.EventType,
+		Payload:     a.Payload,
+		PayloadHash: Hash(a.Payload),
+		Attempt:     a.Attempt,
+		Error:       a.Error,
+		LatencyMs:   int(a.Latency.Milliseconds()),
+		Delivered:   a.Error == "",
+		AttemptedAt: a.At,
+	}
+	if a.StatusCode != 0 {
+		code := a.StatusCode
+		record.StatusCode = &code
+	}
+
+	select {
+	case s.queue <- record:
+	default:
+		log.Warn().Str("target", a.Target).Msg("Webhook audit queue full, dropping attempt")
+	}
+}
+
+func (s *store) run(ctx context.Context) {
+	defer close(s.done)
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	s.prune()
+
+	batch := make([]db.WebhookDelivery, 0, batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case record := <-s.queue:
+					batch = append(batch, record)
+				default:
+					s.write(batch)
+					return
+				}
+			}
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-flush.C:
+		case <-prune.C:
+			s.prune()
+			continue
+		}
+
+		s.write(batch)
+		batch = batch[:0]
+	}
+}
+
+func (s *store) write(records []db.WebhookDelivery) {
+	if len(records) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := s.db.InsertWebhookDeliveries(ctx, records); err != nil {
+		log.Warn().Err(err).Int("attempts", len(records)).Msg("Failed to record webhook attempts")
+	}
+}
+
+func (s *store) prune() {
+	if s.retention <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	deleted, err := s.db.PruneWebhookDeliveries(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to prune webhook attempts")
+		return
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Dur("retention", s.retention).Msg("🧹 Pruned webhook attempts")
+	}
+}
@@ -0,0 +1,349 @@
+// Package diagnose runs the on-call runbook against a live indexer: it
+// inspects lag, checkpoint progress, quarantined events, webhook delivery,
+// the database pool and API keys, and turns what it finds into a prioritized
+// list of findings with remediation steps. It backs POST /admin/diagnose.
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Thresholds for the checks
+const (
+	lagWarning          = 10000 // versions
+	lagCritical         = 100000
+	checkpointStaleFor  = 5 * time.Minute
+	reconcileGapWarning = 50000 // versions between indexer and reconciler
+	quarantineWarning   = 1     // new quarantined events in the last hour
+	quarantineCritical  = 100
+	poolBusyWarning     = 0.8 // fraction of the pool in use
+)
+
+var severityRank = map[string]int{SeverityCritical: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// Finding is one problem found by a check, with what to do about it
+type Finding struct {
+	Severity    string   `json:"severity"`
+	Check       string   `json:"check"`
+	Summary     string   `json:"summary"`
+	Detail      string   `json:"detail,omitempty"`
+	Remediation []string `json:"remediation"`
+}
+
+type Report struct {
+	Healthy  bool      `json:"healthy"`
+	RanAt    time.Time `json:"ran_at"`
+	Checks   []string  `json:"checks"`
+	Errors   []string  `json:"errors,omitempty"` // checks that could not run
+	Findings []Finding `json:"findings"`
+}
+
+// Runner holds the live components to inspect. Rotator may be nil when API
+// key rotation is disabled.
+type Runner struct {
+	DB       *db.DB
+	Client   *aptos.Client
+	Listener *indexer.EventListener
+	Rotator  *aptos.APIKeyRotator
+	Scaling  indexer.ScalingTargets
+}
+
+// Run executes every check and returns the findings, most severe first. The
+// report is healthy when nothing critical or warning-level was found.
+func (r *Runner) Run(ctx context.Context) Report {
+	report := Report{Healthy: true, RanAt: time.Now().UTC(), Findings: []Finding{}}
+
+	checks := []struct {
+		name string
+		fn   func(ctx context.Context) ([]Finding, error)
+	}{
+		{"fullnode", r.checkFullnode},
+		{"lag", r.checkLag},
+		{"checkpoint", r.checkCheckpoint},
+		{"reconciliation_gap", r.checkReconciliationGap},
+		{"quarantine", r.checkQuarantine},
+		{"webhooks", r.checkWebhooks},
+		{"db_pool", r.checkPool},
+		{"api_keys", r.checkAPIKeys},
+	}
+
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		findings, err := c.fn(checkCtx)
+		cancel()
+
+		report.Checks = append(report.Checks, c.name)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", c.name, err))
+			continue
+		}
+		for _, f := range findings {
+			f.Check = c.name
+			report.Findings = append(report.Findings, f)
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severityRank[report.Findings[i].Severity] < severityRank[report.Findings[j].Severity]
+	})
+	for _, f := range report.Findings {
+		if f.Severity != SeverityInfo {
+			report.Healthy = false
+		}
+	}
+	if len(report.Errors) > 0 {
+		report.Healthy = false
+	}
+
+	return report
+}
+
+func (r *Runner) checkFullnode(ctx context.Context) ([]Finding, error) {
+	fresh := r.Client.Freshness()
+	if !fresh.Stale {
+		return nil, nil
+	}
+
+	detail := "no successful fullnode response yet"
+	if fresh.LastFreshAt != nil {
+		detail = fmt.Sprintf("last successful response %s ago", time.Since(*fresh.LastFreshAt).Round(time.Second))
+	}
+	return []Finding{{
+		Severity: SeverityCritical,
+		Summary:  "All RPC endpoints are unreachable; served data is stale",
+		Detail:   detail,
+		Remediation: []string{
+			"Check GET /status rpc_endpoints for per-endpoint errors",
+			"Verify the provider status page and API key quotas",
+			"Add a fallback endpoint to APTOS_RPC_ENDPOINTS and restart",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkLag(ctx context.Context) ([]Finding, error) {
+	signal := r.Listener.Scaling(r.Scaling)
+
+	if signal.Standby {
+		return []Finding{{
+			Severity: SeverityInfo,
+			Summary:  "This instance is on standby; another instance holds the writer lease",
+			Remediation: []string{
+				"Run the diagnosis on the lease holder (see writer_leases.owner)",
+			},
+		}}, nil
+	}
+
+	if signal.Lag < lagWarning {
+		return nil, nil
+	}
+
+	severity := SeverityWarning
+	if signal.Lag >= lagCritical || signal.ProcessingRate == 0 {
+		severity = SeverityCritical
+	}
+	eta := "no recent progress"
+	if signal.LagSeconds >= 0 {
+		eta = fmt.Sprintf("about %s to catch up", (time.Duration(signal.LagSeconds) * time.Second).Round(time.Second))
+	}
+	return []Finding{{
+		Severity: severity,
+		Summary:  fmt.Sprintf("Indexer is %d versions behind the ledger", signal.Lag),
+		Detail:   fmt.Sprintf("processing %.1f versions/s, %s", signal.ProcessingRate, eta),
+		Remediation: []string{
+			"Set NODIT_API_KEYS so large backlogs are caught up through Nodit",
+			"Raise APTOS_RPC_RPS if the rate limiter is the bottleneck (GET /status rpc_rate_limit)",
+			"Check GET /logs for repeated batch failures",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkCheckpoint(ctx context.Context) ([]Finding, error) {
+	// Age is computed in Postgres since updated_at has no time zone
+	var seconds float64
+	err := r.DB.Pool().QueryRow(ctx,
+		`SELECT EXTRACT(EPOCH FROM NOW()::timestamp - updated_at)::float8 FROM sync_state WHERE key = $1`,
+		r.Listener.CheckpointKey(),
+	).Scan(&seconds)
+	if err != nil {
+		return nil, err
+	}
+
+	stalled := time.Duration(seconds) * time.Second
+	signal := r.Listener.Scaling(r.Scaling)
+	if stalled < checkpointStaleFor || signal.Lag == 0 || signal.Standby {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Severity: SeverityCritical,
+		Summary:  fmt.Sprintf("Checkpoint hasn't advanced for %s while behind the ledger", stalled.Round(time.Second)),
+		Remediation: []string{
+			"Check GET /logs for \"Failed to process batch\" and the failing version",
+			"Look for a stuck writer lease: SELECT * FROM writer_leases",
+			"Inspect GET /admin/checkpoints history for when progress stopped",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkReconciliationGap(ctx context.Context) ([]Finding, error) {
+	var indexed, reconciled int64
+	err := r.DB.Pool().QueryRow(ctx, `
+		SELECT
+			COALESCE(MAX(CASE WHEN key = 'last_indexed_version' THEN value::bigint END), 0),
+			COALESCE(MAX(CASE WHEN key = 'last_reconciled_version' THEN value::bigint END), -1)
+		FROM sync_state
+	`).Scan(&indexed, &reconciled)
+	if err != nil {
+		return nil, err
+	}
+	if reconciled < 0 || indexed-reconciled < reconcileGapWarning {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Severity: SeverityWarning,
+		Summary:  fmt.Sprintf("Activities reconciliation is %d versions behind the indexer", indexed-reconciled),
+		Remediation: []string{
+			"Check the sync-service activities job in GET /admin/jobs",
+			"Trigger a run with POST /sync/activities on the sync-service",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkQuarantine(ctx context.Context) ([]Finding, error) {
+	var total, recent int
+	err := r.DB.Pool().QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '1 hour')
+		FROM quarantined_events
+	`).Scan(&total, &recent)
+	if err != nil {
+		return nil, err
+	}
+	if recent < quarantineWarning {
+		if total > 0 {
+			return []Finding{{
+				Severity:    SeverityInfo,
+				Summary:     fmt.Sprintf("%d quarantined events waiting for review", total),
+				Remediation: []string{"Review quarantined_events and replay the ones whose market now exists"},
+			}}, nil
+		}
+		return nil, nil
+	}
+
+	var reason string
+	_ = r.DB.Pool().QueryRow(ctx, `
+		SELECT reason FROM quarantined_events
+		WHERE created_at > NOW() - INTERVAL '1 hour'
+		GROUP BY reason ORDER BY COUNT(*) DESC LIMIT 1
+	`).Scan(&reason)
+
+	severity := SeverityWarning
+	if recent >= quarantineCritical {
+		severity = SeverityCritical
+	}
+	return []Finding{{
+		Severity: severity,
+		Summary:  fmt.Sprintf("%d events quarantined in the last hour (%d total)", recent, total),
+		Detail:   "most common reason: " + reason,
+		Remediation: []string{
+			"\"unknown market\": check that market creation reaches the Market table (webhook to the frontend)",
+			"Decode errors: the Move event layout may have changed; compare with internal/indexer/events.go",
+			"Replay fixed events by re-importing their versions (--import) once the cause is resolved",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkWebhooks(ctx context.Context) ([]Finding, error) {
+	var findings []Finding
+	for _, s := range r.Listener.WebhookStats() {
+		if s.Failed == 0 || s.LastFailureAt == nil || time.Since(*s.LastFailureAt) > time.Hour {
+			continue
+		}
+
+		severity := SeverityWarning
+		if s.Delivered == 0 {
+			severity = SeverityCritical
+		}
+		findings = append(findings, Finding{
+			Severity: severity,
+			Summary:  fmt.Sprintf("Webhook %s failed %d of %d deliveries", s.URL, s.Failed, s.Failed+s.Delivered),
+			Detail:   "last error: " + s.LastError,
+			Remediation: []string{
+				"Check that the receiving app is up and the URL in WEBHOOK_URL / WEBHOOK_TARGETS is correct",
+				"Failed deliveries are not retried; the sync-service reconciler backfills Activity rows",
+			},
+		})
+	}
+	return findings, nil
+}
+
+func (r *Runner) checkPool(ctx context.Context) ([]Finding, error) {
+	stat := r.DB.Pool().Stat()
+	if stat.MaxConns() == 0 {
+		return nil, nil
+	}
+
+	busy := float64(stat.AcquiredConns()) / float64(stat.MaxConns())
+	if busy < poolBusyWarning && stat.EmptyAcquireCount() == 0 {
+		return nil, nil
+	}
+
+	severity := SeverityInfo
+	if busy >= poolBusyWarning {
+		severity = SeverityWarning
+	}
+	return []Finding{{
+		Severity: severity,
+		Summary:  fmt.Sprintf("Database pool %d/%d connections in use", stat.AcquiredConns(), stat.MaxConns()),
+		Detail: fmt.Sprintf("%d acquires had to wait (total wait %s)",
+			stat.EmptyAcquireCount(), stat.AcquireDuration().Round(time.Millisecond)),
+		Remediation: []string{
+			"Raise pool_max_conns in DATABASE_URL if Postgres has headroom",
+			"Look for long-running queries in pg_stat_activity",
+		},
+	}}, nil
+}
+
+func (r *Runner) checkAPIKeys(ctx context.Context) ([]Finding, error) {
+	if r.Rotator == nil {
+		return nil, nil
+	}
+
+	keys := r.Rotator.Keys()
+	var unhealthy []string
+	for _, k := range keys {
+		if !k.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", k.Key, k.Provider))
+		}
+	}
+	if len(unhealthy) == 0 {
+		return nil, nil
+	}
+
+	severity := SeverityWarning
+	if len(unhealthy) == len(keys) {
+		severity = SeverityCritical
+	}
+	return []Finding{{
+		Severity: severity,
+		Summary:  fmt.Sprintf("%d of %d API keys quarantined", len(unhealthy), len(keys)),
+		Detail:   fmt.Sprintf("%v", unhealthy),
+		Remediation: []string{
+			"Check GET /rotator/stats for rate-limit vs error counts per key",
+			"Add keys to APTOS_API_KEYS / NODIT_API_KEYS or lower APTOS_RPC_RPS",
+		},
+	}}, nil
+}
@@ -0,0 +1,232 @@
+// Package errreport sends handler failures, poll errors and recovered panics
+// to Sentry, so they outlive the in-memory log buffer. It speaks Sentry's
+// envelope endpoint directly; until Init is called every function is a no-op.
+package errreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Reports waiting to be sent; more are dropped while Sentry is slow
+const queueSize = 100
+
+// The same error from the same source and event type is sent at most once
+// per window, whatever the tx and version, so a fullnode outage doesn't turn
+// every poll into an event
+const throttleWindow = time.Minute
+
+// Tags are indexed by Sentry and can be searched, e.g. event, tx, version
+type Tags map[string]string
+
+type reporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+
+	queue    chan []byte
+	inflight sync.WaitGroup
+
+	lastSent map[string]time.Time
+	mu       sync.Mutex
+}
+
+var global *reporter
+
+// Init starts reporting to the project in dsn
+// (https://<key>@<host>/<project id>). An empty dsn leaves reporting off.
+func Init(dsn, environment, release string) error {
+	if dsn == "" {
+		return nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.Host == "" {
+		return fmt.Errorf("invalid Sentry DSN")
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" {
+		return fmt.Errorf("Sentry DSN has no project id")
+	}
+	host, _ := os.Hostname()
+
+	r := &reporter{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/envelope/", u.Scheme, u.Host, project),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=verifi-indexer/1.0", u.User.Username()),
+		environment: environment,
+		release:     release,
+		serverName:  host,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan []byte, queueSize),
+		lastSent:    make(map[string]time.Time),
+	}
+	go r.run()
+
+	global = r
+	return nil
+}
+
+// Capture reports err with tags. Nil errors are ignored.
+func Capture(err error, tags Tags) {
+	if global == nil || err == nil {
+		return
+	}
+	global.capture("error", errorType(err), err.Error(), tags, nil)
+}
+
+// errorType names the innermost wrapped error, which is more telling than
+// the *fmt.wrapError every annotated error has on top
+func errorType(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
+
+// CapturePanic reports a recovered panic value with the goroutine's stack
+func CapturePanic(recovered any, stack []byte, tags Tags) {
+	if global == nil {
+		return
+	}
+	global.capture("fatal", "panic", fmt.Sprint(recovered), tags, map[string]any{"stack": string(stack)})
+}
+
+// Flush waits up to timeout for queued reports to be sent
+func Flush(timeout time.Duration) {
+	if global == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		global.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warn().Msg("Error reports still queued at shutdown, dropping them")
+	}
+}
+
+func (r *reporter) capture(level, errType, message string, tags Tags, extra map[string]any) {
+	if r.throttled(errType, message, tags) {
+		return
+	}
+
+	eventID := newEventID()
+	event := map[string]any{
+		"event_id":    eventID,
+		"timestamp":   timeconv.Now(),
+		"platform":    "go",
+		"level":       level,
+		"logger":      "verifi-indexer",
+		"server_name": r.serverName,
+		"environment": r.environment,
+		"release":     r.release,
+		"exception": map[string]any{
+			"values": []map[string]string{{"type": errType, "value": message}},
+		},
+		"tags":  tags,
+		"extra": extra,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to encode error report")
+		return
+	}
+
+	// Envelope: header, item header, item, one JSON document per line
+	var envelope bytes.Buffer
+	fmt.Fprintf(&envelope, `{"event_id":%q,"sent_at":%q}`+"\n", eventID, timeconv.Now())
+	fmt.Fprintf(&envelope, `{"type":"event","length":%d}`+"\n", len(payload))
+	envelope.Write(payload)
+	envelope.WriteByte('\n')
+
+	r.inflight.Add(1)
+	select {
+	case r.queue <- envelope.Bytes():
+	default:
+		r.inflight.Done()
+		log.Debug().Msg("Error report queue full, dropping report")
+	}
+}
+
+// throttled reports whether the same error was sent within throttleWindow
+func (r *reporter) throttled(errType, message string, tags Tags) bool {
+	key := errType + "|" + message
+	for _, name := range []string{"event", "source"} {
+		key += "|" + tags[name]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := r.lastSent[key]; ok && now.Sub(last) < throttleWindow {
+		return true
+	}
+	r.lastSent[key] = now
+
+	// Keep the map from growing with one-off errors
+	for k, sent := range r.lastSent {
+		if now.Sub(sent) >= throttleWindow {
+			delete(r.lastSent, k)
+		}
+	}
+	return false
+}
+
+func (r *reporter) run() {
+	for envelope := range r.queue {
+		if err := r.send(envelope); err != nil {
+			log.Debug().Err(err).Msg("Failed to send error report")
+		}
+		r.inflight.Done()
+	}
+}
+
+func (r *reporter) send(envelope []byte) error {
+	req, err := http.NewRequest("POST", r.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,203 @@
+// Package fixtures records fullnode transactions to JSON files and replays
+// them through the listener's handlers. Replays run in a database
+// transaction that is always rolled back, so a fixture can be checked
+// against any database, including a shared one, without leaving rows
+// behind. It backs the record and fixture commands.
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Transactions fetched per fullnode request while recording
+const recordBatchSize = 100
+
+// Tables whose new rows Replay counts
+var countedTables = []string{
+	`"Activity"`, `"Market"`, `"LpActivity"`, `"LpPosition"`, `"ProtocolFee"`, `"ResolutionHistory"`,
+	"processed_events", "quarantined_events", "deferred_events", "unknown_events",
+}
+
+// Fixture is a recorded set of transactions, as the fullnode returned them
+type Fixture struct {
+	Network       string                   `json:"network"`
+	ModuleAddress string                   `json:"module_address"`
+	FromVersion   uint64                   `json:"from_version"`
+	ToVersion     uint64                   `json:"to_version"`
+	RecordedAt    time.Time                `json:"recorded_at"`
+	Transactions  []aptos.TransactionEvent `json:"transactions"`
+}
+
+// Result is what a replay wrote before it was rolled back
+type Result struct {
+	Transactions  int              `json:"transactions"`
+	Events        int              `json:"events"` // of the module
+	HandlerErrors int64            `json:"handler_errors"`
+	Rows          map[string]int64 `json:"rows"` // new rows per table
+}
+
+// Passed reports whether every handler succeeded and nothing was quarantined
+func (r Result) Passed() bool {
+	return r.HandlerErrors == 0 && r.Rows["quarantined_events"] == 0
+}
+
+// errRollback ends a replay's transaction once it has been inspected
+var errRollback = errors.New("fixture replay rolled back")
+
+// Record fetches from..to and keeps the transactions that emitted events of
+// moduleAddress. Write set changes are dropped; no handler reads them.
+func Record(ctx context.Context, client *aptos.Client, network, moduleAddress string, from, to uint64) (Fixture, error) {
+	if to < from {
+		return Fixture{}, fmt.Errorf("invalid range %d-%d", from, to)
+	}
+
+	f := Fixture{
+		Network:       network,
+		ModuleAddress: moduleAddress,
+		FromVersion:   from,
+		ToVersion:     to,
+		RecordedAt:    time.Now().UTC(),
+		Transactions:  []aptos.TransactionEvent{},
+	}
+	for next := from; next <= to; {
+		batch, err := client.GetTransactionsByVersionRange(ctx, next, min(recordBatchSize, to-next+1))
+		if err != nil {
+			return Fixture{}, fmt.Errorf("failed to fetch versions from %d: %w", next, err)
+		}
+		if len(batch) == 0 {
+			return Fixture{}, fmt.Errorf("fullnode returned no transactions from %d", next)
+		}
+		for _, tx := range batch {
+			version, err := strconv.ParseUint(tx.Version, 10, 64)
+			if err != nil || version < next || version > to {
+				continue
+			}
+			next = version + 1
+			if len(aptos.FilterModuleEvents(tx, moduleAddress).Events) > 0 {
+				tx.Changes = nil
+				f.Transactions = append(f.Transactions, tx)
+			}
+		}
+	}
+	return f, nil
+}
+
+// RecordTx records the single transaction hash
+func RecordTx(ctx context.Context, client *aptos.Client, network, moduleAddress, hash string) (Fixture, error) {
+	tx, err := client.GetTransactionByHash(ctx, hash)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("failed to fetch transaction %s: %w", hash, err)
+	}
+	version, err := strconv.ParseUint(tx.Version, 10, 64)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("invalid version %q: %w", tx.Version, err)
+	}
+	tx.Changes = nil
+	return Fixture{
+		Network:       network,
+		ModuleAddress: moduleAddress,
+		FromVersion:   version,
+		ToVersion:     version,
+		RecordedAt:    time.Now().UTC(),
+		Transactions:  []aptos.TransactionEvent{*tx},
+	}, nil
+}
+
+// Load reads a fixture written by Save
+func Load(path string) (Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Fixture{}, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Save writes f as indented JSON, so fixtures diff well under version control
+func (f Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Replay runs f's transactions through l's handlers, on database, in one
+// transaction and rolls it back. inspect, when given, runs in that
+// transaction after the handlers, to check what they wrote; its error is
+// returned. Events l already claimed in processed_events are skipped as
+// usual, so replay against a database that hasn't indexed the fixture's
+// versions. Webhooks and bus events aren't sent, but markets the handlers
+// created stay in l's market cache.
+func Replay(ctx context.Context, database *db.DB, l *indexer.EventListener, f Fixture, inspect func(context.Context, pgx.Tx) error) (result Result, err error) {
+	result = Result{Transactions: len(f.Transactions), Rows: make(map[string]int64, len(countedTables))}
+	for _, tx := range f.Transactions {
+		result.Events += len(aptos.FilterModuleEvents(tx, l.ModuleAddress()).Events)
+	}
+
+	before, err := countRows(ctx, database.Pool())
+	if err != nil {
+		return result, err
+	}
+	handlerErrors := l.Stats().HandlerErrors
+	defer func() { result.HandlerErrors = l.Stats().HandlerErrors - handlerErrors }()
+
+	err = l.ProcessTransactionsWith(ctx, f.Transactions, func(ctx context.Context, q pgx.Tx) error {
+		after, err := countRows(ctx, q)
+		if err != nil {
+			return err
+		}
+		for table, n := range after {
+			result.Rows[strings.Trim(table, `"`)] = n - before[table]
+		}
+		if inspect != nil {
+			if err := inspect(ctx, q); err != nil {
+				return err
+			}
+		}
+		return errRollback
+	})
+	if errors.Is(err, errRollback) {
+		err = nil
+	}
+	return result, err
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// countRows counts the rows of every counted table that exists
+func countRows(ctx context.Context, q querier) (map[string]int64, error) {
+	counts := make(map[string]int64, len(countedTables))
+	for _, table := range countedTables {
+		var exists bool
+		if err := q.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		var n int64
+		if err := q.QueryRow(ctx, `SELECT count(*) FROM `+table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,263 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/dbtest"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/aptostest"
+)
+
+const (
+	testMarket  = "0xa1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+	testCreator = "0xc0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00"
+	testAlice   = "0xa11ce000a11ce000a11ce000a11ce000a11ce000a11ce000a11ce000a11ce000"
+	testBob     = "0xb0b00000b0b00000b0b00000b0b00000b0b00000b0b00000b0b00000b0b00000"
+)
+
+// newTestListener builds a listener for f's module against a mock fullnode
+// without transactions, so nothing a handler needs comes from the network
+func newTestListener(t *testing.T, database *db.DB, f Fixture) *indexer.EventListener {
+	t.Helper()
+
+	node := aptostest.New()
+	t.Cleanup(node.Close)
+
+	l := indexer.NewEventListener(aptos.NewClientWithURL(node.URL), database, f.ModuleAddress, "")
+	l.SetNetwork(f.Network, true)
+	return l
+}
+
+func loadFixture(t *testing.T, name string) Fixture {
+	t.Helper()
+
+	f, err := Load(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions int
+		events       int
+	}{
+		{"market_lifecycle.json", 4, 4},
+		{"unknown_market.json", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadFixture(t, tt.name)
+			if len(f.Transactions) != tt.transactions {
+				t.Errorf("transactions = %d, want %d", len(f.Transactions), tt.transactions)
+			}
+			events := 0
+			for _, tx := range f.Transactions {
+				events += len(aptos.FilterModuleEvents(tx, f.ModuleAddress).Events)
+			}
+			if events != tt.events {
+				t.Errorf("module events = %d, want %d", events, tt.events)
+			}
+		})
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	f := loadFixture(t, "market_lifecycle.json")
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join("testdata", "market_lifecycle.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != string(original) {
+		t.Errorf("Save didn't reproduce the checked-in fixture:\n%s", saved)
+	}
+}
+
+type activity struct {
+	User       string
+	Action     string
+	Outcome    string
+	Amount     float64
+	TotalValue float64
+	Timestamp  time.Time
+	Network    string
+}
+
+type market struct {
+	Creator     string
+	Description string
+	ResolvesAt  time.Time
+	Status      string
+	Outcome     string
+	Resolver    string
+	Network     string
+}
+
+func TestReplay(t *testing.T) {
+	database := dbtest.Open(t)
+	start := time.Date(2025, 10, 4, 22, 25, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		passed     bool
+		rows       map[string]int64
+		activities []activity
+		market     *market // of testMarket, nil when it must not exist
+	}{
+		{
+			name:   "market_lifecycle.json",
+			passed: true,
+			rows:   map[string]int64{"Activity": 2, "Market": 1, "processed_events": 4, "quarantined_events": 0},
+			activities: []activity{
+				{testAlice, "BUY", "YES", 4, 2.5, start.Add(time.Minute), "testnet"},
+				{testBob, "SELL", "NO", 1.5, 0.9, start.Add(2 * time.Minute), "testnet"},
+			},
+			market: &market{
+				Creator:     testCreator,
+				Description: "Will APT close above $10 on 2025-10-31?",
+				ResolvesAt:  time.Unix(1761955200, 0).UTC(),
+				Status:      "resolved",
+				Outcome:     "YES",
+				Resolver:    testCreator,
+				Network:     "testnet",
+			},
+		},
+		{
+			name:   "unknown_market.json",
+			passed: false,
+			rows:   map[string]int64{"Activity": 0, "Market": 0, "processed_events": 1, "quarantined_events": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := loadFixture(t, tt.name)
+			l := newTestListener(t, database, f)
+
+			var activities []activity
+			var got *market
+			result, err := Replay(ctx, database, l, f, func(ctx context.Context, q pgx.Tx) error {
+				var err error
+				activities, err = queryActivities(ctx, q, f)
+				if err != nil {
+					return err
+				}
+				got, err = queryMarket(ctx, q, testMarket)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+
+			if result.Passed() != tt.passed {
+				t.Errorf("Passed() = %v, want %v (%+v)", result.Passed(), tt.passed, result)
+			}
+			for table, want := range tt.rows {
+				if got := result.Rows[table]; got != want {
+					t.Errorf("new %s rows = %d, want %d", table, got, want)
+				}
+			}
+
+			if len(activities) != len(tt.activities) {
+				t.Fatalf("activities = %+v, want %+v", activities, tt.activities)
+			}
+			for i, want := range tt.activities {
+				if a := activities[i]; a.User != want.User || a.Action != want.Action || a.Outcome != want.Outcome ||
+					a.Amount != want.Amount || a.TotalValue != want.TotalValue || !a.Timestamp.Equal(want.Timestamp) ||
+					a.Network != want.Network {
+					t.Errorf("activity %d = %+v, want %+v", i, a, want)
+				}
+			}
+
+			switch {
+			case tt.market == nil && got != nil:
+				t.Errorf("market = %+v, want none", got)
+			case tt.market != nil && got == nil:
+				t.Errorf("market missing, want %+v", tt.market)
+			case tt.market != nil:
+				if got.Creator != tt.market.Creator || got.Description != tt.market.Description ||
+					!got.ResolvesAt.Equal(tt.market.ResolvesAt) || got.Status != tt.market.Status ||
+					got.Outcome != tt.market.Outcome || got.Resolver != tt.market.Resolver ||
+					got.Network != tt.market.Network {
+					t.Errorf("market = %+v, want %+v", got, tt.market)
+				}
+			}
+
+			// Nothing survives the rollback
+			if m, err := queryMarket(ctx, database.Pool(), testMarket); err != nil {
+				t.Fatal(err)
+			} else if m != nil {
+				t.Errorf("market %s left behind after the replay", testMarket)
+			}
+		})
+	}
+}
+
+type rowQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryActivities returns the activity rows of f's transactions in
+// timestamp order
+func queryActivities(ctx context.Context, q rowQuerier, f Fixture) ([]activity, error) {
+	hashes := make([]string, 0, len(f.Transactions))
+	for _, tx := range f.Transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT "userAddress", "action", COALESCE("outcome", ''), "amount"::float8,
+			COALESCE("totalValue", 0)::float8, "timestamp", "network"
+		FROM "Activity"
+		WHERE "txHash" = ANY($1)
+		ORDER BY "timestamp"
+	`, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (activity, error) {
+		var a activity
+		err := row.Scan(&a.User, &a.Action, &a.Outcome, &a.Amount, &a.TotalValue, &a.Timestamp, &a.Network)
+		return a, err
+	})
+}
+
+// queryMarket returns the Market row of address, or nil
+func queryMarket(ctx context.Context, q rowQuerier, address string) (*market, error) {
+	rows, err := q.Query(ctx, `
+		SELECT COALESCE("creator", ''), "description", "resolutionTimestamp", "status",
+			COALESCE("outcome", ''), COALESCE("resolver", ''), "network"
+		FROM "Market"
+		WHERE "marketAddress" = $1
+	`, address)
+	if err != nil {
+		return nil, err
+	}
+	markets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (market, error) {
+		var m market
+		err := row.Scan(&m.Creator, &m.Description, &m.ResolvesAt, &m.Status, &m.Outcome, &m.Resolver, &m.Network)
+		return m, err
+	})
+	if err != nil || len(markets) == 0 {
+		return nil, err
+	}
+	return &markets[0], nil
+}
@@ -0,0 +1,186 @@
+// Package health backs GET /health: it pings the database, fetches the
+// ledger head from the fullnode and compares it with the indexer checkpoint,
+// reporting each as a component so a 503 says which dependency is degraded.
+// Ready backs GET /readyz with the cheaper checks an orchestrator polls.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+)
+
+// Each dependency gets this long to answer
+const checkTimeout = 3 * time.Second
+
+// Reports are reused for this long, so frequent probes don't turn into a
+// fullnode request each
+const cacheTTL = 5 * time.Second
+
+type Component struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Service    string               `json:"service"`
+	Time       int64                `json:"time"`
+	Components map[string]Component `json:"components"`
+}
+
+// Healthy reports whether every component is healthy
+func (r Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+// Checker runs the checks. A lag above MaxLag versions marks the indexer
+// degraded; 0 disables the lag check. A listener that has run stays ready
+// while it is restarted, for up to ListenerGrace.
+type Checker struct {
+	Service       string
+	DB            *db.DB
+	Client        *aptos.Client
+	Listener      *indexer.EventListener
+	MaxLag        uint64
+	ListenerGrace time.Duration
+
+	cached   Report
+	cachedAt time.Time
+	mu       sync.Mutex
+}
+
+// Check returns the current report, at most cacheTTL old
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.cachedAt) < cacheTTL {
+		return c.cached
+	}
+
+	report := Report{
+		Status:     StatusHealthy,
+		Service:    c.Service,
+		Time:       time.Now().Unix(),
+		Components: make(map[string]Component),
+	}
+
+	report.Components["database"] = c.checkDatabase(ctx)
+	fullnode, ledgerVersion := c.checkFullnode(ctx)
+	report.Components["fullnode"] = fullnode
+	report.Components["indexer"] = c.checkLag(ledgerVersion, fullnode.Status == StatusHealthy)
+	report.Components["listener"] = c.checkListener()
+
+	for _, component := range report.Components {
+		if component.Status != StatusHealthy {
+			report.Status = StatusDegraded
+		}
+	}
+
+	c.cached, c.cachedAt = report, time.Now()
+	return report
+}
+
+// Ready reports whether the instance can serve: the database answers and the
+// listener has loaded its checkpoint and is still polling. It leaves out the
+// fullnode and lag, so a slow chain doesn't pull every replica out of
+// rotation at once, and isn't cached.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{
+		Status:     StatusHealthy,
+		Service:    c.Service,
+		Time:       time.Now().Unix(),
+		Components: make(map[string]Component),
+	}
+
+	report.Components["database"] = c.checkDatabase(ctx)
+	report.Components["listener"] = c.checkListener()
+
+	for _, component := range report.Components {
+		if component.Status != StatusHealthy {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// checkListener fails until Start has loaded the checkpoint, e.g. while the
+// fullnode is unreachable at startup, and once the listener has been down
+// for longer than ListenerGrace
+func (c *Checker) checkListener() Component {
+	if !c.Listener.Running() {
+		restarts := c.Listener.SupervisorStats().Restarts
+		down := c.Listener.DownFor()
+		if !c.Listener.EverStarted() || down > c.ListenerGrace {
+			return Component{Status: StatusDegraded, Detail: fmt.Sprintf("listener not running (down %s, %d restarts)", down.Round(time.Second), restarts)}
+		}
+		return Component{Status: StatusHealthy, Detail: fmt.Sprintf("listener restarting (down %s, %d restarts)", down.Round(time.Second), restarts)}
+	}
+	return Component{Status: StatusHealthy, Detail: fmt.Sprintf("at version %d", c.Listener.GetLastVersion())}
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.DB.Pool().Ping(ctx)
+	component := Component{Status: StatusHealthy, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		component.Status, component.Detail = StatusDegraded, err.Error()
+	}
+	return component
+}
+
+func (c *Checker) checkFullnode(ctx context.Context) (Component, uint64) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	version, err := c.Client.GetLatestLedgerInfo(ctx)
+	component := Component{Status: StatusHealthy, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		component.Status, component.Detail = StatusDegraded, err.Error()
+		return component, 0
+	}
+	component.Detail = fmt.Sprintf("ledger version %d via %s", version, c.Client.ActiveEndpoint())
+	return component, version
+}
+
+// checkLag compares the checkpoint with the safe version below the ledger
+// head. Standby and paused instances aren't expected to keep up, so they
+// stay healthy.
+func (c *Checker) checkLag(ledgerVersion uint64, ledgerKnown bool) Component {
+	last := c.Listener.GetLastVersion()
+	switch {
+	case c.Listener.Paused():
+		return Component{Status: StatusHealthy, Detail: fmt.Sprintf("paused at version %d", last)}
+	case c.Listener.Scaling(indexer.ScalingTargets{}).Standby:
+		return Component{Status: StatusHealthy, Detail: "standby, another instance holds the writer lease"}
+	case !ledgerKnown:
+		return Component{Status: StatusHealthy, Detail: fmt.Sprintf("at version %d, ledger head unknown", last)}
+	}
+
+	var lag uint64
+	if safe := ledgerVersion - min(ledgerVersion, c.Listener.ConfirmationDepth()); safe > last {
+		lag = safe - last
+	}
+	component := Component{Status: StatusHealthy, Detail: fmt.Sprintf("%d versions behind", lag)}
+	if c.MaxLag > 0 && lag > c.MaxLag {
+		component.Status = StatusDegraded
+		component.Detail += fmt.Sprintf(" (max %d)", c.MaxLag)
+	}
+	return component
+}
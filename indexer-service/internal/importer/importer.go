@@ -0,0 +1,288 @@
+// Package importer bootstraps history from an event dump (e.g. an export of
+// the Aptos indexer processor's events table) instead of scanning the
+// fullnode version by version.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/indexer"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Row is one event from the dump. Column names follow the Aptos indexer
+// events table; hash, sender and timestamp are optional.
+type Row struct {
+	TransactionVersion uint64          `json:"transaction_version"`
+	EventIndex         int             `json:"event_index"`
+	TransactionHash    string          `json:"transaction_hash"`
+	Sender             string          `json:"sender"`
+	Timestamp          string          `json:"timestamp"`
+	Type               string          `json:"type"`
+	Data               json.RawMessage `json:"data"`
+}
+
+type Options struct {
+	Path string
+	// csv or ndjson; detected from the file extension when empty
+	Format string
+	// Rows below this version are skipped, for resuming a time-boxed run
+	FromVersion uint64
+	// Stop cleanly after this long (0 = no limit)
+	Timeout time.Duration
+}
+
+type Result struct {
+	Rows         int           `json:"rows"`
+	Events       int           `json:"events"`
+	Transactions int           `json:"transactions"`
+	LastVersion  uint64        `json:"last_version"`
+	Completed    bool          `json:"completed"`
+	Duration     time.Duration `json:"duration"`
+}
+
+type Importer struct {
+	db            *db.DB
+	listener      *indexer.EventListener
+	moduleAddress string
+}
+
+func New(database *db.DB, listener *indexer.EventListener, moduleAddress string) *Importer {
+	return &Importer{
+		db:            database,
+		listener:      listener,
+		moduleAddress: strings.ToLower(moduleAddress),
+	}
+}
+
+// Run stores every module event from the dump in raw_events and runs each
+// transaction through the handler pipeline. Rows must be ordered by version.
+// When the time box expires the import stops between transactions and the
+// result reports the last fully imported version to resume from.
+func (im *Importer) Run(ctx context.Context, opts Options) (*Result, error) {
+	started := time.Now()
+	result := &Result{}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	f, err := os.Open(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	next, err := newReader(f, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	source := filepath.Base(opts.Path)
+	var pending []Row
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := im.importTransaction(context.WithoutCancel(ctx), pending, source); err != nil {
+			return err
+		}
+		result.Transactions++
+		result.Events += len(pending)
+		result.LastVersion = pending[0].TransactionVersion
+		pending = pending[:0]
+		return nil
+	}
+
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("row %d: %w", result.Rows+1, err)
+		}
+		result.Rows++
+
+		if row.TransactionVersion < opts.FromVersion || !strings.HasPrefix(strings.ToLower(row.Type), im.moduleAddress+"::") {
+			continue
+		}
+
+		if len(pending) > 0 && pending[0].TransactionVersion != row.TransactionVersion {
+			if err := flush(); err != nil {
+				return result, err
+			}
+
+			// Only stop on a transaction boundary so the resume point is exact
+			if ctx.Err() != nil {
+				result.Duration = time.Since(started)
+				log.Warn().
+					Uint64("last_version", result.LastVersion).
+					Msg("⏱️  Import time box reached, resume with the next version")
+				return result, nil
+			}
+
+			if result.Transactions%1000 == 0 {
+				log.Info().
+					Int("transactions", result.Transactions).
+					Uint64("version", result.LastVersion).
+					Msg("📥 Import progress")
+			}
+		}
+		pending = append(pending, row)
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	result.Completed = true
+	result.Duration = time.Since(started)
+	return result, nil
+}
+
+// importTransaction archives the rows of one transaction and replays them
+// through the handlers
+func (im *Importer) importTransaction(ctx context.Context, rows []Row, source string) error {
+	batch := &pgx.Batch{}
+	tx := aptos.TransactionEvent{
+		Version:   strconv.FormatUint(rows[0].TransactionVersion, 10),
+		Hash:      rows[0].TransactionHash,
+		Sender:    rows[0].Sender,
+		Timestamp: rows[0].Timestamp,
+		Success:   true,
+		Type:      "user_transaction",
+	}
+
+	for _, row := range rows {
+		batch.Queue(`
+			INSERT INTO raw_events (
+				transaction_version, event_index, tx_hash, sender,
+				tx_timestamp, event_type, data, source, network
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (network, transaction_version, event_index) DO NOTHING
+		`, int64(row.TransactionVersion), row.EventIndex, row.TransactionHash, row.Sender,
+			row.Timestamp, row.Type, string(row.Data), source, im.listener.Network())
+
+		var data map[string]interface{}
+		if err := json.Unmarshal(row.Data, &data); err != nil {
+			return fmt.Errorf("version %d event %d: invalid data: %w", row.TransactionVersion, row.EventIndex, err)
+		}
+		tx.Events = append(tx.Events, aptos.Event{
+			Version: tx.Version,
+			Type:    row.Type,
+			Data:    data,
+		})
+		tx.EventIndexes = append(tx.EventIndexes, row.EventIndex)
+	}
+
+	if err := im.db.Pool().SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store raw events: %w", err)
+	}
+
+	return im.listener.ProcessTransaction(ctx, tx)
+}
+
+func newReader(r io.Reader, opts Options) (func() (Row, error), error) {
+	format := opts.Format
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(opts.Path)) {
+		case ".csv":
+			format = "csv"
+		case ".json", ".jsonl", ".ndjson":
+			format = "ndjson"
+		case ".parquet":
+			format = "parquet"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return csvReader(r)
+	case "ndjson":
+		return ndjsonReader(r), nil
+	case "parquet":
+		return nil, fmt.Errorf("parquet dumps are not supported, convert to CSV first (e.g. duckdb -c \"COPY (SELECT * FROM 'dump.parquet') TO 'dump.csv'\")")
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+}
+
+func ndjsonReader(r io.Reader) func() (Row, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	return func() (Row, error) {
+		var row Row
+		err := dec.Decode(&row)
+		return row, err
+	}
+}
+
+func csvReader(r io.Reader) (func() (Row, error), error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"transaction_version", "event_index", "type", "data"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("CSV is missing column %q", required)
+		}
+	}
+
+	get := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	return func() (Row, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return Row{}, err
+		}
+
+		version, err := strconv.ParseUint(get(record, "transaction_version"), 10, 64)
+		if err != nil {
+			return Row{}, fmt.Errorf("invalid transaction_version: %w", err)
+		}
+		eventIndex, err := strconv.Atoi(get(record, "event_index"))
+		if err != nil {
+			return Row{}, fmt.Errorf("invalid event_index: %w", err)
+		}
+
+		return Row{
+			TransactionVersion: version,
+			EventIndex:         eventIndex,
+			TransactionHash:    get(record, "transaction_hash"),
+			Sender:             get(record, "sender"),
+			Timestamp:          get(record, "timestamp"),
+			Type:               get(record, "type"),
+			Data:               json.RawMessage(get(record, "data")),
+		}, nil
+	}, nil
+}
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// activityRow is a BUY/SELL row waiting to be written with its batch
+type activityRow struct {
+	TxHash        string
+	MarketAddress string
+	UserAddress   string
+	Action        string
+	Outcome       string
+	Amount        string // exact share decimal
+	TotalValue    string // exact APT decimal
+	Timestamp     time.Time
+}
+
+const insertActivityQuery = `
+	INSERT INTO "Activity" (
+		"id", "txHash", "marketAddress", "userAddress",
+		"action", "outcome", "amount", "totalValue", "timestamp", "network"
+	) VALUES (
+		gen_random_uuid(), $1, $2, $3, $4, $5, $6::numeric, $7::numeric, $8, $9
+	)
+`
+
+// queueActivity holds row until the batch flushes. Rows queued by an event
+// whose handler fails are dropped with the rest of its writes.
+func (l *EventListener) queueActivity(row activityRow) {
+	l.activities = append(l.activities, row)
+}
+
+// flushActivities writes every queued row in one round trip. During catch-up
+// a batch can hold hundreds of activities, which one INSERT each would turn
+// into as many round trips.
+func (l *EventListener) flushActivities(ctx context.Context, q pgx.Tx) error {
+	rows := l.activities
+	l.activities = nil
+	if len(rows) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, a := range rows {
+		batch.Queue(insertActivityQuery,
+			a.TxHash,
+			a.MarketAddress,
+			a.UserAddress,
+			a.Action,
+			a.Outcome,
+			a.Amount,
+			a.TotalValue,
+			a.Timestamp,
+			l.network,
+		)
+	}
+
+	if err := q.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert activities: %w", err)
+	}
+
+	log.Info().Int("activities", len(rows)).Msg("✅ Activities written")
+	return nil
+}
@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNotPaused is returned by SetVersion while the listener is polling
+var ErrNotPaused = errors.New("listener must be paused first")
+
+// errPaused stops a poll between batches once Pause is called
+var errPaused = errors.New("listener paused")
+
+// Pause stops polling once the batch in progress commits. The writer leases
+// are kept, so a standby replica doesn't take over while paused.
+func (l *EventListener) Pause() {
+	if !l.paused.Swap(true) {
+		log.Warn().Uint64("version", l.lastVersion.Load()).Msg("⏸️  Listener paused")
+	}
+}
+
+// Resume restarts polling at the base interval
+func (l *EventListener) Resume() {
+	if !l.paused.Swap(false) {
+		return
+	}
+	log.Info().Msg("▶️  Listener resumed")
+
+	// Wake the loop if it's waiting out a backed-off interval
+	select {
+	case l.control <- func() {}:
+	default:
+	}
+}
+
+// Paused reports whether polling is paused
+func (l *EventListener) Paused() bool {
+	return l.paused.Load()
+}
+
+// SetVersion moves the checkpoint to version while paused. Rewinding removes
+// everything indexed above version, like a reorg rollback, so those versions
+// are indexed again on resume; moving forward skips the versions in between.
+// It returns the previous checkpoint.
+func (l *EventListener) SetVersion(ctx context.Context, version uint64) (uint64, error) {
+	if !l.Paused() {
+		return 0, ErrNotPaused
+	}
+
+	type result struct {
+		previous uint64
+		err      error
+	}
+	done := make(chan result, 1)
+
+	// Run on the polling goroutine, which owns the checkpoint
+	fn := func() {
+		previous := l.lastVersion.Load()
+		done <- result{previous, l.setVersion(ctx, version)}
+	}
+	select {
+	case l.control <- fn:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	select {
+	case r := <-done:
+		return r.previous, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (l *EventListener) setVersion(ctx context.Context, version uint64) error {
+	leader, err := l.campaign(ctx)
+	if err != nil {
+		return err
+	}
+	if !leader {
+		return fmt.Errorf("another instance holds the writer lease")
+	}
+
+	if version < l.lastVersion.Load() {
+		return l.rollback(ctx, version, "checkpoint set by admin")
+	}
+
+	dbTx, err := l.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	if err := saveCheckpoint(ctx, dbTx, l.CheckpointKey(), version); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := l.fence(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(ctx); err != nil {
+		return err
+	}
+
+	log.Warn().
+		Uint64("from", l.lastVersion.Load()).
+		Uint64("to", version).
+		Msg("⏩ Checkpoint moved forward by admin, skipping versions")
+
+	l.lastVersion.Store(version)
+	l.sampleVersion = version
+	l.scaling.advance(version)
+	return nil
+}
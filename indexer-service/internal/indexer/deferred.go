@@ -0,0 +1,42 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// errMarketDeferred is returned by checkMarket instead of quarantining when
+// unknown markets are deferred
+var errMarketDeferred = errors.New("market not indexed yet, event deferred")
+
+// SetDeferUnknownMarkets makes events of markets that aren't indexed yet
+// land in deferred_events, unclaimed, instead of quarantined_events. Sharded
+// backfills set it: a trade's shard may commit before the shard holding its
+// MarketCreatedEvent.
+func (l *EventListener) SetDeferUnknownMarkets(deferUnknown bool) {
+	l.deferUnknownMarkets = deferUnknown
+}
+
+// deferEvent records the event at index of tx in deferred_events as part of
+// q
+func (l *EventListener) deferEvent(ctx context.Context, q pgx.Tx, tx aptos.TransactionEvent, index int, eventName string) error {
+	version, err := strconv.ParseInt(tx.Version, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", tx.Version, err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO deferred_events (transaction_version, event_index, event_type, tx_hash, network)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (network, transaction_version, event_index) DO NOTHING
+	`, version, index, eventName, tx.Hash, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to defer event: %w", err)
+	}
+	return nil
+}
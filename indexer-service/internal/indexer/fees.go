@@ -0,0 +1,65 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/pkg/amount"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// handleFeeCollected records a protocol fee in ProtocolFee. Fees are
+// revenue whoever paid them, so the sender lists don't apply.
+func (l *EventListener) handleFeeCollected(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("💰 FeeCollectedEvent detected")
+
+	fee, err := aptos.DecodeFeeCollected(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	aptAmount, err := amount.OctasToAPT(fee.FeeAmount)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	if known, err := l.checkMarket(ctx, q, event, tx, fee.MarketAddress); !known {
+		return err
+	}
+
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO "ProtocolFee" ("txHash", "marketAddress", "amount", "timestamp", "network")
+		VALUES ($1, $2, $3::numeric, $4, $5)
+	`, tx.Hash, fee.MarketAddress, aptAmount, timestamp, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to insert protocol fee: %w", err)
+	}
+
+	log.Info().
+		Str("market", fee.MarketAddress).
+		Str("apt", aptAmount).
+		Msg("✅ Protocol fee recorded")
+
+	if len(l.webhookClients) > 0 {
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = fee.MarketAddress
+		eventData["fee_amount"] = fee.FeeAmount
+		eventData["display"] = map[string]string{
+			"fee_amount": l.display.FormatOctas(fee.FeeAmount),
+		}
+
+		l.sendWebhook(event.Type, eventData, tx)
+	}
+
+	return nil
+}
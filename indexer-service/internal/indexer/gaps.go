@@ -0,0 +1,179 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+const (
+	// How often the leader re-fetches open gaps
+	gapRepairInterval = time.Minute
+
+	// Open gaps attempted per repair run, oldest first
+	gapRepairBatch = 20
+
+	// Gaps failing this often are left open for an operator
+	gapMaxAttempts = 10
+)
+
+// versionGap is a range the checkpoint moved past without indexing all of it
+type versionGap struct {
+	start, end uint64
+	reason     string
+}
+
+// findGaps returns the versions of start..end missing from txs, which the
+// fullnode returns in version order
+func findGaps(txs []aptos.TransactionEvent, start, end uint64) []versionGap {
+	var gaps []versionGap
+	expected := start
+	for _, tx := range txs {
+		version, err := strconv.ParseUint(tx.Version, 10, 64)
+		if err != nil || version < expected || version > end {
+			continue
+		}
+		if version > expected {
+			gaps = append(gaps, versionGap{start: expected, end: version - 1})
+		}
+		expected = version + 1
+	}
+	if expected <= end {
+		gaps = append(gaps, versionGap{start: expected, end: end})
+	}
+	for i := range gaps {
+		gaps[i].reason = fmt.Sprintf("fullnode returned %d of %d transactions", len(txs), end-start+1)
+	}
+	return gaps
+}
+
+// stageGap records a gap with the batch in progress
+func (l *EventListener) stageGap(tx aptos.TransactionEvent, reason string) {
+	version, err := strconv.ParseUint(tx.Version, 10, 64)
+	if err != nil {
+		return
+	}
+	l.gaps = append(l.gaps, versionGap{start: version, end: version, reason: reason})
+}
+
+// flushGaps writes the gaps staged for the batch in q. A range recorded
+// again is reopened.
+func (l *EventListener) flushGaps(ctx context.Context, q pgx.Tx) error {
+	for _, gap := range l.gaps {
+		log.Warn().
+			Uint64("start", gap.start).
+			Uint64("end", gap.end).
+			Str("reason", gap.reason).
+			Msg("🕳️  Version gap recorded for repair")
+
+		_, err := q.Exec(ctx, `
+			INSERT INTO version_gaps (network, start_version, end_version, reason)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (network, start_version, end_version) DO UPDATE SET
+				reason = EXCLUDED.reason,
+				attempts = 0,
+				detected_at = NOW(),
+				repaired_at = NULL
+		`, l.network, int64(gap.start), int64(gap.end), gap.reason)
+		if err != nil {
+			return fmt.Errorf("failed to record version gap: %w", err)
+		}
+	}
+	return nil
+}
+
+// repairGaps re-fetches open gaps below the checkpoint, at most once per
+// gapRepairInterval. Events that did apply are already claimed, so a gap is
+// only filled in where it was missed.
+func (l *EventListener) repairGaps(ctx context.Context) {
+	if time.Since(l.gapsRepairedAt) < gapRepairInterval {
+		return
+	}
+	l.gapsRepairedAt = time.Now()
+
+	rows, err := l.db.Pool().Query(ctx, `
+		SELECT id, start_version, end_version FROM version_gaps
+		WHERE network = $1 AND repaired_at IS NULL AND attempts < $2 AND end_version <= $3
+		ORDER BY start_version
+		LIMIT $4
+	`, l.network, gapMaxAttempts, int64(l.lastVersion.Load()), gapRepairBatch)
+	if err != nil {
+		log.Warn().Err(err).Msg("⚠️  Failed to load version gaps")
+		return
+	}
+	type openGap struct {
+		id         int64
+		start, end int64
+	}
+	var gaps []openGap
+	for rows.Next() {
+		var g openGap
+		if err := rows.Scan(&g.id, &g.start, &g.end); err != nil {
+			rows.Close()
+			log.Warn().Err(err).Msg("⚠️  Failed to load version gaps")
+			return
+		}
+		gaps = append(gaps, g)
+	}
+	rows.Close()
+
+	for _, g := range gaps {
+		if l.Paused() || ctx.Err() != nil {
+			return
+		}
+		err := l.repairGap(ctx, g.id, uint64(g.start), uint64(g.end))
+		if err == nil {
+			log.Info().
+				Int64("start", g.start).
+				Int64("end", g.end).
+				Msg("🩹 Version gap repaired")
+			continue
+		}
+
+		log.Warn().
+			Err(err).
+			Int64("start", g.start).
+			Int64("end", g.end).
+			Msg("⚠️  Version gap repair failed")
+		if _, err := l.db.Pool().Exec(ctx, `
+			UPDATE version_gaps SET attempts = attempts + 1, last_error = $2, last_attempt_at = NOW()
+			WHERE id = $1
+		`, g.id, err.Error()); err != nil {
+			log.Warn().Err(err).Msg("⚠️  Failed to record gap repair attempt")
+		}
+	}
+}
+
+// repairGap fetches start..end and applies it, closing the gap in the same
+// transaction. It fails without writing anything when the fullnode still
+// returns part of the range or a handler fails again.
+func (l *EventListener) repairGap(ctx context.Context, id int64, start, end uint64) error {
+	var txs []aptos.TransactionEvent
+	for next := start; next <= end; {
+		batch, err := l.client.GetTransactionsByVersionRange(ctx, next, min(pollBatchSize, end-next+1))
+		if err != nil {
+			return err
+		}
+		if missing := findGaps(batch, next, min(next+pollBatchSize-1, end)); len(missing) > 0 {
+			return fmt.Errorf("fullnode still missing versions %d-%d", missing[0].start, missing[0].end)
+		}
+		txs = append(txs, batch...)
+		next += uint64(len(batch))
+	}
+
+	return l.ProcessTransactionsWith(ctx, txs, func(ctx context.Context, q pgx.Tx) error {
+		if len(l.gaps) > 0 {
+			return fmt.Errorf("%s", l.gaps[0].reason)
+		}
+		_, err := q.Exec(ctx, `
+			UPDATE version_gaps SET attempts = attempts + 1, last_attempt_at = NOW(), repaired_at = NOW()
+			WHERE id = $1
+		`, id)
+		return err
+	})
+}
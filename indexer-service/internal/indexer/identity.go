@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// ErrMisconfigured is wrapped by VerifyIdentity when the fullnode answers but
+// serves a different chain, or the module isn't published on it. Such a
+// listener would poll without ever finding an event.
+var ErrMisconfigured = errors.New("listener misconfigured")
+
+// ChainIDKey is the sync_state key of the chain ID the listener's checkpoint
+// belongs to
+func (l *EventListener) ChainIDKey() string {
+	return "chain_id" + l.stateSuffix
+}
+
+// VerifyIdentity checks at startup that the fullnode serves the configured
+// network and the checkpoint's chain, and that modules are published at the
+// module address. The first run records the chain ID next to the checkpoint.
+// Errors not wrapping ErrMisconfigured mean the checks couldn't run, e.g.
+// with the fullnode down.
+func (l *EventListener) VerifyIdentity(ctx context.Context, modules []string) error {
+	info, err := l.client.GetLedgerInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get ledger info: %w", err)
+	}
+
+	if expected, ok := aptos.NetworkChainID(l.network); ok && info.ChainID != expected {
+		return fmt.Errorf("%w: fullnode %s serves chain %d, but %s is chain %d",
+			ErrMisconfigured, l.client.RPCURL(), info.ChainID, l.network, expected)
+	}
+
+	var stored string
+	err = l.db.Pool().QueryRow(ctx, `SELECT value FROM sync_state WHERE key = $1`, l.ChainIDKey()).Scan(&stored)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		stored = ""
+	case err != nil:
+		return fmt.Errorf("failed to load chain id: %w", err)
+	}
+	if stored != "" && stored != strconv.Itoa(int(info.ChainID)) {
+		return fmt.Errorf("%w: checkpoint %s was written for chain %s, but fullnode %s serves chain %d",
+			ErrMisconfigured, l.CheckpointKey(), stored, l.client.RPCURL(), info.ChainID)
+	}
+
+	// A checkpoint past the ledger head was written against another chain,
+	// e.g. before the chain ID was recorded
+	var checkpoint string
+	if err := l.db.Pool().QueryRow(ctx, `SELECT value FROM sync_state WHERE key = $1`, l.CheckpointKey()).Scan(&checkpoint); err == nil {
+		if version, err := strconv.ParseUint(checkpoint, 10, 64); err == nil && version > info.LedgerVersion {
+			return fmt.Errorf("%w: checkpoint %s is at version %d, past the ledger head %d of fullnode %s",
+				ErrMisconfigured, l.CheckpointKey(), version, info.LedgerVersion, l.client.RPCURL())
+		}
+	}
+
+	// The fullnode answers 404 for an account that doesn't exist
+	published, err := l.client.GetAccountModules(ctx, l.moduleAddress)
+	if err != nil && !strings.Contains(err.Error(), "status=404") {
+		return fmt.Errorf("failed to list modules at %s: %w", l.moduleAddress, err)
+	}
+	if len(published) == 0 {
+		return fmt.Errorf("%w: no modules published at %s on %s (chain %d)",
+			ErrMisconfigured, l.moduleAddress, l.network, info.ChainID)
+	}
+	var missing []string
+	for _, module := range modules {
+		if !slices.Contains(published, module) {
+			missing = append(missing, module)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: module %s not published at %s on %s (chain %d), found %s",
+			ErrMisconfigured, strings.Join(missing, ", "), l.moduleAddress, l.network, info.ChainID, strings.Join(published, ", "))
+	}
+
+	if stored == "" {
+		if _, err := l.db.Pool().Exec(ctx, `
+			INSERT INTO sync_state (key, value, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (key) DO NOTHING
+		`, l.ChainIDKey(), strconv.Itoa(int(info.ChainID))); err != nil {
+			return fmt.Errorf("failed to record chain id: %w", err)
+		}
+	}
+
+	log.Info().
+		Str("network", l.network).
+		Uint8("chain_id", info.ChainID).
+		Str("module_address", l.moduleAddress).
+		Strs("modules", modules).
+		Msg("✅ Network and module verified")
+	return nil
+}
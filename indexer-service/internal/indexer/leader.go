@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// errLeaseLost aborts a write whose transaction found the writer leases
+// expired or taken over, e.g. after a batch outlasted the lease TTL
+var errLeaseLost = errors.New("writer lease lost to another instance")
+
+// campaign takes or renews the writer leases and reports whether this
+// instance leads. A standby keeps its checkpoint in step with the leader's,
+// and on taking over reloads it before writing anything, so it continues
+// where the previous leader committed.
+func (l *EventListener) campaign(ctx context.Context) (bool, error) {
+	owned, err := l.acquireLeases(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if !owned {
+		if l.leader.Swap(false) {
+			log.Warn().Str("owner", l.owner).Msg("⚠️  Writer lease taken over by another instance, standing by")
+		}
+		if err := l.loadLastVersion(ctx); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Warn().Err(err).Msg("Failed to follow the leader's checkpoint")
+		}
+		return false, nil
+	}
+
+	if l.leader.Load() {
+		return true, nil
+	}
+
+	previous := l.lastVersion.Load()
+	if err := l.loadLastVersion(ctx); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("failed to load checkpoint on taking the writer lease: %w", err)
+	}
+	if err := l.markets.Refresh(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to refresh market cache on taking the writer lease")
+	}
+	l.sampleAt, l.sampleVersion, l.samplePolls = time.Now(), l.lastVersion.Load(), 0
+	l.scaling.advance(l.lastVersion.Load())
+
+	l.leaderSince.Store(time.Now().UnixNano())
+	l.leader.Store(true)
+	log.Info().
+		Str("owner", l.owner).
+		Uint64("version", l.lastVersion.Load()).
+		Uint64("previous_version", previous).
+		Msg("👑 Writer lease acquired, indexing as leader")
+	return true, nil
+}
+
+// resign gives up leadership and the writer leases on shutdown, so a
+// standby takes over on its next poll instead of waiting out the TTL
+func (l *EventListener) resign() {
+	l.leader.Store(false)
+	l.releaseLeases()
+}
+
+// fence renews the writer leases inside q just before it commits. When they
+// were lost, q must not commit: another instance may already be writing.
+func (l *EventListener) fence(ctx context.Context, q pgx.Tx) error {
+	held, err := store.RenewLeases(ctx, q, l.leases(), l.owner, writerLeaseTTL)
+	if err != nil {
+		return err
+	}
+	if !held {
+		l.leader.Store(false)
+		return errLeaseLost
+	}
+	return nil
+}
+
+// AcquireWriterLeases takes the writer leases for a one-off command that
+// writes through ProcessTransactions (imports, replays), reporting whether
+// it got them. They are renewed with every batch it commits; release them
+// with ReleaseWriterLeases when done.
+func (l *EventListener) AcquireWriterLeases(ctx context.Context) (bool, error) {
+	return l.acquireLeases(ctx)
+}
+
+// ReleaseWriterLeases gives up the leases AcquireWriterLeases took
+func (l *EventListener) ReleaseWriterLeases() {
+	l.releaseLeases()
+}
+
+// SetUnfenced lets ProcessTransactions commit without the writer leases.
+// Only sharded backfills set it: each batch is guarded by its shard lease
+// or the job's merge claim instead, so they can run next to the live
+// listener.
+func (l *EventListener) SetUnfenced(unfenced bool) {
+	l.unfenced = unfenced
+}
+
+// Leader reports whether this instance holds the writer leases, and since
+// when
+func (l *EventListener) Leader() (bool, time.Time) {
+	if !l.leader.Load() {
+		return false, time.Time{}
+	}
+	return true, time.Unix(0, l.leaderSince.Load())
+}
@@ -0,0 +1,236 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/pkg/amount"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// lpChange is one deposit or withdrawal, as written to LpActivity and
+// applied to the provider's LpPosition
+type lpChange struct {
+	TxHash        string
+	MarketAddress string
+	UserAddress   string
+	Action        string // ADD or REMOVE
+	APT           string // exact APT decimal
+	LpShares      string // exact LP share decimal
+	Timestamp     time.Time
+	Network       string
+}
+
+func (l *EventListener) handleLiquidityAdded(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("💧 LiquidityAddedEvent detected")
+
+	added, err := aptos.DecodeLiquidityAdded(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	aptAmount, err := amount.OctasToAPT(added.AptAmountIn)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	lpShares, err := amount.SharesToUnits(added.LpSharesMinted)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	if known, err := l.checkMarket(ctx, q, event, tx, added.MarketAddress); !known {
+		return err
+	}
+
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := applyLpChange(ctx, q, lpChange{
+		TxHash:        tx.Hash,
+		MarketAddress: added.MarketAddress,
+		UserAddress:   added.Provider,
+		Action:        "ADD",
+		APT:           aptAmount,
+		LpShares:      lpShares,
+		Timestamp:     timestamp,
+		Network:       l.network,
+	}); err != nil {
+		return err
+	}
+
+	if len(l.webhookClients) > 0 {
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = added.MarketAddress
+		eventData["provider"] = added.Provider
+		eventData["apt_amount_in"] = added.AptAmountIn
+		eventData["lp_shares_minted"] = added.LpSharesMinted
+		eventData["display"] = map[string]string{
+			"apt_amount_in":    l.display.FormatOctas(added.AptAmountIn),
+			"lp_shares_minted": l.display.FormatShares(added.LpSharesMinted),
+		}
+
+		l.sendWebhook(event.Type, eventData, tx)
+	}
+
+	return nil
+}
+
+func (l *EventListener) handleLiquidityRemoved(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("💧 LiquidityRemovedEvent detected")
+
+	removed, err := aptos.DecodeLiquidityRemoved(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	aptAmount, err := amount.OctasToAPT(removed.AptAmountOut)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	lpShares, err := amount.SharesToUnits(removed.LpSharesBurned)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	if known, err := l.checkMarket(ctx, q, event, tx, removed.MarketAddress); !known {
+		return err
+	}
+
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := applyLpChange(ctx, q, lpChange{
+		TxHash:        tx.Hash,
+		MarketAddress: removed.MarketAddress,
+		UserAddress:   removed.Provider,
+		Action:        "REMOVE",
+		APT:           aptAmount,
+		LpShares:      lpShares,
+		Timestamp:     timestamp,
+		Network:       l.network,
+	}); err != nil {
+		return err
+	}
+
+	if len(l.webhookClients) > 0 {
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = removed.MarketAddress
+		eventData["provider"] = removed.Provider
+		eventData["lp_shares_burned"] = removed.LpSharesBurned
+		eventData["apt_amount_out"] = removed.AptAmountOut
+		eventData["display"] = map[string]string{
+			"lp_shares_burned": l.display.FormatShares(removed.LpSharesBurned),
+			"apt_amount_out":   l.display.FormatOctas(removed.AptAmountOut),
+		}
+
+		l.sendWebhook(event.Type, eventData, tx)
+	}
+
+	return nil
+}
+
+// applyLpChange records c in LpActivity and moves the provider's position
+// by it, as part of the event's transaction
+func applyLpChange(ctx context.Context, q pgx.Tx, c lpChange) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO "LpActivity" (
+			"txHash", "marketAddress", "userAddress", "action", "aptAmount", "lpShares", "timestamp", "network"
+		) VALUES ($1, $2, $3, $4, $5::numeric, $6::numeric, $7, $8)
+	`, c.TxHash, c.MarketAddress, c.UserAddress, c.Action, c.APT, c.LpShares, c.Timestamp, c.Network)
+	if err != nil {
+		return fmt.Errorf("failed to insert LP activity: %w", err)
+	}
+
+	shares, deposited, withdrawn := c.LpShares, c.APT, "0"
+	if c.Action == "REMOVE" {
+		shares, deposited, withdrawn = "-"+c.LpShares, "0", c.APT
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO "LpPosition" ("marketAddress", "userAddress", "lpShares", "aptDeposited", "aptWithdrawn", "updatedAt", "network")
+		VALUES ($1, $2, $3::numeric, $4::numeric, $5::numeric, $6, $7)
+		ON CONFLICT ("marketAddress", "userAddress") DO UPDATE SET
+			"lpShares" = "LpPosition"."lpShares" + EXCLUDED."lpShares",
+			"aptDeposited" = "LpPosition"."aptDeposited" + EXCLUDED."aptDeposited",
+			"aptWithdrawn" = "LpPosition"."aptWithdrawn" + EXCLUDED."aptWithdrawn",
+			"updatedAt" = GREATEST("LpPosition"."updatedAt", EXCLUDED."updatedAt")
+	`, c.MarketAddress, c.UserAddress, shares, deposited, withdrawn, c.Timestamp, c.Network)
+	if err != nil {
+		return fmt.Errorf("failed to update LP position: %w", err)
+	}
+
+	log.Info().
+		Str("market", c.MarketAddress).
+		Str("provider", c.UserAddress).
+		Str("action", c.Action).
+		Str("apt", c.APT).
+		Str("lp_shares", c.LpShares).
+		Msg("✅ LP activity recorded")
+	return nil
+}
+
+// rollbackLiquidity deletes the LP activity of network's transactions
+// above version and rebuilds the affected positions from what remains
+func rollbackLiquidity(ctx context.Context, q pgx.Tx, network string, version int64) (int64, error) {
+	rows, err := q.Query(ctx, `
+		DELETE FROM "LpActivity"
+		WHERE "network" = $2
+			AND "txHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)
+		RETURNING "marketAddress", "userAddress"
+	`, version, network)
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll back LP activity: %w", err)
+	}
+	var markets, users []string
+	var removed int64
+	for rows.Next() {
+		var market, user string
+		if err := rows.Scan(&market, &user); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		markets, users = append(markets, market), append(users, user)
+		removed++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to roll back LP activity: %w", err)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE "LpPosition" p SET
+			"lpShares" = COALESCE(t.shares, 0),
+			"aptDeposited" = COALESCE(t.deposited, 0),
+			"aptWithdrawn" = COALESCE(t.withdrawn, 0),
+			"updatedAt" = COALESCE(t.last, p."updatedAt")
+		FROM (SELECT DISTINCT * FROM unnest($1::text[], $2::text[]) AS a(market, "user")) a
+		LEFT JOIN LATERAL (
+			SELECT
+				SUM(CASE WHEN "action" = 'ADD' THEN "lpShares" ELSE -"lpShares" END) AS shares,
+				SUM(CASE WHEN "action" = 'ADD' THEN "aptAmount" ELSE 0 END) AS deposited,
+				SUM(CASE WHEN "action" = 'REMOVE' THEN "aptAmount" ELSE 0 END) AS withdrawn,
+				MAX("timestamp") AS last
+			FROM "LpActivity"
+			WHERE "marketAddress" = a.market AND "userAddress" = a."user"
+		) t ON TRUE
+		WHERE p."marketAddress" = a.market AND p."userAddress" = a."user"
+	`, markets, users)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild LP positions: %w", err)
+	}
+	return removed, nil
+}
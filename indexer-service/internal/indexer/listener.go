@@ -0,0 +1,1667 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/archive"
+	"github.com/verifi-protocol/indexer-service/internal/bus"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/errreport"
+	"github.com/verifi-protocol/indexer-service/internal/notify"
+	"github.com/verifi-protocol/indexer-service/internal/subscriptions"
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+	"github.com/verifi-protocol/pkg/amount"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/eventmap"
+	"github.com/verifi-protocol/pkg/senders"
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+type EventListener struct {
+	client          *aptos.Client
+	db              *db.DB
+	moduleAddress   string
+	network         string
+	stateSuffix     string        // appended to the checkpoint key and writer leases
+	lastVersion     atomic.Uint64 // read by replays and the admin API while polling advances it
+	pollInterval    time.Duration
+	eventHandlers   map[string]EventHandler
+	webhookClients  []*webhook.WebhookClient
+	digests         []*webhook.Digest
+	verboseMode     bool
+	markets         *MarketCache
+	owner           string
+	nodit           *aptos.NoditClient
+	display         amount.Policy
+	senders         *senders.Filter
+	events          *eventmap.Mapper
+	notifier        *notify.Notifier
+	marketView      string
+	subscriptions   *subscriptions.Registry
+	publishers      []*bus.Publisher
+	archiver        *archive.Archiver
+	reorgCheckDepth int
+
+	// Versions kept between the ledger head and what is processed
+	confirmationDepth uint64
+
+	// Gaps staged with the batch in progress, and the last repair run
+	gaps           []versionGap
+	gapsRepairedAt time.Time
+
+	// Batches fetched ahead of the commit and lookups run per batch
+	pipelineDepth   int
+	pipelineWorkers int
+
+	// Write queue between fetching and commits, how long fetching waits on
+	// it when full, and whether the last poll was cut short by that
+	writes          writeQueueTracker
+	writeQueueStall time.Duration
+	backpressured   bool
+
+	// Sharded backfills defer events of markets not indexed yet instead of
+	// quarantining them, since shards commit out of version order
+	deferUnknownMarkets bool
+
+	// Backfills don't hold the writer leases: their shard and merge claims
+	// guard their batches instead
+	unfenced bool
+
+	// Adaptive polling: the interval doubles while idle, up to
+	// maxPollInterval, and drops back to pollInterval on activity
+	maxPollInterval time.Duration
+	currentInterval atomic.Int64
+	moduleTxs       uint64 // transactions with module events, for idle detection
+
+	// Checkpoint history sampling
+	sampleAt      time.Time
+	sampleVersion uint64
+	samplePolls   int
+
+	scaling scalingTracker
+	stats   *statsTracker
+
+	// Webhook and digest notifications and market cache updates held until
+	// the batch that produced them commits
+	pending []func()
+
+	// Activity rows held until the batch flushes them together
+	activities []activityRow
+
+	// Webhook and bus deliveries written to the outbox with the batch, when
+	// enabled, instead of being held in pending
+	useOutbox         bool
+	outboxMaxAttempts int
+	outboxRows        []db.OutboxRecord
+	outboxKick        chan struct{}
+
+	// Settings scheduled by Reload and applied between batches. settingsMu
+	// also guards the webhook targets and sender filter against readers
+	// outside the polling goroutine.
+	reload      *Settings
+	reloaded    chan struct{}
+	settingsMu  sync.Mutex
+	digestCtx   context.Context
+	stopDigests context.CancelFunc
+
+	// Admin control: pause flag and work run on the polling goroutine
+	paused  atomic.Bool
+	control chan func()
+
+	// Set once the checkpoint is loaded and cleared when Start returns
+	running atomic.Bool
+
+	// Restarts of Start under Supervise; the market cache refresher is
+	// started with the first run only
+	supervisor  supervisor
+	marketsOnce sync.Once
+
+	// Unix nanoseconds of the last poll that completed without error
+	lastPoll atomic.Int64
+
+	// Set while this instance holds every writer lease; leaderSince is the
+	// Unix nanoseconds it took them
+	leader      atomic.Bool
+	leaderSince atomic.Int64
+}
+
+// Tables written by the listener. Each is guarded by a single-writer lease so
+// two listeners (e.g. a second replica) never ingest the same events at once.
+var writerLeases = []string{"writer:Activity", "writer:Market"}
+
+const writerLeaseTTL = 30 * time.Second
+
+// Checkpoint advances are sampled into checkpoint_history at most this often
+const checkpointSampleInterval = time.Minute
+
+// Events that produce Activity rows, subject to the sender allow/deny lists
+var activityEvents = map[string]bool{
+	"SharesMintedEvent": true,
+	"SharesBurnedEvent": true,
+}
+
+// Backlogs larger than this are caught up through the Nodit indexer, which
+// only returns the versions that contain our events
+const noditCatchupThreshold = uint64(10000)
+
+func (l *EventListener) GetLastVersion() uint64 {
+	return l.lastVersion.Load()
+}
+
+// SetNetwork labels every row the listener writes with network. A
+// deployment's first network keeps the checkpoint key and writer leases of
+// a single-network indexer, so adding networks doesn't reindex it; the
+// others get their own, suffixed with the network name.
+func (l *EventListener) SetNetwork(network string, first bool) {
+	l.network = network
+	l.markets.SetNetwork(network)
+	l.stateSuffix = ""
+	if !first {
+		l.stateSuffix = ":" + network
+	}
+}
+
+// Network returns the network the listener indexes
+func (l *EventListener) Network() string {
+	return l.network
+}
+
+// ModuleAddress returns the module whose events the listener indexes
+func (l *EventListener) ModuleAddress() string {
+	return l.moduleAddress
+}
+
+// CheckpointKey is the sync_state key of the listener's checkpoint
+func (l *EventListener) CheckpointKey() string {
+	return "last_indexed_version" + l.stateSuffix
+}
+
+// leases are the writer lease names of the listener's network
+func (l *EventListener) leases() []string {
+	names := make([]string, len(writerLeases))
+	for i, name := range writerLeases {
+		names[i] = name + l.stateSuffix
+	}
+	return names
+}
+
+// SetNoditClient enables Nodit-backed catch-up for large backlogs
+func (l *EventListener) SetNoditClient(nodit *aptos.NoditClient) {
+	l.nodit = nodit
+}
+
+// SetDisplayPolicy sets the rounding used for display amounts in webhooks
+func (l *EventListener) SetDisplayPolicy(policy amount.Policy) {
+	l.display = policy
+}
+
+// SetSenderFilter excludes activity from senders outside the allow/deny
+// lists. Excluded events are still archived in raw_events.
+func (l *EventListener) SetSenderFilter(filter *senders.Filter) {
+	l.senders = filter
+}
+
+// SetEventMapper normalizes the events of older contract versions before
+// they reach the handlers
+func (l *EventListener) SetEventMapper(mapper *eventmap.Mapper) {
+	l.events = mapper
+}
+
+// SetNotifier announces market events in chat channels
+func (l *EventListener) SetNotifier(notifier *notify.Notifier) {
+	l.notifier = notifier
+}
+
+// SetSubscriptions delivers processed events to matching user subscriptions
+func (l *EventListener) SetSubscriptions(registry *subscriptions.Registry) {
+	l.subscriptions = registry
+}
+
+// AddPublisher publishes every processed event to a message bus
+func (l *EventListener) AddPublisher(publisher *bus.Publisher) {
+	l.publishers = append(l.publishers, publisher)
+}
+
+// SetArchiver archives the transactions of every committed batch
+func (l *EventListener) SetArchiver(archiver *archive.Archiver) {
+	l.archiver = archiver
+}
+
+// Markets returns the market cache shared by the handlers
+func (l *EventListener) Markets() *MarketCache {
+	return l.markets
+}
+
+// WebhookStats returns the delivery outcomes of every webhook target
+func (l *EventListener) WebhookStats() []webhook.DeliveryStats {
+	l.settingsMu.Lock()
+	defer l.settingsMu.Unlock()
+
+	stats := make([]webhook.DeliveryStats, 0, len(l.webhookClients)+len(l.digests))
+	for _, client := range l.webhookClients {
+		stats = append(stats, client.Stats())
+	}
+	for _, digest := range l.digests {
+		stats = append(stats, digest.Stats())
+	}
+	return stats
+}
+
+// WebhookClient returns the per-event webhook target for url, or nil when
+// no such target is configured
+func (l *EventListener) WebhookClient(url string) *webhook.WebhookClient {
+	l.settingsMu.Lock()
+	defer l.settingsMu.Unlock()
+
+	for _, client := range l.webhookClients {
+		if client.URL == url {
+			return client
+		}
+	}
+	return nil
+}
+
+// AddWebhookTarget registers an extra webhook target. Digest targets get a
+// per-market trade summary every interval instead of one call per event.
+func (l *EventListener) AddWebhookTarget(target webhook.Target) {
+	client := webhook.NewWebhookClient(target.URL)
+	client.SetFormat(target.Format)
+	if target.Mode == webhook.ModeDigest {
+		l.digests = append(l.digests, webhook.NewDigest(client, target.Interval))
+	} else {
+		l.webhookClients = append(l.webhookClients, client)
+	}
+
+	log.Info().
+		Str("webhook_url", target.URL).
+		Str("mode", target.Mode).
+		Dur("interval", target.Interval).
+		Str("format", client.Format().Name()).
+		Msg("📡 Webhook target added")
+}
+
+func (l *EventListener) SetVerboseMode(enable bool) {
+	l.verboseMode = enable
+	log.Info().Bool("verbose", enable).Msg("🔧 Verbose mode toggled")
+}
+
+// EventHandler applies one module event. Its writes go through q, the
+// transaction that claims the event in processed_events, so an event is
+// either fully applied and recorded or not at all.
+type EventHandler func(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error
+
+func NewEventListener(client *aptos.Client, database *db.DB, moduleAddress string, webhookURL string) *EventListener {
+	var webhookClients []*webhook.WebhookClient
+
+	log.Info().
+		Str("webhook_url", webhookURL).
+		Bool("is_empty", webhookURL == "").
+		Msg("🔧 Initializing EventListener with webhook config")
+
+	if webhookURL != "" {
+		webhookClients = append(webhookClients, webhook.NewWebhookClient(webhookURL))
+		log.Info().Str("webhook_url", webhookURL).Msg("📡 Webhook client initialized successfully")
+	} else {
+		log.Warn().Msg("⚠️  No webhook URL provided, notifications will not be sent")
+	}
+
+	l := &EventListener{
+		client:          client,
+		db:              database,
+		moduleAddress:   moduleAddress,
+		pollInterval:    5 * time.Second, // Poll every 5 seconds
+		eventHandlers:   make(map[string]EventHandler),
+		webhookClients:  webhookClients,
+		markets:         NewMarketCache(database),
+		owner:           store.InstanceID("indexer-service"),
+		display:         amount.DefaultPolicy(),
+		reorgCheckDepth: 5,
+		pipelineDepth:   2,
+		pipelineWorkers: 4,
+		writeQueueStall: 30 * time.Second,
+		reloaded:        make(chan struct{}, 1),
+		control:         make(chan func()),
+		stats:           newStatsTracker(),
+	}
+
+	// Register default handlers
+	l.registerDefaultHandlers()
+
+	return l
+}
+
+// Register event handlers
+func (l *EventListener) RegisterHandler(eventType string, handler EventHandler) {
+	l.eventHandlers[eventType] = handler
+}
+
+// Running reports whether Start has loaded the checkpoint and its polling
+// loop hasn't returned
+func (l *EventListener) Running() bool {
+	return l.running.Load()
+}
+
+// LastPoll returns when the last poll completed without error, or when Start
+// loaded the checkpoint if none has yet. It is zero before Start.
+func (l *EventListener) LastPoll() time.Time {
+	if ns := l.lastPoll.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// Start listening for events
+func (l *EventListener) Start(ctx context.Context) error {
+	log.Info().Msg("🎧 Starting event listener...")
+
+	// Get last processed version from DB
+	if err := l.loadLastVersion(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load last version, starting from latest")
+		// Start from current version
+		version, err := l.client.GetLatestLedgerInfo(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get latest ledger info: %w", err)
+		}
+		l.lastVersion.Store(l.safeVersion(version))
+	}
+
+	log.Info().Uint64("version", l.lastVersion.Load()).Msg("Starting from version")
+
+	l.running.Store(true)
+	defer l.running.Store(false)
+	l.supervisor.up()
+	l.lastPoll.Store(time.Now().UnixNano())
+
+	l.sampleAt, l.sampleVersion = time.Now(), l.lastVersion.Load()
+	l.scaling.advance(l.lastVersion.Load())
+
+	// Warm the market cache before processing any events
+	if err := l.markets.Refresh(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load market cache, markets will be resolved on demand")
+	}
+	l.marketsOnce.Do(func() { go l.markets.Run(ctx) })
+
+	l.settingsMu.Lock()
+	l.startDigests(ctx)
+	l.settingsMu.Unlock()
+
+	// Start polling loop
+	interval := l.pollInterval
+	l.currentInterval.Store(int64(interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	leaseTicker := time.NewTicker(leaseRenewInterval)
+	defer leaseTicker.Stop()
+
+	for {
+		next := interval
+		select {
+		case <-ctx.Done():
+			l.resign()
+			log.Info().Msg("Event listener stopped")
+			return nil
+		case <-l.reloaded:
+			l.applySettings()
+			next = l.nextPollInterval(interval, false)
+		case fn := <-l.control:
+			fn()
+			next = l.nextPollInterval(interval, false)
+		case <-leaseTicker.C:
+			// Keep the leases while paused or backed off; poll renews them
+			// otherwise
+			if interval > leaseRenewInterval || l.Paused() {
+				if _, err := l.campaign(ctx); err != nil {
+					log.Error().Err(err).Msg("Lease renewal error")
+				}
+			}
+		case <-ticker.C:
+			if l.Paused() {
+				continue
+			}
+			seen := l.moduleTxs
+			if err := l.poll(ctx); err != nil {
+				log.Error().Err(err).Msg("Polling error")
+				errreport.Capture(err, errreport.Tags{
+					"source":  "poll",
+					"version": strconv.FormatUint(l.lastVersion.Load(), 10),
+				})
+			} else {
+				l.lastPoll.Store(time.Now().UnixNano())
+				next = l.nextPollInterval(interval, l.moduleTxs == seen || l.backpressured)
+				l.backpressured = false
+			}
+		}
+
+		if next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+func (l *EventListener) poll(ctx context.Context) error {
+	log.Debug().
+		Uint64("current_version", l.lastVersion.Load()).
+		Msg("🔄 Starting poll cycle")
+
+	// Only the lease holder writes; everyone else stands by
+	leader, err := l.campaign(ctx)
+	if err != nil {
+		return err
+	}
+	if !leader {
+		log.Debug().Msg("⏸️  Writer lease held by another instance, standing by")
+		l.scaling.setStandby()
+		return nil
+	}
+
+	// Get latest version
+	latestVersion, err := l.client.GetLatestLedgerInfo(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("❌ Failed to get latest ledger info")
+		return err
+	}
+	safeVersion := l.safeVersion(latestVersion)
+	l.scaling.observeLedger(latestVersion, safeVersion)
+
+	log.Debug().
+		Uint64("latest_version", latestVersion).
+		Uint64("safe_version", safeVersion).
+		Uint64("last_processed", l.lastVersion.Load()).
+		Msg("📊 Ledger info retrieved")
+
+	// Make sure what we already indexed is still the chain's history
+	if _, err := l.checkForReorg(ctx, latestVersion); err != nil {
+		log.Error().Err(err).Msg("❌ Reorg check failed")
+		return err
+	}
+
+	// Fill in what earlier polls had to skip
+	l.repairGaps(ctx)
+
+	// No new transactions
+	if safeVersion <= l.lastVersion.Load() {
+		log.Debug().Msg("⏸️  No new transactions to process")
+		return nil
+	}
+
+	log.Info().
+		Uint64("from", l.lastVersion.Load()+1).
+		Uint64("to", safeVersion).
+		Uint64("count", safeVersion-l.lastVersion.Load()).
+		Msg("📥 Processing new transactions")
+
+	start := l.lastVersion.Load() + 1
+	end := safeVersion
+
+	// Large backlogs: ask Nodit which versions matter instead of scanning all
+	if l.nodit != nil && end-start+1 > noditCatchupThreshold {
+		if err := l.catchUpFromNodit(ctx, start, end); errors.Is(err, errPaused) {
+			return nil
+		} else if err != nil {
+			log.Warn().Err(err).Msg("⚠️  Nodit catch-up failed, falling back to fullnode scan")
+			start = l.lastVersion.Load() + 1
+		} else {
+			start = end + 1
+		}
+	}
+
+	// Fetch ahead while earlier batches commit, in version order
+	checkpointHash, err := l.ingest(ctx, start, end)
+	if errors.Is(err, errPaused) {
+		return nil
+	} else if errors.Is(err, errBackpressure) {
+		// Storage is behind; the loop backs off before fetching more
+		l.backpressured = true
+		l.sampleCheckpoint(ctx)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	log.Info().
+		Uint64("new_version", l.lastVersion.Load()).
+		Msg("💾 Checkpoint advanced")
+
+	l.sampleCheckpoint(ctx)
+
+	if err := l.recordCheckpointHash(ctx, end, checkpointHash); err != nil {
+		log.Warn().Err(err).Msg("⚠️  Failed to record checkpoint hash")
+	}
+
+	return nil
+}
+
+// catchUpFromNodit processes every transaction between start and end that
+// emitted an event from our module, using Nodit to find them
+func (l *EventListener) catchUpFromNodit(ctx context.Context, start, end uint64) error {
+	log.Info().
+		Uint64("from", start).
+		Uint64("to", end).
+		Msg("⚡ Catching up via Nodit indexer")
+
+	from := start
+	processed := 0
+	for from <= end {
+		if l.Paused() {
+			return errPaused
+		}
+
+		events, err := l.nodit.GetModuleEvents(ctx, l.moduleAddress, from, end, 100)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		// A transaction can emit several events; fetch and process each once
+		lastVersion := from - 1
+		var txs []aptos.TransactionEvent
+		for _, ev := range events {
+			if ev.TransactionVersion == lastVersion {
+				continue
+			}
+			lastVersion = ev.TransactionVersion
+
+			tx, err := l.client.GetTransactionByVersion(ctx, ev.TransactionVersion)
+			if err != nil {
+				return fmt.Errorf("failed to fetch transaction %d: %w", ev.TransactionVersion, err)
+			}
+			txs = append(txs, *tx)
+		}
+
+		// Everything up to lastVersion is covered once this page commits
+		if err := l.processBatch(ctx, txs, lastVersion); err != nil {
+			return err
+		}
+		processed += len(txs)
+
+		from = lastVersion + 1
+	}
+
+	// No more events up to end; move the checkpoint there
+	if l.lastVersion.Load() < end {
+		if err := l.processBatch(ctx, nil, end); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Int("transactions", processed).
+		Msg("✅ Nodit catch-up complete")
+
+	return nil
+}
+
+// ProcessTransaction runs tx through the registered handlers outside the
+// polling loop (imports, replays). The checkpoint is not touched.
+func (l *EventListener) ProcessTransaction(ctx context.Context, tx aptos.TransactionEvent) error {
+	return l.ProcessTransactions(ctx, []aptos.TransactionEvent{tx})
+}
+
+// ProcessTransactions is ProcessTransaction for several transactions,
+// written in one database transaction
+func (l *EventListener) ProcessTransactions(ctx context.Context, txs []aptos.TransactionEvent) error {
+	return l.ProcessTransactionsWith(ctx, txs, nil)
+}
+
+// ProcessTransactionsWith is ProcessTransactions that also runs with, when
+// given, in the same database transaction just before it commits; an error
+// from with rolls the whole batch back. Like a polled batch it only commits
+// while the listener holds the writer leases, unless SetUnfenced was called.
+// Versions whose handlers failed are recorded as gaps for repair.
+func (l *EventListener) ProcessTransactionsWith(ctx context.Context, txs []aptos.TransactionEvent, with func(context.Context, pgx.Tx) error) error {
+	return l.processTransactions(ctx, txs, nil, with)
+}
+
+// ProcessRangeWith is ProcessTransactionsWith for txs fetched as the range
+// start..end. Versions of the range the fullnode left out are recorded as
+// gaps too, so callers can move past end without losing them.
+func (l *EventListener) ProcessRangeWith(ctx context.Context, start, end uint64, txs []aptos.TransactionEvent, with func(context.Context, pgx.Tx) error) error {
+	return l.processTransactions(ctx, txs, findGaps(txs, start, end), with)
+}
+
+func (l *EventListener) processTransactions(ctx context.Context, txs []aptos.TransactionEvent, gaps []versionGap, with func(context.Context, pgx.Tx) error) error {
+	l.pending, l.activities, l.outboxRows, l.gaps = nil, nil, nil, gaps
+	l.stats.resetBatch()
+	defer func() { l.gaps = nil }()
+
+	dbTx, err := l.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	for _, tx := range txs {
+		if err := l.processTx(ctx, dbTx, tx); err != nil {
+			return fmt.Errorf("version %s: %w", tx.Version, err)
+		}
+	}
+	if err := l.flushActivities(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := l.flushOutbox(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := l.flushGaps(ctx, dbTx); err != nil {
+		return err
+	}
+	if with != nil {
+		if err := with(ctx, dbTx); err != nil {
+			return err
+		}
+	}
+	if !l.unfenced {
+		if err := l.fence(ctx, dbTx); err != nil {
+			return err
+		}
+	}
+	if err := dbTx.Commit(ctx); err != nil {
+		return err
+	}
+
+	l.stats.commit()
+	l.flushNotifications()
+	l.kickOutbox()
+	return nil
+}
+
+// processBatch applies txs and moves the checkpoint to version in a single
+// database transaction, so activities, market updates and the checkpoint
+// are either all written or none are
+func (l *EventListener) processBatch(ctx context.Context, txs []aptos.TransactionEvent, version uint64) error {
+	l.applySettings()
+	l.pending, l.activities, l.outboxRows = nil, nil, nil
+	l.stats.resetBatch()
+	defer func() { l.gaps = nil }()
+
+	dbTx, err := l.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	for _, tx := range txs {
+		if err := l.processTx(ctx, dbTx, tx); err != nil {
+			return fmt.Errorf("version %s: %w", tx.Version, err)
+		}
+	}
+
+	if err := l.flushActivities(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := l.flushOutbox(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := l.flushGaps(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := saveCheckpoint(ctx, dbTx, l.CheckpointKey(), version); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := l.fence(ctx, dbTx); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	if l.archiver != nil {
+		l.archiveBatch(txs, l.lastVersion.Load()+1, version)
+	}
+	l.lastVersion.Store(version)
+	l.scaling.advance(version)
+	l.stats.commit()
+	l.flushNotifications()
+	l.kickOutbox()
+	return nil
+}
+
+// archiveBatch queues the committed range start..end with the transactions
+// in it that emitted module events
+func (l *EventListener) archiveBatch(txs []aptos.TransactionEvent, start, end uint64) {
+	var archived []aptos.TransactionEvent
+	for _, tx := range txs {
+		if emitsModuleEvents(tx, l.moduleAddress) {
+			archived = append(archived, tx)
+		}
+	}
+	l.archiver.Archive(archive.Batch{Start: start, End: end, Transactions: archived})
+}
+
+// processTx applies tx inside q. Handler failures only roll back that event;
+// any other error leaves q unusable and is returned.
+func (l *EventListener) processTx(ctx context.Context, q pgx.Tx, tx aptos.TransactionEvent) error {
+	// Only process successful user transactions
+	if !tx.Success || tx.Type != "user_transaction" {
+		log.Debug().
+			Str("type", tx.Type).
+			Bool("success", tx.Success).
+			Msg("⏭️  Skipping non-user or failed transaction")
+		return nil
+	}
+	tx = l.events.Normalize(tx)
+
+	log.Debug().
+		Str("hash", tx.Hash).
+		Int("event_count", len(tx.Events)).
+		Msg("🔍 Processing user transaction")
+
+	// Process each event in the transaction
+	matched := false
+	for i, event := range tx.Events {
+		tag, matchesModule := aptos.MatchModule(event.Type, l.moduleAddress)
+
+		// Log ALL events only in verbose mode
+		if l.verboseMode {
+			log.Info().
+				Str("tx_hash", tx.Hash).
+				Int("event_index", i).
+				Str("event_type", event.Type).
+				Str("module_address", l.moduleAddress).
+				Bool("matches_module", matchesModule).
+				Bool("module_event", event.IsModuleEvent()).
+				Msg("📝 Checking event (verbose)")
+		}
+
+		// Check if event is from our module
+		if !matchesModule {
+			continue
+		}
+		matched = true
+
+		log.Info().
+			Str("event_type", event.Type).
+			Msg("✅ Found event from our module")
+
+		eventName := tag.Name
+
+		log.Info().
+			Str("event_name", eventName).
+			Msg("🎯 Extracted event name")
+
+		// Internal senders (e.g. market-maker bots) don't count as activity
+		if activityEvents[eventName] && !l.senders.Includes(tx.Sender) {
+			log.Info().
+				Str("event", eventName).
+				Str("sender", tx.Sender).
+				Msg("🚫 Sender excluded from activity, archiving event only")
+			if err := l.archiveEvent(ctx, q, event, tx, tx.EventIndex(i), "excluded_sender"); err != nil {
+				return err
+			}
+			l.stats.staged(eventName).Excluded++
+			continue
+		}
+
+		// Find handler
+		handler, exists := l.eventHandlers[eventName]
+		if !exists {
+			log.Debug().
+				Str("event", eventName).
+				Interface("available_handlers", l.getHandlerNames()).
+				Msg("⚠️  No handler registered for event")
+			if _, known := aptos.EventSchemas[eventName]; !known {
+				if err := l.recordUnknownEvent(ctx, q, eventName, event, tx); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		log.Info().
+			Str("event", eventName).
+			Msg("▶️  Executing handler")
+
+		// Execute handler
+		applied, err := l.applyEvent(ctx, q, handler, eventName, event, tx, tx.EventIndex(i))
+		if errors.Is(err, errMarketDeferred) {
+			// Unclaimed, so the backfill merge applies it once every shard
+			// has written its markets
+			if err := l.deferEvent(ctx, q, tx, tx.EventIndex(i), eventName); err != nil {
+				return err
+			}
+			log.Debug().
+				Str("event", eventName).
+				Str("tx", tx.Hash).
+				Msg("⏳ Event references a market not indexed yet, deferred")
+		} else if err != nil {
+			log.Error().
+				Err(err).
+				Str("event", eventName).
+				Str("tx", tx.Hash).
+				Msg("❌ Handler error")
+			l.stats.handlerError(eventName)
+			l.stageGap(tx, fmt.Sprintf("%s handler failed: %v", eventName, err))
+			errreport.Capture(err, errreport.Tags{
+				"source":  "handler",
+				"event":   eventName,
+				"tx":      tx.Hash,
+				"version": tx.Version,
+			})
+		} else if !applied {
+			log.Info().
+				Str("event", eventName).
+				Str("tx", tx.Hash).
+				Int("event_index", tx.EventIndex(i)).
+				Msg("⏭️  Event already processed, skipping")
+			l.stats.staged(eventName).Skipped++
+		} else {
+			l.stats.staged(eventName).Processed++
+		}
+	}
+
+	// Remember which version wrote rows, so a rollback can remove them
+	if matched {
+		if err := l.recordIndexedTx(ctx, q, tx); err != nil {
+			return fmt.Errorf("failed to record indexed transaction: %w", err)
+		}
+		l.moduleTxs++
+		l.stats.batchTxs++
+	}
+
+	return nil
+}
+
+// applyEvent claims the event in processed_events and runs handler in a
+// savepoint of q, so a failing handler leaves neither its writes nor the
+// claim behind. It reports false without running the handler when the event
+// was already processed.
+func (l *EventListener) applyEvent(ctx context.Context, q pgx.Tx, handler EventHandler, eventName string, event aptos.Event, tx aptos.TransactionEvent, index int) (bool, error) {
+	version, err := strconv.ParseInt(tx.Version, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", tx.Version, err)
+	}
+
+	savepoint, err := q.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	pending, activities, outboxRows := len(l.pending), len(l.activities), len(l.outboxRows)
+
+	claimed, err := store.ClaimEvent(ctx, savepoint, l.network, version, index, eventName, tx.Hash)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	// A payload that doesn't match its schema is quarantined before the
+	// handler, subscriptions or the bus see it
+	if err := aptos.ValidateEvent(eventName, event.Data); err != nil {
+		if err := l.rejectMalformed(ctx, savepoint, event, tx, err); err != nil {
+			return false, err
+		}
+		if err := savepoint.Commit(ctx); err != nil {
+			return false, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		return true, nil
+	}
+
+	if err := handler(ctx, savepoint, event, tx); err != nil {
+		l.discardStaged(pending, activities, outboxRows)
+		return false, err
+	}
+	if err := l.matchSubscriptions(ctx, savepoint, eventName, event, tx); err != nil {
+		l.discardStaged(pending, activities, outboxRows)
+		return false, err
+	}
+	l.publishEvent(eventName, event, tx, index)
+
+	if err := savepoint.Commit(ctx); err != nil {
+		l.discardStaged(pending, activities, outboxRows)
+		return false, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return true, nil
+}
+
+// discardStaged drops what an event that rolled back to its savepoint
+// staged, keeping the first n of each
+func (l *EventListener) discardStaged(pending, activities, outboxRows int) {
+	l.pending, l.activities, l.outboxRows = l.pending[:pending], l.activities[:activities], l.outboxRows[:outboxRows]
+}
+
+// acquireLeases takes or renews every writer lease, reporting whether this
+// listener owns all of them
+func (l *EventListener) acquireLeases(ctx context.Context) (bool, error) {
+	for _, name := range l.leases() {
+		ok, err := l.db.AcquireLease(ctx, name, l.owner, writerLeaseTTL)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			owner, _ := l.db.LeaseOwner(ctx, name)
+			log.Debug().
+				Str("lease", name).
+				Str("owner", owner).
+				Msg("Another writer owns this table")
+			// Don't sit on the other leases, or two standbys racing for a
+			// dead leader's leases could each end up with half of them
+			l.releaseLeases()
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (l *EventListener) releaseLeases() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range l.leases() {
+		if err := l.db.ReleaseLease(ctx, name, l.owner); err != nil {
+			log.Warn().Err(err).Str("lease", name).Msg("Failed to release writer lease")
+		}
+	}
+}
+
+// Helper to get registered handler names for debugging
+func (l *EventListener) getHandlerNames() []string {
+	names := make([]string, 0, len(l.eventHandlers))
+	for name := range l.eventHandlers {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (l *EventListener) registerDefaultHandlers() {
+	// SharesMintedEvent - when user buys shares
+	l.RegisterHandler("SharesMintedEvent", l.handleSharesMinted)
+
+	// SharesBurnedEvent - when user sells shares
+	l.RegisterHandler("SharesBurnedEvent", l.handleSharesBurned)
+
+	// MarketCreatedEvent - when new market is created
+	l.RegisterHandler("MarketCreatedEvent", l.handleMarketCreated)
+
+	// MarketResolvedEvent - when market is resolved
+	l.RegisterHandler("MarketResolvedEvent", l.handleMarketResolved)
+
+	// LiquidityAddedEvent / LiquidityRemovedEvent - LP deposits and withdrawals
+	l.RegisterHandler("LiquidityAddedEvent", l.handleLiquidityAdded)
+	l.RegisterHandler("LiquidityRemovedEvent", l.handleLiquidityRemoved)
+
+	// FeeCollectedEvent - protocol fee taken by a market
+	l.RegisterHandler("FeeCollectedEvent", l.handleFeeCollected)
+
+	// Oracle lifecycle - proposals and disputes before a market resolves
+	l.RegisterHandler("ResolutionProposedEvent", l.handleResolutionProposed)
+	l.RegisterHandler("DisputeRaisedEvent", l.handleDisputeRaised)
+	l.RegisterHandler("DisputeResolvedEvent", l.handleDisputeResolved)
+}
+
+func (l *EventListener) handleSharesMinted(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("📈 SharesMintedEvent detected")
+
+	// Decode event data
+	minted, err := aptos.DecodeSharesMinted(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	marketAddress, user, isYes := minted.MarketAddress, minted.User, minted.IsYes
+	aptAmountIn, sharesOut := minted.AptAmountIn, minted.SharesOut
+
+	// Convert amounts to exact decimals (octas and token decimals)
+	aptAmount, err := amount.OctasToAPT(aptAmountIn)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	shares, err := amount.SharesToUnits(sharesOut)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	outcome := "NO"
+	if isYes {
+		outcome = "YES"
+	}
+
+	if known, err := l.checkMarket(ctx, q, event, tx, marketAddress); !known {
+		return err
+	}
+
+	// Insert activity record
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Queue the activity record; it is written when the batch flushes
+
+	l.queueActivity(activityRow{
+		TxHash:        tx.Hash,
+		MarketAddress: marketAddress,
+		UserAddress:   user,
+		Action:        "BUY",
+		Outcome:       outcome,
+		Amount:        shares,
+		TotalValue:    aptAmount,
+		Timestamp:     timestamp,
+	})
+
+	log.Info().
+		Str("market", aptos.ShortAddress(marketAddress)).
+		Str("user", aptos.ShortAddress(user)).
+		Str("apt", aptAmount).
+		Str("shares", shares).
+		Str("outcome", outcome).
+		Msg("✅ BUY activity queued")
+
+	l.recordTrade(marketAddress, "BUY", outcome, aptAmount, shares, timestamp)
+
+	// Trigger webhook for live notifications
+	if len(l.webhookClients) > 0 {
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = marketAddress
+		eventData["buyer"] = user
+		eventData["is_yes_outcome"] = isYes
+		eventData["apt_amount_in"] = aptAmountIn
+		eventData["shares_out"] = sharesOut
+		eventData["display"] = map[string]string{
+			"apt_amount_in": l.display.FormatOctas(aptAmountIn),
+			"shares_out":    l.display.FormatShares(sharesOut),
+		}
+
+		l.sendWebhook(event.Type, eventData, tx)
+	}
+
+	return nil
+}
+
+func (l *EventListener) handleSharesBurned(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("📉 SharesBurnedEvent detected")
+
+	burned, err := aptos.DecodeSharesBurned(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	marketAddress, user, isYes := burned.MarketAddress, burned.User, burned.IsYes
+	sharesIn, aptAmountOut := burned.SharesIn, burned.AptAmountOut
+
+	aptAmount, err := amount.OctasToAPT(aptAmountOut)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	shares, err := amount.SharesToUnits(sharesIn)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+
+	outcome := "NO"
+	if isYes {
+		outcome = "YES"
+	}
+
+	if known, err := l.checkMarket(ctx, q, event, tx, marketAddress); !known {
+		return err
+	}
+
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Queue the activity record; it is written when the batch flushes
+
+	l.queueActivity(activityRow{
+		TxHash:        tx.Hash,
+		MarketAddress: marketAddress,
+		UserAddress:   user,
+		Action:        "SELL",
+		Outcome:       outcome,
+		Amount:        shares,
+		TotalValue:    aptAmount,
+		Timestamp:     timestamp,
+	})
+
+	log.Info().
+		Str("market", aptos.ShortAddress(marketAddress)).
+		Str("user", aptos.ShortAddress(user)).
+		Str("apt", aptAmount).
+		Str("shares", shares).
+		Str("outcome", outcome).
+		Msg("✅ SELL activity queued")
+
+	l.recordTrade(marketAddress, "SELL", outcome, aptAmount, shares, timestamp)
+
+	// Trigger webhook for live notifications
+	if len(l.webhookClients) > 0 {
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = marketAddress
+		eventData["seller"] = user
+		eventData["is_yes_outcome"] = isYes
+		eventData["apt_amount_out"] = aptAmountOut
+		eventData["shares_in"] = sharesIn
+		eventData["display"] = map[string]string{
+			"apt_amount_out": l.display.FormatOctas(aptAmountOut),
+			"shares_in":      l.display.FormatShares(sharesIn),
+		}
+
+		l.sendWebhook(event.Type, eventData, tx)
+	}
+
+	return nil
+}
+
+func (l *EventListener) handleMarketCreated(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Str("event_type", event.Type).
+		Msg("🎯 MarketCreatedEvent detected")
+
+	// Log raw event data for debugging
+	log.Debug().
+		Interface("event_data", event.Data).
+		Msg("📦 Raw event data")
+
+	// Decode event data - field names follow the Move struct
+	created, err := aptos.DecodeMarketCreated(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	marketAddress, creator := created.MarketAddress, created.Creator
+	description, resolutionTimestamp := created.Description, created.ResolutionTimestamp
+
+	log.Info().
+		Str("market", marketAddress).
+		Str("creator", creator).
+		Str("description", description).
+		Str("resolution_timestamp", resolutionTimestamp).
+		Msg("✅ Extracted market data")
+
+	if err := l.recordMarket(ctx, q, created, tx.Hash); err != nil {
+		return err
+	}
+	l.pending = append(l.pending, func() {
+		l.markets.Put(MarketInfo{Address: marketAddress, Status: "active"})
+	})
+
+	market := notify.Market{Address: marketAddress, Creator: creator, Description: description}
+	if t, err := timeconv.Parse(resolutionTimestamp); err == nil {
+		market.ResolutionTimestamp = t
+	}
+	l.notifyChat(event.Type, market, tx)
+
+	// Trigger webhook for live notifications
+	if len(l.webhookClients) > 0 {
+		log.Info().Msg("🔔 Webhook client exists, preparing to send...")
+
+		eventData := make(map[string]interface{})
+		eventData["market_address"] = marketAddress
+		eventData["creator"] = creator
+		eventData["description"] = description
+		eventData["resolution_timestamp"] = resolutionTimestamp
+
+		log.Info().
+			Interface("event_data", eventData).
+			Int("targets", len(l.webhookClients)).
+			Msg("📤 Sending webhook with data")
+
+		l.sendWebhook(event.Type, eventData, tx)
+	} else {
+		log.Warn().Msg("⚠️  Webhook client is nil, skipping webhook notification")
+	}
+
+	return nil
+}
+
+// recordMarket upserts the market, so it exists whether or not the webhook
+// consumer is up. Every column a Prisma-managed table may leave without a
+// database default (id, status, timestamps) is set explicitly. A row the
+// webhook created first gets the on-chain creator, description and
+// resolution time; its status is left alone. txHash is the creating
+// transaction, so a rollback past it removes the market, or empty when the
+// market is recreated from chain. Should the table still reject the row
+// (e.g. a frontend-only required column), the error fails the event, so
+// applyEvent rolls it back and it is quarantined rather than cached and
+// announced as a market that was never stored.
+func (l *EventListener) recordMarket(ctx context.Context, q pgx.Tx, created aptos.MarketCreatedEvent, txHash string) error {
+	var resolvesAt *time.Time
+	if t, err := timeconv.Parse(created.ResolutionTimestamp); err == nil {
+		resolvesAt = &t
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO "Market" (
+			"id", "marketAddress", "creator", "description", "resolutionTimestamp",
+			"status", "createdAt", "updatedAt", "network", "creationTxHash"
+		) VALUES (gen_random_uuid()::text, $1, $2, $3, $4, 'active', NOW(), NOW(), $5, $6)
+		ON CONFLICT ("network", "marketAddress") DO UPDATE SET
+			"creator" = EXCLUDED."creator",
+			"description" = EXCLUDED."description",
+			"resolutionTimestamp" = EXCLUDED."resolutionTimestamp",
+			"creationTxHash" = COALESCE(EXCLUDED."creationTxHash", "Market"."creationTxHash"),
+			"updatedAt" = NOW()
+	`, created.MarketAddress, created.Creator, created.Description, resolvesAt, l.network, optionalString(txHash))
+	if err != nil {
+		return fmt.Errorf("failed to record market %s: %w", created.MarketAddress, err)
+	}
+
+	return nil
+}
+
+func (l *EventListener) handleMarketResolved(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Msg("✅ MarketResolvedEvent detected")
+
+	resolved, err := aptos.DecodeMarketResolved(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	marketAddress, outcome := resolved.MarketAddress, resolved.Outcome
+
+	// Older module versions don't emit the resolver; it is the sender then
+	resolver := resolved.Resolver
+	if resolver == "" {
+		resolver = tx.Sender
+	}
+
+	resolvedAt, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("market", aptos.ShortAddress(marketAddress)).
+		Str("outcome", outcome).
+		Str("resolver", resolver).
+		Msg("🏁 Market resolved")
+
+	recorded, err := recordResolution(ctx, q, l.network, marketAddress, outcome, resolver, tx.Hash, resolvedAt)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		// The market was never recorded (e.g. created while the webhook
+		// consumer was down); rebuild it from chain before resolving it
+		created, err := l.viewMarket(ctx, marketAddress)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("market", marketAddress).
+				Str("tx", tx.Hash).
+				Msg("⚠️  Resolved market is missing and couldn't be read from chain, quarantining")
+			return l.quarantineEvent(ctx, q, event, tx, marketAddress, "resolved market missing: "+err.Error())
+		}
+		if err := l.recordMarket(ctx, q, created, ""); err != nil {
+			return err
+		}
+		if recorded, err = recordResolution(ctx, q, l.network, marketAddress, outcome, resolver, tx.Hash, resolvedAt); err != nil {
+			return err
+		}
+		if !recorded {
+			log.Warn().
+				Str("market", marketAddress).
+				Str("tx", tx.Hash).
+				Msg("⚠️  Resolved market couldn't be recorded, status not updated")
+		} else {
+			log.Info().Str("market", marketAddress).Msg("🔧 Missing market recreated from chain")
+			l.pending = append(l.pending, func() {
+				l.markets.Put(MarketInfo{Address: marketAddress, Status: "resolved"})
+			})
+		}
+	}
+
+	l.pending = append(l.pending, func() {
+		l.markets.SetStatus(marketAddress, "resolved")
+	})
+
+	if l.notifier.Wants(event.Type) {
+		// The event only carries the address, the description is in Market
+		market := notify.Market{Address: marketAddress, Outcome: outcome}
+		if err := q.QueryRow(ctx, `SELECT COALESCE("description", '') FROM "Market" WHERE "marketAddress" = $1 AND "network" = $2`,
+			marketAddress, l.network).Scan(&market.Description); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to look up market description: %w", err)
+		}
+		l.notifyChat(event.Type, market, tx)
+	}
+
+	return nil
+}
+
+// recordResolution stores the outcome on the market row, reporting false
+// when there is no row to update
+func recordResolution(ctx context.Context, q pgx.Tx, network, marketAddress, outcome, resolver, txHash string, resolvedAt time.Time) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE "Market"
+		SET status = 'resolved',
+			"outcome" = $2,
+			"resolver" = $3,
+			"resolutionTxHash" = $4,
+			"resolvedAt" = $5,
+			"updatedAt" = NOW()
+		WHERE "marketAddress" = $1 AND "network" = $6
+	`, marketAddress, outcome, resolver, txHash, resolvedAt, network)
+	if err != nil {
+		return false, fmt.Errorf("failed to update market status: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetMarketView sets the view function, without the module address, that
+// returns a market's (creator, description, resolution_timestamp). It is
+// used to recreate markets that resolve without a Market row.
+func (l *EventListener) SetMarketView(function string) {
+	l.marketView = function
+}
+
+// viewMarket reads a market's creation data from chain
+func (l *EventListener) viewMarket(ctx context.Context, marketAddress string) (aptos.MarketCreatedEvent, error) {
+	if l.marketView == "" {
+		return aptos.MarketCreatedEvent{}, fmt.Errorf("no market view function configured")
+	}
+	function := l.moduleAddress + "::" + l.marketView
+
+	result, err := l.client.View(ctx, function, []string{}, []string{marketAddress})
+	if err != nil {
+		return aptos.MarketCreatedEvent{}, err
+	}
+	if len(result) < 3 {
+		return aptos.MarketCreatedEvent{}, fmt.Errorf("%s returned %d values, expected 3", function, len(result))
+	}
+
+	// Decode like the creation event so the same checks apply
+	return aptos.DecodeMarketCreated(map[string]interface{}{
+		"market_address":       marketAddress,
+		"creator":              result[0],
+		"description":          result[1],
+		"resolution_timestamp": result[2],
+	})
+}
+
+// sendWebhook notifies every per-event webhook target once the event's
+// batch commits
+func (l *EventListener) sendWebhook(eventType string, eventData map[string]interface{}, tx aptos.TransactionEvent) {
+	payload := webhook.NewEventPayload(eventType, eventData, tx.Hash, tx.Sender)
+	payload.Transaction.Network = l.network
+	if l.useOutbox {
+		for _, client := range l.webhookClients {
+			l.stageOutbox(db.OutboxWebhook, client.URL, payload)
+		}
+		return
+	}
+
+	l.pending = append(l.pending, func() {
+		for _, client := range l.webhookClients {
+			if err := client.Send(payload); err != nil {
+				log.Warn().Err(err).Str("webhook_url", client.URL).Msg("Webhook trigger failed (non-critical)")
+			}
+		}
+	})
+}
+
+// notifyChat announces the event in the chat channels once its batch commits
+func (l *EventListener) notifyChat(eventType string, market notify.Market, tx aptos.TransactionEvent) {
+	if !l.notifier.Wants(eventType) {
+		return
+	}
+	at, _ := timeconv.Parse(tx.Timestamp)
+	l.pending = append(l.pending, func() {
+		l.notifier.Notify(eventType, market, at)
+	})
+}
+
+// publishEvent sends the event to the message buses once its batch commits
+func (l *EventListener) publishEvent(eventName string, event aptos.Event, tx aptos.TransactionEvent, index int) {
+	if len(l.publishers) == 0 {
+		return
+	}
+
+	msg := bus.Message{
+		Event:      eventName,
+		Type:       event.Type,
+		TxHash:     tx.Hash,
+		Version:    tx.Version,
+		EventIndex: index,
+		Network:    l.network,
+		Sender:     tx.Sender,
+		Data:       event.Data,
+	}
+	msg.Market, _ = event.Data["market_address"].(string)
+	if t, err := timeconv.Parse(tx.Timestamp); err == nil {
+		msg.Timestamp = timeconv.Format(t)
+	}
+	if l.useOutbox {
+		for _, publisher := range l.publishers {
+			l.stageOutbox(db.OutboxBus, publisher.Name(), msg)
+		}
+		return
+	}
+
+	l.pending = append(l.pending, func() {
+		for _, publisher := range l.publishers {
+			publisher.Publish(msg)
+		}
+	})
+}
+
+// flushNotifications sends the webhooks and digest trades of the batch that
+// just committed and applies its market cache updates
+func (l *EventListener) flushNotifications() {
+	pending := l.pending
+	l.pending = nil
+	for _, notify := range pending {
+		notify()
+	}
+}
+
+// recordTrade feeds a trade into every digest target. Digests are display
+// only, so the exact decimals are converted to floats here.
+func (l *EventListener) recordTrade(marketAddress, action, outcome, aptAmount, sharesAmount string, timestamp time.Time) {
+	apt, _ := strconv.ParseFloat(aptAmount, 64)
+	shares, _ := strconv.ParseFloat(sharesAmount, 64)
+
+	trade := webhook.Trade{
+		MarketAddress: marketAddress,
+		Action:        action,
+		Outcome:       outcome,
+		APT:           apt,
+		Shares:        shares,
+		Timestamp:     timestamp,
+	}
+	l.pending = append(l.pending, func() {
+		for _, digest := range l.digests {
+			digest.Record(trade)
+		}
+	})
+}
+
+// checkMarket reports whether marketAddress is a known market. Events for
+// unknown markets are quarantined instead of producing orphaned rows.
+func (l *EventListener) checkMarket(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent, marketAddress string) (bool, error) {
+	m, known, err := l.markets.Resolve(ctx, q, marketAddress)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve market: %w", err)
+	}
+	if known {
+		l.pending = append(l.pending, func() { l.markets.Add(m) })
+		return true, nil
+	}
+
+	if l.deferUnknownMarkets {
+		return false, errMarketDeferred
+	}
+
+	log.Warn().
+		Str("market", marketAddress).
+		Str("event_type", event.Type).
+		Str("tx", tx.Hash).
+		Msg("⚠️  Event references unknown market, quarantining")
+
+	return false, l.quarantineEvent(ctx, q, event, tx, marketAddress, "unknown market")
+}
+
+// rejectMalformed quarantines an event whose payload failed to decode, so it
+// never turns into a zero-valued row. The event counts as handled: the
+// quarantine commits with its processed_events claim and isn't repeated on
+// replay.
+func (l *EventListener) rejectMalformed(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent, decodeErr error) error {
+	log.Error().
+		Err(decodeErr).
+		Str("event_type", event.Type).
+		Str("tx", tx.Hash).
+		Msg("❌ Malformed event, quarantining")
+
+	marketAddress, _ := event.Data["market_address"].(string)
+	return l.quarantineEvent(ctx, q, event, tx, marketAddress, decodeErr.Error())
+}
+
+// quarantineEvent stores an event that couldn't be applied so it can be
+// inspected and replayed later
+func (l *EventListener) quarantineEvent(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent, marketAddress, reason string) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	version, _ := strconv.ParseInt(tx.Version, 10, 64)
+
+	query := `
+		INSERT INTO quarantined_events (
+			tx_hash, tx_version, event_type, market_address, reason, payload, network
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = q.Exec(ctx, query, tx.Hash, version, event.Type, marketAddress, reason, payload, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine event: %w", err)
+	}
+
+	return nil
+}
+
+// recordUnknownEvent counts a module event with neither a schema nor a
+// handler in unknown_events, keeping its latest payload as a sample. A
+// version is only counted once, so replays don't inflate the count.
+func (l *EventListener) recordUnknownEvent(ctx context.Context, q pgx.Tx, eventName string, event aptos.Event, tx aptos.TransactionEvent) error {
+	sample, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	version, _ := strconv.ParseInt(tx.Version, 10, 64)
+
+	query := `
+		INSERT INTO unknown_events (
+			network, event_type, event_name, first_version, last_version, last_tx_hash, sample
+		) VALUES ($1, $2, $3, $4, $4, $5, $6)
+		ON CONFLICT (network, event_type) DO UPDATE SET
+			transactions = unknown_events.transactions + 1,
+			last_version = EXCLUDED.last_version,
+			last_tx_hash = EXCLUDED.last_tx_hash,
+			sample = EXCLUDED.sample,
+			last_seen_at = NOW()
+		WHERE EXCLUDED.last_version > unknown_events.last_version
+	`
+
+	_, err = q.Exec(ctx, query, l.network, event.Type, eventName, version, tx.Hash, string(sample))
+	if err != nil {
+		return fmt.Errorf("failed to record unknown event: %w", err)
+	}
+
+	return nil
+}
+
+// archiveEvent stores an event in raw_events without applying it
+func (l *EventListener) archiveEvent(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent, index int, source string) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	version, _ := strconv.ParseInt(tx.Version, 10, 64)
+
+	query := `
+		INSERT INTO raw_events (
+			transaction_version, event_index, tx_hash, sender,
+			tx_timestamp, event_type, data, source, network
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (network, transaction_version, event_index) DO NOTHING
+	`
+
+	_, err = q.Exec(ctx, query, version, index, tx.Hash, tx.Sender, tx.Timestamp, event.Type, string(data), source, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to archive event: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint reads the saved checkpoint without starting the listener,
+// for one-off commands that check versions against it
+func (l *EventListener) LoadCheckpoint(ctx context.Context) error {
+	return l.loadLastVersion(ctx)
+}
+
+func (l *EventListener) loadLastVersion(ctx context.Context) error {
+	query := `
+		SELECT value FROM sync_state WHERE key = $1
+	`
+
+	var versionStr string
+	err := l.db.Pool().QueryRow(ctx, query, l.CheckpointKey()).Scan(&versionStr)
+	if err != nil {
+		return err
+	}
+
+	version, err := strconv.ParseUint(versionStr, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	l.lastVersion.Store(version)
+	return nil
+}
+
+// sampleCheckpoint records the checkpoint advance since the previous sample
+// once checkpointSampleInterval has passed
+func (l *EventListener) sampleCheckpoint(ctx context.Context) {
+	l.samplePolls++
+
+	elapsed := time.Since(l.sampleAt)
+	if elapsed < checkpointSampleInterval {
+		return
+	}
+
+	sample := db.CheckpointSample{
+		Network:   l.network,
+		Version:   l.lastVersion.Load(),
+		Advanced:  l.lastVersion.Load() - l.sampleVersion,
+		Polls:     l.samplePolls,
+		ElapsedMs: elapsed.Milliseconds(),
+	}
+	if err := l.db.RecordCheckpointSample(ctx, sample); err != nil {
+		log.Warn().Err(err).Msg("⚠️  Failed to record checkpoint sample")
+		return
+	}
+
+	l.sampleAt, l.sampleVersion, l.samplePolls = time.Now(), l.lastVersion.Load(), 0
+}
+
+// saveCheckpoint moves the checkpoint at key to version as part of q
+func saveCheckpoint(ctx context.Context, q pgx.Tx, key string, version uint64) error {
+	query := `
+		INSERT INTO sync_state (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = NOW()
+	`
+
+	_, err := q.Exec(ctx, query, key, strconv.FormatUint(version, 10))
+	return err
+}
@@ -0,0 +1,151 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/dbtest"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/aptostest"
+)
+
+const testModule = "0x7c2e9a4d1b3f5e6a8c0d2f4b6a8e0c2d7c2e9a4d1b3f5e6a8c0d2f4b6a8e0c2d"
+
+// cleanupNetwork removes what a listener on network writes once the test
+// ends: rows carrying the network and its suffixed checkpoint and leases
+func cleanupNetwork(t *testing.T, database *db.DB, network string) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		rows, err := database.Pool().Query(ctx, `
+			SELECT quote_ident(table_name) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND column_name = 'network'
+		`)
+		if err != nil {
+			t.Errorf("failed to list tables: %v", err)
+			return
+		}
+		tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			t.Errorf("failed to list tables: %v", err)
+			return
+		}
+
+		for _, table := range tables {
+			if _, err := database.Pool().Exec(ctx, `DELETE FROM `+table+` WHERE network = $1`, network); err != nil {
+				t.Errorf("failed to clean up %s: %v", table, err)
+			}
+		}
+		if _, err := database.Pool().Exec(ctx, `DELETE FROM sync_state WHERE key LIKE $1`, "%:"+network); err != nil {
+			t.Errorf("failed to clean up sync_state: %v", err)
+		}
+		if _, err := database.Pool().Exec(ctx, `DELETE FROM writer_leases WHERE name LIKE $1`, "%:"+network); err != nil {
+			t.Errorf("failed to clean up writer_leases: %v", err)
+		}
+	})
+}
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestPollLoop(t *testing.T) {
+	database := dbtest.Open(t)
+
+	// A network of its own keeps the checkpoint, leases and rows apart from
+	// anything else in the database
+	network := fmt.Sprintf("polltest-%d", time.Now().UnixNano())
+	market := fmt.Sprintf("0x%064x", time.Now().UnixNano())
+	creator, alice := "0xc0ffee", "0xa11ce"
+	cleanupNetwork(t, database, network)
+
+	node := aptostest.New()
+	defer node.Close()
+	node.SetLedgerVersion(100)
+
+	l := NewEventListener(aptos.NewClientWithURL(node.URL), database, testModule, "")
+	l.SetNetwork(network, false)
+	l.SetPollInterval(20 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- l.Start(ctx) }()
+
+	// Without a checkpoint the listener starts at the ledger head
+	waitFor(t, "the listener to start", func() bool { return l.running.Load() && l.GetLastVersion() == 100 })
+
+	// The first polls after the new blocks fail; the loop keeps going
+	node.Fail(aptostest.Fault{Path: "/transactions", Status: http.StatusServiceUnavailable, Times: 2})
+	node.AddTransactions(
+		aptostest.UserTransaction(creator, aptostest.ModuleEvent(testModule, "market", "MarketCreatedEvent", map[string]any{
+			"market_address":       market,
+			"creator":              creator,
+			"description":          "Poll loop test market",
+			"resolution_timestamp": "1761955200",
+		})),
+		aptostest.UserTransaction(alice, aptostest.ModuleEvent(testModule, "market", "SharesMintedEvent", map[string]any{
+			"market_address": market,
+			"user":           alice,
+			"is_yes":         true,
+			"apt_amount_in":  "250000000",
+			"shares_out":     "4000000",
+		})),
+	)
+	node.SetLedgerVersion(110)
+
+	waitFor(t, "the checkpoint to reach the head", func() bool { return l.GetLastVersion() == 110 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if got := node.Requests("/transactions"); got < 3 {
+		t.Errorf("transaction requests = %d, want the 2 failures and a retry", got)
+	}
+
+	var checkpoint string
+	if err := database.Pool().QueryRow(context.Background(),
+		`SELECT value FROM sync_state WHERE key = $1`, l.CheckpointKey()).Scan(&checkpoint); err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if checkpoint != "110" {
+		t.Errorf("checkpoint = %s, want 110", checkpoint)
+	}
+
+	var status string
+	if err := database.Pool().QueryRow(context.Background(),
+		`SELECT status FROM "Market" WHERE "marketAddress" = $1 AND network = $2`, market, network).Scan(&status); err != nil {
+		t.Fatalf("market not recorded: %v", err)
+	}
+	if status != "active" {
+		t.Errorf("market status = %s, want active", status)
+	}
+
+	var user, action, outcome, version string
+	if err := database.Pool().QueryRow(context.Background(), `
+		SELECT a."userAddress", a."action", a."outcome", t.version::text
+		FROM "Activity" a JOIN indexed_transactions t ON t.tx_hash = a."txHash" AND t.network = a.network
+		WHERE a."marketAddress" = $1 AND a.network = $2
+	`, market, network).Scan(&user, &action, &outcome, &version); err != nil {
+		t.Fatalf("activity not recorded: %v", err)
+	}
+	if user != alice || action != "BUY" || outcome != "YES" || version != "102" {
+		t.Errorf("activity = %s %s %s at %s, want %s BUY YES at 102", user, action, outcome, version, alice)
+	}
+}
@@ -0,0 +1,234 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+)
+
+const (
+	// Outcome shares use 6 decimals, collateral is APT in octas
+	DefaultShareDecimals = 6
+	DefaultCollateral    = "0x1::aptos_coin::AptosCoin"
+)
+
+// MarketInfo is the subset of a Market row that handlers need per event
+type MarketInfo struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	Status     string `json:"status"`
+	Decimals   int    `json:"decimals"`
+	Collateral string `json:"collateral"`
+}
+
+// MarketCache keeps known markets in memory so handlers don't query the
+// Market table for every event. It only holds committed markets: the
+// listener updates it once a batch commits.
+type MarketCache struct {
+	db              *db.DB
+	network         string
+	markets         map[string]MarketInfo
+	refreshInterval time.Duration
+	lastRefresh     time.Time
+	mu              sync.RWMutex
+
+	// seq counts Put and SetStatus calls and writes holds the latest one per
+	// market, so a Refresh that read the table before them keeps them
+	seq    uint64
+	writes map[string]uint64
+}
+
+// querier is the batch transaction, or the pool outside of one
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NewMarketCache creates an empty cache backed by the Market table
+func NewMarketCache(database *db.DB) *MarketCache {
+	return &MarketCache{
+		db:              database,
+		markets:         make(map[string]MarketInfo),
+		refreshInterval: 1 * time.Minute,
+		writes:          make(map[string]uint64),
+	}
+}
+
+// SetNetwork limits the cache to the markets of network
+func (c *MarketCache) SetNetwork(network string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.network = network
+}
+
+// Refresh loads every market from the database into the cache. It merges
+// rather than replaces, since a batch committing while the query runs may
+// have cached markets or statuses the query didn't see.
+func (c *MarketCache) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	start, network := c.seq, c.network
+	c.mu.RUnlock()
+
+	query := `
+		SELECT "id", "marketAddress", status
+		FROM "Market"
+		WHERE "network" = $1
+	`
+
+	rows, err := c.db.Pool().Query(ctx, query, network)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	markets := make(map[string]MarketInfo)
+	for rows.Next() {
+		var m MarketInfo
+		if err := rows.Scan(&m.ID, &m.Address, &m.Status); err != nil {
+			return err
+		}
+		m.Decimals = DefaultShareDecimals
+		m.Collateral = DefaultCollateral
+		markets[m.Address] = m
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	for address, m := range markets {
+		if c.writes[address] <= start {
+			c.markets[address] = m
+		}
+	}
+	c.lastRefresh = time.Now()
+	c.mu.Unlock()
+
+	log.Debug().Int("markets", len(markets)).Msg("🗂️  Market cache refreshed")
+	return nil
+}
+
+// Run refreshes the cache periodically until ctx is cancelled
+func (c *MarketCache) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				log.Warn().Err(err).Msg("Failed to refresh market cache")
+			}
+		}
+	}
+}
+
+// Get returns the cached market for address
+func (c *MarketCache) Get(address string) (MarketInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[address]
+	return m, ok
+}
+
+// Resolve returns the market for address, falling back to a single lookup
+// through q for markets created since the last refresh. Passing the batch
+// transaction finds markets created earlier in the batch. The result isn't
+// cached, since q may not have committed; callers Put it once it has.
+func (c *MarketCache) Resolve(ctx context.Context, q querier, address string) (MarketInfo, bool, error) {
+	if m, ok := c.Get(address); ok {
+		return m, true, nil
+	}
+
+	query := `
+		SELECT "id", "marketAddress", status
+		FROM "Market"
+		WHERE "marketAddress" = $1 AND "network" = $2
+	`
+
+	c.mu.RLock()
+	network := c.network
+	c.mu.RUnlock()
+
+	var m MarketInfo
+	err := q.QueryRow(ctx, query, address, network).Scan(&m.ID, &m.Address, &m.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return MarketInfo{}, false, nil
+	}
+	if err != nil {
+		return MarketInfo{}, false, err
+	}
+
+	m.Decimals = DefaultShareDecimals
+	m.Collateral = DefaultCollateral
+
+	return m, true, nil
+}
+
+// Put adds or replaces a market in the cache
+func (c *MarketCache) Put(m MarketInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(m)
+}
+
+func (c *MarketCache) put(m MarketInfo) {
+	if m.Decimals == 0 {
+		m.Decimals = DefaultShareDecimals
+	}
+	if m.Collateral == "" {
+		m.Collateral = DefaultCollateral
+	}
+	c.markets[m.Address] = m
+	c.seq++
+	c.writes[m.Address] = c.seq
+}
+
+// Add caches a market found by Resolve unless it is cached already, so a
+// lookup that raced a batch doesn't undo the batch's updates
+func (c *MarketCache) Add(m MarketInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.markets[m.Address]; !ok {
+		c.put(m)
+	}
+}
+
+// SetStatus updates the cached status of a known market
+func (c *MarketCache) SetStatus(address, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m, ok := c.markets[address]; ok {
+		m.Status = status
+		c.markets[address] = m
+		c.seq++
+		c.writes[address] = c.seq
+	}
+}
+
+// Remove drops a market, e.g. one whose creation was rolled back
+func (c *MarketCache) Remove(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.markets, address)
+	c.seq++
+	c.writes[address] = c.seq
+}
+
+// Len returns the number of cached markets
+func (c *MarketCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.markets)
+}
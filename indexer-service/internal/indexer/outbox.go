@@ -0,0 +1,181 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/bus"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+)
+
+const (
+	// Due rows are claimed this many at a time
+	outboxBatchSize = 100
+
+	// A claimed row is retried after this long if its delivery is never
+	// marked (e.g. the process died mid-delivery)
+	outboxLease = time.Minute
+
+	outboxPollInterval = time.Second
+	outboxMaxBackoff   = time.Hour
+
+	// Delivered and failed rows are kept this long for inspection
+	outboxRetention = 7 * 24 * time.Hour
+	outboxPrune     = time.Hour
+)
+
+// SetOutbox writes webhook and bus deliveries to the outbox table with
+// their batch, to be sent by RunOutbox, instead of sending them from memory
+// after commit. Deliveries are retried with backoff up to maxAttempts times.
+func (l *EventListener) SetOutbox(maxAttempts int) {
+	l.useOutbox = true
+	l.outboxMaxAttempts = maxAttempts
+	l.outboxKick = make(chan struct{}, 1)
+}
+
+// stageOutbox holds a delivery until the batch flushes it
+func (l *EventListener) stageOutbox(kind, target string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn().Err(err).Str("target", target).Msg("Failed to encode outbox payload")
+		return
+	}
+	l.outboxRows = append(l.outboxRows, db.OutboxRecord{Kind: kind, Target: target, Payload: body})
+}
+
+// flushOutbox writes the staged deliveries as part of q
+func (l *EventListener) flushOutbox(ctx context.Context, q pgx.Tx) error {
+	rows := l.outboxRows
+	l.outboxRows = nil
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.InsertOutbox(ctx, q, rows)
+}
+
+// kickOutbox wakes the dispatcher after a commit so deliveries don't wait
+// for its next poll
+func (l *EventListener) kickOutbox() {
+	if l.outboxKick == nil {
+		return
+	}
+	select {
+	case l.outboxKick <- struct{}{}:
+	default:
+	}
+}
+
+// RunOutbox delivers outbox rows until ctx is cancelled. Rows are claimed
+// with SKIP LOCKED, so every replica can run a dispatcher.
+func (l *EventListener) RunOutbox(ctx context.Context) {
+	log.Info().Int("max_attempts", l.outboxMaxAttempts).Msg("📮 Outbox dispatcher started")
+
+	poll := time.NewTicker(outboxPollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(outboxPrune)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+			l.pruneOutbox(ctx)
+			continue
+		case <-poll.C:
+		case <-l.outboxKick:
+		}
+
+		// Keep going while full batches come back
+		for ctx.Err() == nil {
+			records, err := l.db.ClaimOutbox(ctx, outboxBatchSize, outboxLease)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to claim outbox rows")
+				break
+			}
+			for _, record := range records {
+				l.dispatchOutbox(ctx, record)
+			}
+			if len(records) < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (l *EventListener) dispatchOutbox(ctx context.Context, record db.OutboxRecord) {
+	err := l.deliverOutbox(ctx, record)
+	if err == nil {
+		if err := l.db.MarkOutboxDelivered(ctx, record.ID); err != nil {
+			log.Warn().Err(err).Int64("outbox_id", record.ID).Msg("Failed to mark outbox row delivered")
+		}
+		return
+	}
+
+	attempts := record.Attempts + 1
+	var retryAt time.Time
+	if attempts < l.outboxMaxAttempts {
+		backoff := min(time.Duration(1<<min(attempts, 20))*5*time.Second, outboxMaxBackoff)
+		retryAt = time.Now().Add(backoff)
+	}
+	log.Warn().
+		Err(err).
+		Int64("outbox_id", record.ID).
+		Str("kind", record.Kind).
+		Str("target", record.Target).
+		Int("attempts", attempts).
+		Bool("giving_up", retryAt.IsZero()).
+		Msg("Outbox delivery failed")
+
+	if err := l.db.MarkOutboxFailed(ctx, record.ID, err.Error(), retryAt); err != nil {
+		log.Warn().Err(err).Int64("outbox_id", record.ID).Msg("Failed to record outbox failure")
+	}
+}
+
+// deliverOutbox sends record to its target. Targets removed by a reload
+// fail, and are given up on after the usual retries.
+func (l *EventListener) deliverOutbox(ctx context.Context, record db.OutboxRecord) error {
+	switch record.Kind {
+	case db.OutboxWebhook:
+		target := l.WebhookClient(record.Target)
+		if target == nil {
+			return fmt.Errorf("webhook target is no longer configured")
+		}
+		return target.Deliver(record.Payload, record.Attempts+1)
+
+	case db.OutboxBus:
+		var publisher *bus.Publisher
+		for _, p := range l.publishers {
+			if p.Name() == record.Target {
+				publisher = p
+			}
+		}
+		if publisher == nil {
+			return fmt.Errorf("message bus is no longer configured")
+		}
+		var msg bus.Message
+		if err := json.Unmarshal(record.Payload, &msg); err != nil {
+			return err
+		}
+		return publisher.Send(ctx, []bus.Message{msg})
+
+	default:
+		return fmt.Errorf("unknown outbox kind %q", record.Kind)
+	}
+}
+
+func (l *EventListener) pruneOutbox(ctx context.Context) {
+	deleted, err := l.db.PruneOutbox(ctx, time.Now().Add(-outboxRetention))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to prune outbox")
+		return
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("🧹 Pruned outbox")
+	}
+}
@@ -0,0 +1,245 @@
+package indexer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Transactions fetched and committed per polling batch
+const pollBatchSize = uint64(100)
+
+// SetPipeline sets how many batches may be fetched and prepared ahead of the
+// commit (1 fetches the next batch only once the previous one committed) and
+// how many lookups a batch's preparation runs at once
+func (l *EventListener) SetPipeline(depth, workers int) {
+	l.pipelineDepth = max(depth, 1)
+	l.pipelineWorkers = max(workers, 1)
+}
+
+// pipelineBatch is one fetched and prepared batch waiting for its commit
+type pipelineBatch struct {
+	start, limit uint64
+	txs          []aptos.TransactionEvent
+	gaps         []versionGap // versions the fullnode didn't return
+	err          error
+}
+
+// ingest fetches start..end in batches and commits them in version order.
+// While a batch commits, the next ones are fetched from the fullnode and
+// prepared: missing timestamps are looked up and the markets their events
+// reference are loaded into the cache, both concurrently on the pool. Only
+// the commit runs handlers, one batch at a time, so events still apply in
+// chain order per market. It returns the hash of the transaction at end
+// when it was fetched.
+//
+// The prepared batches form the write queue, bounded by the pipeline depth.
+// When commits fall behind and fetching waits on a full queue for longer
+// than the write queue stall limit, fetching stops, the queued batches are
+// committed and ingest returns errBackpressure, handing the poll loop back
+// to lease renewal and control commands instead of blocking on storage.
+func (l *EventListener) ingest(ctx context.Context, start, end uint64) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A slot is taken before a batch is fetched and freed once it commits
+	slots := make(chan struct{}, l.pipelineDepth)
+	queue := make(chan chan pipelineBatch, l.pipelineDepth)
+	workers := make(chan struct{}, l.pipelineWorkers)
+	l.writes.begin(l.pipelineDepth)
+	defer l.writes.end()
+
+	backpressure := make(chan struct{})
+	go func() {
+		defer close(queue)
+		for next := start; next <= end; {
+			if !l.acquireSlot(ctx, slots) {
+				if ctx.Err() == nil {
+					close(backpressure)
+				}
+				return
+			}
+
+			limit := min(pollBatchSize, end-next+1)
+			prepared := make(chan pipelineBatch, 1)
+			queue <- prepared
+			go func(start, limit uint64) {
+				prepared <- l.prepareBatch(ctx, start, limit, workers)
+			}(next, limit)
+			next += limit
+		}
+	}()
+
+	checkpointHash := ""
+	endVersion := strconv.FormatUint(end, 10)
+	for prepared := range queue {
+		batch := <-prepared
+		if batch.err != nil {
+			log.Error().
+				Err(batch.err).
+				Uint64("start", batch.start).
+				Uint64("limit", batch.limit).
+				Msg("❌ Failed to fetch transactions")
+			return "", batch.err
+		}
+		if l.Paused() {
+			return "", errPaused
+		}
+
+		for _, tx := range batch.txs {
+			if tx.Version == endVersion {
+				checkpointHash = tx.Hash
+			}
+		}
+
+		// Apply the batch and advance the checkpoint past it atomically,
+		// recording what the fullnode left out for repair
+		l.gaps = batch.gaps
+		committing := time.Now()
+		if err := l.processBatch(ctx, batch.txs, batch.start+batch.limit-1); err != nil {
+			log.Error().
+				Err(err).
+				Uint64("start", batch.start).
+				Uint64("limit", batch.limit).
+				Msg("❌ Failed to process batch")
+			return "", err
+		}
+		l.writes.committed(time.Since(committing))
+		<-slots
+	}
+
+	select {
+	case <-backpressure:
+		return "", errBackpressure
+	default:
+	}
+
+	// The producer stops early only when ctx is done
+	return checkpointHash, ctx.Err()
+}
+
+// acquireSlot takes a write queue slot, waiting at most the stall limit
+// when the queue is full. It reports false when the wait timed out or ctx
+// is done.
+func (l *EventListener) acquireSlot(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		l.writes.queued()
+		return true
+	default:
+	}
+
+	// Every slot holds a batch waiting for its commit
+	stalled := time.Now()
+	var timeout <-chan time.Time
+	if l.writeQueueStall > 0 {
+		timer := time.NewTimer(l.writeQueueStall)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case slots <- struct{}{}:
+		l.writes.stalled(time.Since(stalled), false)
+		l.writes.queued()
+		return true
+	case <-timeout:
+		l.writes.stalled(time.Since(stalled), true)
+		log.Warn().
+			Dur("waited", time.Since(stalled)).
+			Int("capacity", cap(slots)).
+			Msg("🐢 Write queue full, pausing fetches until storage catches up")
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// prepareBatch fetches limit transactions from start and warms what their
+// handlers would otherwise look up one at a time inside the commit. Lookup
+// failures are left for the handlers to retry; only the fetch can fail.
+func (l *EventListener) prepareBatch(ctx context.Context, start, limit uint64, workers chan struct{}) pipelineBatch {
+	batch := pipelineBatch{start: start, limit: limit}
+
+	log.Debug().
+		Uint64("start", start).
+		Uint64("limit", limit).
+		Msg("🔍 Fetching transaction batch")
+
+	batch.txs, batch.err = l.client.GetTransactionsByVersionRange(ctx, start, limit)
+	if batch.err != nil {
+		return batch
+	}
+	batch.gaps = findGaps(batch.txs, start, start+limit-1)
+
+	log.Debug().
+		Int("tx_count", len(batch.txs)).
+		Msg("✅ Transactions fetched")
+
+	var wg sync.WaitGroup
+	run := func(lookup func()) {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			lookup()
+		}()
+	}
+
+	seen := make(map[string]bool)
+	for i := range batch.txs {
+		tx := &batch.txs[i]
+		if !emitsModuleEvents(*tx, l.moduleAddress) {
+			continue
+		}
+
+		// Each goroutine writes only its own element, and the commit reads
+		// the batch after wg.Wait
+		if tx.Timestamp == "" {
+			run(func() {
+				version, err := strconv.ParseUint(tx.Version, 10, 64)
+				if err != nil {
+					return
+				}
+				if full, err := l.client.GetTransactionByVersion(ctx, version); err == nil {
+					tx.Timestamp = full.Timestamp
+				}
+			})
+		}
+
+		for _, event := range tx.Events {
+			// A MarketCreatedEvent's market only exists once its batch commits
+			if tag, ok := aptos.MatchModule(event.Type, l.moduleAddress); !ok || tag.Name == "MarketCreatedEvent" {
+				continue
+			}
+			address, ok := event.Data["market_address"].(string)
+			if !ok || seen[address] {
+				continue
+			}
+			seen[address] = true
+			if _, cached := l.markets.Get(address); cached {
+				continue
+			}
+			run(func() {
+				m, known, err := l.markets.Resolve(ctx, l.db.Pool(), address)
+				if err != nil {
+					log.Debug().Err(err).Str("market", address).Msg("Market prefetch failed")
+				} else if known {
+					l.markets.Add(m)
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	return batch
+}
@@ -0,0 +1,44 @@
+package indexer
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Writer leases are renewed this often even while polling has backed off
+// past the lease TTL
+const leaseRenewInterval = writerLeaseTTL / 3
+
+// SetMaxPollInterval sets how far the poll interval backs off while idle. A
+// max at or below the poll interval disables backoff.
+func (l *EventListener) SetMaxPollInterval(interval time.Duration) {
+	l.maxPollInterval = interval
+}
+
+// PollInterval returns the interval the listener is currently polling at
+func (l *EventListener) PollInterval() time.Duration {
+	return time.Duration(l.currentInterval.Load())
+}
+
+// nextPollInterval doubles current after an idle poll, up to the max, and
+// returns to the base interval as soon as a poll finds module events.
+// Aptos ledgers advance with block metadata even on a quiet network, so a
+// poll is idle when it indexed nothing from the module, not when the ledger
+// didn't move.
+func (l *EventListener) nextPollInterval(current time.Duration, idle bool) time.Duration {
+	next := l.pollInterval
+	if idle && l.maxPollInterval > l.pollInterval {
+		next = min(max(current*2, l.pollInterval), l.maxPollInterval)
+	}
+
+	if next != current {
+		log.Debug().
+			Dur("from", current).
+			Dur("to", next).
+			Bool("idle", idle).
+			Msg("⏱️  Poll interval adjusted")
+	}
+	l.currentInterval.Store(int64(next))
+	return next
+}
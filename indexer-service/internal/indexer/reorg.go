@@ -0,0 +1,326 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Number of recorded checkpoints kept for verification
+const checkpointHistory = 100
+
+// SetReorgCheckDepth sets how many recent checkpoints are re-verified against
+// the fullnode on every poll (0 disables the check)
+func (l *EventListener) SetReorgCheckDepth(depth int) {
+	l.reorgCheckDepth = depth
+}
+
+// SetConfirmationDepth keeps processing depth versions behind the ledger
+// head, so versions a fullnode might still disagree about (e.g. right after
+// failing over to another one) are never indexed. 0 processes up to the head.
+func (l *EventListener) SetConfirmationDepth(depth uint64) {
+	l.confirmationDepth = depth
+}
+
+// ConfirmationDepth returns how far behind the ledger head processing stays
+func (l *EventListener) ConfirmationDepth() uint64 {
+	return l.confirmationDepth
+}
+
+// safeVersion is the newest version processed for a ledger head
+func (l *EventListener) safeVersion(head uint64) uint64 {
+	if head < l.confirmationDepth {
+		return 0
+	}
+	return head - l.confirmationDepth
+}
+
+// checkForReorg re-fetches the most recent checkpointed versions and rolls
+// back to the newest one that still matches when the chain no longer agrees
+// with what was indexed, or when the ledger is behind our checkpoint (e.g.
+// after failing over to a lagging or different fullnode). It reports whether
+// a rollback happened.
+func (l *EventListener) checkForReorg(ctx context.Context, latestVersion uint64) (bool, error) {
+	if l.reorgCheckDepth <= 0 {
+		return false, nil
+	}
+
+	rows, err := l.db.Pool().Query(ctx, `
+		SELECT version, tx_hash FROM checkpoint_hashes
+		WHERE network = $3 AND version <= $1
+		ORDER BY version DESC
+		LIMIT $2
+	`, int64(l.lastVersion.Load()), l.reorgCheckDepth, l.network)
+	if err != nil {
+		return false, fmt.Errorf("failed to load checkpoint hashes: %w", err)
+	}
+
+	type checkpoint struct {
+		version uint64
+		hash    string
+	}
+	var checkpoints []checkpoint
+	for rows.Next() {
+		var version int64
+		var cp checkpoint
+		if err := rows.Scan(&version, &cp.hash); err != nil {
+			rows.Close()
+			return false, err
+		}
+		cp.version = uint64(version)
+		checkpoints = append(checkpoints, cp)
+	}
+	rows.Close()
+
+	ledgerBehind := latestVersion < l.lastVersion.Load()
+	if len(checkpoints) == 0 && !ledgerBehind {
+		return false, nil
+	}
+
+	// Newest first: stop at the first checkpoint that still matches
+	var good uint64
+	found := false
+	diverged := false
+	for _, cp := range checkpoints {
+		if cp.version > latestVersion {
+			continue
+		}
+		tx, err := l.client.GetTransactionByVersion(ctx, cp.version)
+		if err != nil {
+			return false, fmt.Errorf("failed to verify checkpoint %d: %w", cp.version, err)
+		}
+		if tx.Hash == cp.hash {
+			good, found = cp.version, true
+			break
+		}
+		diverged = true
+		log.Error().
+			Uint64("version", cp.version).
+			Str("recorded", cp.hash).
+			Str("fullnode", tx.Hash).
+			Msg("🔀 Checkpoint hash mismatch")
+	}
+
+	if !diverged && !ledgerBehind {
+		return false, nil
+	}
+
+	if !found {
+		// Nothing in the window still matches: re-index from below the
+		// oldest checked checkpoint, never above the ledger head
+		good = latestVersion
+		if n := len(checkpoints); n > 0 && checkpoints[n-1].version-1 < good {
+			good = checkpoints[n-1].version - 1
+		}
+	}
+	if good >= l.lastVersion.Load() {
+		return false, nil
+	}
+
+	reason := "checkpoint hash mismatch"
+	if ledgerBehind && !diverged {
+		reason = "ledger version went backwards"
+	}
+	if err := l.rollback(ctx, good, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rollback removes everything indexed above version and moves the checkpoint
+// back so those versions are indexed again
+func (l *EventListener) rollback(ctx context.Context, version uint64, reason string) error {
+	log.Warn().
+		Uint64("from", l.lastVersion.Load()).
+		Uint64("to", version).
+		Str("reason", reason).
+		Msg("⏪ Rolling back indexed state")
+
+	tx, err := l.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	v := int64(version)
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM "Activity"
+		WHERE "network" = $2
+			AND "txHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)
+	`, v, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to roll back activities: %w", err)
+	}
+	removed := tag.RowsAffected()
+
+	lpRemoved, err := rollbackLiquidity(ctx, tx, l.network, v)
+	if err != nil {
+		return err
+	}
+
+	removedMarkets, reopenedMarkets, err := rollbackMarkets(ctx, tx, l.network, v)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range []string{
+		`DELETE FROM "ProtocolFee" WHERE "network" = $2 AND "txHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)`,
+		`DELETE FROM "ResolutionHistory" WHERE "network" = $2 AND "txHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)`,
+		`DELETE FROM indexed_transactions WHERE network = $2 AND version > $1`,
+		`DELETE FROM checkpoint_hashes WHERE network = $2 AND version > $1`,
+		`DELETE FROM processed_events WHERE network = $2 AND transaction_version > $1`,
+		`DELETE FROM quarantined_events WHERE network = $2 AND tx_version > $1`,
+		`DELETE FROM deferred_events WHERE network = $2 AND transaction_version > $1`,
+		`DELETE FROM raw_events WHERE network = $2 AND transaction_version > $1 AND source = 'excluded_sender'`,
+		`DELETE FROM version_gaps WHERE network = $2 AND start_version > $1`,
+		`UPDATE version_gaps SET end_version = $1 WHERE network = $2 AND end_version > $1`,
+	} {
+		if _, err := tx.Exec(ctx, q, v, l.network); err != nil {
+			return fmt.Errorf("failed to roll back: %w", err)
+		}
+	}
+
+	if err := saveCheckpoint(ctx, tx, l.CheckpointKey(), version); err != nil {
+		return fmt.Errorf("failed to move checkpoint: %w", err)
+	}
+	if err := l.fence(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, address := range removedMarkets {
+		l.markets.Remove(address)
+	}
+	for _, address := range reopenedMarkets {
+		l.markets.SetStatus(address, "active")
+	}
+
+	l.lastVersion.Store(version)
+	l.sampleVersion = min(l.sampleVersion, version)
+	l.scaling.advance(version)
+	log.Warn().
+		Uint64("version", version).
+		Int64("activities_removed", removed).
+		Int64("lp_activities_removed", lpRemoved).
+		Int("markets_removed", len(removedMarkets)).
+		Int("markets_reopened", len(reopenedMarkets)).
+		Msg("⏪ Rollback complete, re-indexing")
+	return nil
+}
+
+// rollbackMarkets removes the markets created above version and reopens the
+// ones resolved above it, returning the addresses of each. It runs before
+// indexed_transactions is cut back, since that is how the creating and
+// resolving transactions are found.
+func rollbackMarkets(ctx context.Context, tx pgx.Tx, network string, version int64) (removed, reopened []string, err error) {
+	rows, err := tx.Query(ctx, `
+		DELETE FROM "Market"
+		WHERE "network" = $2
+			AND "creationTxHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)
+		RETURNING "marketAddress"
+	`, version, network)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to roll back markets: %w", err)
+	}
+	if removed, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return nil, nil, fmt.Errorf("failed to roll back markets: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		UPDATE "Market"
+		SET status = 'active',
+			"outcome" = NULL,
+			"resolver" = NULL,
+			"resolutionTxHash" = NULL,
+			"resolvedAt" = NULL,
+			"updatedAt" = NOW()
+		WHERE "network" = $2
+			AND "resolutionTxHash" IN (SELECT tx_hash FROM indexed_transactions WHERE network = $2 AND version > $1)
+		RETURNING "marketAddress"
+	`, version, network)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to roll back market resolutions: %w", err)
+	}
+	if reopened, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return nil, nil, fmt.Errorf("failed to roll back market resolutions: %w", err)
+	}
+	return removed, reopened, nil
+}
+
+// recordCheckpointHash stores the hash of the checkpointed transaction and
+// prunes old entries
+func (l *EventListener) recordCheckpointHash(ctx context.Context, version uint64, hash string) error {
+	if l.reorgCheckDepth <= 0 {
+		return nil
+	}
+
+	if hash == "" {
+		tx, err := l.client.GetTransactionByVersion(ctx, version)
+		if err != nil {
+			return err
+		}
+		hash = tx.Hash
+	}
+
+	_, err := l.db.Pool().Exec(ctx, `
+		INSERT INTO checkpoint_hashes (version, tx_hash, network) VALUES ($1, $2, $3)
+		ON CONFLICT (network, version) DO UPDATE SET tx_hash = $2, recorded_at = NOW()
+	`, int64(version), hash, l.network)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.db.Pool().Exec(ctx, `
+		DELETE FROM checkpoint_hashes
+		WHERE network = $2 AND version < (
+			SELECT MIN(version) FROM (
+				SELECT version FROM checkpoint_hashes WHERE network = $2 ORDER BY version DESC LIMIT $1
+			) recent
+		)
+	`, checkpointHistory, l.network)
+	return err
+}
+
+// recordIndexedTx remembers a transaction that emitted module events
+func (l *EventListener) recordIndexedTx(ctx context.Context, q pgx.Tx, tx aptos.TransactionEvent) error {
+	version, err := strconv.ParseInt(tx.Version, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO indexed_transactions (version, tx_hash, network, sender, gas_used, gas_unit_price)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (network, version) DO UPDATE SET
+			tx_hash = $2, indexed_at = NOW(),
+			sender = COALESCE($4, indexed_transactions.sender),
+			gas_used = COALESCE($5, indexed_transactions.gas_used),
+			gas_unit_price = COALESCE($6, indexed_transactions.gas_unit_price)
+	`, version, tx.Hash, l.network, optionalString(tx.Sender), optionalInt(tx.GasUsed), optionalInt(tx.GasUnitPrice))
+	return err
+}
+
+// optionalString is s, or NULL when it's empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalInt parses a Move u64 string, or is NULL when s isn't one (e.g.
+// imported transactions carry no gas)
+func optionalInt(s string) *int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,206 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Transactions fetched and written per replay batch
+const replayBatchSize = 100
+
+var ErrReplayRunning = errors.New("a replay is already running")
+
+// ReplayStatus is the progress of the current or most recent replay
+type ReplayStatus struct {
+	Running      bool     `json:"running"`
+	FromVersion  uint64   `json:"from_version"`
+	ToVersion    uint64   `json:"to_version"`
+	Handlers     []string `json:"handlers,omitempty"` // empty means all
+	Version      uint64   `json:"version"`            // last version replayed
+	Transactions int      `json:"transactions"`       // with module events
+	StartedAt    string   `json:"started_at,omitempty"`
+	FinishedAt   string   `json:"finished_at,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Replayer re-runs a version range through the handlers in the background,
+// next to the live listener. Events already claimed in processed_events are
+// skipped, so a replay only applies what was never handled, e.g. events of a
+// newly added handler. The live checkpoint is never touched.
+type Replayer struct {
+	ctx    context.Context
+	live   *EventListener
+	status ReplayStatus
+	mu     sync.Mutex
+}
+
+// NewReplayer creates a replayer for live's module; replays stop when ctx is
+// cancelled
+func NewReplayer(ctx context.Context, live *EventListener) *Replayer {
+	return &Replayer{ctx: ctx, live: live}
+}
+
+// Status returns the progress of the current or most recent replay
+func (r *Replayer) Status() ReplayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Handlers = append([]string(nil), r.status.Handlers...)
+	return status
+}
+
+// Start replays from..to, restricted to handlers when given. Only versions
+// the live listener has already passed can be replayed, so the replay never
+// claims events ahead of it.
+func (r *Replayer) Start(from, to uint64, handlers []string) (ReplayStatus, error) {
+	l, err := r.begin(from, to, handlers)
+	if err != nil {
+		return ReplayStatus{}, err
+	}
+	go r.run(l, from, to)
+	return r.Status(), nil
+}
+
+// Run is Start in the foreground, for one-off commands: it returns once the
+// range is replayed, with the final status and the replay error
+func (r *Replayer) Run(from, to uint64, handlers []string) (ReplayStatus, error) {
+	l, err := r.begin(from, to, handlers)
+	if err != nil {
+		return ReplayStatus{}, err
+	}
+	r.run(l, from, to)
+
+	status := r.Status()
+	if status.Error != "" {
+		return status, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+// begin checks the range and marks a replay of it as running
+func (r *Replayer) begin(from, to uint64, handlers []string) (*EventListener, error) {
+	if from > to {
+		return nil, fmt.Errorf("from_version must not be above to_version")
+	}
+	if checkpoint := r.live.GetLastVersion(); to > checkpoint {
+		return nil, fmt.Errorf("to_version %d is above the live checkpoint %d", to, checkpoint)
+	}
+
+	l, err := r.newListener(handlers)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.status.Running {
+		r.mu.Unlock()
+		return nil, ErrReplayRunning
+	}
+	r.status = ReplayStatus{
+		Running:     true,
+		FromVersion: from,
+		ToVersion:   to,
+		Handlers:    handlers,
+		StartedAt:   timeconv.Format(time.Now()),
+	}
+	r.mu.Unlock()
+
+	log.Info().
+		Uint64("from", from).
+		Uint64("to", to).
+		Strs("handlers", handlers).
+		Msg("🔁 Replay started")
+	return l, nil
+}
+
+// newListener builds the listener the replay runs through: no webhooks, so
+// history doesn't reach live consumers, the live sender filter and market
+// cache, and only the requested handlers
+func (r *Replayer) newListener(handlers []string) (*EventListener, error) {
+	l := NewEventListener(r.live.client, r.live.db, r.live.moduleAddress, "")
+	l.network, l.stateSuffix = r.live.network, r.live.stateSuffix
+	l.owner = r.live.owner // commits are fenced by the live listener's leases
+	l.SetSenderFilter(r.live.SenderFilter())
+	l.events = r.live.events
+	l.markets = r.live.markets
+	l.marketView = r.live.marketView
+
+	if len(handlers) == 0 {
+		return l, nil
+	}
+
+	keep := make(map[string]bool)
+	for _, name := range handlers {
+		if _, ok := l.eventHandlers[name]; !ok {
+			names := l.getHandlerNames()
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown handler %q (available: %s)", name, strings.Join(names, ", "))
+		}
+		keep[name] = true
+	}
+	for name := range l.eventHandlers {
+		if !keep[name] {
+			delete(l.eventHandlers, name)
+		}
+	}
+	return l, nil
+}
+
+func (r *Replayer) run(l *EventListener, from, to uint64) {
+	err := r.replay(l, from, to)
+
+	r.mu.Lock()
+	r.status.Running = false
+	r.status.FinishedAt = timeconv.Format(time.Now())
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	status := r.status
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Error().Err(err).Uint64("version", status.Version).Msg("❌ Replay failed")
+		return
+	}
+	log.Info().
+		Uint64("from", from).
+		Uint64("to", to).
+		Int("transactions", status.Transactions).
+		Msg("✅ Replay complete")
+}
+
+func (r *Replayer) replay(l *EventListener, from, to uint64) error {
+	for start := from; start <= to; {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+
+		limit := min(uint64(replayBatchSize), to-start+1)
+		txs, err := l.client.GetTransactionsByVersionRange(r.ctx, start, limit)
+		if err != nil {
+			return fmt.Errorf("failed to fetch transactions from %d: %w", start, err)
+		}
+
+		seen := l.moduleTxs
+		if err := l.ProcessRangeWith(r.ctx, start, start+limit-1, txs, nil); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.status.Version = start + limit - 1
+		r.status.Transactions += int(l.moduleTxs - seen)
+		r.mu.Unlock()
+
+		start += limit
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// States recorded in ResolutionHistory
+const (
+	resolutionProposed        = "PROPOSED"
+	resolutionDisputed        = "DISPUTED"
+	resolutionDisputeResolved = "DISPUTE_RESOLVED"
+)
+
+// resolutionStep is one oracle state transition of a market
+type resolutionStep struct {
+	MarketAddress string
+	State         string
+	Outcome       string // empty for disputes
+	Actor         string
+}
+
+func (l *EventListener) handleResolutionProposed(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	proposed, err := aptos.DecodeResolutionProposed(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	return l.recordResolutionStep(ctx, q, event, tx, resolutionStep{
+		MarketAddress: proposed.MarketAddress,
+		State:         resolutionProposed,
+		Outcome:       proposed.Outcome,
+		Actor:         proposed.Proposer,
+	})
+}
+
+func (l *EventListener) handleDisputeRaised(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	raised, err := aptos.DecodeDisputeRaised(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	return l.recordResolutionStep(ctx, q, event, tx, resolutionStep{
+		MarketAddress: raised.MarketAddress,
+		State:         resolutionDisputed,
+		Actor:         raised.Disputer,
+	})
+}
+
+func (l *EventListener) handleDisputeResolved(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent) error {
+	settled, err := aptos.DecodeDisputeResolved(event.Data)
+	if err != nil {
+		return l.rejectMalformed(ctx, q, event, tx, err)
+	}
+	return l.recordResolutionStep(ctx, q, event, tx, resolutionStep{
+		MarketAddress: settled.MarketAddress,
+		State:         resolutionDisputeResolved,
+		Outcome:       settled.Outcome,
+		Actor:         tx.Sender,
+	})
+}
+
+// recordResolutionStep appends step to the market's ResolutionHistory and
+// sends the event to the webhooks. Subscribers are matched by applyEvent
+// like for every other event.
+func (l *EventListener) recordResolutionStep(ctx context.Context, q pgx.Tx, event aptos.Event, tx aptos.TransactionEvent, step resolutionStep) error {
+	log.Info().
+		Str("tx", tx.Hash).
+		Str("market", step.MarketAddress).
+		Str("state", step.State).
+		Str("actor", step.Actor).
+		Msg("⚖️  Oracle state transition")
+
+	if known, err := l.checkMarket(ctx, q, event, tx, step.MarketAddress); !known {
+		return err
+	}
+
+	timestamp, err := l.txTime(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	var outcome *string
+	if step.Outcome != "" {
+		outcome = &step.Outcome
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO "ResolutionHistory" ("marketAddress", "txHash", "state", "outcome", "actor", "data", "timestamp", "network")
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, step.MarketAddress, tx.Hash, step.State, outcome, step.Actor, data, timestamp, l.network)
+	if err != nil {
+		return fmt.Errorf("failed to record resolution history: %w", err)
+	}
+
+	if len(l.webhookClients) > 0 {
+		l.sendWebhook(event.Type, event.Data, tx)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package indexer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Processing rate is averaged over checkpoint advances within this window
+const scalingRateWindow = 5 * time.Minute
+
+// ScalingTargets is how much work one replica is expected to absorb; the
+// desired replica count is the backlog divided by these
+type ScalingTargets struct {
+	LagPerReplica   uint64 // versions behind the safe version
+	QueuePerReplica int    // pending webhook deliveries and digest trades
+	MaxReplicas     int    // 0 means unbounded
+}
+
+// ScalingSignal is a machine-readable load summary for autoscalers such as
+// KEDA's metrics-api scaler or an HPA external metrics adapter
+type ScalingSignal struct {
+	LedgerVersion     uint64  `json:"ledger_version"`
+	SafeVersion       uint64  `json:"safe_version"` // head minus CONFIRMATION_DEPTH
+	LastVersion       uint64  `json:"last_version"`
+	Lag               uint64  `json:"lag"`             // behind the safe version
+	ProcessingRate    float64 `json:"processing_rate"` // versions per second
+	LagSeconds        float64 `json:"lag_seconds"`     // -1 when lagging with no progress
+	WebhookQueueDepth int     `json:"webhook_queue_depth"`
+	DesiredReplicas   int     `json:"desired_replicas"`
+	Standby           bool    `json:"standby"` // another instance holds the writer lease
+	UpdatedAt         string  `json:"updated_at,omitempty"`
+}
+
+type versionSample struct {
+	at      time.Time
+	version uint64
+}
+
+// scalingTracker keeps the ledger head, the safe version below it and
+// recent checkpoint advances. It is written by the polling loop and read by
+// the HTTP server.
+type scalingTracker struct {
+	ledgerVersion uint64
+	safeVersion   uint64
+	updatedAt     time.Time
+	standby       bool
+	samples       []versionSample
+	mu            sync.Mutex
+}
+
+func (s *scalingTracker) observeLedger(version, safe uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ledgerVersion, s.safeVersion = version, safe
+	s.updatedAt = time.Now()
+	s.standby = false
+}
+
+func (s *scalingTracker) setStandby() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.standby = true
+	s.updatedAt = time.Now()
+}
+
+// advance records the checkpoint moving to version. A move backwards
+// (rollback) restarts the rate window.
+func (s *scalingTracker) advance(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if n := len(s.samples); n > 0 && s.samples[n-1].version > version {
+		s.samples = nil
+	}
+	s.samples = append(s.samples, versionSample{at: now, version: version})
+	s.prune(now)
+}
+
+func (s *scalingTracker) prune(now time.Time) {
+	i := 0
+	for i < len(s.samples)-1 && now.Sub(s.samples[i].at) > scalingRateWindow {
+		i++
+	}
+	s.samples = s.samples[i:]
+}
+
+// rate is versions per second across the samples in the window
+func (s *scalingTracker) rate() float64 {
+	s.prune(time.Now())
+	if len(s.samples) < 2 {
+		return 0
+	}
+	first, last := s.samples[0], s.samples[len(s.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.version-first.version) / elapsed
+}
+
+// Scaling returns the current autoscaling signal
+func (l *EventListener) Scaling(targets ScalingTargets) ScalingSignal {
+	queue := 0
+	l.settingsMu.Lock()
+	for _, client := range l.webhookClients {
+		queue += client.Pending()
+	}
+	for _, digest := range l.digests {
+		queue += digest.Pending()
+	}
+	l.settingsMu.Unlock()
+
+	l.scaling.mu.Lock()
+	signal := ScalingSignal{
+		LedgerVersion:     l.scaling.ledgerVersion,
+		SafeVersion:       l.scaling.safeVersion,
+		LastVersion:       l.GetLastVersion(),
+		ProcessingRate:    l.scaling.rate(),
+		WebhookQueueDepth: queue,
+		Standby:           l.scaling.standby,
+	}
+	if !l.scaling.updatedAt.IsZero() {
+		signal.UpdatedAt = timeconv.Format(l.scaling.updatedAt)
+	}
+	l.scaling.mu.Unlock()
+
+	if signal.SafeVersion > signal.LastVersion {
+		signal.Lag = signal.SafeVersion - signal.LastVersion
+	}
+	switch {
+	case signal.Lag == 0:
+		signal.LagSeconds = 0
+	case signal.ProcessingRate > 0:
+		signal.LagSeconds = float64(signal.Lag) / signal.ProcessingRate
+	default:
+		signal.LagSeconds = -1
+	}
+
+	signal.DesiredReplicas = desiredReplicas(signal, targets)
+	return signal
+}
+
+func desiredReplicas(signal ScalingSignal, targets ScalingTargets) int {
+	replicas := 1
+	if targets.LagPerReplica > 0 {
+		replicas = max(replicas, int(math.Ceil(float64(signal.Lag)/float64(targets.LagPerReplica))))
+	}
+	if targets.QueuePerReplica > 0 {
+		replicas = max(replicas, int(math.Ceil(float64(signal.WebhookQueueDepth)/float64(targets.QueuePerReplica))))
+	}
+	if targets.MaxReplicas > 0 {
+		replicas = min(replicas, targets.MaxReplicas)
+	}
+	return replicas
+}
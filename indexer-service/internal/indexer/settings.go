@@ -0,0 +1,124 @@
+package indexer
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+	"github.com/verifi-protocol/pkg/senders"
+)
+
+// Settings are the listener options that can change without a restart
+type Settings struct {
+	WebhookURL      string
+	WebhookTargets  []webhook.Target
+	Senders         *senders.Filter
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+}
+
+// Reload schedules new settings. The polling loop applies them before its
+// next batch, so a catch-up in progress keeps its checkpoint and no batch
+// sees a mix of old and new webhook targets.
+func (l *EventListener) Reload(s Settings) {
+	l.settingsMu.Lock()
+	l.reload = &s
+	l.settingsMu.Unlock()
+
+	select {
+	case l.reloaded <- struct{}{}:
+	default:
+	}
+}
+
+// SetPollInterval sets how often the listener polls the fullnode
+func (l *EventListener) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		l.pollInterval = interval
+	}
+}
+
+// SenderFilter returns the sender allow/deny lists currently applied
+func (l *EventListener) SenderFilter() *senders.Filter {
+	l.settingsMu.Lock()
+	defer l.settingsMu.Unlock()
+
+	return l.senders
+}
+
+// applySettings swaps in settings scheduled by Reload. It runs on the polling
+// goroutine between batches, when no notifications are queued.
+func (l *EventListener) applySettings() {
+	l.settingsMu.Lock()
+	defer l.settingsMu.Unlock()
+
+	s := l.reload
+	if s == nil {
+		return
+	}
+	l.reload = nil
+
+	// Keep clients for URLs that stay, so their delivery stats carry over;
+	// their format follows the new settings
+	existing := make(map[string]*webhook.WebhookClient)
+	for _, client := range l.webhookClients {
+		existing[client.URL] = client
+	}
+	clientFor := func(url string, format webhook.Formatter) *webhook.WebhookClient {
+		client, ok := existing[url]
+		if !ok {
+			client = webhook.NewWebhookClient(url)
+		}
+		client.SetFormat(format)
+		return client
+	}
+
+	var clients []*webhook.WebhookClient
+	var digests []*webhook.Digest
+	if s.WebhookURL != "" {
+		clients = append(clients, clientFor(s.WebhookURL, nil))
+	}
+	for _, target := range s.WebhookTargets {
+		if target.Mode == webhook.ModeDigest {
+			digests = append(digests, webhook.NewDigest(webhook.NewWebhookClient(target.URL), target.Interval))
+		} else {
+			clients = append(clients, clientFor(target.URL, target.Format))
+		}
+	}
+
+	l.webhookClients = clients
+	l.digests = digests
+	if s.Senders != nil {
+		l.senders = s.Senders
+	}
+	l.SetPollInterval(s.PollInterval)
+	l.SetMaxPollInterval(s.MaxPollInterval)
+
+	// Old digests send what they hold before the new ones take over
+	if l.digestCtx != nil {
+		l.startDigests(l.digestCtx)
+	}
+
+	log.Info().
+		Int("webhooks", len(clients)).
+		Int("digests", len(digests)).
+		Dur("poll_interval", l.pollInterval).
+		Dur("max_poll_interval", l.maxPollInterval).
+		Msg("🔁 Listener settings reloaded")
+}
+
+// startDigests runs the current digests until ctx is cancelled or the next
+// reload replaces them. Must hold l.settingsMu or run before polling starts.
+func (l *EventListener) startDigests(ctx context.Context) {
+	if l.stopDigests != nil {
+		l.stopDigests()
+	}
+	l.digestCtx = ctx
+
+	digestCtx, stop := context.WithCancel(ctx)
+	l.stopDigests = stop
+	for _, digest := range l.digests {
+		go digest.Run(digestCtx)
+	}
+}
@@ -0,0 +1,173 @@
+package indexer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/indexer-service/internal/webhook"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Transactions per second are averaged over this window
+const throughputWindow = time.Minute
+
+// EventCounts are the outcomes of one event type since startup. Processed,
+// skipped and excluded count committed batches only; errors count every
+// failed handler run, including those of batches that were retried.
+type EventCounts struct {
+	Processed int64 `json:"processed"`
+	Skipped   int64 `json:"skipped"`  // already in processed_events
+	Excluded  int64 `json:"excluded"` // sender outside the allow/deny lists
+	Errors    int64 `json:"errors"`
+}
+
+// WebhookTotals sums delivery stats across webhook and digest targets
+type WebhookTotals struct {
+	Delivered int64                   `json:"delivered"`
+	Failed    int64                   `json:"failed"`
+	Pending   int                     `json:"pending"`
+	Targets   []webhook.DeliveryStats `json:"targets"`
+}
+
+// Stats is the processing summary on /status
+type Stats struct {
+	StartedAt             string                 `json:"started_at"`
+	UptimeSeconds         int64                  `json:"uptime_seconds"`
+	Events                map[string]EventCounts `json:"events"`
+	HandlerErrors         int64                  `json:"handler_errors"`
+	Transactions          int64                  `json:"transactions"` // with module events, committed
+	TransactionsPerSecond float64                `json:"transactions_per_second"`
+	Webhooks              WebhookTotals          `json:"webhooks"`
+}
+
+type txSample struct {
+	at    time.Time
+	count int
+}
+
+// statsTracker is written by the polling loop and read by the HTTP server.
+// Batch outcomes are staged in batch and merged on commit.
+type statsTracker struct {
+	startedAt    time.Time
+	events       map[string]*EventCounts
+	transactions int64
+	samples      []txSample
+	mu           sync.Mutex
+
+	// Staged by the polling goroutine only
+	batch    map[string]*EventCounts
+	batchTxs int
+}
+
+func newStatsTracker() *statsTracker {
+	return &statsTracker{
+		startedAt: time.Now(),
+		events:    make(map[string]*EventCounts),
+		batch:     make(map[string]*EventCounts),
+	}
+}
+
+// staged returns the batch counts for eventName
+func (s *statsTracker) staged(eventName string) *EventCounts {
+	counts, ok := s.batch[eventName]
+	if !ok {
+		counts = &EventCounts{}
+		s.batch[eventName] = counts
+	}
+	return counts
+}
+
+// resetBatch discards what a failed batch staged
+func (s *statsTracker) resetBatch() {
+	clear(s.batch)
+	s.batchTxs = 0
+}
+
+// handlerError counts a failed handler run right away
+func (s *statsTracker) handlerError(eventName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts(eventName).Errors++
+}
+
+// commit merges the staged batch
+func (s *statsTracker) commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, staged := range s.batch {
+		counts := s.counts(name)
+		counts.Processed += staged.Processed
+		counts.Skipped += staged.Skipped
+		counts.Excluded += staged.Excluded
+	}
+	if s.batchTxs > 0 {
+		now := time.Now()
+		s.transactions += int64(s.batchTxs)
+		s.samples = append(s.samples, txSample{at: now, count: s.batchTxs})
+		s.prune(now)
+	}
+	s.resetBatch()
+}
+
+// counts returns the totals for eventName. Must hold s.mu.
+func (s *statsTracker) counts(eventName string) *EventCounts {
+	counts, ok := s.events[eventName]
+	if !ok {
+		counts = &EventCounts{}
+		s.events[eventName] = counts
+	}
+	return counts
+}
+
+func (s *statsTracker) prune(now time.Time) {
+	i := 0
+	for i < len(s.samples) && now.Sub(s.samples[i].at) > throughputWindow {
+		i++
+	}
+	s.samples = s.samples[i:]
+}
+
+// throughput is transactions per second over the window, or since startup
+// when that is shorter. Must hold s.mu.
+func (s *statsTracker) throughput() float64 {
+	now := time.Now()
+	s.prune(now)
+
+	total := 0
+	for _, sample := range s.samples {
+		total += sample.count
+	}
+	window := min(now.Sub(s.startedAt), throughputWindow).Seconds()
+	if total == 0 || window <= 0 {
+		return 0
+	}
+	return float64(total) / window
+}
+
+// Stats returns per-event-type counts, throughput, uptime and webhook
+// delivery totals
+func (l *EventListener) Stats() Stats {
+	l.stats.mu.Lock()
+	stats := Stats{
+		StartedAt:             timeconv.Format(l.stats.startedAt),
+		UptimeSeconds:         int64(time.Since(l.stats.startedAt).Seconds()),
+		Events:                make(map[string]EventCounts, len(l.stats.events)),
+		Transactions:          l.stats.transactions,
+		TransactionsPerSecond: l.stats.throughput(),
+	}
+	for name, counts := range l.stats.events {
+		stats.Events[name] = *counts
+		stats.HandlerErrors += counts.Errors
+	}
+	l.stats.mu.Unlock()
+
+	stats.Webhooks.Targets = l.WebhookStats()
+	for _, target := range stats.Webhooks.Targets {
+		stats.Webhooks.Delivered += target.Delivered
+		stats.Webhooks.Failed += target.Failed
+		stats.Webhooks.Pending += target.Pending
+	}
+	return stats
+}
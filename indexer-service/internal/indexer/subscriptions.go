@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/verifi-protocol/indexer-service/internal/subscriptions"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Events that concern everyone with a stake in the market: wallet
+// subscriptions match the market's creator and traders
+var marketWideEvents = map[string]bool{
+	"MarketResolvedEvent":     true,
+	"ResolutionProposedEvent": true,
+	"DisputeRaisedEvent":      true,
+	"DisputeResolvedEvent":    true,
+}
+
+// matchSubscriptions queues the event for its subscriptions once the batch
+// commits. Resolutions and disputes look up the market's creator and
+// traders, but only when a subscription filters this event by wallet.
+func (l *EventListener) matchSubscriptions(ctx context.Context, q pgx.Tx, eventName string, event aptos.Event, tx aptos.TransactionEvent) error {
+	if l.subscriptions == nil {
+		return nil
+	}
+
+	e := subscriptions.Event{
+		Type:      eventName,
+		Wallets:   []string{tx.Sender},
+		TxHash:    tx.Hash,
+		Version:   tx.Version,
+		Timestamp: tx.Timestamp,
+		Data:      event.Data,
+	}
+	e.MarketAddress, _ = event.Data["market_address"].(string)
+	for _, field := range []string{"user", "creator", "provider", "proposer", "disputer"} {
+		if wallet, ok := event.Data[field].(string); ok {
+			e.Wallets = append(e.Wallets, wallet)
+		}
+	}
+
+	if marketWideEvents[eventName] && e.MarketAddress != "" && l.subscriptions.WantsWallets(eventName) {
+		rows, err := q.Query(ctx, `
+			SELECT "creator" FROM "Market" WHERE "marketAddress" = $1 AND "network" = $2 AND "creator" IS NOT NULL
+			UNION
+			SELECT "userAddress" FROM "Activity" WHERE "marketAddress" = $1 AND "network" = $2
+		`, e.MarketAddress, l.network)
+		if err != nil {
+			return fmt.Errorf("failed to look up market wallets: %w", err)
+		}
+		wallets, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to look up market wallets: %w", err)
+		}
+		e.Wallets = append(e.Wallets, wallets...)
+	}
+
+	matched := l.subscriptions.Match(e)
+	if len(matched) == 0 {
+		return nil
+	}
+	l.pending = append(l.pending, func() {
+		l.subscriptions.Dispatch(e, matched)
+	})
+	return nil
+}
@@ -0,0 +1,153 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/errreport"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+const (
+	// Restart backoff: doubles from supervisorMinBackoff up to
+	// supervisorMaxBackoff
+	supervisorMinBackoff = time.Second
+	supervisorMaxBackoff = time.Minute
+
+	// A run lasting this long resets the backoff
+	supervisorStableRun = 5 * time.Minute
+)
+
+// SupervisorStats is the listener's restart history, on GET /status
+type SupervisorStats struct {
+	Restarts    int64  `json:"restarts"`
+	LastError   string `json:"last_error,omitempty"`
+	LastErrorAt string `json:"last_error_at,omitempty"`
+	DownSince   string `json:"down_since,omitempty"` // set while Start isn't running
+	NextRetryAt string `json:"next_retry_at,omitempty"`
+}
+
+// supervisor tracks Supervise for the health checks and /status
+type supervisor struct {
+	mu          sync.Mutex
+	started     bool // Start has loaded the checkpoint at least once
+	restarts    int64
+	lastErr     error
+	lastErrAt   time.Time
+	downSince   time.Time
+	nextRetryAt time.Time
+}
+
+// Supervise runs Start until ctx is done, restarting it with backoff when it
+// returns an error or panics, e.g. when the fullnode is unreachable at
+// startup
+func (l *EventListener) Supervise(ctx context.Context) {
+	backoff := supervisorMinBackoff
+	for {
+		began := time.Now()
+		err := l.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("event listener returned")
+		}
+
+		if time.Since(began) > supervisorStableRun {
+			backoff = supervisorMinBackoff
+		}
+		l.supervisor.failed(err, backoff)
+
+		log.Error().
+			Err(err).
+			Str("network", l.network).
+			Dur("retry_in", backoff).
+			Msg("Event listener error, restarting")
+		errreport.Capture(err, errreport.Tags{"source": "listener", "network": l.network})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, supervisorMaxBackoff)
+
+		l.supervisor.mu.Lock()
+		l.supervisor.restarts++
+		l.supervisor.mu.Unlock()
+	}
+}
+
+// runOnce runs Start, turning a panic into an error
+func (l *EventListener) runOnce(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event listener panic: %v", r)
+		}
+	}()
+	return l.Start(ctx)
+}
+
+func (s *supervisor) failed(err error, retryIn time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastErr, s.lastErrAt = err, now
+	if s.downSince.IsZero() {
+		s.downSince = now
+	}
+	s.nextRetryAt = now.Add(retryIn)
+}
+
+// up records Start having loaded the checkpoint
+func (s *supervisor) up() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started = true
+	s.downSince, s.nextRetryAt = time.Time{}, time.Time{}
+}
+
+// DownFor returns how long the listener has been down after having run, or
+// after its first start failed. It is 0 while the listener runs or before
+// Start has been called.
+func (l *EventListener) DownFor() time.Duration {
+	if l.Running() {
+		return 0
+	}
+	l.supervisor.mu.Lock()
+	defer l.supervisor.mu.Unlock()
+	if l.supervisor.downSince.IsZero() {
+		return 0
+	}
+	return time.Since(l.supervisor.downSince)
+}
+
+// EverStarted reports whether Start has loaded the checkpoint at least once
+func (l *EventListener) EverStarted() bool {
+	l.supervisor.mu.Lock()
+	defer l.supervisor.mu.Unlock()
+	return l.supervisor.started
+}
+
+// SupervisorStats returns the listener's restart count and last failure
+func (l *EventListener) SupervisorStats() SupervisorStats {
+	l.supervisor.mu.Lock()
+	defer l.supervisor.mu.Unlock()
+
+	s := &l.supervisor
+	stats := SupervisorStats{Restarts: s.restarts}
+	if s.lastErr != nil {
+		stats.LastError = s.lastErr.Error()
+		stats.LastErrorAt = timeconv.Format(s.lastErrAt)
+	}
+	if !l.Running() && !s.downSince.IsZero() {
+		stats.DownSince = timeconv.Format(s.downSince)
+		stats.NextRetryAt = timeconv.Format(s.nextRetryAt)
+	}
+	return stats
+}
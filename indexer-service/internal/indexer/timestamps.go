@@ -0,0 +1,94 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Activity timestamps before this are placeholders written when the
+// fullnode's microsecond timestamps failed to parse
+const zeroTimestampCutoff = "1970-01-02"
+
+// txTime returns when tx was committed. Imported dumps may leave the
+// timestamp out, in which case it is read from the fullnode.
+func (l *EventListener) txTime(ctx context.Context, tx aptos.TransactionEvent) (time.Time, error) {
+	if tx.Timestamp == "" {
+		version, err := strconv.ParseUint(tx.Version, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid version %q: %w", tx.Version, err)
+		}
+		full, err := l.client.GetTransactionByVersion(ctx, version)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to look up timestamp: %w", err)
+		}
+		tx.Timestamp = full.Timestamp
+	}
+	return timeconv.Parse(tx.Timestamp)
+}
+
+// RepairResult summarizes a timestamp repair run
+type RepairResult struct {
+	Transactions int `json:"transactions"`
+	Repaired     int `json:"repaired"`
+	Failed       int `json:"failed"`
+}
+
+// RepairTimestamps fixes Activity rows that still carry the zero timestamp
+// after migration 010 by reading each transaction's time from the fullnode
+func RepairTimestamps(ctx context.Context, database *db.DB, client *aptos.Client) (*RepairResult, error) {
+	rows, err := database.Pool().Query(ctx, `
+		SELECT DISTINCT "txHash" FROM "Activity" WHERE "timestamp" < $1::timestamp
+	`, zeroTimestampCutoff)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &RepairResult{Transactions: len(hashes)}
+	log.Info().Int("transactions", len(hashes)).Msg("🕰️  Repairing zero Activity timestamps")
+
+	for _, hash := range hashes {
+		tx, err := client.GetTransactionByHash(ctx, hash)
+		if err != nil {
+			log.Warn().Err(err).Str("tx", hash).Msg("Failed to fetch transaction")
+			result.Failed++
+			continue
+		}
+		timestamp, err := timeconv.Parse(tx.Timestamp)
+		if err != nil {
+			log.Warn().Err(err).Str("tx", hash).Msg("Transaction has no usable timestamp")
+			result.Failed++
+			continue
+		}
+
+		tag, err := database.Pool().Exec(ctx, `
+			UPDATE "Activity" SET "timestamp" = $1
+			WHERE "txHash" = $2 AND "timestamp" < $3::timestamp
+		`, timestamp, hash, zeroTimestampCutoff)
+		if err != nil {
+			return result, fmt.Errorf("failed to update %s: %w", hash, err)
+		}
+		result.Repaired += int(tag.RowsAffected())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Mismatches listed in a verify report; the counts cover the rest
+const verifySampleLimit = 50
+
+// VerifyMismatch is one version where indexed_transactions and the chain
+// disagree
+type VerifyMismatch struct {
+	Kind        string `json:"kind"` // missing, unexpected or changed
+	Version     uint64 `json:"version"`
+	ChainHash   string `json:"chain_hash,omitempty"`
+	IndexedHash string `json:"indexed_hash,omitempty"`
+}
+
+// VerifyReport compares a version range of indexed_transactions with the
+// chain. Missing transactions emitted module events but were never indexed,
+// unexpected ones were indexed but emit none on chain, and changed ones were
+// indexed under a different hash.
+type VerifyReport struct {
+	FromVersion uint64           `json:"from_version"`
+	ToVersion   uint64           `json:"to_version"`
+	ChainTxs    int              `json:"chain_transactions"` // with module events
+	IndexedTxs  int              `json:"indexed_transactions"`
+	Missing     int              `json:"missing"`
+	Unexpected  int              `json:"unexpected"`
+	Changed     int              `json:"changed"`
+	Mismatches  []VerifyMismatch `json:"mismatches,omitempty"`
+	Passed      bool             `json:"passed"`
+}
+
+// Verify re-reads from..to from the fullnode and checks that exactly the
+// successful user transactions emitting moduleAddress events were indexed
+// for network, under their chain hash. Nothing is written.
+func Verify(ctx context.Context, client *aptos.Client, database *db.DB, network, moduleAddress string, from, to uint64) (VerifyReport, error) {
+	report := VerifyReport{FromVersion: from, ToVersion: to}
+	if from > to {
+		return report, fmt.Errorf("from_version must not be above to_version")
+	}
+
+	indexed, err := loadIndexedTxs(ctx, database, network, from, to)
+	if err != nil {
+		return report, fmt.Errorf("failed to load indexed transactions: %w", err)
+	}
+	report.IndexedTxs = len(indexed)
+
+	record := func(m VerifyMismatch) {
+		if len(report.Mismatches) < verifySampleLimit {
+			report.Mismatches = append(report.Mismatches, m)
+		}
+	}
+
+	for start := from; start <= to; {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		limit := min(uint64(replayBatchSize), to-start+1)
+		txs, err := client.GetTransactionsByVersionRange(ctx, start, limit)
+		if err != nil {
+			return report, fmt.Errorf("failed to fetch transactions from %d: %w", start, err)
+		}
+
+		for _, tx := range txs {
+			if !emitsModuleEvents(tx, moduleAddress) {
+				continue
+			}
+			version, err := strconv.ParseUint(tx.Version, 10, 64)
+			if err != nil {
+				return report, fmt.Errorf("invalid version %q: %w", tx.Version, err)
+			}
+			report.ChainTxs++
+
+			hash, ok := indexed[version]
+			delete(indexed, version)
+			switch {
+			case !ok:
+				report.Missing++
+				record(VerifyMismatch{Kind: "missing", Version: version, ChainHash: tx.Hash})
+			case hash != tx.Hash:
+				report.Changed++
+				record(VerifyMismatch{Kind: "changed", Version: version, ChainHash: tx.Hash, IndexedHash: hash})
+			}
+		}
+
+		log.Debug().Uint64("version", start+limit-1).Msg("🔎 Verified batch")
+		start += limit
+	}
+
+	// Whatever is left was indexed without module events on chain
+	leftover := make([]uint64, 0, len(indexed))
+	for version := range indexed {
+		leftover = append(leftover, version)
+	}
+	slices.Sort(leftover)
+	for _, version := range leftover {
+		report.Unexpected++
+		record(VerifyMismatch{Kind: "unexpected", Version: version, IndexedHash: indexed[version]})
+	}
+
+	report.Passed = report.Missing == 0 && report.Unexpected == 0 && report.Changed == 0
+	return report, nil
+}
+
+// emitsModuleEvents matches what processTx indexes: successful user
+// transactions with at least one event of the module
+func emitsModuleEvents(tx aptos.TransactionEvent, moduleAddress string) bool {
+	if !tx.Success || tx.Type != "user_transaction" {
+		return false
+	}
+	for _, event := range tx.Events {
+		if _, ok := aptos.MatchModule(event.Type, moduleAddress); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func loadIndexedTxs(ctx context.Context, database *db.DB, network string, from, to uint64) (map[uint64]string, error) {
+	rows, err := database.Pool().Query(ctx, `
+		SELECT version, tx_hash FROM indexed_transactions
+		WHERE network = $3 AND version BETWEEN $1 AND $2
+	`, int64(from), int64(to), network)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexed := make(map[uint64]string)
+	for rows.Next() {
+		var version int64
+		var hash string
+		if err := rows.Scan(&version, &hash); err != nil {
+			return nil, err
+		}
+		indexed[uint64(version)] = hash
+	}
+	return indexed, rows.Err()
+}
@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// errBackpressure cuts a poll short once the write queue stayed full past
+// the stall limit; the batches already queued are committed
+var errBackpressure = errors.New("write queue full")
+
+// Weight of the latest commit in the average commit time
+const commitLatencyAlpha = 0.2
+
+// WriteQueueStats is the state of the queue of fetched batches waiting for
+// their commit, on /status
+type WriteQueueStats struct {
+	Capacity      int     `json:"capacity"` // PIPELINE_DEPTH
+	Depth         int     `json:"depth"`    // batches fetched or queued, not yet committed
+	Full          bool    `json:"full"`
+	Stalls        int64   `json:"stalls"`         // fetches that waited on a full queue
+	StalledMs     int64   `json:"stalled_ms"`     // time those fetches waited
+	Backpressured int64   `json:"backpressured"`  // polls cut short after WRITE_QUEUE_STALL
+	LastCommitMs  int64   `json:"last_commit_ms"` // duration of the latest batch commit
+	AvgCommitMs   float64 `json:"avg_commit_ms"`  // moving average
+	LastStallAt   string  `json:"last_stall_at,omitempty"`
+}
+
+// writeQueueTracker counts what happens to the write queue. It is written by
+// the ingest goroutines and read by the HTTP server.
+type writeQueueTracker struct {
+	mu    sync.Mutex
+	stats WriteQueueStats
+	last  time.Time
+}
+
+// SetWriteQueueStall sets how long fetching waits on a full write queue
+// before the poll is cut short (0 waits as long as the commits take)
+func (l *EventListener) SetWriteQueueStall(limit time.Duration) {
+	l.writeQueueStall = limit
+}
+
+// WriteQueue returns the write queue stats
+func (l *EventListener) WriteQueue() WriteQueueStats {
+	l.writes.mu.Lock()
+	defer l.writes.mu.Unlock()
+
+	stats := l.writes.stats
+	stats.Full = stats.Capacity > 0 && stats.Depth >= stats.Capacity
+	if !l.writes.last.IsZero() {
+		stats.LastStallAt = timeconv.Format(l.writes.last)
+	}
+	return stats
+}
+
+func (w *writeQueueTracker) begin(capacity int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Capacity, w.stats.Depth = capacity, 0
+}
+
+// end empties the queue; slots still taken belong to batches an error or
+// pause dropped
+func (w *writeQueueTracker) end() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Depth = 0
+}
+
+func (w *writeQueueTracker) queued() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Depth++
+}
+
+func (w *writeQueueTracker) committed(took time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stats.Depth = max(w.stats.Depth-1, 0)
+	ms := took.Milliseconds()
+	w.stats.LastCommitMs = ms
+	if w.stats.AvgCommitMs == 0 {
+		w.stats.AvgCommitMs = float64(ms)
+	} else {
+		w.stats.AvgCommitMs += commitLatencyAlpha * (float64(ms) - w.stats.AvgCommitMs)
+	}
+}
+
+func (w *writeQueueTracker) stalled(waited time.Duration, gaveUp bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stats.Stalls++
+	w.stats.StalledMs += waited.Milliseconds()
+	w.last = time.Now()
+	if gaveUp {
+		w.stats.Backpressured++
+	}
+}
@@ -1,54 +1,272 @@
 package logbuffer
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 type LogEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Level     string    `json:"level"`
-	Message   string    `json:"message"`
+	Timestamp time.Time      `json:"timestamp"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// Query filters Find. Zero values match everything.
+type Query struct {
+	Level  string // minimum level, e.g. warn also returns error and fatal
+	Since  time.Time
+	Search string // case-insensitive, in the message and field values
+	Event  string // the "event" field, e.g. SharesMintedEvent
+	Limit  int    // most recent matches returned; 0 means all
+}
+
+// levels are the buffer's rings, least severe first. fatal and panic share
+// the error ring; lines without a known level go to info.
+var levels = []string{"trace", "debug", "info", "warn", "error"}
+
+// Config caps the buffer. An entry larger than EntryBytes keeps its message
+// but not its fields, so one raw event dump can't take the whole budget.
+type Config struct {
+	Entries    map[string]int // per level ring
+	Bytes      int            // across every level
+	EntryBytes int
+}
+
+// DefaultConfig keeps 500 entries per level, fewer for trace and debug,
+// in at most 4 MiB
+func DefaultConfig() Config {
+	return Config{
+		Entries:    map[string]int{"trace": 100, "debug": 200, "info": 500, "warn": 500, "error": 500},
+		Bytes:      4 << 20,
+		EntryBytes: 64 << 10,
+	}
+}
+
+// FromEnv is DefaultConfig with LOG_BUFFER_ENTRIES (a count for every level,
+// or level=count pairs, e.g. debug=50,info=1000) and LOG_BUFFER_BYTES
+// applied. Logging starts before the config is loaded, so values that don't
+// parse are ignored.
+func FromEnv() Config {
+	cfg := DefaultConfig()
+	if entries := os.Getenv("LOG_BUFFER_ENTRIES"); entries != "" {
+		if n, err := strconv.Atoi(entries); err == nil && n > 0 {
+			for _, level := range levels {
+				cfg.Entries[level] = n
+			}
+		}
+		for _, pair := range strings.Split(entries, ",") {
+			level, count, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if n, err := strconv.Atoi(count); ok && err == nil && n > 0 {
+				if _, known := cfg.Entries[level]; known {
+					cfg.Entries[level] = n
+				}
+			}
+		}
+	}
+	if bytes := os.Getenv("LOG_BUFFER_BYTES"); bytes != "" {
+		if n, err := strconv.Atoi(bytes); err == nil && n > 0 {
+			cfg.Bytes = n
+			cfg.EntryBytes = min(cfg.EntryBytes, n)
+		}
+	}
+	return cfg
+}
+
+// Dropped counts the entries the buffer let go of since startup
+type Dropped struct {
+	Total     int64            `json:"total"`
+	Evicted   int64            `json:"evicted"`    // pushed out by their level's entry cap
+	OverBytes int64            `json:"over_bytes"` // pushed out, or refused, by the byte cap
+	ByLevel   map[string]int64 `json:"by_level"`
+	Truncated int64            `json:"truncated"` // kept without their fields, not dropped
+}
+
+// Stats is the buffer's fill and what it dropped, on GET /logs
+type Stats struct {
+	Entries  int     `json:"entries"`
+	Bytes    int     `json:"bytes"`
+	MaxBytes int     `json:"max_bytes"`
+	Dropped  Dropped `json:"dropped"`
+}
+
+// item is an entry with its arrival order and approximate size
+type item struct {
+	entry LogEntry
+	seq   uint64
+	size  int
+}
+
+// ring is a fixed-size circular buffer, oldest entry at head
+type ring struct {
+	items []item
+	head  int
+	n     int
+}
+
+func (r *ring) full() bool { return r.n == len(r.items) }
+
+// at returns the i-th oldest item
+func (r *ring) at(i int) *item { return &r.items[(r.head+i)%len(r.items)] }
+
+func (r *ring) push(it item) {
+	*r.at(r.n) = it
+	r.n++
+}
+
+// pop removes the oldest item, releasing its entry
+func (r *ring) pop() item {
+	it := *r.at(0)
+	*r.at(0) = item{}
+	r.head = (r.head + 1) % len(r.items)
+	r.n--
+	return it
 }
 
 type Buffer struct {
-	entries []LogEntry
-	maxSize int
-	mu      sync.RWMutex
+	rings      map[string]*ring
+	bytes      int
+	maxBytes   int
+	entryBytes int
+	seq        uint64
+	dropped    Dropped
+	mu         sync.RWMutex
 }
 
 var globalBuffer *Buffer
 
-func Init(maxSize int) {
-	globalBuffer = &Buffer{
-		entries: make([]LogEntry, 0, maxSize),
-		maxSize: maxSize,
+func Init(cfg Config) {
+	b := &Buffer{
+		rings:      make(map[string]*ring, len(levels)),
+		maxBytes:   cfg.Bytes,
+		entryBytes: cfg.EntryBytes,
+		dropped:    Dropped{ByLevel: map[string]int64{}},
 	}
+	for _, level := range levels {
+		b.rings[level] = &ring{items: make([]item, max(cfg.Entries[level], 1))}
+	}
+	globalBuffer = b
 }
 
-func Add(level, message string) {
+// Add stores one zerolog JSON line, keeping its level, message and fields
+func Add(level string, line []byte) {
 	if globalBuffer == nil {
 		return
 	}
 
-	globalBuffer.mu.Lock()
-	defer globalBuffer.mu.Unlock()
+	entry := Parse(level, line)
+	globalBuffer.add(entry, len(line))
+}
+
+func (b *Buffer) add(entry LogEntry, size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	entry := LogEntry{
-		Timestamp: time.Now(),
-		Level:     level,
-		Message:   message,
+	if size > b.entryBytes {
+		entry.Fields = map[string]any{"truncated_bytes": size}
+		if len(entry.Message) > b.entryBytes/2 {
+			entry.Message = strings.ToValidUTF8(entry.Message[:b.entryBytes/2], "")
+		}
+		size = len(entry.Message) + 64
+		b.dropped.Truncated++
 	}
 
-	globalBuffer.entries = append(globalBuffer.entries, entry)
+	level := ringLevel(entry.Level)
+	r := b.rings[level]
+	if r.full() {
+		b.drop(r.pop(), level, &b.dropped.Evicted)
+	}
 
-	// Keep only last maxSize entries
-	if len(globalBuffer.entries) > globalBuffer.maxSize {
-		globalBuffer.entries = globalBuffer.entries[1:]
+	// Make room from the least severe levels; an entry never pushes out
+	// anything more severe than itself
+	for b.bytes+size > b.maxBytes {
+		victim := ""
+		for _, l := range levels {
+			if b.rings[l].n > 0 {
+				victim = l
+				break
+			}
+		}
+		if victim == "" || severity(victim) > severity(level) {
+			b.drop(item{size: 0}, level, &b.dropped.OverBytes)
+			return
+		}
+		b.drop(b.rings[victim].pop(), victim, &b.dropped.OverBytes)
 	}
+
+	b.seq++
+	r.push(item{entry: entry, seq: b.seq, size: size})
+	b.bytes += size
+}
+
+// drop accounts for an entry leaving the buffer (or never entering it)
+func (b *Buffer) drop(it item, level string, reason *int64) {
+	b.bytes -= it.size
+	b.dropped.Total++
+	b.dropped.ByLevel[level]++
+	*reason++
+}
+
+// ringLevel is the ring an entry's level belongs to
+func ringLevel(level string) string {
+	switch level {
+	case "trace", "debug", "info", "warn", "error":
+		return level
+	case "fatal", "panic":
+		return "error"
+	}
+	return "info"
+}
+
+func severity(level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+// Parse turns a zerolog JSON line into an entry. Lines that aren't JSON
+// become the message, with level as given.
+func Parse(level string, line []byte) LogEntry {
+	entry := LogEntry{Timestamp: time.Now(), Level: level}
+
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		entry.Message = strings.TrimSpace(string(line))
+		return entry
+	}
+
+	if l, ok := fields[zerolog.LevelFieldName].(string); ok {
+		entry.Level = l
+	}
+	if msg, ok := fields[zerolog.MessageFieldName].(string); ok {
+		entry.Message = msg
+	}
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+	return entry
 }
 
 func GetRecent(limit int) []LogEntry {
+	return Find(Query{Limit: limit})
+}
+
+// Find returns the most recent entries matching q, oldest first
+func Find(q Query) []LogEntry {
 	if globalBuffer == nil {
 		return []LogEntry{}
 	}
@@ -56,22 +274,86 @@ func GetRecent(limit int) []LogEntry {
 	globalBuffer.mu.RLock()
 	defer globalBuffer.mu.RUnlock()
 
-	if limit <= 0 || limit > len(globalBuffer.entries) {
-		limit = len(globalBuffer.entries)
+	q.Search = strings.ToLower(q.Search)
+	minLevel := zerolog.TraceLevel
+	if q.Level != "" {
+		minLevel, _ = zerolog.ParseLevel(q.Level)
 	}
 
-	// Return last 'limit' entries
-	start := len(globalBuffer.entries) - limit
-	if start < 0 {
-		start = 0
+	// Each ring is in time order; walk each back from its newest and merge
+	// by arrival, so Limit keeps the latest matches
+	var matched []*item
+	for _, level := range levels {
+		r := globalBuffer.rings[level]
+		kept := 0
+		for i := r.n - 1; i >= 0; i-- {
+			if q.Limit > 0 && kept == q.Limit {
+				break
+			}
+			it := r.at(i)
+			if !q.Since.IsZero() && it.entry.Timestamp.Before(q.Since) {
+				break
+			}
+			if q.matches(it.entry, minLevel) {
+				matched = append(matched, it)
+				kept++
+			}
+		}
 	}
 
-	result := make([]LogEntry, limit)
-	copy(result, globalBuffer.entries[start:])
+	sort.Slice(matched, func(i, j int) bool { return matched[i].seq < matched[j].seq })
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[len(matched)-q.Limit:]
+	}
 
+	result := make([]LogEntry, len(matched))
+	for i, it := range matched {
+		result[i] = it.entry
+	}
 	return result
 }
 
+func (q Query) matches(entry LogEntry, minLevel zerolog.Level) bool {
+	if q.Level != "" {
+		level, err := zerolog.ParseLevel(entry.Level)
+		if err != nil || level < minLevel {
+			return false
+		}
+	}
+	if q.Event != "" && fmt.Sprint(entry.Fields["event"]) != q.Event {
+		return false
+	}
+	if q.Search == "" || strings.Contains(strings.ToLower(entry.Message), q.Search) {
+		return true
+	}
+	for _, value := range entry.Fields {
+		if strings.Contains(strings.ToLower(fmt.Sprint(value)), q.Search) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStats returns the buffer's fill and dropped-entry counters
+func GetStats() Stats {
+	if globalBuffer == nil {
+		return Stats{Dropped: Dropped{ByLevel: map[string]int64{}}}
+	}
+
+	globalBuffer.mu.RLock()
+	defer globalBuffer.mu.RUnlock()
+
+	stats := Stats{Bytes: globalBuffer.bytes, MaxBytes: globalBuffer.maxBytes, Dropped: globalBuffer.dropped}
+	stats.Dropped.ByLevel = make(map[string]int64, len(globalBuffer.dropped.ByLevel))
+	for level, n := range globalBuffer.dropped.ByLevel {
+		stats.Dropped.ByLevel[level] = n
+	}
+	for _, r := range globalBuffer.rings {
+		stats.Entries += r.n
+	}
+	return stats
+}
+
 func Clear() {
 	if globalBuffer == nil {
 		return
@@ -80,5 +362,11 @@ func Clear() {
 	globalBuffer.mu.Lock()
 	defer globalBuffer.mu.Unlock()
 
-	globalBuffer.entries = make([]LogEntry, 0, globalBuffer.maxSize)
+	for _, r := range globalBuffer.rings {
+		for r.n > 0 {
+			r.pop()
+		}
+		r.head = 0
+	}
+	globalBuffer.bytes = 0
 }
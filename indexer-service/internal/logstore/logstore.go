@@ -0,0 +1,183 @@
+// Package logstore persists warn and above log entries to log_entries, so
+// handler errors and the lead-up to a crash are still there after a restart.
+// Entries are queued and written in batches off the logging path; fatal
+// entries are written before returning, since the process exits right after.
+// Until Start is called every function is a no-op.
+package logstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/internal/logbuffer"
+)
+
+const (
+	// Entries waiting to be written; more are dropped while the database is
+	// slow or down
+	queueSize = 1000
+
+	batchSize     = 100
+	flushInterval = 2 * time.Second
+	pruneInterval = time.Hour
+	writeTimeout  = 5 * time.Second
+)
+
+type store struct {
+	db        *db.DB
+	instance  string
+	retention time.Duration
+
+	queue  chan db.LogRecord
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	global *store
+	mu     sync.Mutex
+)
+
+// Start persists entries from now on, deleting those older than retention
+// (0 keeps everything)
+func Start(database *db.DB, instance string, retention time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &store{
+		db:        database,
+		instance:  instance,
+		retention: retention,
+		queue:     make(chan db.LogRecord, queueSize),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go s.run(ctx)
+
+	mu.Lock()
+	global = s
+	mu.Unlock()
+}
+
+// Stop writes what is still queued, waiting up to writeTimeout
+func Stop() {
+	mu.Lock()
+	s := global
+	global = nil
+	mu.Unlock()
+
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Add queues one zerolog JSON line if level is warn or above
+func Add(level zerolog.Level, line []byte) {
+	if level < zerolog.WarnLevel || level >= zerolog.NoLevel {
+		return
+	}
+
+	mu.Lock()
+	s := global
+	mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	entry := logbuffer.Parse(level.String(), line)
+	record := db.LogRecord{
+		LoggedAt: entry.Timestamp,
+		Level:    entry.Level,
+		Message:  entry.Message,
+		Fields:   entry.Fields,
+		Instance: s.instance,
+	}
+
+	if level >= zerolog.FatalLevel {
+		s.write([]db.LogRecord{record})
+		return
+	}
+
+	select {
+	case s.queue <- record:
+	default:
+		// Logging here at warn would come straight back; debug doesn't
+		log.Debug().Msg("Log entry queue full, dropping entry")
+	}
+}
+
+func (s *store) run(ctx context.Context) {
+	defer close(s.done)
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	s.prune()
+
+	batch := make([]db.LogRecord, 0, batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case record := <-s.queue:
+					batch = append(batch, record)
+				default:
+					s.write(batch)
+					return
+				}
+			}
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-flush.C:
+		case <-prune.C:
+			s.prune()
+			continue
+		}
+
+		s.write(batch)
+		batch = batch[:0]
+	}
+}
+
+// write never logs above debug, so a database outage doesn't feed itself
+func (s *store) write(records []db.LogRecord) {
+	if len(records) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := s.db.InsertLogEntries(ctx, records); err != nil {
+		log.Debug().Err(err).Int("entries", len(records)).Msg("Failed to persist log entries")
+	}
+}
+
+func (s *store) prune() {
+	if s.retention <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	deleted, err := s.db.PruneLogEntries(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to prune log entries")
+		return
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Dur("retention", s.retention).Msg("🧹 Pruned persisted log entries")
+	}
+}
@@ -0,0 +1,241 @@
+// Package notify posts human-readable messages about protocol events to chat
+// channels: Discord through a channel webhook and Telegram through a bot.
+// Each channel subscribes to its own event types. Messages are sent in the
+// background so a slow chat API never holds up indexing.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Event types a channel can subscribe to
+const (
+	MarketCreated  = "MarketCreatedEvent"
+	MarketResolved = "MarketResolvedEvent"
+)
+
+// DefaultEvents is what a channel gets when no event list is configured
+var DefaultEvents = []string{MarketCreated, MarketResolved}
+
+// Messages waiting to be sent; more are dropped
+const queueSize = 100
+
+// Config configures the channels. A channel is enabled by its URL or token.
+type Config struct {
+	DiscordWebhookURL string
+	DiscordEvents     []string
+	TelegramBotToken  string
+	TelegramChatID    string
+	TelegramEvents    []string
+	MarketURL         string        // link template, {address} is replaced
+	MaxAge            time.Duration // older events (e.g. during catch-up) aren't announced; 0 = no limit
+}
+
+// Market is what a message says about a market. Description is looked up
+// for resolved markets, and may be empty.
+type Market struct {
+	Address             string
+	Description         string
+	Creator             string
+	ResolutionTimestamp time.Time
+	Outcome             string
+}
+
+type message struct {
+	event  string
+	market Market
+}
+
+type channel interface {
+	name() string
+	send(ctx context.Context, client *http.Client, title string, m Market, link string) error
+}
+
+type Notifier struct {
+	cfg      Config
+	channels map[string][]channel // by event type
+	client   *http.Client
+	queue    chan message
+}
+
+// New returns a notifier for cfg, or nil when no channel is configured
+func New(cfg Config) *Notifier {
+	n := &Notifier{
+		cfg:      cfg,
+		channels: make(map[string][]channel),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan message, queueSize),
+	}
+	if cfg.DiscordWebhookURL != "" {
+		n.subscribe(discord{url: cfg.DiscordWebhookURL}, cfg.DiscordEvents)
+	}
+	if cfg.TelegramBotToken != "" && cfg.TelegramChatID != "" {
+		n.subscribe(telegram{token: cfg.TelegramBotToken, chatID: cfg.TelegramChatID}, cfg.TelegramEvents)
+	}
+	if len(n.channels) == 0 {
+		return nil
+	}
+	return n
+}
+
+func (n *Notifier) subscribe(c channel, events []string) {
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	for _, event := range events {
+		n.channels[event] = append(n.channels[event], c)
+	}
+	log.Info().Str("channel", c.name()).Strs("events", events).Msg("💬 Chat notifications enabled")
+}
+
+// Wants reports whether any channel subscribes to event. A nil notifier
+// wants nothing.
+func (n *Notifier) Wants(event string) bool {
+	return n != nil && len(n.channels[event]) > 0
+}
+
+// Notify queues a message about m for the channels subscribed to event.
+// Events older than MaxAge are skipped, so a catch-up doesn't replay
+// history into the channels.
+func (n *Notifier) Notify(event string, m Market, at time.Time) {
+	if !n.Wants(event) {
+		return
+	}
+	if n.cfg.MaxAge > 0 && !at.IsZero() && time.Since(at) > n.cfg.MaxAge {
+		log.Debug().Str("event", event).Str("market", m.Address).Msg("Event too old to announce")
+		return
+	}
+
+	select {
+	case n.queue <- message{event: event, market: m}:
+	default:
+		log.Warn().Str("event", event).Msg("Notification queue full, dropping message")
+	}
+}
+
+// Run sends queued messages until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-n.queue:
+			title, link := n.title(msg), n.link(msg.market.Address)
+			for _, c := range n.channels[msg.event] {
+				if err := c.send(ctx, n.client, title, msg.market, link); err != nil {
+					log.Warn().Err(err).Str("channel", c.name()).Str("event", msg.event).Msg("Chat notification failed")
+				}
+			}
+		}
+	}
+}
+
+func (n *Notifier) title(msg message) string {
+	if msg.event == MarketResolved {
+		return fmt.Sprintf("🏁 Market resolved: %s", msg.market.Outcome)
+	}
+	return "🆕 New market"
+}
+
+func (n *Notifier) link(address string) string {
+	if n.cfg.MarketURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(n.cfg.MarketURL, "{address}", address)
+}
+
+// lines are the message body shared by both channels
+func lines(m Market) []string {
+	var out []string
+	if m.Description != "" {
+		out = append(out, m.Description)
+	}
+	if !m.ResolutionTimestamp.IsZero() {
+		out = append(out, "Resolves: "+m.ResolutionTimestamp.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	out = append(out, "Market: "+m.Address)
+	return out
+}
+
+type discord struct {
+	url string
+}
+
+func (discord) name() string { return "discord" }
+
+func (d discord) send(ctx context.Context, client *http.Client, title string, m Market, link string) error {
+	embed := map[string]interface{}{
+		"title":       title,
+		"description": strings.Join(lines(m), "\n"),
+	}
+	if link != "" {
+		embed["url"] = link
+	}
+	return post(ctx, client, d.url, map[string]interface{}{"embeds": []interface{}{embed}})
+}
+
+type telegram struct {
+	token  string
+	chatID string
+}
+
+func (telegram) name() string { return "telegram" }
+
+func (t telegram) send(ctx context.Context, client *http.Client, title string, m Market, link string) error {
+	text := []string{"<b>" + html.EscapeString(title) + "</b>"}
+	for _, line := range lines(m) {
+		text = append(text, html.EscapeString(line))
+	}
+	if link != "" {
+		text = append(text, fmt.Sprintf(`<a href="%s">View market</a>`, html.EscapeString(link)))
+	}
+
+	return post(ctx, client, "https://api.telegram.org/bot"+t.token+"/sendMessage", map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     strings.Join(text, "\n"),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+}
+
+func post(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The Telegram URL carries the bot token, keep it out of the logs
+		return fmt.Errorf("request failed: %w", redact(err, url))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// redact replaces url in err's message, which *url.Error includes
+func redact(err error, url string) error {
+	if i := strings.Index(url, "/bot"); i >= 0 {
+		return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), url, url[:i]+"/bot<token>/sendMessage"))
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+// Package runtimestats backs GET /debug/runtime: goroutine count, heap and
+// GC figures from the Go runtime, enough to spot memory growth in a deployed
+// binary before reaching for a pprof profile.
+package runtimestats
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Most recent GC pauses reported, newest first
+const recentPauses = 16
+
+var started = time.Now()
+
+type Heap struct {
+	AllocBytes    uint64 `json:"alloc_bytes"`    // live objects
+	InuseBytes    uint64 `json:"inuse_bytes"`    // spans in use
+	IdleBytes     uint64 `json:"idle_bytes"`     // spans not in use
+	ReleasedBytes uint64 `json:"released_bytes"` // returned to the OS
+	SysBytes      uint64 `json:"sys_bytes"`      // heap memory obtained from the OS
+	Objects       uint64 `json:"objects"`
+}
+
+type GC struct {
+	Count          uint32    `json:"count"`
+	Forced         uint32    `json:"forced"`
+	NextBytes      uint64    `json:"next_bytes"` // heap size that triggers the next GC
+	Last           string    `json:"last,omitempty"`
+	PauseTotalMs   float64   `json:"pause_total_ms"`
+	RecentPausesMs []float64 `json:"recent_pauses_ms"`
+	CPUFraction    float64   `json:"cpu_fraction"`
+}
+
+type Stats struct {
+	GoVersion     string `json:"go_version"`
+	Uptime        string `json:"uptime"`
+	NumCPU        int    `json:"num_cpu"`
+	GOMAXPROCS    int    `json:"gomaxprocs"`
+	Goroutines    int    `json:"goroutines"`
+	TotalSysBytes uint64 `json:"total_sys_bytes"` // all memory obtained from the OS
+	Heap          Heap   `json:"heap"`
+	GC            GC     `json:"gc"`
+}
+
+// Read collects the current stats. ReadMemStats briefly stops the world, so
+// this is for on-demand requests, not tight loops.
+func Read() Stats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	stats := Stats{
+		GoVersion:     runtime.Version(),
+		Uptime:        time.Since(started).Round(time.Second).String(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Goroutines:    runtime.NumGoroutine(),
+		TotalSysBytes: m.Sys,
+		Heap: Heap{
+			AllocBytes:    m.HeapAlloc,
+			InuseBytes:    m.HeapInuse,
+			IdleBytes:     m.HeapIdle,
+			ReleasedBytes: m.HeapReleased,
+			SysBytes:      m.HeapSys,
+			Objects:       m.HeapObjects,
+		},
+		GC: GC{
+			Count:          m.NumGC,
+			Forced:         m.NumForcedGC,
+			NextBytes:      m.NextGC,
+			PauseTotalMs:   ms(m.PauseTotalNs),
+			RecentPausesMs: []float64{},
+			CPUFraction:    m.GCCPUFraction,
+		},
+	}
+	if m.LastGC > 0 {
+		stats.GC.Last = timeconv.Format(time.Unix(0, int64(m.LastGC)))
+	}
+
+	// PauseNs is a circular buffer with the latest pause at (NumGC+255)%256
+	for i := uint32(0); i < min(m.NumGC, recentPauses); i++ {
+		pause := m.PauseNs[(m.NumGC-1-i)%uint32(len(m.PauseNs))]
+		stats.GC.RecentPausesMs = append(stats.GC.RecentPausesMs, ms(pause))
+	}
+	return stats
+}
+
+func ms(ns uint64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
@@ -0,0 +1,208 @@
+// Package selftest verifies that the indexer's dependencies are reachable and
+// correctly configured. It backs the --selftest flag and POST /admin/selftest.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/verifi-protocol/indexer-service/internal/config"
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/indexer-service/migrations"
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Tables the indexer reads or writes
+var requiredTables = []string{"sync_state", "schema_migrations", "Activity", "Market"}
+
+type Check struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Passed bool      `json:"passed"`
+	RanAt  time.Time `json:"ran_at"`
+	Checks []Check   `json:"checks"`
+}
+
+// Runner holds the dependencies under test. DB may be nil when the
+// connection itself failed; the database checks then report that error.
+type Runner struct {
+	Config *config.Config
+	DB     *db.DB
+	DBErr  error
+	Client *aptos.Client
+}
+
+// Run executes every check and returns the report. A report passes when no
+// check failed; skipped checks don't count against it.
+func (r *Runner) Run(ctx context.Context) Report {
+	report := Report{Passed: true, RanAt: time.Now().UTC()}
+
+	checks := []struct {
+		name string
+		fn   func(ctx context.Context) (string, string)
+	}{
+		{"database", r.checkDatabase},
+		{"schema", r.checkSchema},
+		{"fullnode", r.checkFullnode},
+		{"module", r.checkModule},
+		{"webhook", r.checkWebhook},
+		{"aptos_api_keys", r.checkAPIKeys},
+	}
+
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		started := time.Now()
+		status, detail := c.fn(checkCtx)
+		cancel()
+
+		if status == StatusFail {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, Check{
+			Name:       c.name,
+			Status:     status,
+			Detail:     detail,
+			DurationMs: time.Since(started).Milliseconds(),
+		})
+	}
+
+	return report
+}
+
+func (r *Runner) checkDatabase(ctx context.Context) (string, string) {
+	if r.DB == nil {
+		return StatusFail, fmt.Sprintf("not connected: %v", r.DBErr)
+	}
+	if err := r.DB.Pool().Ping(ctx); err != nil {
+		return StatusFail, err.Error()
+	}
+	return StatusPass, ""
+}
+
+func (r *Runner) checkSchema(ctx context.Context) (string, string) {
+	if r.DB == nil {
+		return StatusSkip, "database unavailable"
+	}
+
+	var missing []string
+	for _, table := range requiredTables {
+		var exists bool
+		err := r.DB.Pool().QueryRow(ctx,
+			`SELECT to_regclass($1) IS NOT NULL`, fmt.Sprintf(`"%s"`, table),
+		).Scan(&exists)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+
+	if len(missing) > 0 {
+		return StatusFail, fmt.Sprintf("missing tables: %v", missing)
+	}
+
+	version, _ := r.DB.SchemaVersion(ctx, migrations.Service)
+	return StatusPass, "schema " + version
+}
+
+func (r *Runner) checkFullnode(ctx context.Context) (string, string) {
+	version, err := r.Client.GetLatestLedgerInfo(ctx)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	if version == 0 {
+		return StatusFail, "ledger version is 0"
+	}
+	return StatusPass, fmt.Sprintf("%s at version %d", r.Config.AptosNetwork, version)
+}
+
+func (r *Runner) checkModule(ctx context.Context) (string, string) {
+	modules, err := r.Client.GetAccountModules(ctx, r.Config.ModuleAddress)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	if len(modules) == 0 {
+		return StatusFail, fmt.Sprintf("no modules published at %s on %s", r.Config.ModuleAddress, r.Config.AptosNetwork)
+	}
+	for _, required := range r.Config.RequiredModules {
+		if !slices.Contains(modules, required) {
+			return StatusFail, fmt.Sprintf("module %s not published at %s on %s, found %v", required, r.Config.ModuleAddress, r.Config.AptosNetwork, modules)
+		}
+	}
+	if info, err := r.Client.GetLedgerInfo(ctx); err == nil {
+		if expected, ok := aptos.NetworkChainID(r.Config.AptosNetwork); ok && info.ChainID != expected {
+			return StatusFail, fmt.Sprintf("fullnode serves chain %d, but %s is chain %d", info.ChainID, r.Config.AptosNetwork, expected)
+		}
+	}
+	return StatusPass, fmt.Sprintf("modules: %v", modules)
+}
+
+func (r *Runner) checkWebhook(ctx context.Context) (string, string) {
+	if r.Config.WebhookURL == "" {
+		return StatusSkip, "WEBHOOK_URL not set"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", r.Config.WebhookURL, nil)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return StatusFail, err.Error()
+	}
+	resp.Body.Close()
+
+	// Any HTTP answer below 500 means the target is up, even if it only
+	// accepts POST
+	if resp.StatusCode >= 500 {
+		return StatusFail, fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return StatusPass, fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+func (r *Runner) checkAPIKeys(ctx context.Context) (string, string) {
+	if len(r.Config.AptosAPIKeys) == 0 {
+		return StatusSkip, "APTOS_API_KEYS not set"
+	}
+
+	var invalid []int
+	for i, key := range r.Config.AptosAPIKeys {
+		req, err := http.NewRequestWithContext(ctx, "GET", r.Client.RPCURL(), nil)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return StatusFail, err.Error()
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			invalid = append(invalid, i)
+		}
+	}
+
+	if len(invalid) > 0 {
+		// Report positions only, never the keys themselves
+		return StatusFail, fmt.Sprintf("rejected keys at positions %v", invalid)
+	}
+	return StatusPass, fmt.Sprintf("%d keys accepted", len(r.Config.AptosAPIKeys))
+}
@@ -0,0 +1,306 @@
+// Package subscriptions delivers processed module events to user-registered
+// subscriptions: a callback URL gets the event as JSON, a Telegram chat gets
+// a short message from the bot. Subscriptions filter by market, wallet and
+// event type. They are cached in memory, refreshed from the database so
+// every replica sees the ones registered elsewhere.
+package subscriptions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/indexer-service/internal/db"
+	"github.com/verifi-protocol/pkg/senders"
+)
+
+// EventTypes are the events a subscription can filter on
+var EventTypes = []string{"SharesMintedEvent", "SharesBurnedEvent", "MarketCreatedEvent", "MarketResolvedEvent"}
+
+const (
+	refreshInterval = 30 * time.Second
+
+	// Deliveries waiting to be sent; more are dropped
+	queueSize = 1000
+)
+
+// Event is a processed module event as subscriptions see it
+type Event struct {
+	Type          string                 `json:"event"`
+	MarketAddress string                 `json:"market_address,omitempty"`
+	Wallets       []string               `json:"-"` // sender, trader, creator or, on resolution, the market's creator and traders
+	TxHash        string                 `json:"tx_hash"`
+	Version       string                 `json:"version"`
+	Timestamp     string                 `json:"timestamp"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+type delivery struct {
+	sub   db.Subscription
+	event Event
+}
+
+type Registry struct {
+	db            *db.DB
+	telegramToken string
+	client        *http.Client
+	queue         chan delivery
+
+	subs []db.Subscription
+	mu   sync.RWMutex
+}
+
+// New returns a registry for database. Telegram subscriptions are accepted
+// when telegramToken is set.
+func New(database *db.DB, telegramToken string) *Registry {
+	return &Registry{
+		db:            database,
+		telegramToken: telegramToken,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		queue:         make(chan delivery, queueSize),
+	}
+}
+
+// Load replaces the cached subscriptions with the stored ones
+func (r *Registry) Load(ctx context.Context) error {
+	subs, err := r.db.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.subs = subs
+	r.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the cache and sends queued deliveries until ctx is cancelled
+func (r *Registry) Run(ctx context.Context) {
+	refresh := time.NewTicker(refreshInterval)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refresh.C:
+			if err := r.Load(ctx); err != nil {
+				log.Warn().Err(err).Msg("Failed to refresh subscriptions")
+			}
+		case d := <-r.queue:
+			if err := r.send(ctx, d); err != nil {
+				log.Warn().Err(err).Int64("subscription", d.sub.ID).Str("event", d.event.Type).Msg("Subscription delivery failed")
+			}
+		}
+	}
+}
+
+// Validate normalizes s and checks it has a usable destination and filters
+func (r *Registry) Validate(s *db.Subscription) error {
+	s.CallbackURL = strings.TrimSpace(s.CallbackURL)
+	s.TelegramChatID = strings.TrimSpace(s.TelegramChatID)
+	s.MarketAddress = senders.Normalize(s.MarketAddress)
+	s.WalletAddress = senders.Normalize(s.WalletAddress)
+
+	if s.CallbackURL == "" && s.TelegramChatID == "" {
+		return fmt.Errorf("callback_url or telegram_chat_id is required")
+	}
+	if s.CallbackURL != "" {
+		u, err := url.Parse(s.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("callback_url must be an http or https URL")
+		}
+	}
+	if s.TelegramChatID != "" && r.telegramToken == "" {
+		return fmt.Errorf("telegram subscriptions need NOTIFY_TELEGRAM_BOT_TOKEN on the indexer")
+	}
+	for _, eventType := range s.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return fmt.Errorf("event_types must be among %s", strings.Join(EventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// Create validates and stores s. Invalid subscriptions are reported as
+// *InvalidError.
+func (r *Registry) Create(ctx context.Context, s *db.Subscription) error {
+	if err := r.Validate(s); err != nil {
+		return &InvalidError{err}
+	}
+	if err := r.db.CreateSubscription(ctx, s); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.subs = append(r.subs, *s)
+	r.mu.Unlock()
+	return nil
+}
+
+// Delete removes the subscription, reporting whether it existed
+func (r *Registry) Delete(ctx context.Context, id int64) (bool, error) {
+	deleted, err := r.db.DeleteSubscription(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.subs = slices.DeleteFunc(r.subs, func(s db.Subscription) bool { return s.ID == id })
+	r.mu.Unlock()
+	return deleted, nil
+}
+
+// List returns the cached subscriptions, only those for wallet when set
+func (r *Registry) List(wallet string) []db.Subscription {
+	wallet = senders.Normalize(wallet)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := []db.Subscription{}
+	for _, s := range r.subs {
+		if wallet == "" || s.WalletAddress == wallet {
+			subs = append(subs, s)
+		}
+	}
+	return subs
+}
+
+// WantsWallets reports whether a subscription to eventType filters by
+// wallet, i.e. whether the event's wallets are worth looking up. A nil
+// registry wants nothing.
+func (r *Registry) WantsWallets(eventType string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.subs {
+		if s.WalletAddress != "" && (len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the subscriptions e should be delivered to
+func (r *Registry) Match(e Event) []db.Subscription {
+	if r == nil {
+		return nil
+	}
+
+	market := senders.Normalize(e.MarketAddress)
+	wallets := make([]string, len(e.Wallets))
+	for i, wallet := range e.Wallets {
+		wallets[i] = senders.Normalize(wallet)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []db.Subscription
+	for _, s := range r.subs {
+		if len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, e.Type) {
+			continue
+		}
+		if s.MarketAddress != "" && s.MarketAddress != market {
+			continue
+		}
+		if s.WalletAddress != "" && !slices.Contains(wallets, s.WalletAddress) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	return matched
+}
+
+// Dispatch queues e for each of subs
+func (r *Registry) Dispatch(e Event, subs []db.Subscription) {
+	for _, s := range subs {
+		select {
+		case r.queue <- delivery{sub: s, event: e}:
+		default:
+			log.Warn().Int64("subscription", s.ID).Str("event", e.Type).Msg("Subscription queue full, dropping delivery")
+		}
+	}
+}
+
+func (r *Registry) send(ctx context.Context, d delivery) error {
+	if d.sub.CallbackURL != "" {
+		payload := struct {
+			SubscriptionID int64 `json:"subscription_id"`
+			Event
+		}{d.sub.ID, d.event}
+		if err := r.post(ctx, d.sub.CallbackURL, payload); err != nil {
+			return fmt.Errorf("callback: %w", err)
+		}
+	}
+	if d.sub.TelegramChatID != "" {
+		if err := r.post(ctx, "https://api.telegram.org/bot"+r.telegramToken+"/sendMessage", map[string]interface{}{
+			"chat_id":                  d.sub.TelegramChatID,
+			"text":                     message(d.event),
+			"disable_web_page_preview": true,
+		}); err != nil {
+			// The bot URL carries the token, keep it out of the logs
+			return fmt.Errorf("telegram: %s", strings.ReplaceAll(err.Error(), r.telegramToken, "<token>"))
+		}
+	}
+	return nil
+}
+
+// message is the plain-text Telegram message for e
+func message(e Event) string {
+	lines := []string{e.Type}
+	if outcome, ok := e.Data["outcome"]; ok && e.Type == "MarketResolvedEvent" {
+		lines[0] = fmt.Sprintf("Market resolved: %v", outcome)
+	}
+	if e.MarketAddress != "" {
+		lines = append(lines, "Market: "+e.MarketAddress)
+	}
+	lines = append(lines, "Tx: "+e.TxHash)
+	return strings.Join(lines, "\n")
+}
+
+func (r *Registry) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// InvalidError reports a subscription rejected by Validate
+type InvalidError struct {
+	err error
+}
+
+func (e *InvalidError) Error() string { return e.err.Error() }
+func (e *InvalidError) Unwrap() error { return e.err }
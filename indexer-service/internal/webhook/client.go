@@ -5,14 +5,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
 )
 
 type WebhookClient struct {
 	URL    string
 	Client *http.Client
+
+	format  atomic.Pointer[Formatter] // nil sends WebhookPayload as is
+	pending atomic.Int64              // deliveries started but not finished
+
+	// Delivery outcomes, for diagnostics
+	delivered     int64
+	failed        int64
+	lastError     string
+	lastFailureAt time.Time
+	mu            sync.Mutex
+}
+
+// DeliveryStats summarizes the outcomes of one target's deliveries
+type DeliveryStats struct {
+	URL           string     `json:"url"`
+	Delivered     int64      `json:"delivered"`
+	Failed        int64      `json:"failed"`
+	Pending       int        `json:"pending"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
 }
 
 type WebhookPayload struct {
@@ -29,6 +53,27 @@ type TransactionData struct {
 	Hash      string `json:"hash"`
 	Sender    string `json:"sender"`
 	Timestamp string `json:"timestamp"`
+	Network   string `json:"network,omitempty"`
+}
+
+// Attempt is one delivery attempt, as reported to the observer
+type Attempt struct {
+	Target     string
+	EventType  string // the event type, or "digest"
+	Payload    []byte
+	Attempt    int // 1 for the first try; outbox retries and redeliveries count up
+	StatusCode int // 0 when no response was received
+	Error      string
+	Latency    time.Duration
+	At         time.Time
+}
+
+var observer atomic.Pointer[func(Attempt)]
+
+// SetObserver has every delivery attempt, by any client, reported to fn.
+// fn must not block.
+func SetObserver(fn func(Attempt)) {
+	observer.Store(&fn)
 }
 
 func NewWebhookClient(url string) *WebhookClient {
@@ -40,8 +85,27 @@ func NewWebhookClient(url string) *WebhookClient {
 	}
 }
 
-func (w *WebhookClient) SendEvent(eventType string, eventData map[string]interface{}, txHash string, sender string) error {
-	payload := WebhookPayload{
+// SetFormat selects how event payloads are rendered for this target.
+// Digests are always sent as DigestPayload.
+func (w *WebhookClient) SetFormat(f Formatter) {
+	if f == nil {
+		f = defaultFormat{}
+	}
+	w.format.Store(&f)
+}
+
+// Format is the target's payload format
+func (w *WebhookClient) Format() Formatter {
+	if f := w.format.Load(); f != nil {
+		return *f
+	}
+	return defaultFormat{}
+}
+
+// NewEventPayload builds the per-event webhook body, stamped with the
+// current time
+func NewEventPayload(eventType string, eventData map[string]interface{}, txHash string, sender string) WebhookPayload {
+	return WebhookPayload{
 		Event: EventData{
 			Type: eventType,
 			Data: eventData,
@@ -49,38 +113,206 @@ func (w *WebhookClient) SendEvent(eventType string, eventData map[string]interfa
 		Transaction: TransactionData{
 			Hash:      txHash,
 			Sender:    sender,
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Timestamp: timeconv.Now(),
 		},
 	}
+}
+
+func (w *WebhookClient) SendEvent(eventType string, eventData map[string]interface{}, txHash string, sender string) error {
+	return w.Send(NewEventPayload(eventType, eventData, txHash, sender))
+}
+
+// Send posts an event payload in the target's format
+func (w *WebhookClient) Send(payload WebhookPayload) error {
+	log.Info().
+		Str("url", w.URL).
+		Str("event", payload.Event.Type).
+		Msg("🔔 Sending webhook")
+
+	return w.post(payload, 1)
+}
+
+// Deliver posts a stored WebhookPayload in the target's format, reporting
+// a failed delivery as an error so the caller can retry it. attempt counts
+// from 1.
+func (w *WebhookClient) Deliver(payload json.RawMessage, attempt int) error {
+	return w.post(payload, attempt)
+}
+
+// Resend posts a body exactly as it was sent before, e.g. one read back from
+// the delivery log
+func (w *WebhookClient) Resend(body []byte, eventType string, attempt int) error {
+	return w.send(body, w.Format().ContentType(), eventType, attempt)
+}
+
+// SendDigest delivers a per-market trade digest
+func (w *WebhookClient) SendDigest(digest DigestPayload) error {
+	log.Info().
+		Str("url", w.URL).
+		Int("markets", len(digest.Markets)).
+		Msg("🔔 Sending digest")
+
+	return w.post(digest, 1)
+}
+
+// Pending returns how many deliveries are currently in flight
+func (w *WebhookClient) Pending() int {
+	return int(w.pending.Load())
+}
+
+// Stats returns the delivery outcomes so far
+func (w *WebhookClient) Stats() DeliveryStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := DeliveryStats{
+		URL:       w.URL,
+		Delivered: w.delivered,
+		Failed:    w.failed,
+		Pending:   w.Pending(),
+		LastError: w.lastError,
+	}
+	if !w.lastFailureAt.IsZero() {
+		at := w.lastFailureAt
+		stats.LastFailureAt = &at
+	}
+	return stats
+}
+
+func (w *WebhookClient) record(failure string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if failure == "" {
+		w.delivered++
+		return
+	}
+	w.failed++
+	w.lastError = failure
+	w.lastFailureAt = time.Now()
+}
 
-	jsonData, err := json.Marshal(payload)
+// post renders payload and delivers it
+func (w *WebhookClient) post(payload interface{}, attempt int) error {
+	body, contentType, eventType, err := w.encode(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		return err
 	}
+	return w.send(body, contentType, eventType, attempt)
+}
+
+// encode renders payload for the wire. Event payloads, including stored
+// ones, go through the target's format; a stored payload in the default
+// format is sent byte for byte.
+func (w *WebhookClient) encode(payload interface{}) ([]byte, string, string, error) {
+	format := w.Format()
+
+	switch p := payload.(type) {
+	case WebhookPayload:
+		body, err := format.Format(p)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to format webhook payload: %w", err)
+		}
+		return body, format.ContentType(), p.Event.Type, nil
+
+	case json.RawMessage:
+		if _, ok := format.(defaultFormat); ok {
+			return p, format.ContentType(), payloadType(p), nil
+		}
+		var event WebhookPayload
+		if err := json.Unmarshal(p, &event); err != nil {
+			return nil, "", "", fmt.Errorf("failed to decode stored webhook payload: %w", err)
+		}
+		return w.encode(event)
+
+	case DigestPayload:
+		body, err := json.Marshal(p)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		return body, "application/json", "digest", nil
+
+	default:
+		return nil, "", "", fmt.Errorf("unsupported webhook payload %T", payload)
+	}
+}
 
-	log.Printf("🔔 Sending webhook to %s for event %s", w.URL, eventType)
+// send delivers body and records the outcome; failures are also returned
+func (w *WebhookClient) send(jsonData []byte, contentType, eventType string, attempt int) error {
+	w.pending.Add(1)
+	defer w.pending.Add(-1)
 
 	req, err := http.NewRequest("POST", w.URL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create webhook request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
+
+	audit := Attempt{
+		Target:    w.URL,
+		EventType: eventType,
+		Payload:   jsonData,
+		Attempt:   attempt,
+		At:        time.Now(),
+	}
+	defer func() {
+		if fn := observer.Load(); fn != nil {
+			audit.Latency = time.Since(audit.At)
+			(*fn)(audit)
+		}
+	}()
 
 	resp, err := w.Client.Do(req)
 	if err != nil {
-		log.Printf("⚠️  Webhook request failed (non-critical): %v", err)
-		return nil
+		log.Warn().
+			Err(err).
+			Str("url", w.URL).
+			Str("event", eventType).
+			Int("attempt", attempt).
+			Msg("⚠️  Webhook request failed (non-critical)")
+		w.record(err.Error())
+		audit.Error = err.Error()
+		return err
 	}
 	defer resp.Body.Close()
+	audit.StatusCode = resp.StatusCode
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		log.Printf("✅ Webhook delivered successfully: %s", string(body))
+		log.Info().
+			Str("url", w.URL).
+			Str("event", eventType).
+			Str("response", string(body)).
+			Msg("✅ Webhook delivered successfully")
+		w.record("")
 	} else {
-		log.Printf("⚠️  Webhook returned non-success status %d: %s", resp.StatusCode, string(body))
+		log.Warn().
+			Str("url", w.URL).
+			Str("event", eventType).
+			Int("status", resp.StatusCode).
+			Int("attempt", attempt).
+			Str("response", string(body)).
+			Msg("⚠️  Webhook returned non-success status")
+		failure := fmt.Sprintf("status %d", resp.StatusCode)
+		w.record(failure)
+		audit.Error = failure
+		return fmt.Errorf("%s", failure)
 	}
 
 	return nil
 }
+
+// payloadType is the event type of a stored payload, for the audit log
+func payloadType(body []byte) string {
+	var stored struct {
+		Type  string    `json:"type"`
+		Event EventData `json:"event"`
+	}
+	json.Unmarshal(body, &stored)
+	if stored.Event.Type != "" {
+		return stored.Event.Type
+	}
+	return stored.Type
+}
@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Delivery modes for a webhook target
+const (
+	ModeEvents = "events" // one webhook per event
+	ModeDigest = "digest" // one per-market summary every interval
+)
+
+const DefaultDigestInterval = 10 * time.Second
+
+// Target is a webhook URL and how it wants to be notified
+type Target struct {
+	URL      string
+	Mode     string
+	Interval time.Duration
+	Format   Formatter // events mode only; nil is the default WebhookPayload
+}
+
+// ParseTargets parses a comma-separated target list. Each entry is a URL,
+// optionally followed by "|events" or "|digest[:interval]" and, for events
+// targets, "|format:<name>", e.g.
+// "https://app/api/webhook,https://app/api/ticker|digest:15s,https://discord.com/api/webhooks/1/x|format:discord".
+func ParseTargets(s string) ([]Target, error) {
+	targets := []Target{}
+	formats := make(map[string]string) // URL -> format, for events targets
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, "|")
+		target := Target{URL: strings.TrimSpace(parts[0]), Mode: ModeEvents}
+		if target.URL == "" {
+			return nil, fmt.Errorf("webhook target %q has no URL", entry)
+		}
+
+		for _, option := range parts[1:] {
+			option = strings.TrimSpace(option)
+			if spec, ok := strings.CutPrefix(option, "format:"); ok {
+				format, err := NewFormatter(spec)
+				if err != nil {
+					return nil, fmt.Errorf("webhook target %q: %w", entry, err)
+				}
+				target.Format = format
+				continue
+			}
+
+			mode, interval, hasInterval := strings.Cut(option, ":")
+			switch mode {
+			case "", ModeEvents:
+				if hasInterval {
+					return nil, fmt.Errorf("webhook target %q: events mode takes no interval", entry)
+				}
+			case ModeDigest:
+				target.Mode = ModeDigest
+				target.Interval = DefaultDigestInterval
+				if hasInterval {
+					d, err := time.ParseDuration(interval)
+					if err != nil || d <= 0 {
+						return nil, fmt.Errorf("webhook target %q: invalid digest interval %q", entry, interval)
+					}
+					target.Interval = d
+				}
+			default:
+				return nil, fmt.Errorf("webhook target %q: unknown option %q (expected events, digest or format:<name>)", entry, option)
+			}
+		}
+
+		if target.Mode == ModeDigest && target.Format != nil {
+			return nil, fmt.Errorf("webhook target %q: digests have a fixed format", entry)
+		}
+		if target.Mode == ModeEvents {
+			// Deliveries are keyed by URL, so a URL gets one format
+			name := FormatDefault
+			if target.Format != nil {
+				name = target.Format.Name()
+			}
+			if previous, ok := formats[target.URL]; ok && previous != name {
+				return nil, fmt.Errorf("webhook target %s is listed with formats %s and %s", target.URL, previous, name)
+			}
+			formats[target.URL] = name
+		}
+
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Trade is a single buy or sell fed into a digest
+type Trade struct {
+	MarketAddress string
+	Action        string // BUY or SELL
+	Outcome       string // YES or NO
+	APT           float64
+	Shares        float64
+	Timestamp     time.Time
+}
+
+// MarketDigest summarizes the trades on one market during a digest window
+type MarketDigest struct {
+	MarketAddress string  `json:"market_address"`
+	TradeCount    int     `json:"trade_count"`
+	NetVolume     float64 `json:"net_volume"` // APT bought minus APT sold
+	LatestPrice   float64 `json:"latest_price"`
+	LatestOutcome string  `json:"latest_outcome"`
+	LatestTradeAt string  `json:"latest_trade_at"`
+}
+
+type DigestPayload struct {
+	Type        string         `json:"type"`
+	WindowStart string         `json:"window_start"`
+	WindowEnd   string         `json:"window_end"`
+	Markets     []MarketDigest `json:"markets"`
+}
+
+// Digest aggregates trades per market and posts one summary per interval
+// instead of one webhook per trade
+type Digest struct {
+	client      *WebhookClient
+	interval    time.Duration
+	windowStart time.Time
+	markets     map[string]*MarketDigest
+	mu          sync.Mutex
+}
+
+func NewDigest(client *WebhookClient, interval time.Duration) *Digest {
+	if interval <= 0 {
+		interval = DefaultDigestInterval
+	}
+	return &Digest{
+		client:      client,
+		interval:    interval,
+		windowStart: time.Now(),
+		markets:     make(map[string]*MarketDigest),
+	}
+}
+
+// Record adds a trade to the current window
+func (d *Digest) Record(trade Trade) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m, ok := d.markets[trade.MarketAddress]
+	if !ok {
+		m = &MarketDigest{MarketAddress: trade.MarketAddress}
+		d.markets[trade.MarketAddress] = m
+	}
+
+	m.TradeCount++
+	if trade.Action == "SELL" {
+		m.NetVolume -= trade.APT
+	} else {
+		m.NetVolume += trade.APT
+	}
+	if trade.Shares > 0 {
+		m.LatestPrice = trade.APT / trade.Shares
+	}
+	m.LatestOutcome = trade.Outcome
+	m.LatestTradeAt = timeconv.Format(trade.Timestamp)
+}
+
+// Stats returns the delivery outcomes of the digest's target
+func (d *Digest) Stats() DeliveryStats {
+	return d.client.Stats()
+}
+
+// Pending returns how many trades are waiting for the next flush
+func (d *Digest) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, m := range d.markets {
+		n += m.TradeCount
+	}
+	return n
+}
+
+// Run flushes the digest every interval until ctx is cancelled, then sends
+// whatever is left
+func (d *Digest) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.Flush()
+			return
+		case <-ticker.C:
+			d.Flush()
+		}
+	}
+}
+
+// Flush sends the current window, if it saw any trades, and starts a new one
+func (d *Digest) Flush() {
+	d.mu.Lock()
+	now := time.Now()
+	payload := DigestPayload{
+		Type:        "digest",
+		WindowStart: timeconv.Format(d.windowStart),
+		WindowEnd:   timeconv.Format(now),
+		Markets:     make([]MarketDigest, 0, len(d.markets)),
+	}
+	for _, m := range d.markets {
+		payload.Markets = append(payload.Markets, *m)
+	}
+	d.markets = make(map[string]*MarketDigest)
+	d.windowStart = now
+	d.mu.Unlock()
+
+	if len(payload.Markets) == 0 {
+		return
+	}
+	sort.Slice(payload.Markets, func(i, j int) bool {
+		return payload.Markets[i].MarketAddress < payload.Markets[j].MarketAddress
+	})
+
+	d.client.SendDigest(payload)
+}
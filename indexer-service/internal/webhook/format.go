@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Payload formats for events targets, selected with "|format:<name>"
+const (
+	FormatDefault     = "default"     // WebhookPayload, what the frontend's /api/webhook expects
+	FormatDiscord     = "discord"     // Discord webhook embed
+	FormatSlack       = "slack"       // Slack incoming webhook text
+	FormatCloudEvents = "cloudevents" // CloudEvents 1.0, structured mode
+	FormatTemplate    = "template"    // template:<path>, a Go text/template file
+)
+
+// Formatter turns an event payload into the body a target expects
+type Formatter interface {
+	Name() string
+	ContentType() string
+	Format(p WebhookPayload) ([]byte, error)
+}
+
+// NewFormatter returns the formatter for spec, e.g. "cloudevents" or
+// "template:/etc/verifi/partner.tmpl". Template files are read here.
+func NewFormatter(spec string) (Formatter, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	switch name {
+	case "", FormatDefault:
+		return defaultFormat{}, nil
+	case FormatDiscord:
+		return discordFormat{}, nil
+	case FormatSlack:
+		return slackFormat{}, nil
+	case FormatCloudEvents:
+		return cloudEventsFormat{}, nil
+	case FormatTemplate:
+		if arg == "" {
+			return nil, fmt.Errorf("template format needs a file, e.g. template:/etc/verifi/partner.tmpl")
+		}
+		text, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		tmpl, err := template.New(arg).Funcs(templateFuncs).Option("missingkey=zero").Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", arg, err)
+		}
+		return templateFormat{spec: spec, tmpl: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected default, discord, slack, cloudevents or template:<file>)", name)
+	}
+}
+
+type defaultFormat struct{}
+
+func (defaultFormat) Name() string        { return FormatDefault }
+func (defaultFormat) ContentType() string { return "application/json" }
+
+func (defaultFormat) Format(p WebhookPayload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+type discordFormat struct{}
+
+func (discordFormat) Name() string        { return FormatDiscord }
+func (discordFormat) ContentType() string { return "application/json" }
+
+func (discordFormat) Format(p WebhookPayload) ([]byte, error) {
+	embed := map[string]interface{}{
+		"title":       p.Event.Type,
+		"description": strings.Join(dataLines(p.Event.Data), "\n"),
+		"footer":      map[string]string{"text": "tx " + p.Transaction.Hash},
+	}
+	if p.Transaction.Timestamp != "" {
+		embed["timestamp"] = p.Transaction.Timestamp
+	}
+	return json.Marshal(map[string]interface{}{"embeds": []interface{}{embed}})
+}
+
+type slackFormat struct{}
+
+func (slackFormat) Name() string        { return FormatSlack }
+func (slackFormat) ContentType() string { return "application/json" }
+
+func (slackFormat) Format(p WebhookPayload) ([]byte, error) {
+	lines := append([]string{"*" + p.Event.Type + "*"}, dataLines(p.Event.Data)...)
+	lines = append(lines, "tx "+p.Transaction.Hash)
+	return json.Marshal(map[string]string{"text": strings.Join(lines, "\n")})
+}
+
+// dataLines renders event data as sorted "key: value" lines
+func dataLines(data map[string]interface{}) []string {
+	lines := make([]string, 0, len(data))
+	for key, value := range data {
+		lines = append(lines, fmt.Sprintf("%s: %v", key, value))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+type cloudEventsFormat struct{}
+
+func (cloudEventsFormat) Name() string        { return FormatCloudEvents }
+func (cloudEventsFormat) ContentType() string { return "application/cloudevents+json" }
+
+// Format builds a structured-mode CloudEvent. The id hashes the event, so
+// a retried delivery keeps its id and consumers can deduplicate on it.
+func (cloudEventsFormat) Format(p WebhookPayload) ([]byte, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(data)
+
+	event := map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              hex.EncodeToString(id[:16]),
+		"source":          "urn:verifi:indexer-service",
+		"type":            "io.verifi." + p.Event.Type,
+		"datacontenttype": "application/json",
+		"data":            json.RawMessage(data),
+	}
+	if p.Transaction.Timestamp != "" {
+		event["time"] = p.Transaction.Timestamp
+	}
+	if market, ok := p.Event.Data["market_address"].(string); ok {
+		event["subject"] = market
+	}
+	return json.Marshal(event)
+}
+
+var templateFuncs = template.FuncMap{
+	// json renders a value as JSON, e.g. {{json .Event.Data.description}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// templateFormat executes a text/template with the WebhookPayload as dot
+type templateFormat struct {
+	spec string
+	tmpl *template.Template
+}
+
+func (t templateFormat) Name() string      { return t.spec }
+func (templateFormat) ContentType() string { return "application/json" }
+
+func (t templateFormat) Format(p WebhookPayload) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("template %s: %w", t.spec, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,13 @@
+-- Events that referenced a market the indexer could not resolve
+CREATE TABLE IF NOT EXISTS quarantined_events (
+    id BIGSERIAL PRIMARY KEY,
+    tx_hash VARCHAR(66) NOT NULL,
+    tx_version BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    market_address VARCHAR(66),
+    reason TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantined_events_market ON quarantined_events (market_address);
@@ -0,0 +1,7 @@
+-- Single-writer leases so only one process writes a table at a time
+CREATE TABLE IF NOT EXISTS writer_leases (
+    name VARCHAR(255) PRIMARY KEY,
+    owner VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
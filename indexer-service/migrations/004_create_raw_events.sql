@@ -0,0 +1,15 @@
+-- Raw module events loaded from historical dumps
+CREATE TABLE IF NOT EXISTS raw_events (
+    transaction_version BIGINT NOT NULL,
+    event_index INT NOT NULL,
+    tx_hash VARCHAR(66),
+    sender VARCHAR(66),
+    tx_timestamp TEXT,
+    event_type TEXT NOT NULL,
+    data JSONB NOT NULL,
+    source TEXT NOT NULL,
+    imported_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (transaction_version, event_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_raw_events_type ON raw_events (event_type);
@@ -0,0 +1,21 @@
+-- Store Activity amounts as exact decimals instead of floating point. Shares
+-- have 6 decimals and APT values 8, so NUMERIC holds every on-chain amount
+-- without rounding. Skipped when the table is missing or already converted.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'Activity' AND column_name = 'amount' AND data_type <> 'numeric'
+    ) THEN
+        ALTER TABLE "Activity"
+            ALTER COLUMN "amount" TYPE NUMERIC(38, 6) USING ROUND("amount"::numeric, 6);
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'Activity' AND column_name = 'totalValue' AND data_type <> 'numeric'
+    ) THEN
+        ALTER TABLE "Activity"
+            ALTER COLUMN "totalValue" TYPE NUMERIC(38, 8) USING ROUND("totalValue"::numeric, 8);
+    END IF;
+END $$;
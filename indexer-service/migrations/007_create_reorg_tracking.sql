@@ -0,0 +1,15 @@
+-- Hash of the transaction at each checkpointed version, re-verified on every
+-- poll to detect rollbacks or a fullnode serving a different history
+CREATE TABLE IF NOT EXISTS checkpoint_hashes (
+    version BIGINT PRIMARY KEY,
+    tx_hash VARCHAR(66) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every transaction that emitted module events, so rows written from a
+-- version range can be found and removed on rollback
+CREATE TABLE IF NOT EXISTS indexed_transactions (
+    version BIGINT PRIMARY KEY,
+    tx_hash VARCHAR(66) NOT NULL,
+    indexed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
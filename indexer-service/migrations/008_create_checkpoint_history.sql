@@ -0,0 +1,12 @@
+-- Samples of checkpoint advances (at most one per minute) for analysing when
+-- and why indexing slowed down
+CREATE TABLE IF NOT EXISTS checkpoint_history (
+    id BIGSERIAL PRIMARY KEY,
+    version BIGINT NOT NULL,
+    advanced BIGINT NOT NULL, -- versions indexed since the previous sample
+    polls INT NOT NULL,       -- poll cycles that advanced the checkpoint
+    elapsed_ms BIGINT NOT NULL, -- wall time since the previous sample
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkpoint_history_recorded_at ON checkpoint_history (recorded_at);
@@ -0,0 +1,45 @@
+-- Ledger of every module event that has been applied, keyed by its position
+-- on chain. Handlers claim an event here in the same transaction as their
+-- writes, so restarts, replays and imports apply each event exactly once.
+CREATE TABLE IF NOT EXISTS processed_events (
+    transaction_version BIGINT NOT NULL,
+    event_index INT NOT NULL,
+    event_type TEXT NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_version, event_index)
+);
+
+-- Versions up to here were indexed while Activity allowed one row per
+-- transaction; a transaction that already has Activity there counts as
+-- processed
+INSERT INTO sync_state (key, value, updated_at)
+SELECT 'processed_events_since', value, NOW()
+FROM sync_state WHERE key = 'last_indexed_version'
+ON CONFLICT (key) DO NOTHING;
+
+-- A transaction can both buy and sell, so "txHash" is no longer unique in
+-- Activity. Skipped when the table is missing.
+DO $$
+DECLARE
+    idx TEXT;
+BEGIN
+    FOR idx IN
+        SELECT c.relname
+        FROM pg_index i
+        JOIN pg_class c ON c.oid = i.indexrelid
+        JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+        WHERE i.indrelid = to_regclass('"Activity"')
+          AND i.indisunique AND i.indnkeyatts = 1 AND a.attname = 'txHash'
+    LOOP
+        IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = idx) THEN
+            EXECUTE format('ALTER TABLE "Activity" DROP CONSTRAINT %I', idx);
+        ELSE
+            EXECUTE format('DROP INDEX %I', idx);
+        END IF;
+    END LOOP;
+
+    IF to_regclass('"Activity"') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS "Activity_txHash_idx" ON "Activity" ("txHash");
+    END IF;
+END $$;
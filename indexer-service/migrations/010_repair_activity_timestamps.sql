@@ -0,0 +1,32 @@
+-- Fullnode timestamps (microseconds since the epoch) used to be parsed as
+-- RFC 3339 with the error ignored, so those Activity rows got the zero time.
+-- Recover them from archived raw events where the transaction is known;
+-- `--repair-timestamps` fetches the rest from the fullnode. Skipped when the
+-- table is missing.
+DO $$
+BEGIN
+    IF to_regclass('"Activity"') IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE "Activity" a
+    SET "timestamp" = CASE
+            WHEN r.tx_timestamp ~ '^[0-9]{16}$'
+                THEN to_timestamp(r.tx_timestamp::bigint / 1000000.0) AT TIME ZONE 'UTC'
+            WHEN r.tx_timestamp ~ '(Z|[+-][0-9]{2}(:?[0-9]{2})?)$'
+                THEN r.tx_timestamp::timestamptz AT TIME ZONE 'UTC'
+            ELSE r.tx_timestamp::timestamp
+        END
+    FROM (
+        SELECT DISTINCT ON (tx_hash) tx_hash, tx_timestamp
+        FROM raw_events
+        WHERE tx_hash IS NOT NULL
+          AND (
+              tx_timestamp ~ '^[0-9]{16}$'
+              OR tx_timestamp ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}[T ][0-9]{2}:[0-9]{2}:[0-9]{2}'
+          )
+        ORDER BY tx_hash, transaction_version
+    ) r
+    WHERE r.tx_hash = a."txHash"
+      AND a."timestamp" < '1970-01-02';
+END $$;
@@ -0,0 +1,34 @@
+-- Create the Activity and Market tables when the frontend's Prisma schema
+-- hasn't, so the services can run standalone against an empty database.
+-- Columns are the ones the Go services read and write; an existing
+-- Prisma-managed table is left untouched.
+CREATE TABLE IF NOT EXISTS "Market" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "marketAddress" TEXT NOT NULL UNIQUE,
+    "creator" TEXT,
+    "description" TEXT NOT NULL DEFAULT '',
+    "resolutionTimestamp" TIMESTAMP(3),
+    "status" TEXT NOT NULL DEFAULT 'active',
+    "volume24h" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "volume7d" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "totalVolume" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "uniqueTraders" INT NOT NULL DEFAULT 0,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "Activity" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "txHash" TEXT NOT NULL,
+    "marketAddress" TEXT NOT NULL,
+    "userAddress" TEXT NOT NULL,
+    "action" TEXT NOT NULL,
+    "outcome" TEXT,
+    "amount" NUMERIC(38, 6) NOT NULL,
+    "totalValue" NUMERIC(38, 8),
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+-- 009 only indexes txHash when Activity already existed
+CREATE INDEX IF NOT EXISTS "Activity_txHash_idx" ON "Activity" ("txHash");
+CREATE INDEX IF NOT EXISTS "Activity_marketAddress_timestamp_idx" ON "Activity" ("marketAddress", "timestamp");
@@ -0,0 +1,13 @@
+-- Warn and above log entries, kept when LOG_DB is enabled so handler errors
+-- and the lead-up to a crash survive a restart. Pruned after LOG_DB_RETENTION.
+CREATE TABLE IF NOT EXISTS log_entries (
+    id BIGSERIAL PRIMARY KEY,
+    logged_at TIMESTAMPTZ NOT NULL,
+    level TEXT NOT NULL,
+    message TEXT NOT NULL,
+    fields JSONB,
+    instance TEXT NOT NULL -- indexer-service:<host>:<pid>
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_entries_logged_at ON log_entries (logged_at);
+CREATE INDEX IF NOT EXISTS idx_log_entries_event ON log_entries ((fields->>'event'));
@@ -0,0 +1,15 @@
+-- Notification subscriptions registered through POST /subscriptions. Each
+-- delivers to a callback URL or a Telegram chat; NULL filters match every
+-- market, wallet or event type.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    callback_url TEXT,
+    telegram_chat_id TEXT,
+    market_address TEXT,
+    wallet_address TEXT,
+    event_types TEXT[],
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (callback_url IS NOT NULL OR telegram_chat_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_wallet ON subscriptions (wallet_address);
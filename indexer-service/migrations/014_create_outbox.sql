@@ -0,0 +1,18 @@
+-- Outgoing webhook and message bus deliveries, written in the same
+-- transaction as the batch that produced them and delivered afterwards by
+-- the outbox dispatcher, so a crash between commit and send loses nothing.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,   -- webhook or bus
+    target TEXT NOT NULL, -- webhook URL, or bus name (kind:topic)
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, delivered, failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_created ON outbox (created_at);
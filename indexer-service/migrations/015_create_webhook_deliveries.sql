@@ -0,0 +1,18 @@
+-- Every webhook delivery attempt, for GET /webhooks/deliveries and
+-- redelivery. Pruned after WEBHOOK_AUDIT_RETENTION.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    target TEXT NOT NULL,
+    event_type TEXT NOT NULL DEFAULT '',
+    payload JSON NOT NULL,      -- JSON, not JSONB, keeps the body byte for byte
+    payload_hash TEXT NOT NULL, -- sha256 of the body as sent
+    attempt INT NOT NULL,
+    status_code INT,            -- NULL when no response was received
+    error TEXT,
+    latency_ms INT NOT NULL,
+    delivered BOOLEAN NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_attempted ON webhook_deliveries (attempted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_failed ON webhook_deliveries (attempted_at) WHERE NOT delivered;
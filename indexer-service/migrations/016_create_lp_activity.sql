@@ -0,0 +1,31 @@
+-- Liquidity provision, next to the trade tables so the sync-service pool
+-- metrics and the LP dashboard can read it. Named like the Prisma tables the
+-- frontend reads. LpActivity has one row per LiquidityAddedEvent (ADD) or
+-- LiquidityRemovedEvent (REMOVE); LpPosition is each provider's running
+-- balance per market and can always be rebuilt from LpActivity.
+CREATE TABLE IF NOT EXISTS "LpActivity" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "txHash" TEXT NOT NULL,
+    "marketAddress" TEXT NOT NULL,
+    "userAddress" TEXT NOT NULL,
+    "action" TEXT NOT NULL CHECK ("action" IN ('ADD', 'REMOVE')),
+    "aptAmount" NUMERIC(38, 8) NOT NULL,
+    "lpShares" NUMERIC(38, 6) NOT NULL,
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "LpActivity_txHash_idx" ON "LpActivity" ("txHash");
+CREATE INDEX IF NOT EXISTS "LpActivity_marketAddress_timestamp_idx" ON "LpActivity" ("marketAddress", "timestamp");
+CREATE INDEX IF NOT EXISTS "LpActivity_userAddress_idx" ON "LpActivity" ("userAddress");
+
+CREATE TABLE IF NOT EXISTS "LpPosition" (
+    "marketAddress" TEXT NOT NULL,
+    "userAddress" TEXT NOT NULL,
+    "lpShares" NUMERIC(38, 6) NOT NULL DEFAULT 0,
+    "aptDeposited" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "aptWithdrawn" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    PRIMARY KEY ("marketAddress", "userAddress")
+);
+
+CREATE INDEX IF NOT EXISTS "LpPosition_userAddress_idx" ON "LpPosition" ("userAddress");
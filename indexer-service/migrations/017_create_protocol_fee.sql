@@ -0,0 +1,13 @@
+-- Protocol fees, one row per FeeCollectedEvent. The sync-service aggregates
+-- them into daily and weekly revenue per market for GET /stats/revenue.
+CREATE TABLE IF NOT EXISTS "ProtocolFee" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "txHash" TEXT NOT NULL,
+    "marketAddress" TEXT NOT NULL,
+    "amount" NUMERIC(38, 8) NOT NULL, -- APT
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ProtocolFee_txHash_idx" ON "ProtocolFee" ("txHash");
+CREATE INDEX IF NOT EXISTS "ProtocolFee_timestamp_idx" ON "ProtocolFee" ("timestamp");
+CREATE INDEX IF NOT EXISTS "ProtocolFee_marketAddress_timestamp_idx" ON "ProtocolFee" ("marketAddress", "timestamp");
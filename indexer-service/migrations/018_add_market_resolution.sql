@@ -0,0 +1,6 @@
+-- Resolution details on Market, set from MarketResolvedEvent: the winning
+-- outcome, who resolved it, and the resolving transaction and its time.
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "outcome" TEXT;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "resolver" TEXT;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "resolutionTxHash" TEXT;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "resolvedAt" TIMESTAMP(3);
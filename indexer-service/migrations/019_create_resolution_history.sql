@@ -0,0 +1,17 @@
+-- Oracle and dispute timeline per market: one row per
+-- ResolutionProposedEvent (PROPOSED), DisputeRaisedEvent (DISPUTED) and
+-- DisputeResolvedEvent (DISPUTE_RESOLVED). "actor" is the proposer, the
+-- disputer or the transaction sender; "data" keeps the full event payload.
+CREATE TABLE IF NOT EXISTS "ResolutionHistory" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "marketAddress" TEXT NOT NULL,
+    "txHash" TEXT NOT NULL,
+    "state" TEXT NOT NULL CHECK ("state" IN ('PROPOSED', 'DISPUTED', 'DISPUTE_RESOLVED')),
+    "outcome" TEXT,
+    "actor" TEXT NOT NULL,
+    "data" JSONB NOT NULL DEFAULT '{}',
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ResolutionHistory_marketAddress_timestamp_idx" ON "ResolutionHistory" ("marketAddress", "timestamp");
+CREATE INDEX IF NOT EXISTS "ResolutionHistory_txHash_idx" ON "ResolutionHistory" ("txHash");
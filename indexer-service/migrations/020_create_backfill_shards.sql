@@ -0,0 +1,42 @@
+-- Sharded backfills: a job splits a version range into shards that worker
+-- processes claim with a lease. "checkpoint" is the last version a shard
+-- committed, so a reclaimed shard resumes after it. The job is complete
+-- once every shard is done and the merge has applied deferred_events.
+CREATE TABLE IF NOT EXISTS backfill_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    from_version BIGINT NOT NULL,
+    to_version BIGINT NOT NULL,
+    shard_size BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'merging', 'complete')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS backfill_shards (
+    job_id BIGINT NOT NULL REFERENCES backfill_jobs (id) ON DELETE CASCADE,
+    shard INT NOT NULL,
+    from_version BIGINT NOT NULL,
+    to_version BIGINT NOT NULL,
+    checkpoint BIGINT,
+    owner TEXT,
+    lease_expires_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    last_error TEXT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (job_id, shard)
+);
+
+CREATE INDEX IF NOT EXISTS idx_backfill_shards_open ON backfill_shards (job_id, shard) WHERE NOT done;
+
+-- Events a shard couldn't apply yet because their market's
+-- MarketCreatedEvent lies in a shard that hadn't committed. They are not
+-- claimed in processed_events, so the merge applies them in version order.
+CREATE TABLE IF NOT EXISTS deferred_events (
+    transaction_version BIGINT NOT NULL,
+    event_index INT NOT NULL,
+    event_type TEXT NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (transaction_version, event_index)
+);
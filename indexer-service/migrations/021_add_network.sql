@@ -0,0 +1,35 @@
+-- Network of every indexed row, so one database can hold several networks'
+-- deployments (INDEXER_NETWORKS). Rows written before this are '' until the
+-- indexer labels them with its first network on startup. The tables keyed
+-- by ledger version are re-keyed per network, since every network has its
+-- own version numbers.
+ALTER TABLE "Activity" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "LpActivity" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "LpPosition" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "ProtocolFee" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "ResolutionHistory" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE quarantined_events ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE checkpoint_history ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE raw_events ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE raw_events DROP CONSTRAINT IF EXISTS raw_events_pkey;
+ALTER TABLE raw_events ADD PRIMARY KEY (network, transaction_version, event_index);
+
+ALTER TABLE processed_events ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE processed_events DROP CONSTRAINT IF EXISTS processed_events_pkey;
+ALTER TABLE processed_events ADD PRIMARY KEY (network, transaction_version, event_index);
+
+ALTER TABLE deferred_events ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE deferred_events DROP CONSTRAINT IF EXISTS deferred_events_pkey;
+ALTER TABLE deferred_events ADD PRIMARY KEY (network, transaction_version, event_index);
+
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE indexed_transactions DROP CONSTRAINT IF EXISTS indexed_transactions_pkey;
+ALTER TABLE indexed_transactions ADD PRIMARY KEY (network, version);
+
+ALTER TABLE checkpoint_hashes ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+ALTER TABLE checkpoint_hashes DROP CONSTRAINT IF EXISTS checkpoint_hashes_pkey;
+ALTER TABLE checkpoint_hashes ADD PRIMARY KEY (network, version);
+
+CREATE INDEX IF NOT EXISTS idx_checkpoint_history_network_recorded_at ON checkpoint_history (network, recorded_at);
@@ -0,0 +1,17 @@
+-- Module events without a schema or handler, one row per network and Move
+-- type, so new contract events show up at GET /admin/unknown-events instead
+-- of being skipped silently. "transactions" counts the distinct versions
+-- they were seen in; "sample" is the latest payload.
+CREATE TABLE IF NOT EXISTS unknown_events (
+    network TEXT NOT NULL DEFAULT '',
+    event_type TEXT NOT NULL,
+    event_name TEXT NOT NULL,
+    transactions BIGINT NOT NULL DEFAULT 1,
+    first_version BIGINT NOT NULL,
+    last_version BIGINT NOT NULL,
+    last_tx_hash TEXT NOT NULL,
+    sample JSONB NOT NULL DEFAULT '{}',
+    first_seen_at TIMESTAMP DEFAULT NOW(),
+    last_seen_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (network, event_type)
+);
@@ -0,0 +1,18 @@
+-- Version ranges the checkpoint moved past without indexing them: the
+-- fullnode returned fewer transactions than requested, or a handler failed.
+-- The listener re-fetches open gaps and sets repaired_at once they apply.
+CREATE TABLE IF NOT EXISTS version_gaps (
+    id BIGSERIAL PRIMARY KEY,
+    network TEXT NOT NULL DEFAULT '',
+    start_version BIGINT NOT NULL,
+    end_version BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    detected_at TIMESTAMP DEFAULT NOW(),
+    last_attempt_at TIMESTAMP,
+    repaired_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_version_gaps_range ON version_gaps(network, start_version, end_version);
+CREATE INDEX IF NOT EXISTS idx_version_gaps_open ON version_gaps(network, start_version) WHERE repaired_at IS NULL;
@@ -0,0 +1,8 @@
+-- Sender and gas of every indexed transaction, so the sync-service can show
+-- traders what their trades cost in gas. NULL for transactions indexed
+-- before this, or imported without their gas.
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS sender TEXT;
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS gas_used BIGINT;
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS gas_unit_price BIGINT; -- octas per gas unit
+
+CREATE INDEX IF NOT EXISTS idx_indexed_transactions_tx_hash ON indexed_transactions (tx_hash);
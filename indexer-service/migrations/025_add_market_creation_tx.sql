@@ -0,0 +1,6 @@
+-- The transaction whose MarketCreatedEvent recorded the market, so a
+-- rollback can remove markets created above its version. NULL for markets
+-- created by the webhook, recreated from chain, or recorded before this.
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "creationTxHash" TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_market_creation_tx_hash ON "Market" ("creationTxHash");
@@ -0,0 +1,8 @@
+-- Key Market by network and address rather than address alone, so the same
+-- market address on two networks (INDEXER_NETWORKS) is two rows instead of
+-- one overwritten by whichever network indexed it last. The old key is a
+-- constraint when 011 created the table and a unique index when Prisma did.
+ALTER TABLE "Market" DROP CONSTRAINT IF EXISTS "Market_marketAddress_key";
+DROP INDEX IF EXISTS "Market_marketAddress_key";
+
+CREATE UNIQUE INDEX IF NOT EXISTS "Market_network_marketAddress_key" ON "Market" ("network", "marketAddress");
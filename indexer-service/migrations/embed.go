@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema files so the binary can apply
+// them at startup without shipping the migrations directory alongside it.
+package migrations
+
+import "embed"
+
+// Service keys these migrations in the shared schema_migrations table
+const Service = "indexer-service"
+
+//go:embed *.sql
+var FS embed.FS
@@ -12,7 +12,11 @@ echo "🚀 Deploying VeriFi Indexer Service to VPS..."
 
 # Build the binary locally
 echo "📦 Building Go binary..."
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o indexer ./cmd/server
+BUILDINFO="github.com/verifi-protocol/pkg/buildinfo"
+LDFLAGS="-X ${BUILDINFO}.Version=$(git describe --tags --always 2>/dev/null || echo dev)"
+LDFLAGS="$LDFLAGS -X ${BUILDINFO}.Commit=$(git rev-parse HEAD 2>/dev/null || echo unknown)"
+LDFLAGS="$LDFLAGS -X ${BUILDINFO}.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o indexer ./cmd/server
 
 # Create deployment package
 echo "📦 Creating deployment package..."
@@ -0,0 +1,46 @@
+// Package amount converts raw on-chain integer amounts (octas, share units)
+// to exact decimals for NUMERIC columns.
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal converts raw, an on-chain integer with unitDecimals implied
+// decimals, to an exact decimal string for NUMERIC columns, e.g.
+// Decimal("150000000", OctaDecimals) = "1.5". Unlike float division it never
+// loses precision, however large the amount.
+func Decimal(raw string, unitDecimals int) (string, error) {
+	value, ok := new(big.Int).SetString(strings.TrimSpace(raw), 10)
+	if !ok {
+		return "", fmt.Errorf("invalid integer amount %q", raw)
+	}
+
+	negative := value.Sign() < 0
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= unitDecimals {
+		digits = strings.Repeat("0", unitDecimals-len(digits)+1) + digits
+	}
+
+	whole, frac := digits[:len(digits)-unitDecimals], strings.TrimRight(digits[len(digits)-unitDecimals:], "0")
+	out := whole
+	if frac != "" {
+		out += "." + frac
+	}
+	if negative {
+		out = "-" + out
+	}
+	return out, nil
+}
+
+// OctasToAPT converts a raw octa amount to an exact APT decimal
+func OctasToAPT(raw string) (string, error) {
+	return Decimal(raw, OctaDecimals)
+}
+
+// SharesToUnits converts a raw share amount to an exact share decimal
+func SharesToUnits(raw string) (string, error) {
+	return Decimal(raw, ShareDecimals)
+}
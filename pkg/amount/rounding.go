@@ -0,0 +1,102 @@
+// Package amount formats raw on-chain integer amounts (octas, share units)
+// for display under a single rounding policy, so every API response and
+// webhook payload shows the same numbers.
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// On-chain decimals
+	OctaDecimals  = 8 // 1 APT = 1e8 octas
+	ShareDecimals = 6 // outcome shares
+)
+
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half_even" // banker's rounding
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundDown     RoundingMode = "down" // truncate toward zero
+)
+
+// Policy controls how raw amounts are rounded for display
+type Policy struct {
+	Mode        RoundingMode
+	APTPlaces   int
+	SharePlaces int
+}
+
+// DefaultPolicy is banker's rounding to 4 APT and 2 share decimals
+func DefaultPolicy() Policy {
+	return Policy{Mode: RoundHalfEven, APTPlaces: 4, SharePlaces: 2}
+}
+
+// ParseMode validates a rounding mode name
+func ParseMode(s string) (RoundingMode, error) {
+	switch RoundingMode(s) {
+	case RoundHalfEven, RoundHalfUp, RoundDown:
+		return RoundingMode(s), nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q (expected half_even, half_up or down)", s)
+}
+
+// FormatOctas formats a raw octa amount as APT
+func (p Policy) FormatOctas(raw string) string {
+	return p.format(raw, OctaDecimals, p.APTPlaces)
+}
+
+// FormatShares formats a raw share amount
+func (p Policy) FormatShares(raw string) string {
+	return p.format(raw, ShareDecimals, p.SharePlaces)
+}
+
+// format scales raw (an integer with unitDecimals implied decimals) to
+// places decimals. Unparseable input is returned unchanged.
+func (p Policy) format(raw string, unitDecimals, places int) string {
+	value, ok := new(big.Int).SetString(strings.TrimSpace(raw), 10)
+	if !ok {
+		return raw
+	}
+	if places > unitDecimals {
+		places = unitDecimals
+	}
+
+	negative := value.Sign() < 0
+	value.Abs(value)
+
+	// Drop the digits beyond places, rounding on the remainder
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(unitDecimals-places)), nil)
+	quotient, remainder := new(big.Int).QuoRem(value, divisor, new(big.Int))
+
+	if remainder.Sign() != 0 {
+		cmp := new(big.Int).Mul(remainder, big.NewInt(2)).Cmp(divisor)
+		switch p.Mode {
+		case RoundHalfUp:
+			if cmp >= 0 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		case RoundDown:
+		default:
+			if cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+
+	digits := quotient.String()
+	if places > 0 {
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+	}
+	if negative && quotient.Sign() != 0 {
+		digits = "-" + digits
+	}
+
+	return digits
+}
@@ -0,0 +1,300 @@
+package aptos
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Consecutive errors before a key is quarantined
+	keyFailureThreshold = 3
+	// Base quarantine, doubled for every further failure while quarantined
+	keyBaseQuarantine = 30 * time.Second
+	keyMaxQuarantine  = 10 * time.Minute
+)
+
+// keyHealth tracks outcomes for a single API key
+type keyHealth struct {
+	provider            string
+	successes           int
+	rateLimited         int
+	errors              int
+	consecutiveFailures int
+	quarantines         int
+	quarantinedUntil    time.Time
+	lastUsed            time.Time
+	lastStatus          int
+}
+
+// APIKeyRotator manages rotation between multiple API keys to avoid rate limits.
+// Keys that get rate-limited or keep failing are quarantined for a while and
+// healthy keys are preferred.
+type APIKeyRotator struct {
+	aptosKeys []string
+	noditKeys []string
+	aptosIdx  int
+	noditIdx  int
+	rotations int
+	mu        sync.Mutex
+	health    map[string]*keyHealth
+	minDelay  time.Duration
+}
+
+// NewAPIKeyRotator creates a new API key rotator
+func NewAPIKeyRotator(aptosKeys, noditKeys []string) *APIKeyRotator {
+	r := &APIKeyRotator{
+		aptosKeys: aptosKeys,
+		noditKeys: noditKeys,
+		health:    make(map[string]*keyHealth),
+		minDelay:  100 * time.Millisecond, // Minimum delay between uses of same key
+	}
+	for _, key := range aptosKeys {
+		r.health[key] = &keyHealth{provider: "aptos"}
+	}
+	for _, key := range noditKeys {
+		r.health[key] = &keyHealth{provider: "nodit"}
+	}
+	return r
+}
+
+// SetKeys replaces the key lists at runtime. Keys that stay keep their health
+// history, including any quarantine; removed keys are forgotten.
+func (r *APIKeyRotator) SetKeys(aptosKeys, noditKeys []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health := make(map[string]*keyHealth)
+	for provider, keys := range map[string][]string{"aptos": aptosKeys, "nodit": noditKeys} {
+		for _, key := range keys {
+			if h, ok := r.health[key]; ok {
+				health[key] = h
+			} else {
+				health[key] = &keyHealth{provider: provider}
+			}
+		}
+	}
+
+	r.aptosKeys, r.noditKeys = aptosKeys, noditKeys
+	r.aptosIdx, r.noditIdx = 0, 0
+	r.health = health
+
+	log.Info().
+		Int("aptos_keys", len(aptosKeys)).
+		Int("nodit_keys", len(noditKeys)).
+		Msg("🔑 API keys reloaded")
+}
+
+// GetNextAptosKey returns the next healthy Aptos API key in rotation
+func (r *APIKeyRotator) GetNextAptosKey() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.next(r.aptosKeys, &r.aptosIdx)
+}
+
+// GetNextNoditKey returns the next healthy Nodit API key in rotation
+func (r *APIKeyRotator) GetNextNoditKey() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.next(r.noditKeys, &r.noditIdx)
+}
+
+// next picks the next key after *idx that isn't quarantined. When every key
+// is quarantined it falls back to the one released soonest. Must hold r.mu.
+func (r *APIKeyRotator) next(keys []string, idx *int) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	key := ""
+	for i := 0; i < len(keys); i++ {
+		candidate := keys[(*idx+i)%len(keys)]
+		if r.health[candidate].quarantinedUntil.Before(now) {
+			key = candidate
+			*idx += i + 1
+			break
+		}
+	}
+
+	if key == "" {
+		for _, candidate := range keys {
+			if key == "" || r.health[candidate].quarantinedUntil.Before(r.health[key].quarantinedUntil) {
+				key = candidate
+			}
+		}
+		*idx++
+	}
+
+	// Wait if this key was used too recently
+	h := r.health[key]
+	if elapsed := time.Since(h.lastUsed); elapsed < r.minDelay {
+		time.Sleep(r.minDelay - elapsed)
+	}
+
+	h.lastUsed = time.Now()
+	r.rotations++
+
+	return key
+}
+
+// ReportResult records the outcome of a request made with key. 429s and
+// repeated errors quarantine the key; any success clears its failure streak.
+func (r *APIKeyRotator) ReportResult(key string, resp *http.Response, err error) {
+	if key == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.health[key]
+	if !ok {
+		return
+	}
+
+	switch {
+	case err != nil || resp == nil:
+		h.errors++
+		h.consecutiveFailures++
+		h.lastStatus = 0
+		if h.consecutiveFailures >= keyFailureThreshold {
+			r.quarantine(key, h, 0)
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		h.rateLimited++
+		h.consecutiveFailures++
+		h.lastStatus = resp.StatusCode
+		r.quarantine(key, h, retryAfter(resp))
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		h.errors++
+		h.consecutiveFailures++
+		h.lastStatus = resp.StatusCode
+		if h.consecutiveFailures >= keyFailureThreshold {
+			r.quarantine(key, h, 0)
+		}
+	default:
+		h.successes++
+		h.consecutiveFailures = 0
+		h.lastStatus = resp.StatusCode
+	}
+}
+
+// quarantine takes key out of rotation for wait, or an exponential backoff
+// when the server gave no Retry-After. Must hold r.mu.
+func (r *APIKeyRotator) quarantine(key string, h *keyHealth, wait time.Duration) {
+	if wait <= 0 {
+		wait = keyBaseQuarantine << min(h.quarantines, 5)
+		if wait > keyMaxQuarantine {
+			wait = keyMaxQuarantine
+		}
+	}
+
+	h.quarantines++
+	h.quarantinedUntil = time.Now().Add(wait)
+
+	log.Warn().
+		Str("provider", h.provider).
+		Str("key", maskKey(key)).
+		Int("status", h.lastStatus).
+		Dur("quarantine", wait).
+		Msg("🔑 API key quarantined")
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// maskKey keeps only a short prefix so stats and logs never leak keys
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
+
+// KeyStats is the health snapshot of a single key
+type KeyStats struct {
+	Key                 string     `json:"key"`
+	Provider            string     `json:"provider"`
+	Healthy             bool       `json:"healthy"`
+	Successes           int        `json:"successes"`
+	RateLimited         int        `json:"rate_limited"`
+	Errors              int        `json:"errors"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Quarantines         int        `json:"quarantines"`
+	QuarantinedUntil    *time.Time `json:"quarantined_until,omitempty"`
+	LastUsed            *time.Time `json:"last_used,omitempty"`
+	LastStatus          int        `json:"last_status"`
+}
+
+// GetStats returns usage statistics for monitoring
+func (r *APIKeyRotator) GetStats() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := r.keyStats()
+	healthy := 0
+	for _, ks := range keys {
+		if ks.Healthy {
+			healthy++
+		}
+	}
+
+	return map[string]interface{}{
+		"aptos_keys_count": len(r.aptosKeys),
+		"nodit_keys_count": len(r.noditKeys),
+		"healthy_keys":     healthy,
+		"total_rotations":  r.rotations,
+		"keys":             keys,
+	}
+}
+
+// Keys returns the health of every configured key
+func (r *APIKeyRotator) Keys() []KeyStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.keyStats()
+}
+
+// keyStats snapshots every key; r.mu must be held
+func (r *APIKeyRotator) keyStats() []KeyStats {
+	now := time.Now()
+	keys := make([]KeyStats, 0, len(r.health))
+	for _, list := range [][]string{r.aptosKeys, r.noditKeys} {
+		for _, key := range list {
+			h := r.health[key]
+			ks := KeyStats{
+				Key:                 maskKey(key),
+				Provider:            h.provider,
+				Healthy:             h.quarantinedUntil.Before(now),
+				Successes:           h.successes,
+				RateLimited:         h.rateLimited,
+				Errors:              h.errors,
+				ConsecutiveFailures: h.consecutiveFailures,
+				Quarantines:         h.quarantines,
+				LastStatus:          h.lastStatus,
+			}
+			if !ks.Healthy {
+				until := h.quarantinedUntil
+				ks.QuarantinedUntil = &until
+			}
+			if !h.lastUsed.IsZero() {
+				lastUsed := h.lastUsed
+				ks.LastUsed = &lastUsed
+			}
+			keys = append(keys, ks)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,375 @@
+// Package aptos is the Aptos fullnode and Nodit client shared by the
+// services, plus the typed decoders for the VeriFi module events.
+package aptos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AptosTestnetRPC = "https://fullnode.testnet.aptoslabs.com/v1"
+	AptosMainnetRPC = "https://fullnode.mainnet.aptoslabs.com/v1"
+	AptosDevnetRPC  = "https://fullnode.devnet.aptoslabs.com/v1"
+	// Default REST port of `aptos node run-localnet`
+	AptosLocalRPC = "http://127.0.0.1:8080/v1"
+)
+
+// NetworkRPCURL returns the public fullnode URL for a network name
+func NetworkRPCURL(network string) (string, bool) {
+	switch network {
+	case "mainnet":
+		return AptosMainnetRPC, true
+	case "testnet":
+		return AptosTestnetRPC, true
+	case "devnet":
+		return AptosDevnetRPC, true
+	case "local", "localnet":
+		return AptosLocalRPC, true
+	default:
+		return "", false
+	}
+}
+
+// RPC providers differ in which keys they accept and how they're sent
+const (
+	ProviderAptos = "aptos" // Authorization: Bearer <key>
+	ProviderNodit = "nodit" // X-API-KEY: <key>
+)
+
+type Client struct {
+	endpoints  []*endpoint
+	httpClient *http.Client
+	apiRotator *APIKeyRotator
+	limiter    *RateLimiter
+	freshness  freshnessTracker
+	mu         sync.Mutex
+}
+
+// providerForURL infers the RPC provider from the fullnode URL
+func providerForURL(rpcURL string) string {
+	if strings.Contains(rpcURL, "nodit.io") {
+		return ProviderNodit
+	}
+	return ProviderAptos
+}
+
+func NewClient(network string) *Client {
+	rpcURL, ok := NetworkRPCURL(network)
+	if !ok {
+		rpcURL = AptosTestnetRPC
+	}
+
+	return NewClientWithEndpoints([]string{rpcURL})
+}
+
+// NewClientWithEndpoints creates a client that fails over between rpcURLs,
+// in priority order
+func NewClientWithEndpoints(rpcURLs []string) *Client {
+	endpoints := make([]*endpoint, 0, len(rpcURLs))
+	for _, rpcURL := range rpcURLs {
+		endpoints = append(endpoints, newEndpoint(strings.TrimRight(rpcURL, "/")))
+	}
+
+	return &Client{
+		endpoints: endpoints,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		apiRotator: nil, // Set later via SetAPIRotator
+	}
+}
+
+// NewClientWithURL creates a client for a single fullnode, e.g. a
+// self-hosted node or localnet
+func NewClientWithURL(rpcURL string) *Client {
+	return NewClientWithEndpoints([]string{rpcURL})
+}
+
+func (c *Client) SetAPIRotator(rotator *APIKeyRotator) {
+	c.apiRotator = rotator
+}
+
+// SetRateLimiter caps the request rate of every call made by the client
+func (c *Client) SetRateLimiter(limiter *RateLimiter) {
+	c.limiter = limiter
+}
+
+// RateLimiter returns the client's rate limiter, or nil if unlimited
+func (c *Client) RateLimiter() *RateLimiter {
+	return c.limiter
+}
+
+// do sends req with the next API key for the endpoint's provider (if a
+// rotator is set) and reports the outcome back to the rotator's health tracking
+func (c *Client) do(req *http.Request, provider string) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.apiRotator == nil {
+		return c.httpClient.Do(req)
+	}
+
+	var apiKey string
+	switch provider {
+	case ProviderNodit:
+		if apiKey = c.apiRotator.GetNextNoditKey(); apiKey != "" {
+			req.Header.Set("X-API-KEY", apiKey)
+		}
+	default:
+		if apiKey = c.apiRotator.GetNextAptosKey(); apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	c.apiRotator.ReportResult(apiKey, resp, err)
+	return resp, err
+}
+
+// RPCURL returns the fullnode base URL the client currently talks to
+func (c *Client) RPCURL() string {
+	return c.ActiveEndpoint()
+}
+
+type EventQuery struct {
+	EventType string
+	Start     uint64
+	Limit     int
+}
+
+type Event struct {
+	Version         string                 `json:"version"`
+	GUID            map[string]interface{} `json:"guid"`
+	SequenceNumber  string                 `json:"sequence_number"`
+	Type            string                 `json:"type"`
+	Data            map[string]interface{} `json:"data"`
+}
+
+type TransactionEvent struct {
+	Version         string                 `json:"version"`
+	Hash            string                 `json:"hash"`
+	StateChangeHash string                 `json:"state_change_hash"`
+	EventRootHash   string                 `json:"event_root_hash"`
+	GasUsed         string                 `json:"gas_used"`
+	GasUnitPrice    string                 `json:"gas_unit_price"` // octas
+	Success         bool                   `json:"success"`
+	VMStatus        string                 `json:"vm_status"`
+	AccumulatorRootHash string             `json:"accumulator_root_hash"`
+	Changes         []interface{}          `json:"changes"`
+	Sender          string                 `json:"sender"`
+	Events          []Event                `json:"events"`
+	Timestamp       string                 `json:"timestamp"`
+	Type            string                 `json:"type"`
+
+	// EventIndexes holds the on-chain index of each entry in Events when
+	// Events is only a subset of the transaction's events (imports). Nil
+	// means Events is complete and indexes are positions.
+	EventIndexes []int `json:"-"`
+}
+
+// EventIndex returns the on-chain index of tx.Events[i]
+func (tx TransactionEvent) EventIndex(i int) int {
+	if i < len(tx.EventIndexes) {
+		return tx.EventIndexes[i]
+	}
+	return i
+}
+
+// Get events by event handle. Only handle events (event v1) are returned;
+// module events have no handle, see GetModuleEvents.
+func (c *Client) GetEventsByEventHandle(ctx context.Context, address, eventHandle, fieldName string, start, limit uint64) ([]Event, error) {
+	path := fmt.Sprintf("/accounts/%s/events/%s/%s?start=%d&limit=%d", address, eventHandle, fieldName, start, limit)
+
+	resp, err := c.send(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var events []Event
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Get transactions by version range
+func (c *Client) GetTransactionsByVersionRange(ctx context.Context, start, limit uint64) ([]TransactionEvent, error) {
+	path := fmt.Sprintf("/transactions?start=%d&limit=%d", start, limit)
+
+	resp, err := c.send(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var txs []TransactionEvent
+	if err := json.NewDecoder(resp.Body).Decode(&txs); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// Get a single transaction by version
+func (c *Client) GetTransactionByVersion(ctx context.Context, version uint64) (*TransactionEvent, error) {
+	path := fmt.Sprintf("/transactions/by_version/%d", version)
+
+	resp, err := c.send(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var tx TransactionEvent
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// Get a transaction by its hash
+func (c *Client) GetTransactionByHash(ctx context.Context, hash string) (*TransactionEvent, error) {
+	path := fmt.Sprintf("/transactions/by_hash/%s", hash)
+
+	resp, err := c.send(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var tx TransactionEvent
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// Get the names of the Move modules published under address
+func (c *Client) GetAccountModules(ctx context.Context, address string) ([]string, error) {
+	path := fmt.Sprintf("/accounts/%s/modules", address)
+
+	resp, err := c.send(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var modules []struct {
+		ABI struct {
+			Name string `json:"name"`
+		} `json:"abi"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&modules); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(modules))
+	for _, m := range modules {
+		names = append(names, m.ABI.Name)
+	}
+
+	return names, nil
+}
+
+// Get latest ledger info
+func (c *Client) GetLatestLedgerInfo(ctx context.Context) (uint64, error) {
+	resp, err := c.send(ctx, "GET", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		LedgerVersion string `json:"ledger_version"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+
+	var version uint64
+	fmt.Sscanf(result.LedgerVersion, "%d", &version)
+	return version, nil
+}
+
+// View function call
+func (c *Client) View(ctx context.Context, function string, typeArgs, args []string) ([]interface{}, error) {
+	type ViewRequest struct {
+		Function      string   `json:"function"`
+		TypeArguments []string `json:"type_arguments"`
+		Arguments     []string `json:"arguments"`
+	}
+
+	reqBody := ViewRequest{
+		Function:      function,
+		TypeArguments: typeArgs,
+		Arguments:     args,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, "POST", "/view", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("view call error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result []interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package aptos_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/aptostest"
+)
+
+// newNode starts a mock fullnode at version head
+func newNode(t *testing.T, head uint64) *aptostest.Server {
+	t.Helper()
+
+	node := aptostest.New()
+	t.Cleanup(node.Close)
+	node.SetLedgerVersion(head)
+	return node
+}
+
+// waitFor polls cond until it holds or a few seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientFailover(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault aptostest.Fault
+	}{
+		{"server error", aptostest.Fault{Status: http.StatusInternalServerError}},
+		{"unavailable", aptostest.Fault{Status: http.StatusServiceUnavailable}},
+		{"rate limited", aptostest.Fault{Status: http.StatusTooManyRequests}},
+		{"connection dropped", aptostest.Fault{Drop: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			primary, secondary := newNode(t, 10), newNode(t, 20)
+			primary.Fail(tt.fault)
+			client := aptos.NewClientWithEndpoints([]string{primary.URL, secondary.URL})
+
+			// Every call is answered by the secondary
+			for i := 0; i < 2; i++ {
+				version, err := client.GetLatestLedgerInfo(ctx)
+				if err != nil {
+					t.Fatalf("call %d: %v", i, err)
+				}
+				if version != 20 {
+					t.Errorf("call %d: version = %d, want the secondary's 20", i, version)
+				}
+			}
+
+			// Two failures in a row take the primary out of rotation
+			if got := client.ActiveEndpoint(); got != secondary.URL {
+				t.Errorf("ActiveEndpoint() = %s, want %s", got, secondary.URL)
+			}
+			if status := client.Endpoints()[0]; status.Healthy || status.ConsecutiveFailures != 2 {
+				t.Errorf("primary = %+v, want unhealthy after 2 failures", status)
+			}
+
+			asked := primary.Requests("")
+			if _, err := client.GetLatestLedgerInfo(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got := primary.Requests(""); got != asked {
+				t.Errorf("primary got %d requests during its cooldown, want none", got-asked)
+			}
+		})
+	}
+}
+
+func TestClientRetriesOnNextEndpoint(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newNode(t, 10), newNode(t, 20)
+	primary.Fail(aptostest.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	client := aptos.NewClientWithEndpoints([]string{primary.URL, secondary.URL})
+
+	version, err := client.GetLatestLedgerInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 20 {
+		t.Errorf("version = %d, want the secondary's 20", version)
+	}
+
+	// A single failure doesn't demote the primary
+	if status := client.Endpoints()[0]; !status.Healthy || !status.Active || status.ConsecutiveFailures != 1 {
+		t.Errorf("primary = %+v, want healthy and active with 1 failure", status)
+	}
+
+	version, err = client.GetLatestLedgerInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 10 {
+		t.Errorf("version = %d, want the primary's 10", version)
+	}
+	if status := client.Endpoints()[0]; status.ConsecutiveFailures != 0 {
+		t.Errorf("primary consecutive failures = %d, want 0 after a success", status.ConsecutiveFailures)
+	}
+}
+
+func TestClientNoFailoverOnClientError(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newNode(t, 10), newNode(t, 20)
+	client := aptos.NewClientWithEndpoints([]string{primary.URL, secondary.URL})
+
+	// A version past the head is a 404 the caller has to handle
+	_, err := client.GetTransactionByVersion(ctx, 15)
+	if err == nil || !strings.Contains(err.Error(), "status=404") {
+		t.Fatalf("err = %v, want a 404", err)
+	}
+	if got := secondary.Requests(""); got != 0 {
+		t.Errorf("secondary got %d requests, want none", got)
+	}
+	if status := client.Endpoints()[0]; !status.Healthy || status.Failures != 0 {
+		t.Errorf("primary = %+v, want healthy without failures", status)
+	}
+}
+
+func TestClientAllEndpointsFail(t *testing.T) {
+	primary, secondary := newNode(t, 10), newNode(t, 20)
+	primary.Fail(aptostest.Fault{Status: http.StatusBadGateway})
+	secondary.Fail(aptostest.Fault{Drop: true})
+	client := aptos.NewClientWithEndpoints([]string{primary.URL, secondary.URL})
+
+	_, err := client.GetLatestLedgerInfo(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "all RPC endpoints failed") {
+		t.Fatalf("err = %v, want all endpoints failed", err)
+	}
+	if primary.Requests("") != 1 || secondary.Requests("") != 1 {
+		t.Errorf("requests = %d, %d, want one per endpoint", primary.Requests(""), secondary.Requests(""))
+	}
+}
+
+func TestClientFailsBackAfterHealthCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary, secondary := newNode(t, 10), newNode(t, 20)
+	primary.Fail(aptostest.Fault{Status: http.StatusServiceUnavailable})
+	client := aptos.NewClientWithEndpoints([]string{primary.URL, secondary.URL})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetLatestLedgerInfo(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := client.ActiveEndpoint(); got != secondary.URL {
+		t.Fatalf("ActiveEndpoint() = %s, want %s", got, secondary.URL)
+	}
+
+	primary.ClearFaults()
+	go client.RunHealthChecks(ctx, 20*time.Millisecond)
+	waitFor(t, "the primary to recover", func() bool { return client.ActiveEndpoint() == primary.URL })
+
+	version, err := client.GetLatestLedgerInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 10 {
+		t.Errorf("version = %d, want the primary's 10", version)
+	}
+}
+
+func TestClientRateLimitQuarantinesKey(t *testing.T) {
+	ctx := context.Background()
+	node := newNode(t, 10)
+	node.SetRateLimit(1, time.Minute)
+
+	client := aptos.NewClientWithURL(node.URL)
+	rotator := aptos.NewAPIKeyRotator([]string{"aptos-key-1", "aptos-key-2"}, nil)
+	client.SetAPIRotator(rotator)
+
+	if _, err := client.GetLatestLedgerInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetLatestLedgerInfo(ctx); err == nil {
+		t.Fatal("second call succeeded, want a 429")
+	}
+
+	stats := rotator.GetStats()
+	if got := stats["healthy_keys"]; got != 1 {
+		t.Errorf("healthy_keys = %v, want 1", got)
+	}
+	for _, key := range stats["keys"].([]aptos.KeyStats) {
+		if key.Healthy {
+			continue
+		}
+		if key.RateLimited != 1 || key.LastStatus != http.StatusTooManyRequests {
+			t.Errorf("quarantined key = %+v, want one 429", key)
+		}
+		// Retry-After (the rest of the minute) beats the 30s default
+		if key.QuarantinedUntil == nil || time.Until(*key.QuarantinedUntil) < 45*time.Second {
+			t.Errorf("quarantined until %v, want about a minute from now", key.QuarantinedUntil)
+		}
+	}
+
+	// Once the window passes, the healthy key gets through
+	node.SetRateLimit(0, 0)
+	if _, err := client.GetLatestLedgerInfo(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
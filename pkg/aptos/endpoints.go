@@ -0,0 +1,245 @@
+package aptos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Consecutive failures before an endpoint is taken out of rotation
+	endpointFailureThreshold = 2
+	// How long a failed endpoint is skipped before it's tried again
+	endpointCooldown = 30 * time.Second
+)
+
+// endpoint is one fullnode in the client's prioritized failover list
+type endpoint struct {
+	url                 string
+	provider            string
+	requests            int
+	failures            int
+	consecutiveFailures int
+	downUntil           time.Time
+	lastError           string
+	lastSuccess         time.Time
+}
+
+func newEndpoint(url string) *endpoint {
+	return &endpoint{url: url, provider: providerForURL(url)}
+}
+
+func (e *endpoint) healthy(now time.Time) bool {
+	return !now.Before(e.downUntil)
+}
+
+// EndpointStatus is the health snapshot of one fullnode endpoint
+type EndpointStatus struct {
+	URL                 string     `json:"url"`
+	Provider            string     `json:"provider"`
+	Priority            int        `json:"priority"`
+	Active              bool       `json:"active"`
+	Healthy             bool       `json:"healthy"`
+	Requests            int        `json:"requests"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+// send issues method+path against the highest-priority healthy endpoint and
+// fails over to the next one on network errors, timeouts, 5xx and 429s.
+// Endpoints that keep failing are skipped for a cooldown period.
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var lastErr error
+
+	for _, ep := range c.candidates() {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, ep.url+path, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.do(req, ep.provider)
+
+		// The caller gave up; don't blame the endpoint
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			c.markSuccess(ep)
+			c.freshness.observe(true, nil)
+			return resp, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("RPC error: status=%d", resp.StatusCode)
+			resp.Body.Close()
+		}
+		c.markFailure(ep, lastErr)
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no RPC endpoints configured")
+	}
+	c.freshness.observe(false, lastErr)
+	return nil, fmt.Errorf("all RPC endpoints failed: %w", lastErr)
+}
+
+// candidates returns the endpoints to try, healthy ones first in priority
+// order. Endpoints in cooldown are only used when nothing else is left.
+func (c *Client) candidates() []*endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]*endpoint, 0, len(c.endpoints))
+	var cooling []*endpoint
+	for _, ep := range c.endpoints {
+		if ep.healthy(now) {
+			healthy = append(healthy, ep)
+		} else {
+			cooling = append(cooling, ep)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+func (c *Client) markSuccess(ep *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ep.requests++
+	ep.consecutiveFailures = 0
+	ep.downUntil = time.Time{}
+	ep.lastSuccess = time.Now()
+}
+
+func (c *Client) markFailure(ep *endpoint, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ep.requests++
+	ep.failures++
+	ep.consecutiveFailures++
+	ep.lastError = err.Error()
+
+	if ep.consecutiveFailures >= endpointFailureThreshold && ep.healthy(time.Now()) {
+		ep.downUntil = time.Now().Add(endpointCooldown)
+		log.Warn().
+			Err(err).
+			Str("endpoint", ep.url).
+			Int("failures", ep.consecutiveFailures).
+			Msg("🔀 RPC endpoint marked down, failing over")
+	}
+}
+
+// ActiveEndpoint returns the URL requests currently go to
+func (c *Client) ActiveEndpoint() string {
+	candidates := c.candidates()
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0].url
+}
+
+// Endpoints returns the health of every configured endpoint
+func (c *Client) Endpoints() []EndpointStatus {
+	active := c.ActiveEndpoint()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]EndpointStatus, 0, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		status := EndpointStatus{
+			URL:                 ep.url,
+			Provider:            ep.provider,
+			Priority:            i,
+			Active:              ep.url == active,
+			Healthy:             ep.healthy(now),
+			Requests:            ep.requests,
+			Failures:            ep.failures,
+			ConsecutiveFailures: ep.consecutiveFailures,
+			LastError:           ep.lastError,
+		}
+		if !ep.lastSuccess.IsZero() {
+			lastSuccess := ep.lastSuccess
+			status.LastSuccess = &lastSuccess
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// RunHealthChecks probes every endpoint's ledger info each interval, so
+// endpoints in cooldown come back (and the client fails back to the primary)
+// as soon as they recover
+func (c *Client) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	if len(c.endpoints) < 2 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, ep := range c.endpoints {
+				c.probe(ctx, ep)
+			}
+		}
+	}
+}
+
+func (c *Client) probe(ctx context.Context, ep *endpoint) {
+	probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(probeCtx, "GET", ep.url, nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := c.do(req, ep.provider)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			c.mu.Lock()
+			recovered := !ep.healthy(time.Now())
+			ep.consecutiveFailures = 0
+			ep.downUntil = time.Time{}
+			c.mu.Unlock()
+
+			if recovered {
+				log.Info().Str("endpoint", ep.url).Msg("✅ RPC endpoint recovered")
+			}
+			return
+		}
+		err = fmt.Errorf("health check status=%d", resp.StatusCode)
+	}
+	c.markFailure(ep, err)
+}
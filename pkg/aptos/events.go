@@ -0,0 +1,373 @@
+package aptos
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Typed module events. Move u64/u128 values arrive as decimal strings and
+// are kept as strings here; addresses are 0x-prefixed hex strings.
+
+type SharesMintedEvent struct {
+	MarketAddress string
+	User          string
+	IsYes         bool
+	AptAmountIn   string // octas
+	SharesOut     string // share units
+}
+
+type SharesBurnedEvent struct {
+	MarketAddress string
+	User          string
+	IsYes         bool
+	SharesIn      string // share units
+	AptAmountOut  string // octas
+}
+
+type MarketCreatedEvent struct {
+	MarketAddress       string
+	Creator             string
+	Description         string
+	ResolutionTimestamp string // seconds
+}
+
+type MarketResolvedEvent struct {
+	MarketAddress string
+	Outcome       string
+	Resolver      string // empty when the event doesn't carry one
+}
+
+type ResolutionProposedEvent struct {
+	MarketAddress string
+	Proposer      string
+	Outcome       string
+}
+
+type DisputeRaisedEvent struct {
+	MarketAddress string
+	Disputer      string
+}
+
+type DisputeResolvedEvent struct {
+	MarketAddress string
+	Outcome       string // the outcome that stands after the dispute
+}
+
+type FeeCollectedEvent struct {
+	MarketAddress string
+	FeeAmount     string // octas
+}
+
+type LiquidityAddedEvent struct {
+	MarketAddress  string
+	Provider       string
+	AptAmountIn    string // octas
+	LpSharesMinted string // LP share units
+}
+
+type LiquidityRemovedEvent struct {
+	MarketAddress  string
+	Provider       string
+	LpSharesBurned string // LP share units
+	AptAmountOut   string // octas
+}
+
+// DecodeError reports a module event whose payload is missing a field or has
+// the wrong type for it
+type DecodeError struct {
+	Event string
+	Field string
+	Issue string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("malformed %s: field %q %s", e.Event, e.Field, e.Issue)
+}
+
+// eventDecoder reads typed fields out of an event payload, keeping the first
+// error so a decode function can check once at the end
+type eventDecoder struct {
+	event string
+	data  map[string]interface{}
+	err   error
+}
+
+func newEventDecoder(event string, data map[string]interface{}) *eventDecoder {
+	return &eventDecoder{event: event, data: data}
+}
+
+func (d *eventDecoder) fail(field, issue string) {
+	if d.err == nil {
+		d.err = &DecodeError{Event: d.event, Field: field, Issue: issue}
+	}
+}
+
+func (d *eventDecoder) value(field string) (interface{}, bool) {
+	v, ok := d.data[field]
+	if !ok || v == nil {
+		d.fail(field, "is missing")
+		return nil, false
+	}
+	return v, true
+}
+
+func (d *eventDecoder) string(field string) string {
+	v, ok := d.value(field)
+	if !ok {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		d.fail(field, fmt.Sprintf("is %T, want string", v))
+	}
+	return s
+}
+
+// optionalAddress reads an address that older module versions don't emit,
+// returning "" when it is absent
+func (d *eventDecoder) optionalAddress(field string) string {
+	if v, ok := d.data[field]; !ok || v == nil {
+		return ""
+	}
+	return d.address(field)
+}
+
+func (d *eventDecoder) address(field string) string {
+	s := d.string(field)
+	if d.err == nil && (len(s) < 3 || s[:2] != "0x") {
+		d.fail(field, fmt.Sprintf("is %q, want a 0x address", s))
+	}
+	return s
+}
+
+// uint reads a Move integer, which the REST API serializes as a string
+func (d *eventDecoder) uint(field string) string {
+	s := d.string(field)
+	if d.err != nil {
+		return s
+	}
+	if n, ok := new(big.Int).SetString(s, 10); !ok || n.Sign() < 0 {
+		d.fail(field, fmt.Sprintf("is %q, want an unsigned integer", s))
+	}
+	return s
+}
+
+func (d *eventDecoder) bool(field string) bool {
+	v, ok := d.value(field)
+	if !ok {
+		return false
+	}
+	b, ok := v.(bool)
+	if !ok {
+		d.fail(field, fmt.Sprintf("is %T, want bool", v))
+	}
+	return b
+}
+
+func DecodeSharesMinted(data map[string]interface{}) (SharesMintedEvent, error) {
+	d := newEventDecoder("SharesMintedEvent", data)
+	e := SharesMintedEvent{
+		MarketAddress: d.address("market_address"),
+		User:          d.address("user"),
+		IsYes:         d.bool("is_yes"),
+		AptAmountIn:   d.uint("apt_amount_in"),
+		SharesOut:     d.uint("shares_out"),
+	}
+	return e, d.err
+}
+
+func DecodeSharesBurned(data map[string]interface{}) (SharesBurnedEvent, error) {
+	d := newEventDecoder("SharesBurnedEvent", data)
+	e := SharesBurnedEvent{
+		MarketAddress: d.address("market_address"),
+		User:          d.address("user"),
+		IsYes:         d.bool("is_yes"),
+		SharesIn:      d.uint("shares_in"),
+		AptAmountOut:  d.uint("apt_amount_out"),
+	}
+	return e, d.err
+}
+
+func DecodeMarketCreated(data map[string]interface{}) (MarketCreatedEvent, error) {
+	d := newEventDecoder("MarketCreatedEvent", data)
+	e := MarketCreatedEvent{
+		MarketAddress:       d.address("market_address"),
+		Creator:             d.address("creator"),
+		Description:         d.string("description"),
+		ResolutionTimestamp: d.uint("resolution_timestamp"),
+	}
+	return e, d.err
+}
+
+func DecodeMarketResolved(data map[string]interface{}) (MarketResolvedEvent, error) {
+	d := newEventDecoder("MarketResolvedEvent", data)
+	e := MarketResolvedEvent{
+		MarketAddress: d.address("market_address"),
+		Outcome:       d.string("outcome"),
+		Resolver:      d.optionalAddress("resolver"),
+	}
+	return e, d.err
+}
+
+func DecodeLiquidityAdded(data map[string]interface{}) (LiquidityAddedEvent, error) {
+	d := newEventDecoder("LiquidityAddedEvent", data)
+	e := LiquidityAddedEvent{
+		MarketAddress:  d.address("market_address"),
+		Provider:       d.address("provider"),
+		AptAmountIn:    d.uint("apt_amount_in"),
+		LpSharesMinted: d.uint("lp_shares_minted"),
+	}
+	return e, d.err
+}
+
+func DecodeLiquidityRemoved(data map[string]interface{}) (LiquidityRemovedEvent, error) {
+	d := newEventDecoder("LiquidityRemovedEvent", data)
+	e := LiquidityRemovedEvent{
+		MarketAddress:  d.address("market_address"),
+		Provider:       d.address("provider"),
+		LpSharesBurned: d.uint("lp_shares_burned"),
+		AptAmountOut:   d.uint("apt_amount_out"),
+	}
+	return e, d.err
+}
+
+func DecodeFeeCollected(data map[string]interface{}) (FeeCollectedEvent, error) {
+	d := newEventDecoder("FeeCollectedEvent", data)
+	e := FeeCollectedEvent{
+		MarketAddress: d.address("market_address"),
+		FeeAmount:     d.uint("fee_amount"),
+	}
+	return e, d.err
+}
+
+func DecodeResolutionProposed(data map[string]interface{}) (ResolutionProposedEvent, error) {
+	d := newEventDecoder("ResolutionProposedEvent", data)
+	e := ResolutionProposedEvent{
+		MarketAddress: d.address("market_address"),
+		Proposer:      d.address("proposer"),
+		Outcome:       d.string("outcome"),
+	}
+	return e, d.err
+}
+
+func DecodeDisputeRaised(data map[string]interface{}) (DisputeRaisedEvent, error) {
+	d := newEventDecoder("DisputeRaisedEvent", data)
+	e := DisputeRaisedEvent{
+		MarketAddress: d.address("market_address"),
+		Disputer:      d.address("disputer"),
+	}
+	return e, d.err
+}
+
+func DecodeDisputeResolved(data map[string]interface{}) (DisputeResolvedEvent, error) {
+	d := newEventDecoder("DisputeResolvedEvent", data)
+	e := DisputeResolvedEvent{
+		MarketAddress: d.address("market_address"),
+		Outcome:       d.string("outcome"),
+	}
+	return e, d.err
+}
+
+// FieldType is how a schema field is checked: address (0x hex string), uint
+// (Move integer as a decimal string), string or bool
+type FieldType string
+
+const (
+	FieldAddress FieldType = "address"
+	FieldUint    FieldType = "uint"
+	FieldString  FieldType = "string"
+	FieldBool    FieldType = "bool"
+)
+
+// Field is one field of an event schema. Optional fields may be absent,
+// e.g. because older module versions don't emit them.
+type Field struct {
+	Name     string    `json:"name"`
+	Type     FieldType `json:"type"`
+	Optional bool      `json:"optional,omitempty"`
+}
+
+// EventSchemas are the module events the services know, by event name,
+// with the fields their Decode functions read. Fields outside the schema
+// are allowed.
+var EventSchemas = map[string][]Field{
+	"SharesMintedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "user", Type: FieldAddress},
+		{Name: "is_yes", Type: FieldBool},
+		{Name: "apt_amount_in", Type: FieldUint},
+		{Name: "shares_out", Type: FieldUint},
+	},
+	"SharesBurnedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "user", Type: FieldAddress},
+		{Name: "is_yes", Type: FieldBool},
+		{Name: "shares_in", Type: FieldUint},
+		{Name: "apt_amount_out", Type: FieldUint},
+	},
+	"MarketCreatedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "creator", Type: FieldAddress},
+		{Name: "description", Type: FieldString},
+		{Name: "resolution_timestamp", Type: FieldUint},
+	},
+	"MarketResolvedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "outcome", Type: FieldString},
+		{Name: "resolver", Type: FieldAddress, Optional: true},
+	},
+	"LiquidityAddedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "provider", Type: FieldAddress},
+		{Name: "apt_amount_in", Type: FieldUint},
+		{Name: "lp_shares_minted", Type: FieldUint},
+	},
+	"LiquidityRemovedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "provider", Type: FieldAddress},
+		{Name: "lp_shares_burned", Type: FieldUint},
+		{Name: "apt_amount_out", Type: FieldUint},
+	},
+	"FeeCollectedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "fee_amount", Type: FieldUint},
+	},
+	"ResolutionProposedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "proposer", Type: FieldAddress},
+		{Name: "outcome", Type: FieldString},
+	},
+	"DisputeRaisedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "disputer", Type: FieldAddress},
+	},
+	"DisputeResolvedEvent": {
+		{Name: "market_address", Type: FieldAddress},
+		{Name: "outcome", Type: FieldString},
+	},
+}
+
+// ValidateEvent checks data against the schema of the event named name,
+// returning a *DecodeError for the first field that doesn't match. Events
+// without a schema pass.
+func ValidateEvent(name string, data map[string]interface{}) error {
+	d := newEventDecoder(name, data)
+	for _, field := range EventSchemas[name] {
+		if field.Optional {
+			if v, ok := data[field.Name]; !ok || v == nil {
+				continue
+			}
+		}
+		switch field.Type {
+		case FieldAddress:
+			d.address(field.Name)
+		case FieldUint:
+			d.uint(field.Name)
+		case FieldBool:
+			d.bool(field.Name)
+		default:
+			d.string(field.Name)
+		}
+	}
+	return d.err
+}
@@ -0,0 +1,68 @@
+package aptos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Freshness reports whether the fullnode is reachable. While Stale is true
+// every RPC endpoint is failing, so the API keeps serving what is in the
+// database but that data stopped updating at LastFreshAt.
+type Freshness struct {
+	Stale       bool       `json:"stale"`
+	LastFreshAt *time.Time `json:"last_fresh_at,omitempty"`
+	StaleSince  *time.Time `json:"stale_since,omitempty"`
+}
+
+// freshnessTracker follows whether the latest fullnode request succeeded on
+// any endpoint
+type freshnessTracker struct {
+	lastFresh  time.Time
+	staleSince time.Time
+	mu         sync.Mutex
+}
+
+func (f *freshnessTracker) observe(ok bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if ok {
+		if !f.staleSince.IsZero() {
+			log.Info().
+				Dur("stale_for", now.Sub(f.staleSince)).
+				Msg("✅ Fullnode reachable again, data is fresh")
+		}
+		f.lastFresh = now
+		f.staleSince = time.Time{}
+		return
+	}
+
+	if f.staleSince.IsZero() {
+		f.staleSince = now
+		log.Warn().Err(err).Msg("⚠️  All RPC endpoints unreachable, serving stale data")
+	}
+}
+
+func (f *freshnessTracker) snapshot() Freshness {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fresh := Freshness{Stale: !f.staleSince.IsZero()}
+	if !f.lastFresh.IsZero() {
+		lastFresh := f.lastFresh
+		fresh.LastFreshAt = &lastFresh
+	}
+	if fresh.Stale {
+		staleSince := f.staleSince
+		fresh.StaleSince = &staleSince
+	}
+	return fresh
+}
+
+// Freshness returns whether data fed from the fullnode is currently stale
+func (c *Client) Freshness() Freshness {
+	return c.freshness.snapshot()
+}
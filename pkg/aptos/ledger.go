@@ -0,0 +1,63 @@
+package aptos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// LedgerInfo is the fullnode's index response: which chain it serves and
+// how far its ledger goes
+type LedgerInfo struct {
+	ChainID             uint8
+	LedgerVersion       uint64
+	OldestLedgerVersion uint64
+	NodeRole            string
+}
+
+// Chain IDs of the public networks. Devnet's changes on every reset and a
+// localnet's is whatever it was started with, so they have none.
+var networkChainIDs = map[string]uint8{
+	"mainnet": 1,
+	"testnet": 2,
+}
+
+// NetworkChainID returns the chain ID of a public network
+func NetworkChainID(network string) (uint8, bool) {
+	id, ok := networkChainIDs[network]
+	return id, ok
+}
+
+// GetLedgerInfo returns the chain ID and ledger range of the fullnode
+func (c *Client) GetLedgerInfo(ctx context.Context) (LedgerInfo, error) {
+	resp, err := c.send(ctx, "GET", "", nil)
+	if err != nil {
+		return LedgerInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return LedgerInfo{}, fmt.Errorf("RPC error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		ChainID             uint8  `json:"chain_id"`
+		LedgerVersion       string `json:"ledger_version"`
+		OldestLedgerVersion string `json:"oldest_ledger_version"`
+		NodeRole            string `json:"node_role"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return LedgerInfo{}, err
+	}
+
+	info := LedgerInfo{ChainID: result.ChainID, NodeRole: result.NodeRole}
+	if info.LedgerVersion, err = strconv.ParseUint(result.LedgerVersion, 10, 64); err != nil {
+		return LedgerInfo{}, fmt.Errorf("invalid ledger_version %q", result.LedgerVersion)
+	}
+	info.OldestLedgerVersion, _ = strconv.ParseUint(result.OldestLedgerVersion, 10, 64)
+	return info, nil
+}
@@ -0,0 +1,52 @@
+package aptos
+
+import (
+	"context"
+	"strconv"
+)
+
+// The fullnode has no endpoint for module events (event v2): they belong to
+// no event handle, so GetEventsByEventHandle can't see them and the only
+// source is the transactions that emitted them. GetModuleEvents scans a
+// version range for them; GetEventsByEventHandle stays for contracts that
+// still emit handle events.
+
+// FilterModuleEvents returns tx with only the events declared by
+// moduleAddress, keeping their on-chain indexes in EventIndexes
+func FilterModuleEvents(tx TransactionEvent, moduleAddress string) TransactionEvent {
+	var events []Event
+	var indexes []int
+	for i, event := range tx.Events {
+		if _, ok := MatchModule(event.Type, moduleAddress); ok {
+			events = append(events, event)
+			indexes = append(indexes, tx.EventIndex(i))
+		}
+	}
+	tx.Events, tx.EventIndexes = events, indexes
+	return tx
+}
+
+// GetModuleEvents scans up to limit transactions from start and returns the
+// successful user transactions that emitted events of moduleAddress, with
+// only those events. next is the version to continue from; it is start when
+// the range is past the ledger head.
+func (c *Client) GetModuleEvents(ctx context.Context, moduleAddress string, start, limit uint64) (txs []TransactionEvent, next uint64, err error) {
+	scanned, err := c.GetTransactionsByVersionRange(ctx, start, limit)
+	if err != nil {
+		return nil, start, err
+	}
+
+	next = start
+	for _, tx := range scanned {
+		if version, err := strconv.ParseUint(tx.Version, 10, 64); err == nil && version >= next {
+			next = version + 1
+		}
+		if !tx.Success || tx.Type != "user_transaction" {
+			continue
+		}
+		if tx = FilterModuleEvents(tx, moduleAddress); len(tx.Events) > 0 {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, next, nil
+}
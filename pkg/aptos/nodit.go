@@ -0,0 +1,147 @@
+package aptos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	NoditTestnetGraphQL = "https://aptos-testnet.nodit.io/v1/graphql"
+	NoditMainnetGraphQL = "https://aptos-mainnet.nodit.io/v1/graphql"
+)
+
+// NoditClient queries Nodit's Aptos GraphQL indexer. Unlike the fullnode it
+// can filter events by type, so historical queries only touch the versions
+// that actually contain our events.
+type NoditClient struct {
+	graphqlURL string
+	httpClient *http.Client
+	apiRotator *APIKeyRotator
+}
+
+func NewNoditClient(network string, rotator *APIKeyRotator) *NoditClient {
+	graphqlURL := NoditTestnetGraphQL
+	if network == "mainnet" {
+		graphqlURL = NoditMainnetGraphQL
+	}
+
+	return &NoditClient{
+		graphqlURL: graphqlURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		apiRotator: rotator,
+	}
+}
+
+// IndexedEvent is an event row from the indexer's events table
+type IndexedEvent struct {
+	TransactionVersion uint64                 `json:"transaction_version"`
+	EventIndex         int                    `json:"event_index"`
+	Type               string                 `json:"type"`
+	IndexedType        string                 `json:"indexed_type"`
+	AccountAddress     string                 `json:"account_address"`
+	Data               map[string]interface{} `json:"data"`
+}
+
+const moduleEventsQuery = `
+query ModuleEvents($type: String!, $from: bigint!, $to: bigint!, $limit: Int!) {
+  events(
+    where: {
+      indexed_type: { _like: $type }
+      transaction_version: { _gte: $from, _lte: $to }
+    }
+    order_by: [{ transaction_version: asc }, { event_index: asc }]
+    limit: $limit
+  ) {
+    transaction_version
+    event_index
+    type
+    indexed_type
+    account_address
+    data
+  }
+}`
+
+// GetModuleEvents returns up to limit events emitted by moduleAddress between
+// fromVersion and toVersion (inclusive), ordered by version
+func (n *NoditClient) GetModuleEvents(ctx context.Context, moduleAddress string, fromVersion, toVersion uint64, limit int) ([]IndexedEvent, error) {
+	variables := map[string]interface{}{
+		"type":  strings.ToLower(moduleAddress) + "::%",
+		"from":  fromVersion,
+		"to":    toVersion,
+		"limit": limit,
+	}
+
+	var result struct {
+		Events []IndexedEvent `json:"events"`
+	}
+	if err := n.query(ctx, moduleEventsQuery, variables, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Events, nil
+}
+
+func (n *NoditClient) query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	reqBody := struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}{
+		Query:     query,
+		Variables: variables,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.graphqlURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	apiKey := ""
+	if n.apiRotator != nil {
+		if apiKey = n.apiRotator.GetNextNoditKey(); apiKey != "" {
+			req.Header.Set("X-API-KEY", apiKey)
+		}
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if n.apiRotator != nil {
+		n.apiRotator.ReportResult(apiKey, resp, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("nodit error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return err
+	}
+	if len(envelope.Errors) > 0 {
+		return fmt.Errorf("nodit graphql error: %s", envelope.Errors[0].Message)
+	}
+
+	return json.Unmarshal(envelope.Data, out)
+}
@@ -0,0 +1,91 @@
+package aptos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StructTag is a parsed Move struct type, address::module::Name<TypeArgs>
+type StructTag struct {
+	Address  string // normalized, see NormalizeAddress
+	Module   string
+	Name     string
+	TypeArgs string // generic arguments without the brackets, empty if none
+}
+
+// ParseStructTag parses a Move struct type such as an event's type. The
+// address may be in short (0x1) or long form.
+func ParseStructTag(s string) (StructTag, error) {
+	base, args := s, ""
+	if i := strings.IndexByte(s, '<'); i >= 0 {
+		if !strings.HasSuffix(s, ">") {
+			return StructTag{}, fmt.Errorf("invalid struct tag %q: unbalanced type arguments", s)
+		}
+		base, args = s[:i], s[i+1:len(s)-1]
+	}
+
+	parts := strings.Split(base, "::")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return StructTag{}, fmt.Errorf("invalid struct tag %q: want address::module::Name", s)
+	}
+	address := NormalizeAddress(parts[0])
+	if address == "" {
+		return StructTag{}, fmt.Errorf("invalid struct tag %q: bad address", s)
+	}
+
+	return StructTag{Address: address, Module: parts[1], Name: parts[2], TypeArgs: args}, nil
+}
+
+// String returns the tag with its address in long form
+func (t StructTag) String() string {
+	s := t.Address + "::" + t.Module + "::" + t.Name
+	if t.TypeArgs != "" {
+		s += "<" + t.TypeArgs + ">"
+	}
+	return s
+}
+
+// NormalizeAddress returns addr as lowercase 0x-prefixed hex padded to 64
+// digits, so 0x1 and 0x0...01 compare equal. It returns "" when addr isn't
+// an address.
+func NormalizeAddress(addr string) string {
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X"))
+	if hex == "" || len(hex) > 64 {
+		return ""
+	}
+	for _, c := range hex {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ""
+		}
+	}
+	return "0x" + strings.Repeat("0", 64-len(hex)) + hex
+}
+
+// ShortAddress abbreviates addr to its first 10 characters for logs. Shorter
+// strings, such as malformed payload fields, are returned whole.
+func ShortAddress(addr string) string {
+	if len(addr) <= 10 {
+		return addr
+	}
+	return addr[:10] + "..."
+}
+
+// MatchModule parses eventType and reports whether its struct is declared at
+// moduleAddress. Unlike a substring check, this ignores the address showing
+// up in type arguments and tolerates short and long address forms.
+func MatchModule(eventType, moduleAddress string) (StructTag, bool) {
+	tag, err := ParseStructTag(eventType)
+	if err != nil {
+		return StructTag{}, false
+	}
+	return tag, tag.Address == NormalizeAddress(moduleAddress)
+}
+
+// IsModuleEvent reports whether e is a module event (event v2), emitted with
+// no account event handle. Module events carry a zero GUID, so the event
+// handle APIs never return them.
+func (e Event) IsModuleEvent() bool {
+	account, _ := e.GUID["account_address"].(string)
+	creation, _ := e.GUID["creation_number"].(string)
+	return (account == "" || NormalizeAddress(account) == NormalizeAddress("0x0")) && (creation == "" || creation == "0")
+}
@@ -0,0 +1,41 @@
+package aptostest
+
+import (
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// UserTransaction is a successful user transaction from sender emitting
+// events. AddTransactions fills in its version, hash and timestamp.
+func UserTransaction(sender string, events ...aptos.Event) aptos.TransactionEvent {
+	if events == nil {
+		events = []aptos.Event{}
+	}
+	return aptos.TransactionEvent{
+		Sender:       sender,
+		Success:      true,
+		VMStatus:     "Executed successfully",
+		GasUsed:      "100",
+		GasUnitPrice: "100",
+		Events:       events,
+		Type:         "user_transaction",
+	}
+}
+
+// FailedTransaction is UserTransaction aborted with vmStatus, which the
+// listener skips
+func FailedTransaction(sender, vmStatus string, events ...aptos.Event) aptos.TransactionEvent {
+	tx := UserTransaction(sender, events...)
+	tx.Success, tx.VMStatus = false, vmStatus
+	return tx
+}
+
+// ModuleEvent is a module event (event v2) of type
+// moduleAddress::module::name, with the zero GUID the fullnode gives them
+func ModuleEvent(moduleAddress, module, name string, data map[string]any) aptos.Event {
+	return aptos.Event{
+		GUID:           map[string]any{"creation_number": "0", "account_address": "0x0"},
+		SequenceNumber: "0",
+		Type:           moduleAddress + "::" + module + "::" + name,
+		Data:           data,
+	}
+}
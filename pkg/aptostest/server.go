@@ -0,0 +1,502 @@
+// Package aptostest is an in-process Aptos fullnode for integration tests,
+// in the spirit of net/http/httptest. It serves ledger info, transactions by
+// range, version and hash, account modules and view calls from a scripted
+// chain, and can throttle or fail requests, so endpoint failover, key
+// rotation, retries and the listener's poll loop run end to end without
+// testnet:
+//
+//	node := aptostest.New()
+//	defer node.Close()
+//	node.AddTransactions(aptostest.UserTransaction("0xcafe",
+//		aptostest.ModuleEvent("0xcafe", "market", "SharesMintedEvent", data)))
+//	node.Fail(aptostest.Fault{Path: "/transactions", Status: 503, Times: 2})
+//	client := aptos.NewClientWithURL(node.URL)
+//
+// Every version up to the ledger head exists: versions without a scripted
+// transaction are served as empty block metadata transactions, as a real
+// fullnode would, so the listener's gap detection doesn't trip.
+package aptostest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// Chain ID reported by default, that of a localnet
+const DefaultChainID = 4
+
+// ViewFunc answers a view call with its return values, or fails it with
+// the error as the fullnode's message
+type ViewFunc func(typeArgs, args []string) ([]any, error)
+
+// Fault fails requests whose path (after /v1) starts with Path; an empty
+// Path matches every request. The request waits Delay, e.g. to trip client
+// timeouts, then its connection is dropped (Drop), it gets Status, or, with
+// neither, it is served normally. Times limits the fault to that many
+// requests; 0 keeps it until ClearFaults.
+type Fault struct {
+	Path   string
+	Status int
+	Drop   bool
+	Times  int
+	Delay  time.Duration
+	Body   string // defaults to a fullnode-style error
+}
+
+// Server is a mock fullnode. Its methods may be called while clients are
+// talking to it.
+type Server struct {
+	// URL is the fullnode base URL including /v1, for aptos.NewClientWithURL
+	URL string
+
+	srv *httptest.Server
+
+	mu            sync.Mutex
+	chainID       uint8
+	ledgerVersion uint64
+	txs           map[uint64]aptos.TransactionEvent
+	hashes        map[string]uint64
+	modules       map[string][]string
+	views         map[string]ViewFunc
+	faults        []*Fault
+	latency       time.Duration
+	rateLimit     int
+	ratePer       time.Duration
+	windowStart   time.Time
+	windowCount   int
+	requests      map[string]int
+}
+
+// New starts a mock fullnode at version 0 with chain ID DefaultChainID
+func New() *Server {
+	s := &Server{
+		chainID:  DefaultChainID,
+		txs:      make(map[uint64]aptos.TransactionEvent),
+		hashes:   make(map[string]uint64),
+		modules:  make(map[string][]string),
+		views:    make(map[string]ViewFunc),
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1", s.handleLedgerInfo)
+	mux.HandleFunc("GET /v1/{$}", s.handleLedgerInfo)
+	mux.HandleFunc("GET /v1/transactions", s.handleTransactions)
+	mux.HandleFunc("GET /v1/transactions/by_version/{version}", s.handleByVersion)
+	mux.HandleFunc("GET /v1/transactions/by_hash/{hash}", s.handleByHash)
+	mux.HandleFunc("GET /v1/accounts/{address}/modules", s.handleModules)
+	mux.HandleFunc("POST /v1/view", s.handleView)
+
+	s.srv = httptest.NewServer(s.intercept(mux))
+	s.URL = s.srv.URL + "/v1"
+	return s
+}
+
+// Close shuts the server down, failing requests in flight
+func (s *Server) Close() {
+	s.srv.CloseClientConnections()
+	s.srv.Close()
+}
+
+// SetChainID changes the chain ID in ledger info
+func (s *Server) SetChainID(id uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chainID = id
+}
+
+// SetLedgerVersion moves the ledger head, e.g. past the last scripted
+// transaction to simulate quiet blocks. It never moves below a scripted
+// transaction.
+func (s *Server) SetLedgerVersion(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for v := range s.txs {
+		version = max(version, v)
+	}
+	s.ledgerVersion = version
+}
+
+// LedgerVersion returns the ledger head
+func (s *Server) LedgerVersion() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ledgerVersion
+}
+
+// AddTransactions appends txs to the chain. A transaction without a version
+// gets the one after the head; missing hashes and timestamps are derived
+// from the version. The head moves to the highest version added.
+func (s *Server) AddTransactions(txs ...aptos.TransactionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tx := range txs {
+		version := s.ledgerVersion + 1
+		if tx.Version != "" {
+			v, err := strconv.ParseUint(tx.Version, 10, 64)
+			if err != nil {
+				panic(fmt.Sprintf("aptostest: invalid version %q", tx.Version))
+			}
+			version = v
+		}
+		tx.Version = strconv.FormatUint(version, 10)
+		if tx.Hash == "" {
+			tx.Hash = fmt.Sprintf("0x%064x", version)
+		}
+		if tx.Timestamp == "" {
+			tx.Timestamp = timestamp(version)
+		}
+		for i := range tx.Events {
+			tx.Events[i].Version = tx.Version
+		}
+
+		s.txs[version] = tx
+		s.hashes[tx.Hash] = version
+		s.ledgerVersion = max(s.ledgerVersion, version)
+	}
+}
+
+// SetModules publishes the named modules at address
+func (s *Server) SetModules(address string, names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modules[aptos.NormalizeAddress(address)] = names
+}
+
+// HandleView answers view calls of function (address::module::name) with fn
+func (s *Server) HandleView(function string, fn ViewFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.views[normalizeFunction(function)] = fn
+}
+
+// Fail adds a fault; faults are checked in the order they were added
+func (s *Server) Fail(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault, the latency and the rate limit
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults, s.latency, s.rateLimit = nil, 0, 0
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetRateLimit answers 429 with a Retry-After header once more than
+// requests arrive within per; 0 removes the limit
+func (s *Server) SetRateLimit(requests int, per time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit, s.ratePer = requests, per
+	s.windowStart, s.windowCount = time.Time{}, 0
+}
+
+// Requests returns how many requests arrived for paths (after /v1) starting
+// with prefix, including failed ones; "" counts all
+func (s *Server) Requests(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for path, count := range s.requests {
+		if strings.HasPrefix(path, prefix) {
+			n += count
+		}
+	}
+	return n
+}
+
+// intercept counts requests and applies latency, the rate limit and faults
+// before the fullnode handlers
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+		s.mu.Lock()
+		s.requests[path]++
+		latency := s.latency
+		limited, retryAfter := s.overRateLimit()
+		fault, faulted := s.matchFault(path)
+		s.mu.Unlock()
+
+		if wait := latency + fault.Delay; wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case limited:
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded", "")
+		case faulted && fault.Drop:
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+			panic(http.ErrAbortHandler)
+		case faulted && fault.Status != 0 && fault.Body != "":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(fault.Status)
+			w.Write([]byte(fault.Body))
+		case faulted && fault.Status != 0:
+			writeError(w, fault.Status, "injected fault", "internal_error")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// overRateLimit counts a request against the rate limit. Must hold s.mu.
+func (s *Server) overRateLimit() (bool, int) {
+	if s.rateLimit <= 0 {
+		return false, 0
+	}
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.ratePer {
+		s.windowStart, s.windowCount = now, 0
+	}
+	s.windowCount++
+	if s.windowCount <= s.rateLimit {
+		return false, 0
+	}
+	retry := s.windowStart.Add(s.ratePer).Sub(now)
+	return true, max(1, int((retry+time.Second-1)/time.Second))
+}
+
+// matchFault returns the first fault for path, using up one of its Times.
+// Must hold s.mu.
+func (s *Server) matchFault(path string) (Fault, bool) {
+	for i, f := range s.faults {
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return *f, true
+	}
+	return Fault{}, false
+}
+
+func (s *Server) handleLedgerInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	head, chainID := s.ledgerVersion, s.chainID
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{
+		"chain_id":              chainID,
+		"epoch":                 "1",
+		"ledger_version":        strconv.FormatUint(head, 10),
+		"oldest_ledger_version": "0",
+		"ledger_timestamp":      timestamp(head),
+		"node_role":             "full_node",
+		"oldest_block_height":   "0",
+		"block_height":          strconv.FormatUint(head, 10),
+		"git_hash":              "aptostest",
+	})
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	start, err := strconv.ParseUint(r.URL.Query().Get("start"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid start", "invalid_input")
+		return
+	}
+	limit := uint64(25)
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.ParseUint(l, 10, 64); err != nil || limit == 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit", "invalid_input")
+			return
+		}
+	}
+	limit = min(limit, 100)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if start > s.ledgerVersion {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Ledger version(%d) not found", start), "version_not_found")
+		return
+	}
+	txs := []aptos.TransactionEvent{}
+	for v := start; v <= s.ledgerVersion && v < start+limit; v++ {
+		txs = append(txs, s.transaction(v))
+	}
+	writeJSON(w, txs)
+}
+
+func (s *Server) handleByVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(r.PathValue("version"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid version", "invalid_input")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version > s.ledgerVersion {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction not found by Transaction version(%d)", version), "transaction_not_found")
+		return
+	}
+	writeJSON(w, s.transaction(version))
+}
+
+func (s *Server) handleByHash(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version, ok := s.hashes[hash]
+	if !ok {
+		// Filler transactions' hashes are their versions
+		if v, err := strconv.ParseUint(strings.TrimPrefix(hash, "0x"), 16, 64); err == nil && v <= s.ledgerVersion {
+			version, ok = v, s.transaction(v).Hash == hash
+		}
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction not found by Transaction hash(%s)", hash), "transaction_not_found")
+		return
+	}
+	writeJSON(w, s.transaction(version))
+}
+
+func (s *Server) handleModules(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+
+	s.mu.Lock()
+	names, ok := s.modules[aptos.NormalizeAddress(address)]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Account not found by Address(%s)", address), "account_not_found")
+		return
+	}
+
+	modules := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		modules = append(modules, map[string]any{
+			"bytecode": "0x",
+			"abi":      map[string]any{"address": address, "name": name},
+		})
+	}
+	writeJSON(w, modules)
+}
+
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Function      string   `json:"function"`
+		TypeArguments []string `json:"type_arguments"`
+		Arguments     []string `json:"arguments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+
+	s.mu.Lock()
+	fn, ok := s.views[normalizeFunction(req.Function)]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Function %s not found", req.Function), "invalid_input")
+		return
+	}
+
+	result, err := fn(req.TypeArguments, req.Arguments)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	if result == nil {
+		result = []any{}
+	}
+	writeJSON(w, result)
+}
+
+// transaction returns the scripted transaction at version, or an empty
+// block metadata transaction. Must hold s.mu.
+func (s *Server) transaction(version uint64) aptos.TransactionEvent {
+	if tx, ok := s.txs[version]; ok {
+		return tx
+	}
+	return aptos.TransactionEvent{
+		Version:   strconv.FormatUint(version, 10),
+		Hash:      fmt.Sprintf("0x%064x", version),
+		Success:   true,
+		VMStatus:  "Executed successfully",
+		Events:    []aptos.Event{},
+		Timestamp: timestamp(version),
+		Type:      "block_metadata_transaction",
+	}
+}
+
+// Versions returns the versions of the scripted transactions, in order
+func (s *Server) Versions() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make([]uint64, 0, len(s.txs))
+	for v := range s.txs {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// timestamp is a deterministic block time for version, one second per
+// version from 2024-01-01, in microseconds like the fullnode's
+func timestamp(version uint64) string {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMicro()
+	return strconv.FormatInt(base+int64(version)*1_000_000, 10)
+}
+
+// normalizeFunction puts the address of address::module::name in long form
+func normalizeFunction(function string) string {
+	address, rest, ok := strings.Cut(function, "::")
+	if !ok {
+		return function
+	}
+	if long := aptos.NormalizeAddress(address); long != "" {
+		return long + "::" + rest
+	}
+	return function
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers like the fullnode does on errors
+func writeError(w http.ResponseWriter, status int, message, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":       message,
+		"error_code":    code,
+		"vm_error_code": nil,
+	})
+}
@@ -0,0 +1,189 @@
+// Package auth is the HTTP middleware that guards the service's routes with
+// bearer tokens. Each route gets a policy from the longest matching rule:
+// public routes need nothing, token routes any configured API token (or the
+// admin token), consumer routes also take an issued consumer key for reads,
+// and admin routes the admin token.
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Route policies
+const (
+	Public   = "public"
+	Consumer = "consumer"
+	Token    = "token"
+	Admin    = "admin"
+)
+
+// Rule applies Policy to requests whose path is Prefix or below it. An empty
+// Method matches every method.
+type Rule struct {
+	Method string
+	Prefix string
+	Policy string
+}
+
+type Config struct {
+	APITokens  []string
+	AdminToken string
+	Default    string // policy for routes no rule matches
+	Rules      []Rule
+	// Keys are issued consumer keys. They pass consumer routes for GET and
+	// HEAD requests only, so consumers can read the data they were given
+	// but not operational routes, and can't trigger anything.
+	Keys Keys
+}
+
+// Keys looks up issued consumer keys and counts their requests
+type Keys interface {
+	Lookup(token string) (id int64, ok bool)
+	Record(id int64, status int)
+}
+
+// ParseRules parses a comma-separated rule list, each "[METHOD ]/prefix=policy",
+// e.g. "GET /markets=public,/logs=admin".
+func ParseRules(s string) ([]Rule, error) {
+	rules := []Rule{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, policy, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("auth rule %q has no policy", entry)
+		}
+		rule := Rule{Prefix: strings.TrimSpace(route), Policy: strings.TrimSpace(policy)}
+		if method, prefix, ok := strings.Cut(rule.Prefix, " "); ok {
+			rule.Method, rule.Prefix = strings.ToUpper(method), strings.TrimSpace(prefix)
+		}
+
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			return nil, fmt.Errorf("auth rule %q: path must start with /", entry)
+		}
+		if !validPolicy(rule.Policy) {
+			return nil, fmt.Errorf("auth rule %q: policy must be public, consumer, token or admin", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func validPolicy(policy string) bool {
+	return policy == Public || policy == Consumer || policy == Token || policy == Admin
+}
+
+// New returns the middleware. Token and consumer routes stay open while no
+// token at all is configured, so existing deployments keep working until they opt in;
+// admin routes are refused until ADMIN_TOKEN is set.
+func New(cfg Config) fiber.Handler {
+	if cfg.Default == "" {
+		cfg.Default = Token
+	}
+	enabled := cfg.AdminToken != "" || len(cfg.APITokens) > 0
+	if !enabled {
+		log.Warn().Msg("⚠️  No API_TOKENS or ADMIN_TOKEN set, token routes are unauthenticated")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Routing is case-insensitive, so matching must be too
+		policy := cfg.Policy(c.Method(), strings.ToLower(c.Path()))
+		if policy == Public || ((policy == Token || policy == Consumer) && !enabled) {
+			return c.Next()
+		}
+		if policy == Admin && cfg.AdminToken == "" {
+			return c.Status(403).JSON(fiber.Map{"error": "admin routes disabled, set ADMIN_TOKEN"})
+		}
+
+		token := credential(c)
+		if matches(token, cfg.AdminToken) || (policy != Admin && matchesAny(token, cfg.APITokens)) {
+			return c.Next()
+		}
+		if policy == Consumer && cfg.Keys != nil && (c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead) {
+			if id, ok := cfg.Keys.Lookup(token); ok {
+				err := c.Next()
+				cfg.Keys.Record(id, status(c, err))
+				return err
+			}
+		}
+
+		log.Warn().
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Str("ip", c.IP()).
+			Str("policy", policy).
+			Msg("🔒 Unauthorized request")
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+}
+
+// Policy is the policy of a request to path (lowercase), from the longest
+// matching prefix. At equal length a rule for the method wins over one for
+// every method, and otherwise the later rule wins, so configured rules
+// override built-in ones.
+func (cfg Config) Policy(method, path string) string {
+	policy, best, bestMethod := cfg.Default, -1, false
+	for _, rule := range cfg.Rules {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+		if !underPrefix(path, strings.ToLower(rule.Prefix)) {
+			continue
+		}
+		n, hasMethod := len(strings.TrimSuffix(rule.Prefix, "/")), rule.Method != ""
+		if n > best || (n == best && (hasMethod || !bestMethod)) {
+			policy, best, bestMethod = rule.Policy, n, hasMethod
+		}
+	}
+	return policy
+}
+
+// underPrefix matches whole path segments, so /admin covers /admin/jobs but
+// not /administrator
+func underPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// status is the response status of a handled request. An error is answered
+// by the app's error handler after the middleware returns.
+func status(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// credential reads "Authorization: Bearer <token>" or "X-API-Key: <token>"
+func credential(c *fiber.Ctx) string {
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(c.Get("X-API-Key"))
+}
+
+func matches(token, want string) bool {
+	return token != "" && want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+func matchesAny(token string, tokens []string) bool {
+	for _, want := range tokens {
+		if matches(token, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// keys accepts a single consumer key and counts its requests
+type keys struct {
+	key      string
+	requests int
+}
+
+func (k *keys) Lookup(token string) (int64, bool) { return 1, token == k.key }
+func (k *keys) Record(id int64, status int)       { k.requests++ }
+
+func TestConsumerKeys(t *testing.T) {
+	consumer := &keys{key: "vfk_consumer"}
+	app := fiber.New()
+	app.Use(New(Config{
+		APITokens:  []string{"api-token"},
+		AdminToken: "admin-token",
+		Rules: []Rule{
+			{Prefix: "/markets", Policy: Consumer},
+			{Prefix: "/admin", Policy: Admin},
+		},
+		Keys: consumer,
+	}))
+	app.All("/*", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"key reads consumer route", "GET", "/markets/0x1", "vfk_consumer", 200},
+		{"key can't write consumer route", "POST", "/markets/0x1", "vfk_consumer", 401},
+		{"key can't read token route", "GET", "/logs", "vfk_consumer", 401},
+		{"key can't read admin route", "GET", "/admin/jobs", "vfk_consumer", 401},
+		{"api token reads consumer route", "GET", "/markets", "api-token", 200},
+		{"api token reads token route", "GET", "/logs", "api-token", 200},
+		{"api token can't read admin route", "GET", "/admin/jobs", "api-token", 401},
+		{"admin token reads admin route", "GET", "/admin/jobs", "admin-token", 200},
+		{"unknown key", "GET", "/markets", "vfk_other", 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+	if consumer.requests != 1 {
+		t.Errorf("recorded %d key requests, want 1", consumer.requests)
+	}
+}
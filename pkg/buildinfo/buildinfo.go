@@ -0,0 +1,58 @@
+// Package buildinfo exposes build metadata injected at link time:
+//
+//	go build -ldflags "-X github.com/verifi-protocol/pkg/buildinfo.Version=v1.2.0 \
+//	  -X github.com/verifi-protocol/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/verifi-protocol/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set via -ldflags
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type Info struct {
+	Service       string          `json:"service"`
+	Version       string          `json:"version"`
+	Commit        string          `json:"commit"`
+	BuildTime     string          `json:"build_time"`
+	GoVersion     string          `json:"go_version"`
+	SchemaVersion string          `json:"schema_version"`
+	Features      map[string]bool `json:"features"`
+}
+
+// Get returns the build metadata for service. When the binary wasn't built
+// with ldflags, the commit falls back to the VCS stamp added by the go tool.
+func Get(service string) Info {
+	info := Info{
+		Service:   service,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Features:  map[string]bool{},
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "unknown" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "unknown" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,172 @@
+// Package eventmap normalizes module events emitted by older contract
+// versions to the schema the handlers decode, so one set of handlers serves
+// every deployed version. Renamed events and fields are configured as rules;
+// an event split in two is configured as two rules for the same old event.
+package eventmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/verifi-protocol/pkg/aptos"
+)
+
+// SplitStride separates the event indexes of the parts of a split event:
+// part k of the event at index i is indexed i + k*SplitStride, so each part
+// is claimed in processed_events on its own. Part 0 keeps i.
+const SplitStride = 1 << 20
+
+// FieldRenames apply to every event without a rule, and after its rule's own
+// renames to every event with one. They cover drift that isn't specific to
+// an event, like the v1 module's market_obj_addr.
+var FieldRenames = map[string]string{
+	"market_obj_addr": "market_address",
+}
+
+// Rule rewrites an event named From into one named To, renaming Fields (old
+// name to current name). From is an event name, e.g. SharesMinted, or
+// module::Event to only match one module.
+type Rule struct {
+	From   string            `json:"from"`
+	To     string            `json:"to"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Mapper applies rules to transactions. A nil Mapper only applies
+// FieldRenames.
+type Mapper struct {
+	all   []Rule
+	rules map[string][]Rule
+}
+
+// New builds a mapper from rules, in order
+func New(rules []Rule) (*Mapper, error) {
+	m := &Mapper{all: rules, rules: make(map[string][]Rule)}
+	for i, rule := range rules {
+		if rule.From == "" || rule.To == "" {
+			return nil, fmt.Errorf("rule %d needs from and to", i+1)
+		}
+		if strings.Contains(rule.To, "::") {
+			return nil, fmt.Errorf("rule %d: to %q must be an event name without its module", i+1, rule.To)
+		}
+		m.rules[rule.From] = append(m.rules[rule.From], rule)
+	}
+	return m, nil
+}
+
+// Load reads a JSON array of rules from path
+func Load(path string) (*Mapper, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", path, err)
+	}
+	return New(rules)
+}
+
+// FromEnv loads the rules file named by EVENT_MAPPINGS. Without it only
+// FieldRenames apply.
+func FromEnv() (*Mapper, error) {
+	path := os.Getenv("EVENT_MAPPINGS")
+	if path == "" {
+		return nil, nil
+	}
+	m, err := Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("EVENT_MAPPINGS: %w", err)
+	}
+	return m, nil
+}
+
+// Rules returns the configured rules, in order
+func (m *Mapper) Rules() []Rule {
+	if m == nil {
+		return []Rule{}
+	}
+	return m.all
+}
+
+// Normalize returns tx with its events in the current schema. Events are
+// copied when rewritten; tx itself is left alone. A split event's parts
+// follow each other in Events, with EventIndexes set accordingly.
+func (m *Mapper) Normalize(tx aptos.TransactionEvent) aptos.TransactionEvent {
+	var events []aptos.Event
+	var indexes []int
+	split := false
+	for i, event := range tx.Events {
+		index := tx.EventIndex(i)
+		rules := m.match(event.Type)
+		if len(rules) == 0 {
+			events = append(events, renamed(event, "", nil))
+			indexes = append(indexes, index)
+			continue
+		}
+		split = split || len(rules) > 1
+		for k, rule := range rules {
+			events = append(events, renamed(event, rule.To, rule.Fields))
+			indexes = append(indexes, index+k*SplitStride)
+		}
+	}
+
+	tx.Events = events
+	if split || tx.EventIndexes != nil {
+		tx.EventIndexes = indexes
+	}
+	return tx
+}
+
+// match returns the rules for an event type, module-qualified ones first
+func (m *Mapper) match(eventType string) []Rule {
+	if m == nil {
+		return nil
+	}
+	tag, err := aptos.ParseStructTag(eventType)
+	if err != nil {
+		return nil
+	}
+	if rules, ok := m.rules[tag.Module+"::"+tag.Name]; ok {
+		return rules
+	}
+	return m.rules[tag.Name]
+}
+
+// renamed returns event as name (unchanged when empty) with fields and then
+// FieldRenames applied. A rename never overwrites a field the event already
+// has under its current name.
+func renamed(event aptos.Event, name string, fields map[string]string) aptos.Event {
+	if name != "" {
+		event.Type = event.Type[:strings.LastIndex(event.Type, "::")+2] + name
+	}
+
+	var data map[string]interface{}
+	for _, renames := range []map[string]string{fields, FieldRenames} {
+		for from, to := range renames {
+			v, ok := event.Data[from]
+			if data != nil {
+				v, ok = data[from]
+			}
+			if !ok {
+				continue
+			}
+			if data == nil {
+				data = make(map[string]interface{}, len(event.Data))
+				for k, v := range event.Data {
+					data[k] = v
+				}
+			}
+			if _, exists := data[to]; !exists {
+				data[to] = v
+			}
+			delete(data, from)
+		}
+	}
+	if data != nil {
+		event.Data = data
+	}
+	return event
+}
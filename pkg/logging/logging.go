@@ -0,0 +1,91 @@
+// Package logging sets up the global zerolog logger. The console format is
+// for people at a terminal; the json format writes one object per line with
+// service, instance and version fields for log aggregators.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	Console = "console"
+	JSON    = "json"
+)
+
+// Meta identifies the process in every JSON log line
+type Meta struct {
+	Service  string
+	Instance string
+	Version  string
+}
+
+// Setup points the global logger at stderr in format ("" means console),
+// plus any extra writers, e.g. the in-memory log buffer
+func Setup(format string, meta Meta, extra ...io.Writer) error {
+	var out io.Writer
+	switch format {
+	case "", Console:
+		zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+		out = zerolog.ConsoleWriter{Out: os.Stderr}
+	case JSON:
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+		out = os.Stderr
+	default:
+		return fmt.Errorf("LOG_FORMAT must be console or json")
+	}
+
+	if len(extra) > 0 {
+		out = zerolog.MultiLevelWriter(append([]io.Writer{out}, extra...)...)
+	}
+
+	logger := zerolog.New(out).With().Timestamp()
+	if format == JSON {
+		logger = logger.
+			Str("service", meta.Service).
+			Str("instance", meta.Instance).
+			Str("version", meta.Version)
+	}
+	log.Logger = logger.Logger()
+	return nil
+}
+
+// Requests returns the access log middleware: Fiber's plain-text logger on
+// the console, or one zerolog line per request in json mode, so access logs
+// parse like everything else
+func Requests(format string) fiber.Handler {
+	if format != JSON {
+		return logger.New()
+	}
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler hasn't run yet, so take the status it will set
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		log.Info().
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("ip", c.IP()).
+			Err(err).
+			Msg("request")
+		return err
+	}
+}
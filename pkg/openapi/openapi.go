@@ -0,0 +1,360 @@
+// Package openapi builds the services' OpenAPI 3 documents from their typed
+// request and response structs. Each service lists its routes as
+// Operations; the schemas are derived by reflection from the json tags, so
+// the document follows the structs the handlers encode.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version is the OpenAPI version of the documents
+const Version = "3.0.3"
+
+// Operation is one route. Response is a value of the success body's type
+// (nil for none), Body of the request body's; Status defaults to 200.
+type Operation struct {
+	Method   string
+	Path     string // Fiber syntax, e.g. /markets/:address
+	Tag      string
+	Summary  string
+	Query    []Param
+	Body     any
+	Response any
+	Status   int
+	// ContentType replaces JSON for a streamed Response, which is then
+	// described as a string
+	ContentType string
+}
+
+// Param is a query parameter. Type is string unless set to integer,
+// number or boolean.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// Error is the body of every error response
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*pathItem `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type pathItem struct {
+	Tags        []string               `json:"tags,omitempty"`
+	Summary     string                 `json:"summary,omitempty"`
+	OperationID string                 `json:"operationId"`
+	Parameters  []parameter            `json:"parameters,omitempty"`
+	RequestBody *body                  `json:"requestBody,omitempty"`
+	Responses   map[string]response    `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of the OpenAPI schema object the structs need
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Policy reports a route's auth policy ("public", "consumer", "token" or
+// "admin"), so the document can say which routes need which credential
+type Policy func(method, path string) string
+
+// Build returns the document of ops. policy may be nil when every route is
+// open.
+func Build(title, version string, ops []Operation, policy Policy) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]map[string]*pathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]securityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+				"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+	g := &generator{schemas: doc.Components.Schemas, names: map[string]reflect.Type{}}
+	errorSchema := g.schema(reflect.TypeOf(Error{}))
+
+	for _, op := range ops {
+		path, params := pathParams(op.Path)
+		item := &pathItem{
+			Summary:     op.Summary,
+			OperationID: operationID(op.Method, op.Path),
+			Parameters:  params,
+			Responses:   map[string]response{},
+		}
+		if op.Tag != "" {
+			item.Tags = []string{op.Tag}
+		}
+		for _, q := range op.Query {
+			typ := q.Type
+			if typ == "" {
+				typ = "string"
+			}
+			item.Parameters = append(item.Parameters, parameter{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: typ},
+			})
+		}
+		if op.Body != nil {
+			item.RequestBody = &body{Required: true, Content: map[string]mediaType{
+				fiber.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(op.Body))},
+			}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = 200
+		}
+		ok := response{Description: "OK"}
+		switch {
+		case op.ContentType != "":
+			ok.Content = map[string]mediaType{op.ContentType: {Schema: &Schema{Type: "string"}}}
+		case op.Response != nil:
+			ok.Content = map[string]mediaType{fiber.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(op.Response))}}
+		}
+		item.Responses[fmt.Sprint(status)] = ok
+		item.Responses["default"] = response{
+			Description: "Error",
+			Content:     map[string]mediaType{fiber.MIMEApplicationJSON: {Schema: errorSchema}},
+		}
+
+		if policy != nil {
+			switch policy(op.Method, strings.ToLower(path)) {
+			case "public":
+				item.Security = &[]map[string][]string{}
+			case "admin":
+				item.Summary = strings.TrimSpace(item.Summary + " (admin token)")
+				fallthrough
+			default:
+				item.Security = &[]map[string][]string{{"bearer": {}}, {"apiKey": {}}}
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*pathItem{}
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = item
+	}
+	return doc
+}
+
+// Handler serves doc as JSON. It is encoded once.
+func Handler(doc *Document) fiber.Handler {
+	raw, err := json.Marshal(doc)
+	return func(c *fiber.Ctx) error {
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to encode openapi document"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(raw)
+	}
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)\??`)
+
+// pathParams turns /markets/:address into /markets/{address} and its
+// parameters
+func pathParams(path string) (string, []parameter) {
+	var params []parameter
+	for _, m := range routeParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return routeParam.ReplaceAllString(path, "{$1}"), params
+}
+
+// operationID is e.g. getMarketsByAddress for GET /markets/:address
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' }) {
+		if name, ok := strings.CutPrefix(part, ":"); ok {
+			part = "By" + strings.TrimSuffix(name, "?")
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// generator derives schemas, adding named structs to schemas once
+type generator struct {
+	schemas map[string]*Schema
+	names   map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			return &Schema{AllOf: []*Schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := g.name(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = &Schema{} // placeholder for recursive types
+			*g.schemas[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interface{} and anything else: any value
+	return &Schema{}
+}
+
+// name is the component name of t: its type name, qualified by its package
+// when another package has a type of the same name
+func (g *generator) name(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := g.names[name]; ok && other != t {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[name] = t
+	return name
+}
+
+// object is a struct's schema, with embedded structs' fields inlined as
+// encoding/json does. Fields without omitempty are required.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, s)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(embedded, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var field *Schema
+		if strings.Contains(","+opts+",", ",string,") {
+			field = &Schema{Type: "string"}
+		} else {
+			field = g.schema(f.Type)
+		}
+		s.Properties[name] = field
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package page is the query layer of the services' list endpoints: limit
+// caps, whitelisted sorts, opaque cursors and filter parsing, so every list
+// pages and fails the same way.
+//
+// Pages are keyset paginated. A cursor holds the sort keys of the last row
+// served, and the next page starts after them with a row comparison on an
+// index, so a deep page costs the same as the first one; there are no
+// offsets.
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Options describe what a list endpoint accepts
+type Options struct {
+	DefaultLimit int
+	MaxLimit     int
+	// Sorts whitelists ?sort; the first is the default. Empty when the list
+	// has a single order.
+	Sorts []string
+	// Ascending is the default direction; lists are newest or largest first
+	// otherwise
+	Ascending bool
+	// Keys is the number of sort keys in a cursor: the sort column and the
+	// unique columns that break its ties
+	Keys int
+}
+
+// Params are the paging parameters of one request
+type Params struct {
+	Limit      int
+	Sort       string
+	Descending bool
+	// Sort keys of the previous page's last row; nil on the first page
+	After []string
+}
+
+// cursor is the decoded form of a cursor. Sort and direction are kept so a
+// cursor can't be replayed under another order.
+type cursor struct {
+	Sort       string   `json:"s,omitempty"`
+	Descending bool     `json:"d,omitempty"`
+	Keys       []string `json:"k"`
+}
+
+// Parse reads ?limit, ?sort and ?cursor. limit must be a positive integer
+// and is capped at MaxLimit. sort is a whitelisted name, descending with a
+// leading "-". cursor must come from a page of the same sort. An error is a
+// malformed parameter, to be answered with 400.
+func Parse(c *fiber.Ctx, opts Options) (Params, error) {
+	p := Params{Limit: opts.DefaultLimit, Descending: !opts.Ascending}
+
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return Params{}, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = n
+	}
+	p.Limit = min(p.Limit, opts.MaxLimit)
+
+	if len(opts.Sorts) > 0 {
+		p.Sort = opts.Sorts[0]
+		if s := c.Query("sort"); s != "" {
+			name, descending := strings.CutPrefix(s, "-")
+			if !slices.Contains(opts.Sorts, name) {
+				return Params{}, fmt.Errorf("sort must be one of %s, with a leading - for descending", strings.Join(opts.Sorts, ", "))
+			}
+			p.Sort, p.Descending = name, descending
+		}
+	}
+
+	if s := c.Query("cursor"); s != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(s)
+		var cur cursor
+		if err == nil {
+			err = json.Unmarshal(raw, &cur)
+		}
+		if err != nil || len(cur.Keys) != opts.Keys {
+			return Params{}, fmt.Errorf("invalid cursor")
+		}
+		if cur.Sort != p.Sort || cur.Descending != p.Descending {
+			return Params{}, fmt.Errorf("cursor belongs to another sort")
+		}
+		p.After = cur.Keys
+	}
+	return p, nil
+}
+
+// Next returns the cursor of the page after one of n rows whose last row has
+// keys, or nil when the page wasn't full and so was the last
+func (p Params) Next(n int, keys ...string) *string {
+	if n < p.Limit || len(keys) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(cursor{Sort: p.Sort, Descending: p.Descending, Keys: keys})
+	s := base64.RawURLEncoding.EncodeToString(raw)
+	return &s
+}
+
+// Column is a sort key column and the Postgres type its cursor value is cast
+// to
+type Column struct {
+	Expr string
+	Type string
+}
+
+// Keyset returns the condition that starts a page after p.After on columns,
+// as a row comparison whose parameters are numbered from $first, and their
+// values. It is TRUE on the first page. columns are the ORDER BY (see
+// Order), one per cursor key.
+func (p Params) Keyset(columns []Column, first int) (string, []any) {
+	if p.After == nil {
+		return "TRUE", nil
+	}
+
+	exprs, params, args := make([]string, len(columns)), make([]string, len(columns)), make([]any, len(columns))
+	for i, col := range columns {
+		exprs[i] = col.Expr
+		params[i] = fmt.Sprintf("$%d::%s", first+i, col.Type)
+		args[i] = p.After[i]
+	}
+	op := ">"
+	if p.Descending {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), op, strings.Join(params, ", ")), args
+}
+
+// Order is the ORDER BY list of columns in p's direction
+func (p Params) Order(columns []Column) string {
+	dir := " ASC"
+	if p.Descending {
+		dir = " DESC"
+	}
+	order := make([]string, len(columns))
+	for i, col := range columns {
+		order[i] = col.Expr + dir
+	}
+	return strings.Join(order, ", ")
+}
+
+// TimeKey is the cursor key of a timestamp column
+func TimeKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Time reads an optional RFC 3339 or epoch timestamp filter; the zero time
+// when absent
+func Time(c *fiber.Ctx, name string) (time.Time, error) {
+	s := c.Query(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := timeconv.Parse(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 or epoch timestamp", name)
+	}
+	return t, nil
+}
+
+// OneOf reads an optional filter restricted to allowed values; "" when absent
+func OneOf(c *fiber.Ctx, name string, allowed ...string) (string, error) {
+	s := c.Query(name)
+	if s != "" && !slices.Contains(allowed, s) {
+		return "", fmt.Errorf("%s must be one of %s", name, strings.Join(allowed, ", "))
+	}
+	return s, nil
+}
@@ -0,0 +1,333 @@
+// Package parquet writes flat Parquet files a row group at a time, so an
+// export can be streamed in pages without holding the whole file.
+//
+// It covers what the exports need and nothing more: required and optional
+// columns of strings, 64-bit integers, doubles and timestamps, PLAIN
+// encoded, one Snappy compressed data page per column chunk and no
+// statistics. Any Parquet reader (pandas, DuckDB, Spark) can load the
+// result.
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// Type is a column's value type
+type Type int
+
+const (
+	String    Type = iota // UTF-8 text
+	Int64                 // signed 64-bit integer
+	Double                // 64-bit float
+	Timestamp             // microseconds since the epoch, UTC
+)
+
+// Column is one column of a file. Optional columns take nil values.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Physical types, converted types, encodings and codecs of the format
+const (
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecSnappy = 1
+	pageData    = 0
+)
+
+const magic = "PAR1"
+
+// chunk holds the buffered values of one column of the row group being
+// written, PLAIN encoded, and its definition levels (1 set, 0 null)
+type chunk struct {
+	values []byte
+	levels []byte
+}
+
+type columnMeta struct {
+	offset       int64
+	values       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroup struct {
+	columns []columnMeta
+	bytes   int64
+	rows    int64
+}
+
+// Writer writes a Parquet file to w. Rows are buffered until Flush writes
+// them as a row group; Close writes the last one and the footer.
+type Writer struct {
+	w       io.Writer
+	columns []Column
+	chunks  []chunk
+	rows    int64
+	groups  []rowGroup
+	offset  int64
+	err     error
+}
+
+// NewWriter returns a writer of a file with columns. Nothing is written
+// until the first Flush or Close.
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{w: w, columns: columns, chunks: make([]chunk, len(columns))}
+}
+
+// Write buffers a row, one value per column: a string, an int64 (or int),
+// a float64 or a time.Time matching the column's type, or nil in optional
+// columns
+func (w *Writer) Write(row ...any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(row), len(w.columns))
+	}
+
+	// Check every value before buffering any, so a bad row leaves the
+	// columns the same length
+	for i, v := range row {
+		if err := w.columns[i].check(v); err != nil {
+			return err
+		}
+	}
+	for i, v := range row {
+		c, col := &w.chunks[i], w.columns[i]
+		if col.Optional {
+			if v == nil {
+				c.levels = append(c.levels, 0)
+				continue
+			}
+			c.levels = append(c.levels, 1)
+		}
+		c.values = col.appendPlain(c.values, v)
+	}
+	w.rows++
+	return nil
+}
+
+func (col Column) check(v any) error {
+	var ok bool
+	switch v.(type) {
+	case nil:
+		ok = col.Optional
+	case string:
+		ok = col.Type == String
+	case int64, int:
+		ok = col.Type == Int64
+	case float64:
+		ok = col.Type == Double
+	case time.Time:
+		ok = col.Type == Timestamp
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %s can't hold %T", col.Name, v)
+	}
+	return nil
+}
+
+func (col Column) appendPlain(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case string:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		return append(b, v...)
+	case int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v))
+	case int:
+		return binary.LittleEndian.AppendUint64(b, uint64(v))
+	case float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	case time.Time:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.UnixMicro()))
+	}
+	return b
+}
+
+// Flush writes the buffered rows as a row group
+func (w *Writer) Flush() error {
+	if w.err != nil || w.rows == 0 {
+		return w.err
+	}
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+
+	group := rowGroup{rows: w.rows, columns: make([]columnMeta, len(w.columns))}
+	for i, col := range w.columns {
+		c := &w.chunks[i]
+		var page []byte
+		if col.Optional {
+			levels := encodeLevels(c.levels)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+			page = append(page, levels...)
+		}
+		page = append(page, c.values...)
+		compressed := snappy.Encode(nil, page)
+
+		var header encoder
+		header.begin()
+		header.i32(1, pageData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(compressed)))
+		header.structField(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.end()
+		header.end()
+
+		group.columns[i] = columnMeta{
+			offset:       w.offset,
+			values:       w.rows,
+			uncompressed: int64(len(header.buf) + len(page)),
+			compressed:   int64(len(header.buf) + len(compressed)),
+		}
+		group.bytes += group.columns[i].uncompressed
+		w.write(header.buf)
+		w.write(compressed)
+
+		c.values, c.levels = c.values[:0], c.levels[:0]
+	}
+	w.rows = 0
+	w.groups = append(w.groups, group)
+	return w.err
+}
+
+// Close writes the remaining rows and the footer. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+
+	footer := w.footer()
+	w.write(footer)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	w.write([]byte(magic))
+	return w.err
+}
+
+// footer is the FileMetaData of the row groups written
+func (w *Writer) footer() []byte {
+	var rows int64
+	for _, g := range w.groups {
+		rows += g.rows
+	}
+
+	var e encoder
+	e.begin()
+	e.i32(1, 1)
+	e.list(2, thriftStruct, len(w.columns)+1)
+	e.begin()
+	e.string(4, "schema")
+	e.i32(5, int32(len(w.columns)))
+	e.end()
+	for _, col := range w.columns {
+		physical, converted := col.types()
+		repetition := int32(repetitionRequired)
+		if col.Optional {
+			repetition = repetitionOptional
+		}
+		e.begin()
+		e.i32(1, physical)
+		e.i32(3, repetition)
+		e.string(4, col.Name)
+		if converted >= 0 {
+			e.i32(6, converted)
+		}
+		e.end()
+	}
+	e.i64(3, rows)
+	e.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		e.begin()
+		e.list(1, thriftStruct, len(g.columns))
+		for i, m := range g.columns {
+			col := w.columns[i]
+			physical, _ := col.types()
+			e.begin()
+			e.i64(2, m.offset)
+			e.structField(3)
+			e.i32(1, physical)
+			e.list(2, thriftI32, 2)
+			e.listI32(encodingPlain)
+			e.listI32(encodingRLE)
+			e.list(3, thriftBinary, 1)
+			e.listString(col.Name)
+			e.i32(4, codecSnappy)
+			e.i64(5, m.values)
+			e.i64(6, m.uncompressed)
+			e.i64(7, m.compressed)
+			e.i64(9, m.offset)
+			e.end()
+			e.end()
+		}
+		e.i64(2, g.bytes)
+		e.i64(3, g.rows)
+		e.end()
+	}
+	e.string(6, "verifi-protocol parquet")
+	e.end()
+	return e.buf
+}
+
+// types are the column's physical and converted type, -1 for none
+func (col Column) types() (int32, int32) {
+	switch col.Type {
+	case Int64:
+		return physicalInt64, -1
+	case Double:
+		return physicalDouble, -1
+	case Timestamp:
+		return physicalInt64, convertedTimestampMicros
+	default:
+		return physicalByteArray, convertedUTF8
+	}
+}
+
+// encodeLevels writes definition levels (bit width 1) as RLE runs of the
+// RLE/bit-packing hybrid encoding
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+func (w *Writer) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	w.err = err
+}
@@ -0,0 +1,202 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// decoder reads Thrift compact structs into maps of field id to value:
+// int64 for integers, []byte for binaries, []any for lists and map[int16]any
+// for structs
+type decoder struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (d *decoder) byte() byte {
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.t.Fatal("bad varint")
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *decoder) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return d.int()
+	case thriftBinary:
+		n := d.uvarint()
+		b := d.buf[:n]
+		d.buf = d.buf[n:]
+		return b
+	case thriftList:
+		h := d.byte()
+		n, elem := uint64(h>>4), h&0x0f
+		if n == 15 {
+			n = d.uvarint()
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = d.value(elem)
+		}
+		return list
+	case thriftStruct:
+		s := map[int16]any{}
+		var last int16
+		for {
+			h := d.byte()
+			if h == 0 {
+				return s
+			}
+			id := last + int16(h>>4)
+			if h>>4 == 0 {
+				id = int16(d.int())
+			}
+			s[id] = d.value(h & 0x0f)
+			last = id
+		}
+	}
+	d.t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func readFile(t *testing.T, file []byte) map[int16]any {
+	t.Helper()
+	if !bytes.HasPrefix(file, []byte(magic)) || !bytes.HasSuffix(file, []byte(magic)) {
+		t.Fatal("missing PAR1 magic")
+	}
+	n := binary.LittleEndian.Uint32(file[len(file)-8:])
+	footer := file[len(file)-8-int(n) : len(file)-8]
+	d := &decoder{t: t, buf: footer}
+	meta := d.value(thriftStruct).(map[int16]any)
+	if len(d.buf) != 0 {
+		t.Fatalf("%d bytes left after footer", len(d.buf))
+	}
+	return meta
+}
+
+// readPage decodes the data page at offset, returning its header and
+// uncompressed body
+func readPage(t *testing.T, file []byte, offset int64) (map[int16]any, []byte) {
+	t.Helper()
+	d := &decoder{t: t, buf: file[offset:]}
+	header := d.value(thriftStruct).(map[int16]any)
+	compressed := d.buf[:header[3].(int64)]
+	page, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(page)) != header[2].(int64) {
+		t.Fatalf("page is %d bytes, header says %d", len(page), header[2])
+	}
+	return header, page
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []Column{
+		{Name: "id", Type: String},
+		{Name: "count", Type: Int64},
+		{Name: "price", Type: Double, Optional: true},
+		{Name: "at", Type: Timestamp},
+	})
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	if err := w.Write("a", int64(1), 0.5, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("b", 2, nil, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("c", int64(3), nil, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("d", "4", nil, at); err == nil {
+		t.Fatal("wrote a string to an int64 column")
+	}
+	if err := w.Write(nil, int64(4), nil, at); err == nil {
+		t.Fatal("wrote nil to a required column")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := buf.Bytes()
+	meta := readFile(t, file)
+	if meta[3].(int64) != 3 {
+		t.Errorf("num_rows = %d, want 3", meta[3])
+	}
+	schema := meta[2].([]any)
+	if len(schema) != 5 || schema[0].(map[int16]any)[5].(int64) != 4 {
+		t.Fatalf("schema = %v", schema)
+	}
+	if price := schema[3].(map[int16]any); string(price[4].([]byte)) != "price" || price[3].(int64) != repetitionOptional {
+		t.Errorf("price schema = %v", price)
+	}
+	groups := meta[4].([]any)
+	if len(groups) != 2 {
+		t.Fatalf("%d row groups, want 2", len(groups))
+	}
+
+	first := groups[0].(map[int16]any)
+	if first[3].(int64) != 2 {
+		t.Errorf("first row group has %d rows, want 2", first[3])
+	}
+	columns := first[1].([]any)
+	columnMeta := func(i int) map[int16]any { return columns[i].(map[int16]any)[3].(map[int16]any) }
+
+	_, ids := readPage(t, file, columnMeta(0)[9].(int64))
+	if want := []byte("\x01\x00\x00\x00a\x01\x00\x00\x00b"); !bytes.Equal(ids, want) {
+		t.Errorf("id page = %q, want %q", ids, want)
+	}
+
+	header, prices := readPage(t, file, columnMeta(2)[9].(int64))
+	if n := header[5].(map[int16]any)[1].(int64); n != 2 {
+		t.Errorf("price page has %d values, want 2", n)
+	}
+	// Definition levels: a 4 byte length, then runs of one set and one null
+	levels := []byte{4, 0, 0, 0, 1 << 1, 1, 1 << 1, 0}
+	if !bytes.HasPrefix(prices, levels) {
+		t.Fatalf("price levels = %v", prices)
+	}
+	if v := math.Float64frombits(binary.LittleEndian.Uint64(prices[len(levels):])); v != 0.5 {
+		t.Errorf("price = %v, want 0.5", v)
+	}
+
+	_, times := readPage(t, file, columnMeta(3)[9].(int64))
+	if v := int64(binary.LittleEndian.Uint64(times)); v != at.UnixMicro() {
+		t.Errorf("at = %d, want %d", v, at.UnixMicro())
+	}
+}
+
+func TestEmptyFile(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []Column{{Name: "id", Type: String}})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	meta := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 0 || len(meta[4].([]any)) != 0 {
+		t.Errorf("empty file metadata = %v", meta)
+	}
+}
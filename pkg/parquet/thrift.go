@@ -0,0 +1,94 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol types used by the file metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// encoder writes Thrift compact protocol structs. Field ids are written as
+// deltas from the previous field of the same struct, so nested structs keep
+// their parent's last id on a stack.
+type encoder struct {
+	buf   []byte
+	last  int16
+	stack []int16
+}
+
+func (e *encoder) field(id int16, typ byte) {
+	if delta := id - e.last; delta > 0 && delta <= 15 {
+		e.buf = append(e.buf, byte(delta)<<4|typ)
+	} else {
+		e.buf = append(e.buf, typ)
+		e.varint(zigzag(int64(id)))
+	}
+	e.last = id
+}
+
+func (e *encoder) varint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (e *encoder) i32(id int16, v int32) {
+	e.field(id, thriftI32)
+	e.varint(zigzag(int64(v)))
+}
+
+func (e *encoder) i64(id int16, v int64) {
+	e.field(id, thriftI64)
+	e.varint(zigzag(v))
+}
+
+func (e *encoder) string(id int16, s string) {
+	e.field(id, thriftBinary)
+	e.listString(s)
+}
+
+// list starts a list field of n elements of type elem. Elements follow
+// without field headers: listI32, listString, or begin/end for structs.
+func (e *encoder) list(id int16, elem byte, n int) {
+	e.field(id, thriftList)
+	if n < 15 {
+		e.buf = append(e.buf, byte(n)<<4|elem)
+		return
+	}
+	e.buf = append(e.buf, 0xf0|elem)
+	e.varint(uint64(n))
+}
+
+func (e *encoder) listI32(v int32) {
+	e.varint(zigzag(int64(v)))
+}
+
+func (e *encoder) listString(s string) {
+	e.varint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// structField starts a struct field; end closes it
+func (e *encoder) structField(id int16) {
+	e.field(id, thriftStruct)
+	e.begin()
+}
+
+// begin starts a struct: the top-level one or a list element
+func (e *encoder) begin() {
+	e.stack = append(e.stack, e.last)
+	e.last = 0
+}
+
+// end writes the struct's stop field
+func (e *encoder) end() {
+	e.buf = append(e.buf, 0)
+	e.last = e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+}
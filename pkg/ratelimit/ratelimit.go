@@ -0,0 +1,332 @@
+// Package ratelimit is the HTTP middleware that limits how often each
+// client may call the service's routes. A client is its API token or issued
+// key when it sends a valid one, and its IP address otherwise. Each route belongs to
+// a class from the longest matching rule, as auth policies do: read routes
+// and expensive ones (exports, manual syncs) draw from separate budgets, and
+// unlimited routes such as health probes from none.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// Route classes
+const (
+	Read      = "read"
+	Expensive = "expensive"
+	Unlimited = "unlimited"
+)
+
+// Budget allows Requests per Per for each client, spent at any pace. Zero
+// Requests is no limit.
+type Budget struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (b Budget) String() string {
+	if b.Requests == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", b.Requests, b.Per)
+}
+
+// Rule puts requests whose path is Prefix or below it in Class. An empty
+// Method matches every method.
+type Rule struct {
+	Method string
+	Prefix string
+	Class  string
+}
+
+type Config struct {
+	Read      Budget
+	Expensive Budget
+	Rules     []Rule
+	// Tokens identify clients by API token instead of IP; any other
+	// credential is ignored, so made-up tokens can't dodge the IP budget
+	Tokens []string
+	// Keys looks up issued consumer keys, which are limited per key
+	Keys func(token string) (id int64, ok bool)
+	// IPHeader is read for the client IP behind a reverse proxy, e.g.
+	// X-Forwarded-For. Proxies append to the list a client may have
+	// started, so the address TrustedProxies from the right counts.
+	IPHeader       string
+	TrustedProxies int
+}
+
+// Enabled reports whether any budget is set
+func (cfg Config) Enabled() bool {
+	return cfg.Read.Requests > 0 || cfg.Expensive.Requests > 0
+}
+
+// FromEnv reads RATE_LIMIT_READ, RATE_LIMIT_EXPENSIVE, RATE_LIMIT_ROUTES,
+// RATE_LIMIT_IP_HEADER and RATE_LIMIT_TRUSTED_PROXIES (default 1). Both
+// budgets are off unless set.
+func FromEnv() (Config, error) {
+	var cfg Config
+	var err error
+	if cfg.Read, err = ParseBudget(os.Getenv("RATE_LIMIT_READ")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_READ: %w", err)
+	}
+	if cfg.Expensive, err = ParseBudget(os.Getenv("RATE_LIMIT_EXPENSIVE")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_EXPENSIVE: %w", err)
+	}
+	if cfg.Rules, err = ParseRules(os.Getenv("RATE_LIMIT_ROUTES")); err != nil {
+		return Config{}, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err)
+	}
+	cfg.IPHeader = strings.TrimSpace(os.Getenv("RATE_LIMIT_IP_HEADER"))
+	cfg.TrustedProxies = 1
+	if v := strings.TrimSpace(os.Getenv("RATE_LIMIT_TRUSTED_PROXIES")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("RATE_LIMIT_TRUSTED_PROXIES: %q must be a positive number of proxies", v)
+		}
+		cfg.TrustedProxies = n
+	}
+	return cfg, nil
+}
+
+// ParseBudget parses "<requests>/<period>", where the period is s, m, h or a
+// duration such as 10s, e.g. "120/m". An empty string or "0" is no limit.
+func ParseBudget(s string) (Budget, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Budget{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if !ok || err != nil || n < 0 {
+		return Budget{}, fmt.Errorf("budget %q must be <requests>/<period>, e.g. 120/m", s)
+	}
+	per, err := parsePeriod(strings.TrimSpace(period))
+	if err != nil || per <= 0 {
+		return Budget{}, fmt.Errorf("budget %q: period must be s, m, h or a positive duration", s)
+	}
+	return Budget{Requests: n, Per: per}, nil
+}
+
+func parsePeriod(s string) (time.Duration, error) {
+	switch s {
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseRules parses a comma-separated rule list, each "[METHOD ]/prefix=class",
+// e.g. "/markets/search=expensive,GET /status=unlimited".
+func ParseRules(s string) ([]Rule, error) {
+	rules := []Rule{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, class, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit rule %q has no class", entry)
+		}
+		rule := Rule{Prefix: strings.TrimSpace(route), Class: strings.TrimSpace(class)}
+		if method, prefix, ok := strings.Cut(rule.Prefix, " "); ok {
+			rule.Method, rule.Prefix = strings.ToUpper(method), strings.TrimSpace(prefix)
+		}
+
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			return nil, fmt.Errorf("rate limit rule %q: path must start with /", entry)
+		}
+		if rule.Class != Read && rule.Class != Expensive && rule.Class != Unlimited {
+			return nil, fmt.Errorf("rate limit rule %q: class must be read, expensive or unlimited", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// New returns the middleware. A request over its client's budget is answered
+// with 429 and Retry-After, the seconds until the next one would be allowed.
+func New(cfg Config) fiber.Handler {
+	if !cfg.Enabled() {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	log.Info().
+		Str("read", cfg.Read.String()).
+		Str("expensive", cfg.Expensive.String()).
+		Msg("🚦 Rate limiting enabled")
+
+	limiters := map[string]*limiter{
+		Read:      newLimiter(cfg.Read),
+		Expensive: newLimiter(cfg.Expensive),
+	}
+
+	return func(c *fiber.Ctx) error {
+		l := limiters[cfg.class(c.Method(), strings.ToLower(c.Path()))]
+		if l == nil {
+			return c.Next()
+		}
+
+		client := cfg.client(c)
+		wait := l.take(client, time.Now())
+		if wait == 0 {
+			return c.Next()
+		}
+
+		log.Debug().
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Str("client", client).
+			Msg("🚦 Rate limited request")
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "rate limit exceeded"})
+	}
+}
+
+// class picks the longest matching prefix, Read when none matches. At equal
+// length a rule for the method wins over one for every method, and otherwise
+// the later rule wins, so configured rules override built-in ones.
+func (cfg Config) class(method, path string) string {
+	class, best, bestMethod := Read, -1, false
+	for _, rule := range cfg.Rules {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+		prefix := strings.TrimSuffix(strings.ToLower(rule.Prefix), "/")
+		if prefix != "" && path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		n, hasMethod := len(prefix), rule.Method != ""
+		if n > best || (n == best && (hasMethod || !bestMethod)) {
+			class, best, bestMethod = rule.Class, n, hasMethod
+		}
+	}
+	return class
+}
+
+// client is "token:" and a hash of a configured token the request carries,
+// "key:" and the ID of an issued key, else "ip:" and its address
+func (cfg Config) client(c *fiber.Ctx) string {
+	token := credential(c)
+	for _, want := range cfg.Tokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:8])
+		}
+	}
+	if cfg.Keys != nil && token != "" {
+		if id, ok := cfg.Keys(token); ok {
+			return "key:" + strconv.FormatInt(id, 10)
+		}
+	}
+
+	if cfg.IPHeader != "" {
+		if ip := forwardedIP(c.Get(cfg.IPHeader), cfg.TrustedProxies); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+// forwardedIP picks the client address out of a forwarding header. Each of
+// the proxies appends the address it saw, so the one proxies places from the
+// right was added by the outermost proxy; anything further left came from
+// the client and can be forged. A shorter list falls back to its left-most
+// address.
+func forwardedIP(header string, proxies int) string {
+	var addrs []string
+	for _, addr := range strings.Split(header, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrs[max(len(addrs)-max(proxies, 1), 0)]
+}
+
+// credential reads "Authorization: Bearer <token>" or "X-API-Key: <token>"
+func credential(c *fiber.Ctx) string {
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(c.Get("X-API-Key"))
+}
+
+// limiter is a token bucket per client. Buckets left idle until full are
+// dropped, since a full bucket and a missing one are the same.
+type limiter struct {
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*bucket
+	pruned  time.Time
+	mu      sync.Mutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(budget Budget) *limiter {
+	if budget.Requests == 0 {
+		return nil
+	}
+	return &limiter{
+		rate:    float64(budget.Requests) / budget.Per.Seconds(),
+		burst:   float64(budget.Requests),
+		buckets: make(map[string]*bucket),
+		pruned:  time.Now(),
+	}
+}
+
+// take spends one of client's tokens, or returns how long until one is
+// available, without spending it
+func (l *limiter) take(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.pruned) > time.Minute {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return max(time.Duration((1-b.tokens)/l.rate*float64(time.Second)), time.Millisecond)
+	}
+	b.tokens--
+	return 0
+}
+
+func (l *limiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.pruned = now
+}
@@ -0,0 +1,27 @@
+package ratelimit
+
+import "testing"
+
+func TestForwardedIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		proxies int
+		want    string
+	}{
+		{"single address", "203.0.113.7", 1, "203.0.113.7"},
+		{"spoofed prefix", "10.9.9.9, 203.0.113.7", 1, "203.0.113.7"},
+		{"two proxies", "10.9.9.9, 203.0.113.7, 192.0.2.10", 2, "203.0.113.7"},
+		{"fewer addresses than proxies", "203.0.113.7", 3, "203.0.113.7"},
+		{"unset proxies", "10.9.9.9,203.0.113.7", 0, "203.0.113.7"},
+		{"empty entries", " , 203.0.113.7 ,", 1, "203.0.113.7"},
+		{"empty", "", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := forwardedIP(tt.header, tt.proxies); got != tt.want {
+				t.Errorf("forwardedIP(%q, %d) = %q, want %q", tt.header, tt.proxies, got, tt.want)
+			}
+		})
+	}
+}
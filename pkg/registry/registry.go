@@ -0,0 +1,205 @@
+// Package registry announces a running instance to the ops tooling so it can
+// be discovered instead of hardcoding URLs. Instances register on startup,
+// heartbeat while running and deregister on shutdown, either in the shared
+// service_registry table or with a Consul agent.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// Supported backends for SERVICE_REGISTRY
+const (
+	BackendTable  = "table"
+	BackendConsul = "consul"
+)
+
+const HeartbeatInterval = 30 * time.Second
+
+// Registration is the metadata published for one instance
+type Registration struct {
+	InstanceID string            `json:"instance_id"`
+	Service    string            `json:"service"`
+	Version    string            `json:"version"`
+	Commit     string            `json:"commit"`
+	URL        string            `json:"url"`       // base URL other services reach us at
+	Endpoints  map[string]string `json:"endpoints"` // name -> path, e.g. "health": "/health"
+}
+
+type Registry interface {
+	Register(ctx context.Context, reg Registration) error
+	Heartbeat(ctx context.Context, reg Registration) error
+	Deregister(ctx context.Context, reg Registration) error
+}
+
+// New returns the registry for backend
+func New(backend string, database *store.DB, consulAddr string) (Registry, error) {
+	switch backend {
+	case BackendTable:
+		return &tableRegistry{db: database}, nil
+	case BackendConsul:
+		return &consulRegistry{addr: consulAddr, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend %q (expected %s or %s)", backend, BackendTable, BackendConsul)
+	}
+}
+
+// Start registers reg and heartbeats it in the background until ctx is
+// done. The returned stop function deregisters and should run on shutdown.
+func Start(ctx context.Context, r Registry, reg Registration) (func(), error) {
+	if err := r.Register(ctx, reg); err != nil {
+		return nil, err
+	}
+
+	hbCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hbCtx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Heartbeat(hbCtx, reg); err != nil && hbCtx.Err() == nil {
+					log.Warn().Err(err).Msg("Service registry heartbeat failed")
+				}
+			}
+		}
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+
+		deregCtx, cancelDereg := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelDereg()
+		if err := r.Deregister(deregCtx, reg); err != nil {
+			log.Warn().Err(err).Msg("Failed to deregister service")
+			return
+		}
+		log.Info().Str("instance", reg.InstanceID).Msg("📇 Service deregistered")
+	}
+	return stop, nil
+}
+
+// tableRegistry keeps one row per instance in service_registry
+type tableRegistry struct {
+	db *store.DB
+}
+
+func (t *tableRegistry) Register(ctx context.Context, reg Registration) error {
+	endpoints, err := json.Marshal(reg.Endpoints)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.db.Pool().Exec(ctx, `
+		INSERT INTO service_registry (
+			instance_id, service, version, commit, url, endpoints, started_at, heartbeat_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (instance_id) DO UPDATE SET
+			service = $2, version = $3, commit = $4, url = $5, endpoints = $6,
+			started_at = NOW(), heartbeat_at = NOW()
+	`, reg.InstanceID, reg.Service, reg.Version, reg.Commit, reg.URL, endpoints)
+	if err != nil {
+		return fmt.Errorf("failed to register instance: %w", err)
+	}
+	return nil
+}
+
+func (t *tableRegistry) Heartbeat(ctx context.Context, reg Registration) error {
+	_, err := t.db.Pool().Exec(ctx,
+		`UPDATE service_registry SET heartbeat_at = NOW() WHERE instance_id = $1`, reg.InstanceID)
+	return err
+}
+
+func (t *tableRegistry) Deregister(ctx context.Context, reg Registration) error {
+	_, err := t.db.Pool().Exec(ctx,
+		`DELETE FROM service_registry WHERE instance_id = $1`, reg.InstanceID)
+	return err
+}
+
+// consulRegistry registers with the local Consul agent. Consul polls the
+// health endpoint itself, so heartbeats are a no-op.
+type consulRegistry struct {
+	addr   string
+	client *http.Client
+}
+
+func (c *consulRegistry) Register(ctx context.Context, reg Registration) error {
+	u, err := url.Parse(reg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid advertise URL %q: %w", reg.URL, err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	meta := map[string]string{"version": reg.Version, "commit": reg.Commit}
+	for name, path := range reg.Endpoints {
+		meta["endpoint_"+name] = path
+	}
+
+	service := map[string]interface{}{
+		"ID":      reg.InstanceID,
+		"Name":    reg.Service,
+		"Address": u.Hostname(),
+		"Port":    port,
+		"Meta":    meta,
+	}
+	if health, ok := reg.Endpoints["health"]; ok {
+		service["Check"] = map[string]interface{}{
+			"HTTP":                           reg.URL + health,
+			"Interval":                       HeartbeatInterval.String(),
+			"DeregisterCriticalServiceAfter": "10m",
+		}
+	}
+
+	return c.put(ctx, "/v1/agent/service/register", service)
+}
+
+func (c *consulRegistry) Heartbeat(ctx context.Context, reg Registration) error {
+	return nil
+}
+
+func (c *consulRegistry) Deregister(ctx context.Context, reg Registration) error {
+	return c.put(ctx, "/v1/agent/service/deregister/"+url.PathEscape(reg.InstanceID), nil)
+}
+
+func (c *consulRegistry) put(ctx context.Context, path string, body interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.addr+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("consul returned status %d for %s", resp.StatusCode, path)
+	}
+	return nil
+}
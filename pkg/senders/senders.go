@@ -0,0 +1,95 @@
+// Package senders decides which transaction senders count towards public
+// activity. Internal operators such as the protocol's market-maker bots are
+// excluded so their liquidity operations don't inflate volume or trader
+// counts.
+package senders
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Filter holds the sender allow and deny lists. An empty allowlist allows
+// everyone; the denylist always wins.
+type Filter struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// New builds a filter from address lists
+func New(allow, deny []string) *Filter {
+	f := &Filter{allow: make(map[string]bool), deny: make(map[string]bool)}
+	for _, addr := range allow {
+		if addr = Normalize(addr); addr != "" {
+			f.allow[addr] = true
+		}
+	}
+	for _, addr := range deny {
+		if addr = Normalize(addr); addr != "" {
+			f.deny[addr] = true
+		}
+	}
+	return f
+}
+
+// FromEnv reads the comma-separated ACTIVITY_SENDER_ALLOWLIST and
+// ACTIVITY_SENDER_DENYLIST variables
+func FromEnv() *Filter {
+	return New(
+		strings.Split(os.Getenv("ACTIVITY_SENDER_ALLOWLIST"), ","),
+		strings.Split(os.Getenv("ACTIVITY_SENDER_DENYLIST"), ","),
+	)
+}
+
+// Includes reports whether activity from addr should be recorded
+func (f *Filter) Includes(addr string) bool {
+	if f == nil {
+		return true
+	}
+	addr = Normalize(addr)
+	if f.deny[addr] {
+		return false
+	}
+	return len(f.allow) == 0 || f.allow[addr]
+}
+
+// Allowed returns the normalized allowlist, sorted
+func (f *Filter) Allowed() []string {
+	if f == nil {
+		return []string{}
+	}
+	return keys(f.allow)
+}
+
+// Denied returns the normalized denylist, sorted
+func (f *Filter) Denied() []string {
+	if f == nil {
+		return []string{}
+	}
+	return keys(f.deny)
+}
+
+// Normalize returns the long form of an Aptos address (0x + 64 lowercase
+// hex digits) so "0x1" and "0x0...01" compare equal. Non-hex input is only
+// trimmed and lowercased.
+func Normalize(addr string) string {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if addr == "" {
+		return ""
+	}
+	hex := strings.TrimPrefix(addr, "0x")
+	if len(hex) > 64 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return addr
+	}
+	return "0x" + strings.Repeat("0", 64-len(hex)) + hex
+}
+
+func keys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
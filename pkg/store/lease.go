@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// InstanceID identifies this process as a lease owner
+func InstanceID(service string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%s:%d", service, host, os.Getpid())
+}
+
+// AcquireLease takes or renews the named writer lease for owner. It returns
+// false when another owner holds a lease that hasn't expired yet.
+func (db *DB) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	query := `
+		INSERT INTO writer_leases (name, owner, expires_at, updated_at)
+		VALUES ($1, $2, NOW() + $3::interval, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET owner = $2, expires_at = NOW() + $3::interval, updated_at = NOW()
+		WHERE writer_leases.owner = $2 OR writer_leases.expires_at < NOW()
+	`
+
+	tag, err := db.pool.Exec(ctx, query, name, owner, fmt.Sprintf("%d milliseconds", ttl.Milliseconds()))
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// RenewLeases extends every named lease owner still holds, as part of tx. It
+// returns false when any of them expired or changed hands, in which case the
+// caller must roll tx back. The renewed rows stay locked until tx ends, so
+// nobody can take the leases over while tx's writes commit.
+func RenewLeases(ctx context.Context, tx pgx.Tx, names []string, owner string, ttl time.Duration) (bool, error) {
+	query := `
+		UPDATE writer_leases
+		SET expires_at = NOW() + $3::interval, updated_at = NOW()
+		WHERE name = ANY($1) AND owner = $2 AND expires_at >= NOW()
+	`
+
+	tag, err := tx.Exec(ctx, query, names, owner, fmt.Sprintf("%d milliseconds", ttl.Milliseconds()))
+	if err != nil {
+		return false, fmt.Errorf("failed to renew leases: %w", err)
+	}
+
+	return tag.RowsAffected() == int64(len(names)), nil
+}
+
+// ReleaseLease gives up the named lease if owner still holds it
+func (db *DB) ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := db.pool.Exec(ctx,
+		`DELETE FROM writer_leases WHERE name = $1 AND owner = $2`, name, owner)
+	return err
+}
+
+// LeaseOwner returns the current holder of the named lease, or "" if it is
+// free or expired
+func (db *DB) LeaseOwner(ctx context.Context, name string) (string, error) {
+	var owner string
+	err := db.pool.QueryRow(ctx,
+		`SELECT COALESCE(MAX(owner), '') FROM writer_leases WHERE name = $1 AND expires_at >= NOW()`, name,
+	).Scan(&owner)
+	return owner, err
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Versions both services shipped under the same name while schema_migrations
+// was keyed by version alone, so only whichever service migrated first ran
+// its file. Each service reruns its own copy instead of adopting the row;
+// all of them are idempotent.
+var sharedLegacyVersions = []string{"001_create_sync_state", "009_create_processed_events"}
+
+// Migrate applies every *.sql file in files that service has not yet recorded
+// in schema_migrations, in lexical order. Each file runs in its own
+// transaction. Services sharing a database track their migrations separately,
+// so equal file names don't collide.
+func (db *DB) Migrate(ctx context.Context, service string, files fs.FS) error {
+	if err := db.upgradeSchemaMigrations(ctx); err != nil {
+		return err
+	}
+
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(name, ".sql")
+
+		var applied bool
+		err := db.pool.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE service = $1 AND version = $2)`, service, version,
+		).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		// Rows recorded before migrations were tracked per service
+		if !slices.Contains(sharedLegacyVersions, version) {
+			tag, err := db.pool.Exec(ctx, `
+				INSERT INTO schema_migrations (service, version, applied_at)
+				SELECT $1, version, applied_at FROM schema_migrations
+				WHERE service = '' AND version = $2
+			`, service, version)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() > 0 {
+				continue
+			}
+		}
+
+		sql, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (service, version) VALUES ($1, $2)`, service, version); err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		log.Info().Str("service", service).Str("version", version).Msg("📜 Migration applied")
+	}
+
+	return nil
+}
+
+// upgradeSchemaMigrations creates schema_migrations, or re-keys one created
+// before migrations were tracked per service. Existing rows keep an empty
+// service until Migrate adopts them.
+func (db *DB) upgradeSchemaMigrations(ctx context.Context) error {
+	_, err := db.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			service VARCHAR(64) NOT NULL DEFAULT '',
+			version VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (service, version)
+		);
+
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS service VARCHAR(64) NOT NULL DEFAULT '';
+
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1
+				FROM pg_index i
+				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey)
+				WHERE i.indrelid = 'schema_migrations'::regclass AND i.indisprimary AND a.attname = 'service'
+			) THEN
+				ALTER TABLE schema_migrations DROP CONSTRAINT IF EXISTS schema_migrations_pkey;
+				ALTER TABLE schema_migrations ADD PRIMARY KEY (service, version);
+			END IF;
+		END $$;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the most recently applied migration of service, or
+// "" if none
+func (db *DB) SchemaVersion(ctx context.Context, service string) (string, error) {
+	var version string
+	err := db.pool.QueryRow(ctx,
+		`SELECT COALESCE(MAX(version), '') FROM schema_migrations WHERE service = $1`, service,
+	).Scan(&version)
+	return version, err
+}
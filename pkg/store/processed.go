@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimEvent records the event at (version, index) of network in
+// processed_events as part of tx. It returns false when the event was
+// already processed, in which case the caller must not apply it again.
+// Transactions indexed before the ledger existed count as processed once
+// they have Activity rows, and claims made before claims carried their
+// network count for every network until the indexer labels them.
+func ClaimEvent(ctx context.Context, tx pgx.Tx, network string, version int64, index int, eventType, txHash string) (bool, error) {
+	query := `
+		INSERT INTO processed_events (transaction_version, event_index, event_type, tx_hash, network)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (
+			SELECT 1 FROM sync_state s
+			WHERE s.key = 'processed_events_since'
+			  AND $1 <= s.value::bigint
+			  AND EXISTS (SELECT 1 FROM "Activity" a WHERE a."txHash" = $4)
+		) AND NOT EXISTS (
+			SELECT 1 FROM processed_events p
+			WHERE p.network = '' AND p.transaction_version = $1 AND p.event_index = $2
+		)
+		ON CONFLICT (network, transaction_version, event_index) DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, query, version, index, eventType, txHash, network)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event %d/%d: %w", version, index, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,76 @@
+// Package timeconv parses and formats the timestamp encodings the services
+// exchange. The fullnode returns microseconds since the epoch as a decimal
+// string, Nodit and historical dumps use ISO 8601 (often without a zone),
+// and webhooks and the HTTP API emit RFC 3339 in UTC.
+package timeconv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ISO layouts accepted by Parse, tried in order. Layouts without a zone are
+// interpreted as UTC, which is what Nodit returns.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Parse converts any supported timestamp to a UTC time. Integer strings are
+// epoch values whose unit follows from their magnitude: up to 11 digits are
+// seconds, up to 14 milliseconds, up to 17 microseconds (the fullnode's
+// format) and anything longer nanoseconds.
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+
+	if isDigits(s) {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch timestamp %q: %w", s, err)
+		}
+		switch {
+		case len(s) <= 11:
+			return time.Unix(n, 0).UTC(), nil
+		case len(s) <= 14:
+			return time.UnixMilli(n).UTC(), nil
+		case len(s) <= 17:
+			return time.UnixMicro(n).UTC(), nil
+		default:
+			return time.Unix(0, n).UTC(), nil
+		}
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+}
+
+// Format renders t as RFC 3339 in UTC, the format of every emitted timestamp
+func Format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Now returns the current time formatted by Format
+func Now() string {
+	return Format(time.Now())
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
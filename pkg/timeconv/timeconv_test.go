@@ -0,0 +1,109 @@
+package timeconv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	want := time.Date(2025, 10, 4, 22, 25, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   string
+		want time.Time
+	}{
+		{"seconds", "1759616700", want},
+		{"milliseconds", "1759616700123", want.Add(123 * time.Millisecond)},
+		{"microseconds", "1759616700123456", want.Add(123456 * time.Microsecond)},
+		{"nanoseconds", "1759616700123456789", want.Add(123456789 * time.Nanosecond)},
+		{"epoch zero", "0", time.Unix(0, 0).UTC()},
+		{"surrounding whitespace", " 1759616700\n", want},
+		{"rfc3339 utc", "2025-10-04T22:25:00Z", want},
+		{"rfc3339 offset", "2025-10-05T00:25:00+02:00", want},
+		{"rfc3339 fraction", "2025-10-04T22:25:00.5Z", want.Add(500 * time.Millisecond)},
+		{"iso without zone", "2025-10-04T22:25:00", want},
+		{"iso without zone fraction", "2025-10-04T22:25:00.123456", want.Add(123456 * time.Microsecond)},
+		{"space separated", "2025-10-04 22:25:00", want},
+		{"space separated offset", "2025-10-04 17:25:00-05:00", want},
+		{"space separated short offset", "2025-10-05 00:25:00+02", want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("Parse(%q) location = %v, want UTC", tt.in, got.Location())
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"blank", "   "},
+		{"garbage", "yesterday"},
+		{"signed epoch", "-1759616700"},
+		{"epoch overflow", "99999999999999999999"},
+		{"date only", "2025-10-04"},
+		{"invalid date", "2025-13-04T22:25:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Parse(tt.in); err == nil {
+				t.Errorf("Parse(%q) = %v, want error", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"rfc3339 utc", "2025-10-04T22:25:00Z", "2025-10-04T22:25:00Z"},
+		{"offset", "2025-10-05T00:25:00+02:00", "2025-10-04T22:25:00Z"},
+		{"fraction dropped", "2025-10-04T22:25:00.999Z", "2025-10-04T22:25:00Z"},
+		{"fullnode microseconds", "1759616700123456", "2025-10-04T22:25:00Z"},
+		{"iso without zone", "2025-10-04 22:25:00", "2025-10-04T22:25:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			formatted := Format(parsed)
+			if formatted != tt.want {
+				t.Errorf("Format(Parse(%q)) = %q, want %q", tt.in, formatted, tt.want)
+			}
+
+			// Formatted timestamps parse back to the same second
+			again, err := Parse(formatted)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", formatted, err)
+			}
+			if !again.Equal(parsed.Truncate(time.Second)) {
+				t.Errorf("Parse(%q) = %v, want %v", formatted, again, parsed.Truncate(time.Second))
+			}
+		})
+	}
+}
+
+func TestFormatUTC(t *testing.T) {
+	local := time.Date(2025, 10, 4, 17, 25, 0, 0, time.FixedZone("EST", -5*60*60))
+	if got, want := Format(local), "2025-10-04T22:25:00Z"; got != want {
+		t.Errorf("Format(%v) = %q, want %q", local, got, want)
+	}
+}
@@ -0,0 +1,635 @@
+// Package app wires the sync service: scheduled jobs, the markets API and
+// the admin routes. cmd/server runs it on its own; the verifi-services binary
+// runs it next to the indexer on one pool and one HTTP server.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/page"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/registry"
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/sync-service/internal/api"
+	"github.com/verifi-protocol/sync-service/internal/apikeys"
+	"github.com/verifi-protocol/sync-service/internal/config"
+	"github.com/verifi-protocol/sync-service/internal/i18n"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+	"github.com/verifi-protocol/sync-service/internal/sync"
+	"github.com/verifi-protocol/sync-service/migrations"
+)
+
+// Service is the name the sync service reports in logs, /health and the
+// registry
+const Service = "verifi-sync-service"
+
+// Built-in route policies; AUTH_ROUTES entries override them. Everything
+// else needs an API token once API_TOKENS or ADMIN_TOKEN is set. The market
+// data routes also take issued consumer keys.
+var defaultAuthRules = []auth.Rule{
+	{Prefix: "/health", Policy: auth.Public},
+	{Prefix: "/openapi.json", Policy: auth.Public},
+	{Prefix: "/markets", Policy: auth.Consumer},
+	{Prefix: "/users", Policy: auth.Consumer},
+	{Prefix: "/stats", Policy: auth.Consumer},
+	{Prefix: "/export", Policy: auth.Consumer},
+	{Prefix: "/admin", Policy: auth.Admin},
+	{Method: "GET", Prefix: "/admin/jobs", Policy: auth.Token},
+	{Method: "POST", Prefix: "/jobs", Policy: auth.Admin},
+}
+
+// Built-in rate limit classes; RATE_LIMIT_ROUTES entries override them.
+// Everything else is a read.
+var defaultRateLimitRules = []ratelimit.Rule{
+	{Prefix: "/health", Class: ratelimit.Unlimited},
+	{Prefix: "/export", Class: ratelimit.Expensive},
+	{Prefix: "/sync", Class: ratelimit.Expensive},
+	{Method: "POST", Prefix: "/jobs", Class: ratelimit.Expensive},
+}
+
+// App is a wired sync service. Build it with New, mount its routes, then
+// Start it.
+type App struct {
+	cfg         *config.Config
+	database    *store.DB
+	aptosClient *aptos.Client
+	syncService *sync.Service
+	marketsAPI  *api.Handler
+	keys        *apikeys.Store
+	jobs        *scheduler.Scheduler
+	buildInfo   buildinfo.Info
+
+	// Cancelled by Close; stops the scheduler
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Migrate applies the sync service's migrations
+func Migrate(ctx context.Context, database *store.DB) error {
+	return database.Migrate(ctx, migrations.Service, migrations.FS)
+}
+
+// Load reads the sync configuration from the environment and wires the
+// service on database
+func Load(database *store.DB) (*App, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg, database)
+}
+
+// New wires the sync service and registers its jobs. Migrations must have
+// run.
+func New(cfg *config.Config, database *store.DB) (*App, error) {
+	a := &App{cfg: cfg, database: database}
+
+	// Initialize Aptos client (used by the activities reconciliation).
+	// APTOS_RPC_URL points at a self-hosted fullnode or localnet instead of
+	// the network's public one.
+	if cfg.AptosRPCURL != "" {
+		a.aptosClient = aptos.NewClientWithURL(cfg.AptosRPCURL)
+	} else if _, ok := aptos.NetworkRPCURL(cfg.AptosNetwork); ok {
+		a.aptosClient = aptos.NewClient(cfg.AptosNetwork)
+	} else {
+		return nil, fmt.Errorf("unknown network %q, set APTOS_RPC_URL", cfg.AptosNetwork)
+	}
+
+	// Initialize sync service
+	a.syncService = sync.NewService(database, a.aptosClient, cfg)
+
+	// Machine translation of market descriptions (optional)
+	if cfg.TranslationURL != "" {
+		a.syncService.SetTranslator(i18n.NewLibreTranslate(cfg.TranslationURL, cfg.TranslationAPIKey))
+		log.Info().Strs("locales", cfg.TranslationLocales).Msg("✅ Market translation enabled")
+	}
+
+	// Build metadata for /version and incident triage
+	a.buildInfo = buildinfo.Get(Service)
+	a.buildInfo.SchemaVersion, _ = database.SchemaVersion(context.Background(), migrations.Service)
+	a.buildInfo.Features["activities_reconciliation"] = cfg.ModuleAddress != ""
+	a.buildInfo.Features["translations"] = cfg.TranslationURL != ""
+
+	log.Info().
+		Str("version", a.buildInfo.Version).
+		Str("commit", a.buildInfo.Commit).
+		Str("build_time", a.buildInfo.BuildTime).
+		Str("go", a.buildInfo.GoVersion).
+		Str("schema", a.buildInfo.SchemaVersion).
+		Interface("features", a.buildInfo.Features).
+		Msg("📦 Build info")
+
+	// Markets read API (localized via Accept-Language) and translation admin
+	a.marketsAPI = api.New(database, cfg.TranslationLocales)
+	a.marketsAPI.SetFreshness(a.aptosClient.Freshness)
+
+	// Consumer API keys, issued through /admin/api-keys
+	a.keys = apikeys.New(database)
+
+	// Scheduled jobs. Schedules live in scheduled_jobs so they survive
+	// restarts and can be edited through /admin/jobs; the values below are
+	// only the defaults for a fresh database. A schedule set in the
+	// environment (SYNC_*_CRON) replaces the persisted one at startup.
+	a.jobs = scheduler.New(database)
+	for _, j := range []struct {
+		name, schedule, catchUp string
+		fn                      scheduler.Func
+		configured              string
+	}{
+		// Metrics sync - every hour
+		{"metrics", "0 0 * * * *", scheduler.CatchUpOnce, a.syncService.SyncMetrics, cfg.MetricsCron},
+		// Pools sync - every 15 minutes
+		{"pools", "0 */15 * * * *", scheduler.CatchUpOnce, a.syncService.SyncPools, cfg.PoolsCron},
+		// Activities sync - every 5 minutes
+		{"activities", "0 */5 * * * *", scheduler.CatchUpOnce, a.syncService.SyncActivities, cfg.ActivitiesCron},
+		// Prices sync - every minute; a missed run is superseded by the next
+		{"prices", "0 * * * * *", scheduler.CatchUpSkip, a.syncService.SyncPrices, cfg.PricesCron},
+		// Market expiry - every minute, and once on restart
+		{"expiry", "0 * * * * *", scheduler.CatchUpOnce, a.syncService.SyncExpiry, cfg.ExpiryCron},
+		// Volume buckets - every 5 minutes; a missed run is superseded by the next
+		{"volume", "0 */5 * * * *", scheduler.CatchUpSkip, a.syncService.SyncVolume, ""},
+		// Protocol-wide stats - every 15 minutes
+		{"protocol_stats", "0 */15 * * * *", scheduler.CatchUpOnce, a.syncService.SyncProtocolStats, ""},
+		// Translations sync - every 10 minutes (no-op without a provider)
+		{"translations", "0 */10 * * * *", scheduler.CatchUpSkip, a.syncService.SyncTranslations, ""},
+		// Unit reconciliation - nightly at 03:00
+		{"unit_reconciliation", "0 0 3 * * *", scheduler.CatchUpOnce, a.syncService.SyncUnitReconciliation, ""},
+		// On-chain vs database state reconciliation - nightly at 03:30
+		{"state_reconciliation", "0 30 3 * * *", scheduler.CatchUpOnce, a.syncService.SyncStateReconciliation, ""},
+		// Activity retention - nightly at 04:00 (no-op unless enabled)
+		{"activity_retention", "0 0 4 * * *", scheduler.CatchUpOnce, a.syncService.SyncActivityRetention, ""},
+	} {
+		register, schedule := a.jobs.Register, j.schedule
+		if j.configured != "" {
+			register, schedule = a.jobs.RegisterOverride, j.configured
+			log.Info().Str("job", j.name).Str("schedule", schedule).Msg("⏰ Job schedule set from environment")
+		}
+		if err := register(j.name, schedule, j.catchUp, j.fn); err != nil {
+			return nil, fmt.Errorf("failed to register job: %w", err)
+		}
+	}
+	for _, name := range []string{"metrics", "prices", "expiry", "volume", "protocol_stats", "activity_retention"} {
+		if err := a.jobs.AllowDryRun(name); err != nil {
+			return nil, err
+		}
+	}
+	for name, timeout := range cfg.JobTimeouts {
+		if err := a.jobs.SetTimeout(name, timeout); err != nil {
+			return nil, fmt.Errorf("SYNC_JOB_TIMEOUTS: %w", err)
+		}
+	}
+	a.jobs.SetFailureAlert(cfg.JobFailureAlertThreshold, func(name string, c scheduler.Counters) {
+		log.Error().
+			Str("job", name).
+			Int("consecutive_failures", c.ConsecutiveFailures).
+			Msg("🚨 Job keeps failing")
+		a.syncService.SendAlert(context.Background(), "job_failing", map[string]interface{}{
+			"job":                  name,
+			"consecutive_failures": c.ConsecutiveFailures,
+			"last_error":           c.LastError,
+			"last_error_at":        c.LastErrorAt,
+		})
+	})
+
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	return a, nil
+}
+
+// Port is the configured PORT
+func (a *App) Port() string {
+	if a.cfg.Port == "" {
+		return "3001"
+	}
+	return a.cfg.Port
+}
+
+// BuildInfo is what GET /version returns
+func (a *App) BuildInfo() buildinfo.Info {
+	return a.buildInfo
+}
+
+// Auth is the route authentication for the sync routes: the configured
+// tokens, the built-in policies and AUTH_ROUTES
+func (a *App) Auth() auth.Config {
+	return auth.Config{
+		APITokens:  a.cfg.APITokens,
+		AdminToken: a.cfg.AdminToken,
+		Rules:      append(append([]auth.Rule{}, defaultAuthRules...), a.cfg.AuthRules...),
+		Keys:       a.keys,
+	}
+}
+
+// RateLimit is the per-client rate limiting of the sync routes: the configured
+// budgets, the built-in classes and RATE_LIMIT_ROUTES. Clients sending one of
+// the configured tokens are limited per token.
+func (a *App) RateLimit() ratelimit.Config {
+	cfg := a.cfg.RateLimit
+	cfg.Rules = append(append([]ratelimit.Rule{}, defaultRateLimitRules...), a.cfg.RateLimit.Rules...)
+	cfg.Tokens = append([]string{}, a.cfg.APITokens...)
+	if a.cfg.AdminToken != "" {
+		cfg.Tokens = append(cfg.Tokens, a.cfg.AdminToken)
+	}
+	cfg.Keys = a.keys.Lookup
+	return cfg
+}
+
+// Start runs the job scheduler and the API key refresh until Close
+func (a *App) Start() error {
+	if err := a.jobs.Start(a.ctx); err != nil {
+		return fmt.Errorf("failed to start scheduler: %w", err)
+	}
+	log.Info().Msg("⏰ Scheduler started")
+	go a.keys.Run(a.ctx)
+	return nil
+}
+
+// InitialSync runs the metrics and pools syncs once, so a fresh deployment
+// doesn't wait for their first schedule
+func (a *App) InitialSync(ctx context.Context) {
+	log.Info().Msg("🔄 Running initial sync...")
+	if err := a.jobs.Run(ctx, "metrics", scheduler.TriggerStartup); err != nil {
+		log.Warn().Err(err).Msg("Initial metrics sync failed")
+	}
+	if err := a.jobs.Run(ctx, "pools", scheduler.TriggerStartup); err != nil {
+		log.Warn().Err(err).Msg("Initial pools sync failed")
+	}
+}
+
+// Close stops the scheduler and writes the pending API key usage. It
+// doesn't close the pool.
+func (a *App) Close() {
+	a.cancel()
+	a.jobs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.keys.Flush(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to write API key usage")
+	}
+}
+
+// JobSchedule is a job's effective schedule as reported by /status
+type JobSchedule struct {
+	Job      string    `json:"job"`
+	Schedule string    `json:"schedule"`
+	Enabled  bool      `json:"enabled"`
+	NextRun  time.Time `json:"nextRun"`
+}
+
+// StatusReport is the body of GET /status
+type StatusReport struct {
+	sync.Stats
+	api.Freshness
+	Lifetime  *sync.Stats                   `json:"lifetime,omitempty"`
+	Schedules []JobSchedule                 `json:"schedules,omitempty"`
+	Jobs      map[string]scheduler.Counters `json:"jobs"`
+}
+
+// Status is the body of GET /status. Schedules are read from
+// scheduled_jobs, so they include admin API edits, and lifetime totals from
+// sync_stats; each is left out when its table can't be read.
+func (a *App) Status(ctx context.Context) StatusReport {
+	var schedules []JobSchedule
+	jobs, err := a.jobs.Jobs(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load job schedules for status")
+	}
+	for _, j := range jobs {
+		schedules = append(schedules, JobSchedule{Job: j.Name, Schedule: j.Schedule, Enabled: j.Enabled, NextRun: j.NextRun})
+	}
+
+	var lifetime *sync.Stats
+	if stats, err := a.syncService.LifetimeStats(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load lifetime stats for status")
+	} else {
+		lifetime = &stats
+	}
+
+	return StatusReport{a.syncService.GetStats(), api.Freshness(a.aptosClient.Freshness()), lifetime, schedules, a.jobs.Counters()}
+}
+
+// Health is the body of GET /health
+type Health struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+	Time    int64  `json:"time"`
+}
+
+// Probes mounts /health, /version and /status. The unified binary serves
+// its own combined versions instead.
+func (a *App) Probes(r fiber.Router) {
+	// Health check
+	r.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(Health{Status: "healthy", Service: Service, Time: time.Now().Unix()})
+	})
+
+	// Version endpoint
+	r.Get("/version", func(c *fiber.Ctx) error {
+		return c.JSON(a.buildInfo)
+	})
+
+	// Status endpoint
+	r.Get("/status", func(c *fiber.Ctx) error {
+		return c.JSON(a.Status(c.Context()))
+	})
+}
+
+// manualSyncs are the POST /sync/* triggers: path, job and the start, done
+// and failure messages
+var manualSyncs = []struct {
+	path, job, start, done, failed string
+}{
+	{"/sync/metrics", "metrics", "📊 Manual metrics sync triggered", "Metrics synced", "Metrics sync failed"},
+	{"/sync/pools", "pools", "💧 Manual pools sync triggered", "Pools synced", "Pools sync failed"},
+	{"/sync/activities", "activities", "📝 Manual activities sync triggered", "Activities synced", "Activities sync failed"},
+	{"/sync/prices", "prices", "💹 Manual prices sync triggered", "Prices synced", "Prices sync failed"},
+	{"/sync/expiry", "expiry", "⌛ Manual market expiry triggered", "Expired markets marked", "Market expiry failed"},
+	{"/sync/volume", "volume", "📈 Manual volume buckets sync triggered", "Volume buckets synced", "Volume buckets sync failed"},
+	{"/sync/protocol-stats", "protocol_stats", "🌐 Manual protocol stats sync triggered", "Protocol stats synced", "Protocol stats sync failed"},
+	{"/sync/reconciliation", "unit_reconciliation", "⚖️  Manual unit reconciliation triggered", "Unit reconciliation completed", "Unit reconciliation failed"},
+	{"/sync/state-reconciliation", "state_reconciliation", "🔎 Manual state reconciliation triggered", "State reconciliation completed", "State reconciliation failed"},
+}
+
+// JobList is the body of GET /jobs and GET /admin/jobs
+type JobList struct {
+	Jobs []scheduler.Job `json:"jobs"`
+}
+
+// RunList is a page of a job's runs, newest first
+type RunList struct {
+	Job        string          `json:"job"`
+	Runs       []scheduler.Run `json:"runs"`
+	NextCursor *string         `json:"nextCursor"`
+}
+
+// JobStarted is the body of POST /jobs/:name/run
+type JobStarted struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	DryRun  bool   `json:"dryRun"`
+}
+
+// KeyRequest is the body of POST /admin/api-keys
+type KeyRequest struct {
+	Name string `json:"name"`
+}
+
+// IssuedKey is a new API key with its secret, shown only once
+type IssuedKey struct {
+	apikeys.Key
+	Secret string `json:"key"`
+}
+
+type KeyList struct {
+	Keys []apikeys.Key `json:"keys"`
+}
+
+// KeyUsage is a key's requests per UTC day, newest first, and their totals
+type KeyUsage struct {
+	Key      apikeys.Key     `json:"key"`
+	Days     int             `json:"days"`
+	Requests int64           `json:"requests"`
+	Errors   int64           `json:"errors"`
+	Usage    []apikeys.Usage `json:"usage"`
+}
+
+// Routes mounts the manual sync triggers, the markets API, the job run
+// history and the job schedule admin
+func (a *App) Routes(r fiber.Router) {
+	jobs := a.jobs
+
+	// Manual sync endpoints. They run through the scheduler, so each run is
+	// recorded in job_runs and can't overlap a scheduled run of the same job.
+	for _, t := range manualSyncs {
+		r.Post(t.path, func(c *fiber.Ctx) error {
+			log.Info().Msg(t.start)
+			err := jobs.Run(context.Background(), t.job, scheduler.TriggerManual)
+			if errors.Is(err, scheduler.ErrJobRunning) {
+				return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+			}
+			if err != nil {
+				log.Error().Err(err).Msg(t.failed)
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.JSON(api.Result{Status: "success", Message: t.done})
+		})
+	}
+
+	a.marketsAPI.Register(r)
+
+	// Job schedules and run history
+	r.Get("/jobs", func(c *fiber.Ctx) error {
+		list, err := jobs.Jobs(c.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list jobs")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list jobs"})
+		}
+		return c.JSON(JobList{Jobs: list})
+	})
+
+	r.Get("/jobs/:name/runs", func(c *fiber.Ctx) error {
+		p, err := page.Parse(c, scheduler.RunPage)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		runs, err := jobs.Runs(c.Context(), c.Params("name"), p)
+		if errors.Is(err, scheduler.ErrUnknownJob) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Error().Err(err).Str("job", c.Params("name")).Msg("Failed to list job runs")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list job runs"})
+		}
+		var next *string
+		if n := len(runs); n > 0 {
+			next = p.Next(n, page.TimeKey(runs[n-1].StartedAt), strconv.FormatInt(runs[n-1].ID, 10))
+		}
+		return c.JSON(RunList{Job: c.Params("name"), Runs: runs, NextCursor: next})
+	})
+
+	// Start a job in the background (?dry_run=true computes without writing)
+	r.Post("/jobs/:name/run", func(c *fiber.Ctx) error {
+		dryRun := c.QueryBool("dry_run")
+		err := jobs.Trigger(c.Params("name"), scheduler.TriggerManual, dryRun)
+		switch {
+		case errors.Is(err, scheduler.ErrUnknownJob):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, scheduler.ErrDryRunUnsupported):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Info().Str("job", c.Params("name")).Bool("dry_run", dryRun).Msg("▶️  Job triggered")
+		return c.JSON(JobStarted{Status: "success", Message: "Job started", DryRun: dryRun})
+	})
+
+	// Stop a runaway run; its queries are cancelled through the context
+	r.Post("/jobs/:name/cancel", func(c *fiber.Ctx) error {
+		err := jobs.Cancel(c.Params("name"))
+		switch {
+		case errors.Is(err, scheduler.ErrUnknownJob):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(api.Result{Status: "success", Message: "Job cancelled"})
+	})
+
+	// Job schedule admin
+	r.Get("/admin/jobs", func(c *fiber.Ctx) error {
+		list, err := jobs.Jobs(c.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list jobs")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list jobs"})
+		}
+		return c.JSON(JobList{Jobs: list})
+	})
+
+	r.Put("/admin/jobs/:name", func(c *fiber.Ctx) error {
+		var update scheduler.Update
+		if err := c.BodyParser(&update); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+		}
+		job, err := jobs.Update(c.Context(), c.Params("name"), update)
+		if errors.Is(err, scheduler.ErrUnknownJob) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Info().Str("job", job.Name).Str("schedule", job.Schedule).Bool("enabled", job.Enabled).Msg("⏰ Job schedule updated")
+		return c.JSON(job)
+	})
+
+	r.Post("/admin/jobs/:name/run", func(c *fiber.Ctx) error {
+		err := jobs.Trigger(c.Params("name"), scheduler.TriggerAdmin, false)
+		if errors.Is(err, scheduler.ErrUnknownJob) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(api.Result{Status: "success", Message: "Job started"})
+	})
+
+	// Consumer API keys (admin token). The secret is only in the response
+	// that issues it.
+	r.Post("/admin/api-keys", func(c *fiber.Ctx) error {
+		var req KeyRequest
+		if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+		}
+		key, secret, err := a.keys.Issue(c.Context(), strings.TrimSpace(req.Name))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to issue API key")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to issue api key"})
+		}
+		log.Info().Int64("key", key.ID).Str("name", key.Name).Msg("🔑 API key issued")
+		return c.Status(201).JSON(IssuedKey{key, secret})
+	})
+
+	r.Get("/admin/api-keys", func(c *fiber.Ctx) error {
+		keys, err := a.keys.List(c.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list API keys")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to list api keys"})
+		}
+		return c.JSON(KeyList{Keys: keys})
+	})
+
+	r.Delete("/admin/api-keys/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "id must be an integer"})
+		}
+		key, err := a.keys.Revoke(c.Context(), int64(id))
+		if errors.Is(err, apikeys.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to revoke API key")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to revoke api key"})
+		}
+		log.Info().Int64("key", key.ID).Str("name", key.Name).Msg("🔑 API key revoked")
+		return c.JSON(key)
+	})
+
+	// Requests per UTC day over the last ?days (default 30, max 366)
+	r.Get("/admin/api-keys/:id/usage", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "id must be an integer"})
+		}
+		days := c.QueryInt("days", 30)
+		if days < 1 || days > 366 {
+			return c.Status(400).JSON(fiber.Map{"error": "days must be between 1 and 366"})
+		}
+		key, usage, err := a.keys.Usage(c.Context(), int64(id), days)
+		if errors.Is(err, apikeys.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to load API key usage")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load api key usage"})
+		}
+
+		report := KeyUsage{Key: key, Days: days, Usage: usage}
+		for _, u := range usage {
+			report.Requests += u.Requests
+			report.Errors += u.Errors
+		}
+		return c.JSON(report)
+	})
+}
+
+// Announce publishes this instance to the configured service registry and
+// returns the function that deregisters it. Without a registry it does
+// nothing; an unknown SERVICE_REGISTRY is an error.
+func (a *App) Announce() (func(), error) {
+	if a.cfg.Registry == "" {
+		return func() {}, nil
+	}
+
+	reg, err := registry.New(a.cfg.Registry, a.database, a.cfg.ConsulAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVICE_REGISTRY: %w", err)
+	}
+
+	registration := registry.Registration{
+		InstanceID: store.InstanceID("sync-service"),
+		Service:    a.buildInfo.Service,
+		Version:    a.buildInfo.Version,
+		Commit:     a.buildInfo.Commit,
+		URL:        a.cfg.AdvertiseURL,
+		Endpoints: map[string]string{
+			"health":  "/health",
+			"status":  "/status",
+			"version": "/version",
+			"jobs":    "/admin/jobs",
+		},
+	}
+
+	stop, err := registry.Start(a.ctx, reg, registration)
+	if err != nil {
+		log.Warn().Err(err).Str("registry", a.cfg.Registry).Msg("Service registration failed")
+		return func() {}, nil
+	}
+
+	log.Info().
+		Str("registry", a.cfg.Registry).
+		Str("instance", registration.InstanceID).
+		Str("url", registration.URL).
+		Msg("📇 Service registered")
+	return stop, nil
+}
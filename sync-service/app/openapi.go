@@ -0,0 +1,61 @@
+package app
+
+import (
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/openapi"
+	"github.com/verifi-protocol/sync-service/internal/api"
+	"github.com/verifi-protocol/sync-service/internal/apikeys"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// ProbeOperations documents the routes Probes mounts
+func (a *App) ProbeOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: "GET", Path: "/health", Tag: "service", Summary: "Liveness", Response: Health{}},
+		{Method: "GET", Path: "/version", Tag: "service", Summary: "Build metadata", Response: buildinfo.Info{}},
+		{Method: "GET", Path: "/status", Tag: "service", Summary: "Sync counters, freshness and job schedules", Response: StatusReport{}},
+	}
+}
+
+// Operations documents the routes Routes mounts
+func (a *App) Operations() []openapi.Operation {
+	var ops []openapi.Operation
+	for _, t := range manualSyncs {
+		ops = append(ops, openapi.Operation{
+			Method: "POST", Path: t.path, Tag: "sync", Summary: "Run the " + t.job + " job and wait for it",
+			Response: api.Result{},
+		})
+	}
+	ops = append(ops, api.Operations()...)
+
+	return append(ops,
+		openapi.Operation{Method: "GET", Path: "/jobs", Tag: "jobs", Summary: "List jobs with their schedule and last run",
+			Response: JobList{}},
+		openapi.Operation{Method: "GET", Path: "/jobs/:name/runs", Tag: "jobs", Summary: "List a job's runs, newest first",
+			Query: []openapi.Param{
+				{Name: "limit", Type: "integer", Description: "Page size"},
+				{Name: "cursor", Description: "nextCursor of the previous page"},
+			},
+			Response: RunList{}},
+		openapi.Operation{Method: "POST", Path: "/jobs/:name/run", Tag: "jobs", Summary: "Start a job in the background",
+			Query:    []openapi.Param{{Name: "dry_run", Type: "boolean", Description: "Compute without writing, for jobs that support it"}},
+			Response: JobStarted{}},
+		openapi.Operation{Method: "POST", Path: "/jobs/:name/cancel", Tag: "jobs", Summary: "Cancel a job's run in progress",
+			Response: api.Result{}},
+		openapi.Operation{Method: "GET", Path: "/admin/jobs", Tag: "admin", Summary: "List job schedules",
+			Response: JobList{}},
+		openapi.Operation{Method: "PUT", Path: "/admin/jobs/:name", Tag: "admin", Summary: "Change a job's schedule or pause it",
+			Body: scheduler.Update{}, Response: scheduler.Job{}},
+		openapi.Operation{Method: "POST", Path: "/admin/jobs/:name/run", Tag: "admin", Summary: "Start a job in the background",
+			Response: api.Result{}},
+		openapi.Operation{Method: "POST", Path: "/admin/api-keys", Tag: "admin", Summary: "Issue an API key",
+			Body: KeyRequest{}, Response: IssuedKey{}, Status: 201},
+		openapi.Operation{Method: "GET", Path: "/admin/api-keys", Tag: "admin", Summary: "List API keys",
+			Response: KeyList{}},
+		openapi.Operation{Method: "DELETE", Path: "/admin/api-keys/:id", Tag: "admin", Summary: "Revoke an API key",
+			Response: apikeys.Key{}},
+		openapi.Operation{Method: "GET", Path: "/admin/api-keys/:id/usage", Tag: "admin", Summary: "Get an API key's daily usage",
+			Query:    []openapi.Param{{Name: "days", Type: "integer", Description: "Days back (default 30, max 366)"}},
+			Response: KeyUsage{}},
+	)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/sync-service/app"
+	"github.com/verifi-protocol/sync-service/internal/candles"
+	"github.com/verifi-protocol/sync-service/internal/config"
+)
+
+// LOG_FORMAT, also used by serve for request logs
+var logFormat string
+
+// command is one subcommand: sync-service <name> [flags]
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
+
+var commands = []command{
+	{"serve", "", "Run the sync jobs and HTTP API (default)", serve},
+	{"migrate", "", "Apply pending migrations and exit", migrateCmd},
+	{"rebuild-candles", "[--interval 1h] MARKET", "Rebuild a market's OHLCV candles and probability history from Activity", rebuildCandlesCmd},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: sync-service <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+		if cmd.args != "" {
+			fmt.Fprintf(os.Stderr, "  %-16s   %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run sync-service <command> -h for the flags of a command.")
+}
+
+func openMigratedDB(cfg *config.Config) (*store.DB, error) {
+	database, err := store.New(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	log.Info().Msg("✅ Database connected")
+
+	if err := app.Migrate(context.Background(), database); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return database, nil
+}
+
+func migrateCmd(cfg *config.Config, args []string) error {
+	flag.NewFlagSet("migrate", flag.ExitOnError).Parse(args)
+
+	database, err := openMigratedDB(cfg)
+	if err != nil {
+		return err
+	}
+	database.Close()
+
+	log.Info().Msg("✅ Migrations applied")
+	return nil
+}
+
+func rebuildCandlesCmd(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rebuild-candles", flag.ExitOnError)
+	interval := flags.String("interval", "1h", "Candle interval: 1m, 5m, 15m, 1h, 4h or 1d")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("rebuild-candles takes exactly one market address")
+	}
+
+	return runRebuildCandles(cfg, flags.Arg(0), *interval)
+}
+
+// runRebuildCandles rebuilds one market's candles: no HTTP server, no jobs
+func runRebuildCandles(cfg *config.Config, market, interval string) error {
+	database, err := openMigratedDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	result, err := candles.Rebuild(context.Background(), database, market, interval)
+	if err != nil {
+		return fmt.Errorf("candle rebuild failed: %w", err)
+	}
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
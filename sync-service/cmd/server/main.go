@@ -2,38 +2,64 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/joho/godotenv"
-	"github.com/robfig/cron/v3"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/logging"
+	"github.com/verifi-protocol/pkg/openapi"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/sync-service/app"
 	"github.com/verifi-protocol/sync-service/internal/config"
-	"github.com/verifi-protocol/sync-service/internal/db"
-	"github.com/verifi-protocol/sync-service/internal/indexer"
-	"github.com/verifi-protocol/sync-service/internal/sync"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Warn().Msg("No .env file found, using system environment variables")
+	// The first argument names the command; without one (or when it is a
+	// flag, as with the older --rebuild-candles) the server runs
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		printUsage()
+		if name == "help" {
+			return
+		}
+		os.Exit(2)
 	}
 
-	// Setup logger
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-
-	log.Info().Msg("🚀 VeriFi Sync Service Starting...")
+	// Load environment variables (LOG_FORMAT may be set there, so report a
+	// missing file once the logger is set up)
+	envErr := godotenv.Load()
+
+	// Setup logger. LOG_FORMAT is read here rather than in config so config
+	// errors come out in the same format.
+	logFormat = os.Getenv("LOG_FORMAT")
+	err := logging.Setup(logFormat, logging.Meta{
+		Service:  app.Service,
+		Instance: store.InstanceID("sync-service"),
+		Version:  buildinfo.Version,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid log format")
+	}
+	if envErr != nil {
+		log.Warn().Msg("No .env file found, using system environment variables")
+	}
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -41,138 +67,95 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
-	// Initialize database
-	database, err := db.New(cfg.DatabaseURL)
+	if err := cmd.run(cfg, args); err != nil {
+		log.Fatal().Err(err).Str("command", cmd.name).Msg("Command failed")
+	}
+}
+
+// serve runs the sync jobs and HTTP API until SIGINT or SIGTERM
+func serve(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	// Older spelling of the rebuild-candles command
+	rebuildMarket := flags.String("rebuild-candles", "", "Same as the rebuild-candles command")
+	candleInterval := flags.String("candle-interval", "1h", "Candle interval for --rebuild-candles")
+	flags.Parse(args)
+
+	if *rebuildMarket != "" {
+		return runRebuildCandles(cfg, *rebuildMarket, *candleInterval)
+	}
+
+	log.Info().Msg("🚀 VeriFi Sync Service Starting...")
+
+	database, err := openMigratedDB(cfg)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to database")
+		return err
 	}
 	defer database.Close()
 
-	log.Info().Msg("✅ Database connected")
-
-	// Initialize sync service
-	syncService := sync.NewService(database, cfg)
+	syncApp, err := app.New(cfg, database)
+	if err != nil {
+		return fmt.Errorf("failed to start sync service: %w", err)
+	}
 
 	// Setup Fiber app
-	app := fiber.New(fiber.Config{
+	server := fiber.New(fiber.Config{
 		AppName:      "VeriFi Sync Service",
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	})
 
 	// Middleware
-	app.Use(recover.New())
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
+	server.Use(recover.New())
+	server.Use(logging.Requests(logFormat))
+	server.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowMethods: "GET,POST",
+		AllowMethods: "GET,POST,PUT,DELETE",
 	}))
+	server.Use(ratelimit.New(syncApp.RateLimit()))
+	server.Use(auth.New(syncApp.Auth()))
 
-	// Health check
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":  "healthy",
-			"service": "verifi-sync-service",
-			"time":    time.Now().Unix(),
-		})
-	})
-
-	// Manual sync endpoints
-	app.Post("/sync/metrics", func(c *fiber.Ctx) error {
-		log.Info().Msg("📊 Manual metrics sync triggered")
-		if err := syncService.SyncMetrics(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Metrics sync failed")
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.JSON(fiber.Map{"status": "success", "message": "Metrics synced"})
-	})
-
-	app.Post("/sync/pools", func(c *fiber.Ctx) error {
-		log.Info().Msg("💧 Manual pools sync triggered")
-		if err := syncService.SyncPools(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Pools sync failed")
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.JSON(fiber.Map{"status": "success", "message": "Pools synced"})
-	})
+	syncApp.Probes(server)
+	syncApp.Routes(server)
 
-	app.Post("/sync/activities", func(c *fiber.Ctx) error {
-		log.Info().Msg("📝 Manual activities sync triggered")
-		if err := syncService.SyncActivities(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Activities sync failed")
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.JSON(fiber.Map{"status": "success", "message": "Activities synced"})
-	})
-
-	// Status endpoint
-	app.Get("/status", func(c *fiber.Ctx) error {
-		stats := syncService.GetStats()
-		return c.JSON(stats)
-	})
+	// OpenAPI document of every route, for generated clients
+	ops := append(syncApp.ProbeOperations(), syncApp.Operations()...)
+	ops = append(ops, openapi.Operation{Method: "GET", Path: "/openapi.json", Tag: "service", Summary: "This document"})
+	server.Get("/openapi.json", openapi.Handler(openapi.Build(app.Service, buildinfo.Version, ops, syncApp.Auth().Policy)))
 
-	// Setup cron jobs
-	cronScheduler := cron.New(cron.WithSeconds())
-
-	// Metrics sync - every hour
-	cronScheduler.AddFunc("0 0 * * * *", func() {
-		log.Info().Msg("⏰ Running scheduled metrics sync")
-		if err := syncService.SyncMetrics(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Scheduled metrics sync failed")
-		}
-	})
-
-	// Pools sync - every 15 minutes
-	cronScheduler.AddFunc("0 */15 * * * *", func() {
-		log.Info().Msg("⏰ Running scheduled pools sync")
-		if err := syncService.SyncPools(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Scheduled pools sync failed")
-		}
-	})
-
-	// Activities sync - every 5 minutes
-	cronScheduler.AddFunc("0 */5 * * * *", func() {
-		log.Info().Msg("⏰ Running scheduled activities sync")
-		if err := syncService.SyncActivities(context.Background()); err != nil {
-			log.Error().Err(err).Msg("Scheduled activities sync failed")
-		}
-	})
-
-	cronScheduler.Start()
-	log.Info().Msg("⏰ Cron scheduler started")
-
-	// Start server in goroutine
-	port := cfg.Port
-	if port == "" {
-		port = "3001"
+	if err := syncApp.Start(); err != nil {
+		return fmt.Errorf("failed to start scheduler: %w", err)
 	}
 
+	// Start server in goroutine
+	port := syncApp.Port()
 	go func() {
 		log.Info().Msgf("🌐 Server listening on :%s", port)
-		if err := app.Listen(":" + port); err != nil {
+		if err := server.Listen(":" + port); err != nil {
 			log.Fatal().Err(err).Msg("Failed to start server")
 		}
 	}()
 
-	// Run initial sync
-	log.Info().Msg("🔄 Running initial sync...")
-	if err := syncService.SyncMetrics(context.Background()); err != nil {
-		log.Warn().Err(err).Msg("Initial metrics sync failed")
-	}
-	if err := syncService.SyncPools(context.Background()); err != nil {
-		log.Warn().Err(err).Msg("Initial pools sync failed")
+	// Announce this instance to the service registry (optional)
+	deregister, err := syncApp.Announce()
+	if err != nil {
+		return fmt.Errorf("invalid SERVICE_REGISTRY: %w", err)
 	}
 
+	// Run initial sync
+	syncApp.InitialSync(context.Background())
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info().Msg("🛑 Shutting down server...")
-	cronScheduler.Stop()
-	if err := app.Shutdown(); err != nil {
+	deregister()
+	syncApp.Close()
+	if err := server.Shutdown(); err != nil {
 		log.Error().Err(err).Msg("Server shutdown error")
 	}
 
 	log.Info().Msg("✅ Server stopped")
+	return nil
 }
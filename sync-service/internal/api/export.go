@@ -0,0 +1,321 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/parquet"
+)
+
+// Rows read per query while streaming an export. Each page is one Parquet
+// row group.
+const exportPageSize = 5000
+
+// exportFormat validates ?format, csv (the default) or parquet
+func exportFormat(c *fiber.Ctx) (string, error) {
+	switch format := c.Query("format", "csv"); format {
+	case "csv", "parquet":
+		return format, nil
+	default:
+		return "", c.Status(400).JSON(fiber.Map{"error": "format must be csv or parquet"})
+	}
+}
+
+// exportWriter writes the rows of an export. Values are nil, strings,
+// int64s, float64s or times, matching the export's columns; Flush ends a
+// page and sends what was written.
+type exportWriter interface {
+	Write(row ...any) error
+	Flush() error
+}
+
+// csvExport writes rows as CSV: nil as an empty field and times as RFC 3339
+// in UTC
+type csvExport struct {
+	w  *csv.Writer
+	bw *bufio.Writer
+}
+
+func (e *csvExport) Write(row ...any) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) Flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	return e.bw.Flush()
+}
+
+// parquetExport writes each page as a row group
+type parquetExport struct {
+	*parquet.Writer
+	bw *bufio.Writer
+}
+
+func (e *parquetExport) Flush() error {
+	if err := e.Writer.Flush(); err != nil {
+		return err
+	}
+	return e.bw.Flush()
+}
+
+// streamExport sends an attachment named name plus the format's extension,
+// whose rows are written by fill after the handler returns. fill gets a
+// background context, since the request's is gone by then, and calls Flush
+// after each page to send it. A write error means the client went away.
+func streamExport(c *fiber.Ctx, format, name string, columns []parquet.Column, fill func(ctx context.Context, w exportWriter) error) error {
+	filename := name + "." + format
+	if format == "parquet" {
+		c.Set(fiber.HeaderContentType, "application/vnd.apache.parquet")
+	} else {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	c.Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+		var err error
+		if format == "parquet" {
+			w := &parquetExport{Writer: parquet.NewWriter(bw, columns), bw: bw}
+			if err = fill(context.Background(), w); err == nil {
+				err = w.Close()
+			}
+		} else {
+			w := &csvExport{w: csv.NewWriter(bw), bw: bw}
+			header := make([]string, len(columns))
+			for i, col := range columns {
+				header[i] = col.Name
+			}
+			if err = w.w.Write(header); err == nil {
+				err = fill(context.Background(), w)
+			}
+			w.w.Flush()
+		}
+		if err != nil {
+			log.Error().Err(err).Str("export", filename).Msg("Export stopped")
+		}
+		bw.Flush()
+	})
+	return nil
+}
+
+// exportActivities streams the Activity rows of a time range, optionally of
+// one market, oldest first. Rows are read in pages keyed on (timestamp, id),
+// so a large export never holds more than a page in memory. Days compacted
+// by the activity retention are not included.
+func (h *Handler) exportActivities(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if format == "" {
+		return err
+	}
+
+	to := time.Now().UTC()
+	if s := c.Query("to"); s != "" {
+		t, err := parseDay(s)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "to must be a date (2006-01-02), RFC 3339 or epoch timestamp"})
+		}
+		to = t
+	}
+	var from time.Time
+	if s := c.Query("from"); s != "" {
+		t, err := parseDay(s)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "from must be a date (2006-01-02), RFC 3339 or epoch timestamp"})
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return c.Status(400).JSON(fiber.Map{"error": "from must be before to"})
+	}
+	market := c.Query("market")
+
+	query := `
+		SELECT "id"::text, "txHash", "marketAddress", "userAddress", action, outcome,
+			amount::text, "totalValue"::text, "timestamp"
+		FROM "Activity"
+		WHERE "timestamp" >= $1 AND "timestamp" < $2
+			AND ($3 = '' OR "marketAddress" = $3)
+			AND ("timestamp", "id"::text) > ($4, $5)
+		ORDER BY "timestamp", "id"::text
+		LIMIT $6
+	`
+
+	// Amounts stay decimal strings so they aren't rounded
+	columns := []parquet.Column{
+		{Name: "id", Type: parquet.String},
+		{Name: "txHash", Type: parquet.String},
+		{Name: "marketAddress", Type: parquet.String},
+		{Name: "userAddress", Type: parquet.String},
+		{Name: "action", Type: parquet.String},
+		{Name: "outcome", Type: parquet.String, Optional: true},
+		{Name: "amount", Type: parquet.String},
+		{Name: "totalValue", Type: parquet.String, Optional: true},
+		{Name: "timestamp", Type: parquet.Timestamp},
+	}
+	return streamExport(c, format, "activities", columns, func(ctx context.Context, w exportWriter) error {
+		// The cursor starts just before from
+		afterTime, afterID := from, ""
+		for {
+			rows, err := h.db.Pool().Query(ctx, query, from, to, market, afterTime, afterID, exportPageSize)
+			if err != nil {
+				return err
+			}
+
+			n := 0
+			for rows.Next() {
+				var id, txHash, marketAddress, user, action, amount string
+				var outcome, totalValue *string
+				var ts time.Time
+				if err := rows.Scan(&id, &txHash, &marketAddress, &user, &action, &outcome, &amount, &totalValue, &ts); err != nil {
+					rows.Close()
+					return err
+				}
+				if err := w.Write(id, txHash, marketAddress, user, action, optional(outcome), amount, optional(totalValue), ts); err != nil {
+					rows.Close()
+					return err
+				}
+				afterTime, afterID = ts, id
+				n++
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if n < exportPageSize {
+				return nil
+			}
+		}
+	})
+}
+
+// exportMetrics streams a snapshot of every market's metric columns,
+// optionally filtered by ?market and ?status, with the time it was taken
+func (h *Handler) exportMetrics(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if format == "" {
+		return err
+	}
+	market, status := c.Query("market"), c.Query("status")
+
+	query := `
+		SELECT
+			"marketAddress", status,
+			COALESCE("volume24h", 0)::text, COALESCE("volume7d", 0)::text, COALESCE("totalVolume", 0)::text,
+			COALESCE("uniqueTraders", 0), COALESCE("uniqueTraders24h", 0), COALESCE("uniqueTraders7d", 0),
+			COALESCE("tradeCount24h", 0), COALESCE("avgTradeSize", 0)::text,
+			COALESCE("openInterestYes", 0)::text, COALESCE("openInterestNo", 0)::text, COALESCE("liquidity", 0)::text,
+			"priceChange24h", "currentPriceYes", "currentPriceNo", "updatedAt"
+		FROM "Market"
+		WHERE ($1 = '' OR "marketAddress" = $1)
+			AND ($2 = '' OR status = $2)
+			AND "marketAddress" > $3
+		ORDER BY "marketAddress"
+		LIMIT $4
+	`
+
+	columns := []parquet.Column{
+		{Name: "snapshotAt", Type: parquet.Timestamp},
+		{Name: "marketAddress", Type: parquet.String},
+		{Name: "status", Type: parquet.String},
+		{Name: "volume24h", Type: parquet.String},
+		{Name: "volume7d", Type: parquet.String},
+		{Name: "totalVolume", Type: parquet.String},
+		{Name: "uniqueTraders", Type: parquet.Int64},
+		{Name: "uniqueTraders24h", Type: parquet.Int64},
+		{Name: "uniqueTraders7d", Type: parquet.Int64},
+		{Name: "tradeCount24h", Type: parquet.Int64},
+		{Name: "avgTradeSize", Type: parquet.String},
+		{Name: "openInterestYes", Type: parquet.String},
+		{Name: "openInterestNo", Type: parquet.String},
+		{Name: "liquidity", Type: parquet.String},
+		{Name: "priceChange24h", Type: parquet.Double, Optional: true},
+		{Name: "currentPriceYes", Type: parquet.Double, Optional: true},
+		{Name: "currentPriceNo", Type: parquet.Double, Optional: true},
+		{Name: "updatedAt", Type: parquet.Timestamp},
+	}
+	// Whole seconds, like every other timestamp the API emits
+	snapshotAt := time.Now().UTC().Truncate(time.Second)
+	return streamExport(c, format, "market-metrics", columns, func(ctx context.Context, w exportWriter) error {
+		after := ""
+		for {
+			rows, err := h.db.Pool().Query(ctx, query, market, status, after, exportPageSize)
+			if err != nil {
+				return err
+			}
+
+			n := 0
+			for rows.Next() {
+				var address, marketStatus, volume24h, volume7d, totalVolume, avgTradeSize, oiYes, oiNo, liquidity string
+				var traders, traders24h, traders7d, trades24h int64
+				var priceChange, priceYes, priceNo *float64
+				var updatedAt time.Time
+				err := rows.Scan(&address, &marketStatus, &volume24h, &volume7d, &totalVolume,
+					&traders, &traders24h, &traders7d, &trades24h, &avgTradeSize,
+					&oiYes, &oiNo, &liquidity, &priceChange, &priceYes, &priceNo, &updatedAt)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				err = w.Write(
+					snapshotAt, address, marketStatus,
+					volume24h, volume7d, totalVolume,
+					traders, traders24h, traders7d,
+					trades24h, avgTradeSize,
+					oiYes, oiNo, liquidity,
+					optional(priceChange), optional(priceYes), optional(priceNo),
+					updatedAt.UTC().Truncate(time.Second),
+				)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				after = address
+				n++
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if n < exportPageSize {
+				return nil
+			}
+		}
+	})
+}
+
+// optional returns a nullable column's value, or an untyped nil when unset
+func optional[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,335 @@
+// Package api serves the read endpoints backed by the synced and indexed
+// tables.
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/page"
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/sync-service/internal/i18n"
+)
+
+type Handler struct {
+	db        *store.DB
+	locales   []string
+	freshness func() aptos.Freshness
+}
+
+func New(database *store.DB, locales []string) *Handler {
+	return &Handler{
+		db:      database,
+		locales: locales,
+	}
+}
+
+// SetFreshness reports fullnode freshness on market responses, so clients
+// can tell when the data stopped updating
+func (h *Handler) SetFreshness(fn func() aptos.Freshness) {
+	h.freshness = fn
+}
+
+// Freshness is aptos.Freshness with this API's camelCase field names
+type Freshness struct {
+	Stale       bool       `json:"stale"`
+	LastFreshAt *time.Time `json:"lastFreshAt,omitempty"`
+	StaleSince  *time.Time `json:"staleSince,omitempty"`
+}
+
+func (h *Handler) fresh() Freshness {
+	if h.freshness == nil {
+		return Freshness{}
+	}
+	return Freshness(h.freshness())
+}
+
+// Register mounts the read and admin routes on app
+func (h *Handler) Register(app fiber.Router) {
+	app.Get("/markets", h.listMarkets)
+	app.Get("/markets/search", h.searchMarkets)
+	app.Get("/markets/:address", h.getMarket)
+	app.Get("/markets/:address/translations", h.listTranslations)
+	app.Get("/markets/:address/resolution-history", h.resolutionHistory)
+	app.Get("/markets/:address/volume", h.marketVolume)
+	app.Get("/markets/:address/summary", h.marketSummary)
+	app.Put("/admin/markets/:address/translations/:locale", h.putTranslation)
+	app.Put("/admin/markets/:address/category", h.putCategory)
+	app.Get("/users/:address", h.getUser)
+	app.Get("/stats", h.protocolStats)
+	app.Get("/stats/revenue", h.revenue)
+	app.Get("/export/activities", h.exportActivities)
+	app.Get("/export/metrics", h.exportMetrics)
+}
+
+// Result is the body of a write that returns nothing else
+type Result struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type Market struct {
+	MarketAddress string  `json:"marketAddress"`
+	Description   string  `json:"description"`
+	Locale        string  `json:"locale"`
+	Status        string  `json:"status"`
+	Category      *string `json:"category"`
+	Volume24h     float64 `json:"volume24h"`
+	Volume7d      float64 `json:"volume7d"`
+	TotalVolume   float64 `json:"totalVolume"`
+	UniqueTraders int     `json:"uniqueTraders"`
+
+	// Recent engagement, for trending sorts
+	UniqueTraders24h int     `json:"uniqueTraders24h"`
+	UniqueTraders7d  int     `json:"uniqueTraders7d"`
+	TradeCount24h    int     `json:"tradeCount24h"`
+	AvgTradeSize     float64 `json:"avgTradeSize"` // APT per trade, all time
+
+	// Outstanding shares per outcome and the APT held by the pool
+	OpenInterestYes float64 `json:"openInterestYes"`
+	OpenInterestNo  float64 `json:"openInterestNo"`
+	Liquidity       float64 `json:"liquidity"`
+
+	// Change of the YES price over 24h; null until the market has traded
+	PriceChange24h *float64 `json:"priceChange24h"`
+
+	// Implied prices from the pool reserves; null until SyncPrices reads
+	// the pool
+	CurrentPriceYes *float64   `json:"currentPriceYes"`
+	CurrentPriceNo  *float64   `json:"currentPriceNo"`
+	PricesUpdatedAt *time.Time `json:"pricesUpdatedAt"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Description falls back to the original text when no translation exists
+const marketColumns = `
+	m."marketAddress",
+	COALESCE(t.description, m."description"),
+	COALESCE(t.locale, '` + i18n.SourceLocale + `'),
+	m.status,
+	m."category",
+	COALESCE(m."volume24h", 0),
+	COALESCE(m."volume7d", 0),
+	COALESCE(m."totalVolume", 0),
+	COALESCE(m."uniqueTraders", 0),
+	COALESCE(m."uniqueTraders24h", 0),
+	COALESCE(m."uniqueTraders7d", 0),
+	COALESCE(m."tradeCount24h", 0),
+	COALESCE(m."avgTradeSize", 0),
+	COALESCE(m."openInterestYes", 0),
+	COALESCE(m."openInterestNo", 0),
+	COALESCE(m."liquidity", 0),
+	m."priceChange24h",
+	m."currentPriceYes",
+	m."currentPriceNo",
+	m."pricesUpdatedAt",
+	m."updatedAt"
+`
+
+const marketJoin = `
+	FROM "Market" m
+	LEFT JOIN market_translations t
+		ON t.market_address = m."marketAddress" AND t.locale = $1
+`
+
+// scanMarket scans marketColumns, then any columns selected after them into
+// extra
+func scanMarket(row pgx.Row, extra ...any) (Market, error) {
+	var m Market
+	err := row.Scan(append([]any{
+		&m.MarketAddress, &m.Description, &m.Locale, &m.Status, &m.Category,
+		&m.Volume24h, &m.Volume7d, &m.TotalVolume, &m.UniqueTraders,
+		&m.UniqueTraders24h, &m.UniqueTraders7d, &m.TradeCount24h, &m.AvgTradeSize,
+		&m.OpenInterestYes, &m.OpenInterestNo, &m.Liquidity, &m.PriceChange24h,
+		&m.CurrentPriceYes, &m.CurrentPriceNo, &m.PricesUpdatedAt, &m.UpdatedAt,
+	}, extra...)...)
+	return m, err
+}
+
+// locale negotiates the response language and advertises it
+func (h *Handler) locale(c *fiber.Ctx) string {
+	locale := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage), h.locales)
+	c.Set(fiber.HeaderContentLanguage, locale)
+	c.Vary(fiber.HeaderAcceptLanguage)
+	return locale
+}
+
+// MarketList is a page of markets with the fullnode freshness
+type MarketList struct {
+	Markets     []Market   `json:"markets"`
+	Count       int        `json:"count"`
+	NextCursor  *string    `json:"nextCursor"`
+	Stale       bool       `json:"stale"`
+	LastFreshAt *time.Time `json:"lastFreshAt"`
+	StaleSince  *time.Time `json:"staleSince"`
+}
+
+// MarketDetail is one market with the fullnode freshness
+type MarketDetail struct {
+	Market
+	Freshness
+}
+
+// Market list sorts and their columns; ties are broken by address
+var marketSorts = map[string]page.Column{
+	"updatedAt":        {Expr: `m."updatedAt"`, Type: "timestamp"},
+	"createdAt":        {Expr: `m."createdAt"`, Type: "timestamp"},
+	"volume24h":        {Expr: `COALESCE(m."volume24h", 0)`, Type: "numeric"},
+	"totalVolume":      {Expr: `COALESCE(m."totalVolume", 0)`, Type: "numeric"},
+	"tradeCount24h":    {Expr: `COALESCE(m."tradeCount24h", 0)`, Type: "int"},
+	"uniqueTraders24h": {Expr: `COALESCE(m."uniqueTraders24h", 0)`, Type: "int"},
+}
+
+var marketPage = page.Options{
+	DefaultLimit: 50,
+	MaxLimit:     200,
+	Sorts:        []string{"updatedAt", "createdAt", "volume24h", "totalVolume", "tradeCount24h", "uniqueTraders24h"},
+	Keys:         2,
+}
+
+// listMarkets pages through the markets, optionally of one ?status, by
+// ?sort (default most recently updated first)
+func (h *Handler) listMarkets(c *fiber.Ctx) error {
+	locale := h.locale(c)
+
+	p, err := page.Parse(c, marketPage)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	columns := []page.Column{marketSorts[p.Sort], {Expr: `m."marketAddress"`, Type: "text"}}
+	after, afterArgs := p.Keyset(columns, 4)
+
+	// The sort key is read back as text for the cursor, so numeric keys
+	// round-trip exactly
+	query := `SELECT ` + marketColumns + `, (` + columns[0].Expr + `)::text` + marketJoin + `
+		WHERE ($2 = '' OR m.status = $2) AND ` + after + `
+		ORDER BY ` + p.Order(columns) + `
+		LIMIT $3
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, append([]any{locale, c.Query("status"), p.Limit}, afterArgs...)...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list markets")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to list markets"})
+	}
+	defer rows.Close()
+
+	markets := []Market{}
+	var lastKey string
+	for rows.Next() {
+		m, err := scanMarket(rows, &lastKey)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to scan market")
+			continue
+		}
+		markets = append(markets, m)
+	}
+
+	var next *string
+	if n := len(markets); n > 0 {
+		next = p.Next(n, lastKey, markets[n-1].MarketAddress)
+	}
+	fresh := h.fresh()
+	return c.JSON(MarketList{
+		Markets:     markets,
+		Count:       len(markets),
+		NextCursor:  next,
+		Stale:       fresh.Stale,
+		LastFreshAt: fresh.LastFreshAt,
+		StaleSince:  fresh.StaleSince,
+	})
+}
+
+func (h *Handler) getMarket(c *fiber.Ctx) error {
+	locale := h.locale(c)
+
+	query := `SELECT ` + marketColumns + marketJoin + `
+		WHERE m."marketAddress" = $2
+	`
+
+	m, err := scanMarket(h.db.Pool().QueryRow(c.Context(), query, locale, c.Params("address")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"error": "market not found"})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load market")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load market"})
+	}
+
+	return c.JSON(MarketDetail{m, h.fresh()})
+}
+
+type TranslationList struct {
+	Translations []Translation `json:"translations"`
+}
+
+type Translation struct {
+	Locale      string    `json:"locale"`
+	Description string    `json:"description"`
+	Source      string    `json:"source"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (h *Handler) listTranslations(c *fiber.Ctx) error {
+	query := `
+		SELECT locale, description, source, updated_at
+		FROM market_translations
+		WHERE market_address = $1
+		ORDER BY locale
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, c.Params("address"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list translations")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to list translations"})
+	}
+	defer rows.Close()
+
+	translations := []Translation{}
+	for rows.Next() {
+		var t Translation
+		if err := rows.Scan(&t.Locale, &t.Description, &t.Source, &t.UpdatedAt); err != nil {
+			log.Error().Err(err).Msg("Failed to scan translation")
+			continue
+		}
+		translations = append(translations, t)
+	}
+
+	return c.JSON(TranslationList{Translations: translations})
+}
+
+// TranslationUpdate is the body of PUT
+// /admin/markets/:address/translations/:locale
+type TranslationUpdate struct {
+	Description string `json:"description"`
+}
+
+// putTranslation stores a manual translation. Manual entries replace
+// machine translations and are never overwritten by the sync job.
+func (h *Handler) putTranslation(c *fiber.Ctx) error {
+	var req TranslationUpdate
+	if err := c.BodyParser(&req); err != nil || req.Description == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "description is required"})
+	}
+
+	query := `
+		INSERT INTO market_translations (market_address, locale, description, source, updated_at)
+		VALUES ($1, $2, $3, 'manual', NOW())
+		ON CONFLICT (market_address, locale)
+		DO UPDATE SET description = $3, source = 'manual', updated_at = NOW()
+	`
+
+	_, err := h.db.Pool().Exec(c.Context(), query, c.Params("address"), strings.ToLower(c.Params("locale")), req.Description)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store translation")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to store translation"})
+	}
+
+	return c.JSON(Result{Status: "success"})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"github.com/verifi-protocol/pkg/openapi"
+)
+
+// Query parameters shared by the list endpoints
+var (
+	limitParam  = openapi.Param{Name: "limit", Type: "integer", Description: "Page size"}
+	cursorParam = openapi.Param{Name: "cursor", Description: "nextCursor of the previous page"}
+)
+
+// Operations documents the routes Register mounts, in the same order
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: "GET", Path: "/markets", Tag: "markets", Summary: "List markets",
+			Query: []openapi.Param{
+				{Name: "status", Description: "Only markets in this status"},
+				{Name: "sort", Description: "updatedAt, createdAt, volume24h, totalVolume, tradeCount24h or uniqueTraders24h; a leading - for descending"},
+				limitParam, cursorParam,
+			},
+			Response: MarketList{}},
+		{Method: "GET", Path: "/markets/search", Tag: "markets", Summary: "Search markets by description and category",
+			Query: []openapi.Param{
+				{Name: "q", Required: true, Description: "Web search syntax: quoted phrases, or, -word"},
+				{Name: "status", Description: "Only markets in this status"},
+				{Name: "category", Description: "Only markets in this category (case-insensitive)"},
+				limitParam,
+			},
+			Response: SearchResults{}},
+		{Method: "GET", Path: "/markets/:address", Tag: "markets", Summary: "Get a market",
+			Response: MarketDetail{}},
+		{Method: "GET", Path: "/markets/:address/translations", Tag: "markets", Summary: "List a market's description translations",
+			Response: TranslationList{}},
+		{Method: "GET", Path: "/markets/:address/resolution-history", Tag: "markets", Summary: "Get a market's dispute timeline",
+			Response: ResolutionHistory{}},
+		{Method: "GET", Path: "/markets/:address/volume", Tag: "markets", Summary: "Get a market's volume series",
+			Query: []openapi.Param{
+				{Name: "interval", Description: "1h (default), 4h, 1d or 1w"},
+				{Name: "range", Description: "How far back, e.g. 24h, 7d (default) or 4w"},
+			},
+			Response: VolumeSeries{}},
+		{Method: "GET", Path: "/markets/:address/summary", Tag: "markets", Summary: "Get everything a market page shows",
+			Query: []openapi.Param{
+				{Name: "trades", Type: "integer", Description: "Recent trades returned (default 20, max 100)"},
+				{Name: "holders", Type: "integer", Description: "Top holders returned (default 10, max 100)"},
+			},
+			Response: MarketSummary{}},
+		{Method: "PUT", Path: "/admin/markets/:address/translations/:locale", Tag: "admin", Summary: "Set a manual translation",
+			Body: TranslationUpdate{}, Response: Result{}},
+		{Method: "PUT", Path: "/admin/markets/:address/category", Tag: "admin", Summary: "Set or clear a market's category",
+			Body: CategoryUpdate{}, Response: Result{}},
+		{Method: "GET", Path: "/users/:address", Tag: "users", Summary: "Get a trader's stats, positions and trades",
+			Query:    []openapi.Param{limitParam, cursorParam},
+			Response: UserProfile{}},
+		{Method: "GET", Path: "/stats", Tag: "stats", Summary: "Get protocol-wide totals",
+			Response: ProtocolStats{}},
+		{Method: "GET", Path: "/stats/revenue", Tag: "stats", Summary: "Get protocol fee revenue per period and market",
+			Query: []openapi.Param{
+				{Name: "period", Description: "day (default) or week"},
+				{Name: "from", Description: "Date, RFC 3339 or epoch timestamp"},
+				{Name: "to", Description: "Date, RFC 3339 or epoch timestamp"},
+				{Name: "market", Description: "Only this market"},
+			},
+			Response: Revenue{}},
+		{Method: "GET", Path: "/export/activities", Tag: "export", Summary: "Export activities as CSV",
+			Query: []openapi.Param{
+				{Name: "format", Description: "csv"},
+				{Name: "from", Description: "Date, RFC 3339 or epoch timestamp"},
+				{Name: "to", Description: "Date, RFC 3339 or epoch timestamp"},
+				{Name: "market", Description: "Only this market"},
+			},
+			ContentType: "text/csv"},
+		{Method: "GET", Path: "/export/metrics", Tag: "export", Summary: "Export market metrics as CSV",
+			Query: []openapi.Param{
+				{Name: "format", Description: "csv"},
+				{Name: "market", Description: "Only this market"},
+				{Name: "status", Description: "Only markets in this status"},
+			},
+			ContentType: "text/csv"},
+	}
+}
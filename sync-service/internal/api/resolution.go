@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// ResolutionStep is one oracle state transition of a market, as indexed
+// from ResolutionProposedEvent, DisputeRaisedEvent and DisputeResolvedEvent
+type ResolutionStep struct {
+	State     string          `json:"state"` // PROPOSED, DISPUTED or DISPUTE_RESOLVED
+	Outcome   *string         `json:"outcome"`
+	Actor     string          `json:"actor"`
+	TxHash    string          `json:"txHash"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// ResolutionHistory is a market's dispute timeline
+type ResolutionHistory struct {
+	History []ResolutionStep `json:"history"`
+	Count   int              `json:"count"`
+}
+
+// resolutionHistory returns a market's dispute timeline, oldest first
+func (h *Handler) resolutionHistory(c *fiber.Ctx) error {
+	steps, err := h.loadResolutionHistory(c.Context(), c.Params("address"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load resolution history")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load resolution history"})
+	}
+
+	return c.JSON(ResolutionHistory{History: steps, Count: len(steps)})
+}
+
+func (h *Handler) loadResolutionHistory(ctx context.Context, marketAddress string) ([]ResolutionStep, error) {
+	query := `
+		SELECT "state", "outcome", "actor", "txHash", "data", "timestamp"
+		FROM "ResolutionHistory"
+		WHERE "marketAddress" = $1
+		ORDER BY "timestamp", "id"
+	`
+
+	rows, err := h.db.Pool().Query(ctx, query, marketAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []ResolutionStep{}
+	for rows.Next() {
+		var s ResolutionStep
+		if err := rows.Scan(&s.State, &s.Outcome, &s.Actor, &s.TxHash, &s.Data, &s.Timestamp); err != nil {
+			log.Error().Err(err).Msg("Failed to scan resolution step")
+			continue
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Revenue periods and how far back each looks by default
+var revenuePeriods = map[string]time.Duration{
+	"day":  30 * 24 * time.Hour,
+	"week": 12 * 7 * 24 * time.Hour,
+}
+
+// RevenueBucket is the protocol fees one market collected in one period.
+// Amounts are exact APT decimals.
+type RevenueBucket struct {
+	PeriodStart   time.Time `json:"periodStart"`
+	MarketAddress string    `json:"marketAddress"`
+	Fees          string    `json:"fees"`
+	FeeCount      int64     `json:"feeCount"`
+}
+
+// RevenuePeriod is the fees of every market in one period
+type RevenuePeriod struct {
+	PeriodStart time.Time `json:"periodStart"`
+	Fees        string    `json:"fees"`
+	FeeCount    int64     `json:"feeCount"`
+}
+
+// Revenue is the protocol fees of a date range, per market and period and
+// in total
+type Revenue struct {
+	Period   string          `json:"period"`
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Markets  []RevenueBucket `json:"markets"`
+	Periods  []RevenuePeriod `json:"periods"`
+	Total    string          `json:"total"`
+	FeeCount int64           `json:"feeCount"`
+}
+
+// revenue aggregates ProtocolFee into daily or weekly revenue per market.
+// Periods are UTC; weeks start on Monday.
+func (h *Handler) revenue(c *fiber.Ctx) error {
+	period := c.Query("period", "day")
+	lookback, ok := revenuePeriods[period]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "period must be day or week"})
+	}
+
+	to := time.Now().UTC()
+	if s := c.Query("to"); s != "" {
+		t, err := parseDay(s)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "to must be a date (2006-01-02), RFC 3339 or epoch timestamp"})
+		}
+		to = t
+	}
+	from := to.Add(-lookback)
+	if s := c.Query("from"); s != "" {
+		t, err := parseDay(s)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "from must be a date (2006-01-02), RFC 3339 or epoch timestamp"})
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return c.Status(400).JSON(fiber.Map{"error": "from must be before to"})
+	}
+
+	// One pass: (period, market) rows, per-period totals with a NULL market
+	// and the grand total with both NULL
+	query := `
+		SELECT
+			date_trunc($1, "timestamp") AS period_start,
+			"marketAddress",
+			SUM("amount")::text,
+			COUNT(*)
+		FROM "ProtocolFee"
+		WHERE "timestamp" >= $2 AND "timestamp" < $3
+		  AND ($4 = '' OR "marketAddress" = $4)
+		GROUP BY GROUPING SETS (
+			(date_trunc($1, "timestamp"), "marketAddress"),
+			(date_trunc($1, "timestamp")),
+			()
+		)
+		ORDER BY period_start NULLS LAST, "marketAddress" NULLS FIRST
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, period, from, to, c.Query("market"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to aggregate protocol revenue")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to aggregate protocol revenue"})
+	}
+	defer rows.Close()
+
+	markets := []RevenueBucket{}
+	periods := []RevenuePeriod{}
+	total, count := "0", int64(0)
+	for rows.Next() {
+		var start *time.Time
+		var market *string
+		var fees string
+		var n int64
+		if err := rows.Scan(&start, &market, &fees, &n); err != nil {
+			log.Error().Err(err).Msg("Failed to scan protocol revenue")
+			continue
+		}
+		switch {
+		case start == nil:
+			total, count = fees, n
+		case market == nil:
+			periods = append(periods, RevenuePeriod{PeriodStart: start.UTC(), Fees: fees, FeeCount: n})
+		default:
+			markets = append(markets, RevenueBucket{PeriodStart: start.UTC(), MarketAddress: *market, Fees: fees, FeeCount: n})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to aggregate protocol revenue")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to aggregate protocol revenue"})
+	}
+
+	return c.JSON(Revenue{
+		Period:   period,
+		From:     timeconv.Format(from),
+		To:       timeconv.Format(to),
+		Markets:  markets,
+		Periods:  periods,
+		Total:    total,
+		FeeCount: count,
+	})
+}
+
+// parseDay accepts a plain date (midnight UTC) besides the timeconv formats
+func parseDay(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return timeconv.Parse(s)
+}
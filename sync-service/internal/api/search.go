@@ -0,0 +1,110 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/page"
+)
+
+// Longest accepted search query
+const maxSearchLength = 200
+
+// Results are ranked, so a search returns one page
+var searchPage = page.Options{DefaultLimit: 50, MaxLimit: 200}
+
+// SearchResult is a market matching a search, with its relevance
+type SearchResult struct {
+	Market
+	Rank float64 `json:"rank"`
+}
+
+// SearchResults are the markets matching a query, most relevant first
+type SearchResults struct {
+	Query   string         `json:"query"`
+	Markets []SearchResult `json:"markets"`
+	Count   int            `json:"count"`
+}
+
+// CategoryUpdate is the body of PUT /admin/markets/:address/category
+type CategoryUpdate struct {
+	Category string `json:"category"`
+}
+
+// searchMarkets finds markets whose description or category matches ?q,
+// most relevant first. q uses web search syntax: words are all required,
+// "quoted phrases" match in order, "or" between words and -word to exclude.
+// ?status and ?category filter the results.
+func (h *Handler) searchMarkets(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" || len(q) > maxSearchLength {
+		return c.Status(400).JSON(fiber.Map{"error": "q is required and at most 200 characters"})
+	}
+	p, err := page.Parse(c, searchPage)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	locale := h.locale(c)
+
+	// Ties, e.g. between markets matching only on the category, go to the
+	// most traded
+	query := `SELECT ` + marketColumns + `, ts_rank_cd(m."searchVector", query)::float8 AS rank` + marketJoin + `
+		CROSS JOIN websearch_to_tsquery('english', $2) AS query
+		WHERE m."searchVector" @@ query
+			AND ($3 = '' OR m.status = $3)
+			AND ($4 = '' OR LOWER(m."category") = LOWER($4))
+		ORDER BY rank DESC, m."totalVolume" DESC, m."marketAddress"
+		LIMIT $5
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, locale, q, c.Query("status"), c.Query("category"), p.Limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to search markets")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to search markets"})
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		r.Market, err = scanMarket(rows, &r.Rank)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to scan market")
+			continue
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to search markets")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to search markets"})
+	}
+
+	return c.JSON(SearchResults{Query: q, Markets: results, Count: len(results)})
+}
+
+// putCategory sets a market's category, or clears it with an empty one
+func (h *Handler) putCategory(c *fiber.Ctx) error {
+	var req CategoryUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	category := strings.TrimSpace(req.Category)
+	if len(category) > 64 {
+		return c.Status(400).JSON(fiber.Map{"error": "category must be at most 64 characters"})
+	}
+
+	tag, err := h.db.Pool().Exec(c.Context(), `
+		UPDATE "Market" SET "category" = NULLIF($2, ''), "updatedAt" = NOW()
+		WHERE "marketAddress" = $1
+	`, c.Params("address"), category)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store category")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to store category"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "market not found"})
+	}
+
+	return c.JSON(Result{Status: "success"})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ProtocolStats are the protocol-wide totals kept by the protocol_stats job.
+// Amounts are exact APT decimals.
+type ProtocolStats struct {
+	TotalMarkets  int       `json:"totalMarkets"`
+	ActiveMarkets int       `json:"activeMarkets"`
+	TotalVolume   string    `json:"totalVolume"`
+	Volume24h     string    `json:"volume24h"`
+	UniqueTraders int       `json:"uniqueTraders"`
+	TVL           string    `json:"tvl"`
+	TotalFees     string    `json:"totalFees"`
+	Fees24h       string    `json:"fees24h"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// protocolStats returns the cached protocol-wide totals. It reads one row,
+// so it is cheap enough for public pages.
+func (h *Handler) protocolStats(c *fiber.Ctx) error {
+	query := `
+		SELECT "totalMarkets", "activeMarkets", "totalVolume"::text, "volume24h"::text,
+			"uniqueTraders", "tvl"::text, "totalFees"::text, "fees24h"::text, "updatedAt"
+		FROM "ProtocolStats"
+		WHERE "id" = 1
+	`
+
+	var s ProtocolStats
+	err := h.db.Pool().QueryRow(c.Context(), query).Scan(
+		&s.TotalMarkets, &s.ActiveMarkets, &s.TotalVolume, &s.Volume24h,
+		&s.UniqueTraders, &s.TVL, &s.TotalFees, &s.Fees24h, &s.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(503).JSON(fiber.Map{"error": "protocol stats not computed yet"})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load protocol stats")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load protocol stats"})
+	}
+
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	return c.JSON(s)
+}
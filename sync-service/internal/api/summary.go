@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Default and maximum trades and holders in a market summary
+const (
+	summaryTrades   = 20
+	summaryHolders  = 10
+	maxSummaryItems = 100
+)
+
+// PoolReserves are a market pool's reserves in raw share units, as last
+// read by SyncPrices
+type PoolReserves struct {
+	Yes       int64      `json:"yes"`
+	No        int64      `json:"no"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// Trade is one trade of a market, from Activity
+type Trade struct {
+	ID          string    `json:"id"`
+	TxHash      string    `json:"txHash"`
+	UserAddress string    `json:"userAddress"`
+	Action      string    `json:"action"`
+	Outcome     *string   `json:"outcome"`
+	Amount      float64   `json:"amount"`
+	TotalValue  *float64  `json:"totalValue"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Holder is a trader's outstanding shares in a market: bought minus sold
+type Holder struct {
+	UserAddress string  `json:"userAddress"`
+	SharesYes   float64 `json:"sharesYes"`
+	SharesNo    float64 `json:"sharesNo"`
+}
+
+// Resolution is how and when a market resolves, with its dispute timeline
+type Resolution struct {
+	ResolutionTimestamp *time.Time       `json:"resolutionTimestamp"`
+	Outcome             *string          `json:"outcome"`
+	Resolver            *string          `json:"resolver"`
+	ResolutionTxHash    *string          `json:"resolutionTxHash"`
+	ResolvedAt          *time.Time       `json:"resolvedAt"`
+	History             []ResolutionStep `json:"history"`
+}
+
+// MarketSummary is everything a market page shows
+type MarketSummary struct {
+	Market       Market        `json:"market"`
+	Reserves     *PoolReserves `json:"reserves"`
+	RecentTrades []Trade       `json:"recentTrades"`
+	TopHolders   []Holder      `json:"topHolders"`
+	Resolution   Resolution    `json:"resolution"`
+	Freshness    Freshness     `json:"freshness"`
+}
+
+// marketSummary assembles everything a market page shows: the market row
+// with its current prices, the pool reserves, the latest trades, the largest
+// holders and the resolution. ?trades and ?holders set how many of each are
+// returned. Everything is read from the database, so the page needs no view
+// calls.
+func (h *Handler) marketSummary(c *fiber.Ctx) error {
+	tradeCount := c.QueryInt("trades", summaryTrades)
+	holderCount := c.QueryInt("holders", summaryHolders)
+	if tradeCount < 0 || tradeCount > maxSummaryItems || holderCount < 0 || holderCount > maxSummaryItems {
+		return c.Status(400).JSON(fiber.Map{"error": "trades and holders must be between 0 and 100"})
+	}
+	address := c.Params("address")
+	locale := h.locale(c)
+
+	query := `SELECT ` + marketColumns + `,
+			m."reserveYes", m."reserveNo",
+			m."resolutionTimestamp", m."outcome", m."resolver", m."resolutionTxHash", m."resolvedAt"
+		` + marketJoin + `
+		WHERE m."marketAddress" = $2
+	`
+
+	var reserveYes, reserveNo *int64
+	var resolution Resolution
+	m, err := scanMarket(h.db.Pool().QueryRow(c.Context(), query, locale, address),
+		&reserveYes, &reserveNo,
+		&resolution.ResolutionTimestamp, &resolution.Outcome, &resolution.Resolver,
+		&resolution.ResolutionTxHash, &resolution.ResolvedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"error": "market not found"})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load market")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load market"})
+	}
+
+	// null until SyncPrices has read the pool
+	var reserves *PoolReserves
+	if reserveYes != nil && reserveNo != nil {
+		reserves = &PoolReserves{Yes: *reserveYes, No: *reserveNo, UpdatedAt: m.PricesUpdatedAt}
+	}
+
+	trades, err := h.marketTrades(c, address, tradeCount)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load market trades")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load market trades"})
+	}
+	holders, err := h.topHolders(c, address, holderCount)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load market holders")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load market holders"})
+	}
+	resolution.History, err = h.loadResolutionHistory(c.Context(), address)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load resolution history")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load resolution history"})
+	}
+
+	return c.JSON(MarketSummary{
+		Market:       m,
+		Reserves:     reserves,
+		RecentTrades: trades,
+		TopHolders:   holders,
+		Resolution:   resolution,
+		Freshness:    h.fresh(),
+	})
+}
+
+// marketTrades returns a market's latest limit trades, newest first
+func (h *Handler) marketTrades(c *fiber.Ctx, address string, limit int) ([]Trade, error) {
+	query := `
+		SELECT "id"::text, "txHash", "userAddress", action, outcome, amount::float8, "totalValue"::float8, "timestamp"
+		FROM "Activity"
+		WHERE "marketAddress" = $1
+		ORDER BY "timestamp" DESC, "id"::text DESC
+		LIMIT $2
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, address, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []Trade{}
+	for rows.Next() {
+		var t Trade
+		if err := rows.Scan(&t.ID, &t.TxHash, &t.UserAddress, &t.Action, &t.Outcome, &t.Amount, &t.TotalValue, &t.Timestamp); err != nil {
+			return nil, err
+		}
+		t.Timestamp = t.Timestamp.UTC()
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+// topHolders returns the limit traders holding the most shares of a market,
+// both outcomes together, counting compacted days
+func (h *Handler) topHolders(c *fiber.Ctx, address string, limit int) ([]Holder, error) {
+	query := `
+		SELECT
+			"userAddress",
+			COALESCE(SUM(shares) FILTER (WHERE outcome = 'YES'), 0)::float8 AS yes,
+			COALESCE(SUM(shares) FILTER (WHERE outcome = 'NO'), 0)::float8 AS no
+		FROM (
+			SELECT "userAddress", outcome, CASE WHEN action = 'BUY' THEN amount ELSE -amount END AS shares
+			FROM "Activity"
+			WHERE "marketAddress" = $1 AND action IN ('BUY', 'SELL')
+			UNION ALL
+			SELECT user_address, outcome, CASE WHEN action = 'BUY' THEN amount ELSE -amount END
+			FROM activity_rollups
+			WHERE market_address = $1 AND action IN ('BUY', 'SELL')
+		) p
+		GROUP BY "userAddress"
+		HAVING COALESCE(SUM(shares), 0) > 0
+		ORDER BY COALESCE(SUM(shares), 0) DESC, "userAddress"
+		LIMIT $2
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, address, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := []Holder{}
+	for rows.Next() {
+		var holder Holder
+		if err := rows.Scan(&holder.UserAddress, &holder.SharesYes, &holder.SharesNo); err != nil {
+			return nil, err
+		}
+		holders = append(holders, holder)
+	}
+	return holders, rows.Err()
+}
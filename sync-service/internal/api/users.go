@@ -0,0 +1,338 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/page"
+)
+
+// UserStats summarizes a trader's activity across every market. PnL is in
+// APT: realized covers sells and resolved markets, where a winning share is
+// worth 1 APT and a losing one nothing; unrealized values the open shares at
+// the current pool price.
+type UserStats struct {
+	MarketsTraded int      `json:"marketsTraded"`
+	TradeCount    int      `json:"tradeCount"`
+	TotalVolume   float64  `json:"totalVolume"`
+	RealizedPnL   float64  `json:"realizedPnl"`
+	UnrealizedPnL float64  `json:"unrealizedPnl"`
+	Wins          int      `json:"wins"`
+	Losses        int      `json:"losses"`
+	WinRate       *float64 `json:"winRate"` // null before a traded market resolves
+	UserCosts
+}
+
+// UserCosts is what a trader's trades cost on top of their price: gas (in
+// gas units and APT) and protocol fees (APT). Gas is only known for
+// transactions the indexer recorded it for; trades in compacted days
+// aren't counted.
+type UserCosts struct {
+	GasUsed     int64   `json:"gasUsed"`
+	GasSpent    float64 `json:"gasSpent"`
+	FeesPaid    float64 `json:"feesPaid"`
+	TradingCost float64 `json:"tradingCost"` // gasSpent + feesPaid
+}
+
+// UserMarketCost is UserCosts for the trades in one market
+type UserMarketCost struct {
+	MarketAddress string `json:"marketAddress"`
+	Transactions  int    `json:"transactions"`
+	UserCosts
+}
+
+// UserPosition is the shares a trader holds in one outcome of an
+// unresolved market, at average cost
+type UserPosition struct {
+	MarketAddress string   `json:"marketAddress"`
+	Description   string   `json:"description"`
+	Status        string   `json:"status"`
+	Outcome       string   `json:"outcome"`
+	Shares        float64  `json:"shares"`
+	AvgPrice      float64  `json:"avgPrice"`
+	CostBasis     float64  `json:"costBasis"`
+	CurrentPrice  *float64 `json:"currentPrice"` // null until SyncPrices reads the pool
+	Value         *float64 `json:"value"`
+	UnrealizedPnL *float64 `json:"unrealizedPnl"`
+	RealizedPnL   float64  `json:"realizedPnl"`
+}
+
+// UserTrade is one trade from Activity
+type UserTrade struct {
+	ID            string    `json:"id"`
+	TxHash        string    `json:"txHash"`
+	MarketAddress string    `json:"marketAddress"`
+	Action        string    `json:"action"`
+	Outcome       *string   `json:"outcome"`
+	Amount        float64   `json:"amount"`
+	TotalValue    *float64  `json:"totalValue"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// UserProfile is a trader's stats, open positions and a page of trades
+type UserProfile struct {
+	Address    string           `json:"address"`
+	Stats      UserStats        `json:"stats"`
+	Positions  []UserPosition   `json:"positions"`
+	Trades     []UserTrade      `json:"trades"`
+	Costs      []UserMarketCost `json:"costs"` // per market
+	NextCursor *string          `json:"nextCursor"`
+}
+
+// userCosts sums gas and protocol fees per market over the transactions of
+// a trader's Activity rows. A transaction touching two markets counts in
+// both; the last row is the trader's total, with each transaction once.
+const userCosts = `
+	WITH txs AS (
+		SELECT DISTINCT "txHash" AS tx_hash, "marketAddress" AS market
+		FROM "Activity"
+		WHERE LOWER("userAddress") = ANY($1)
+	),
+	costs AS (
+		SELECT txs.tx_hash, txs.market, it.gas_used, it.gas_used * it.gas_unit_price AS gas_octas,
+			(SELECT SUM(f.amount) FROM "ProtocolFee" f WHERE f."txHash" = txs.tx_hash AND f."marketAddress" = txs.market) AS fees
+		FROM txs
+		LEFT JOIN indexed_transactions it ON it.tx_hash = txs.tx_hash
+	)
+	SELECT market, COUNT(DISTINCT tx_hash)::int, COALESCE(SUM(gas_used), 0)::bigint,
+		COALESCE(SUM(gas_octas), 0)::float8 / 1e8, COALESCE(SUM(fees), 0)::float8
+	FROM costs
+	GROUP BY market
+	UNION ALL
+	SELECT NULL, COUNT(*)::int, COALESCE(SUM(gas_used), 0)::bigint,
+		COALESCE(SUM(gas_octas), 0)::float8 / 1e8,
+		COALESCE((SELECT SUM(fees) FROM costs), 0)::float8
+	FROM (SELECT DISTINCT tx_hash, gas_used, gas_octas FROM costs) t
+	ORDER BY 1 NULLS LAST
+`
+
+// userPositions sums a trader's BUY and SELL rows, including compacted days,
+// per market and outcome, with what the market row says about its
+// resolution and current prices
+const userPositions = `
+	SELECT
+		p.market,
+		COALESCE(t.description, m."description", ''),
+		COALESCE(m.status, ''),
+		p.outcome,
+		COALESCE(SUM(p.shares) FILTER (WHERE p.action = 'BUY'), 0)::float8,
+		COALESCE(SUM(p.value) FILTER (WHERE p.action = 'BUY'), 0)::float8,
+		COALESCE(SUM(p.shares) FILTER (WHERE p.action = 'SELL'), 0)::float8,
+		COALESCE(SUM(p.value) FILTER (WHERE p.action = 'SELL'), 0)::float8,
+		SUM(p.trades)::int,
+		UPPER(COALESCE(m."outcome", '')),
+		CASE WHEN p.outcome = 'NO' THEN m."currentPriceNo" ELSE m."currentPriceYes" END
+	FROM (
+		SELECT "marketAddress" AS market, action, outcome, amount AS shares, COALESCE("totalValue", 0) AS value, 1 AS trades
+		FROM "Activity"
+		WHERE LOWER("userAddress") = ANY($1) AND action IN ('BUY', 'SELL') AND outcome IN ('YES', 'NO')
+		UNION ALL
+		SELECT market_address, action, outcome, amount, total_value, trade_count
+		FROM activity_rollups
+		WHERE LOWER(user_address) = ANY($1) AND action IN ('BUY', 'SELL') AND outcome IN ('YES', 'NO')
+	) p
+	LEFT JOIN "Market" m ON m."marketAddress" = p.market
+	LEFT JOIN market_translations t ON t.market_address = p.market AND t.locale = $2
+	GROUP BY p.market, t.description, m."description", m.status, p.outcome, m."outcome", m."currentPriceYes", m."currentPriceNo"
+	ORDER BY p.market, p.outcome
+`
+
+var userTradePage = page.Options{DefaultLimit: 50, MaxLimit: 200, Keys: 2}
+
+// getUser returns a trader's profile: aggregated stats, open positions and
+// a page of their trade history, newest first. ?limit sets the page size
+// and ?cursor continues from a previous page's nextCursor. Trades in days
+// compacted by the activity retention count towards the stats but aren't
+// listed.
+func (h *Handler) getUser(c *fiber.Ctx) error {
+	forms, ok := addressForms(c.Params("address"))
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "address must be a hex account address"})
+	}
+	p, err := page.Parse(c, userTradePage)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	locale := h.locale(c)
+
+	rows, err := h.db.Pool().Query(c.Context(), userPositions, forms, locale)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load user positions")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load user positions"})
+	}
+
+	stats := UserStats{}
+	positions := []UserPosition{}
+	marketPnL := map[string]float64{}
+	resolvedMarkets := map[string]bool{}
+	for rows.Next() {
+		var p UserPosition
+		var bought, cost, sold, proceeds float64
+		var trades int
+		var winner string
+		err := rows.Scan(&p.MarketAddress, &p.Description, &p.Status, &p.Outcome,
+			&bought, &cost, &sold, &proceeds, &trades, &winner, &p.CurrentPrice)
+		if err != nil {
+			rows.Close()
+			log.Error().Err(err).Msg("Failed to scan user position")
+			return c.Status(500).JSON(fiber.Map{"error": "failed to load user positions"})
+		}
+
+		stats.TradeCount += trades
+		stats.TotalVolume += cost + proceeds
+
+		// Average cost: shares sold and still held are valued at what the
+		// shares cost on average
+		if bought > 0 {
+			p.AvgPrice = cost / bought
+		}
+		p.Shares = max(bought-sold, 0)
+		p.CostBasis = p.Shares * p.AvgPrice
+		p.RealizedPnL = proceeds - sold*p.AvgPrice
+
+		if p.Status == "resolved" {
+			payout := 0.0
+			if winner == p.Outcome {
+				payout = p.Shares
+			}
+			p.RealizedPnL += payout - p.CostBasis
+			stats.RealizedPnL += p.RealizedPnL
+			marketPnL[p.MarketAddress] += p.RealizedPnL
+			resolvedMarkets[p.MarketAddress] = true
+			continue
+		}
+
+		stats.RealizedPnL += p.RealizedPnL
+		marketPnL[p.MarketAddress] += p.RealizedPnL
+		if p.Shares <= 0 {
+			continue
+		}
+		if p.CurrentPrice != nil {
+			value := p.Shares * *p.CurrentPrice
+			unrealized := value - p.CostBasis
+			p.Value, p.UnrealizedPnL = &value, &unrealized
+			stats.UnrealizedPnL += unrealized
+		}
+		positions = append(positions, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to load user positions")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load user positions"})
+	}
+
+	// A resolved market is won when everything traded in it, sells included,
+	// made money
+	stats.MarketsTraded = len(marketPnL)
+	for market := range resolvedMarkets {
+		if marketPnL[market] > 0 {
+			stats.Wins++
+		} else {
+			stats.Losses++
+		}
+	}
+	if stats.Wins+stats.Losses > 0 {
+		rate := float64(stats.Wins) / float64(stats.Wins+stats.Losses)
+		stats.WinRate = &rate
+	}
+
+	costs, totals, err := h.userCosts(c, forms)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load user costs")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load user costs"})
+	}
+	stats.UserCosts = totals
+
+	trades, err := h.userTrades(c, forms, p)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load user trades")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load user trades"})
+	}
+	var nextCursor *string
+	if n := len(trades); n > 0 {
+		nextCursor = p.Next(n, page.TimeKey(trades[n-1].Timestamp), trades[n-1].ID)
+	}
+
+	return c.JSON(UserProfile{
+		Address:    forms[0],
+		Stats:      stats,
+		Positions:  positions,
+		Trades:     trades,
+		Costs:      costs,
+		NextCursor: nextCursor,
+	})
+}
+
+// userCosts returns a trader's gas and fees per market and in total
+func (h *Handler) userCosts(c *fiber.Ctx, forms []string) ([]UserMarketCost, UserCosts, error) {
+	rows, err := h.db.Pool().Query(c.Context(), userCosts, forms)
+	if err != nil {
+		return nil, UserCosts{}, err
+	}
+	defer rows.Close()
+
+	costs := []UserMarketCost{}
+	var totals UserCosts
+	for rows.Next() {
+		var market *string
+		var m UserMarketCost
+		if err := rows.Scan(&market, &m.Transactions, &m.GasUsed, &m.GasSpent, &m.FeesPaid); err != nil {
+			return nil, UserCosts{}, err
+		}
+		m.TradingCost = m.GasSpent + m.FeesPaid
+		if market == nil {
+			totals = m.UserCosts
+			continue
+		}
+		m.MarketAddress = *market
+		costs = append(costs, m)
+	}
+	return costs, totals, rows.Err()
+}
+
+// userTrades returns a page of a trader's activities, newest first
+func (h *Handler) userTrades(c *fiber.Ctx, forms []string, p page.Params) ([]UserTrade, error) {
+	columns := []page.Column{{Expr: `"timestamp"`, Type: "timestamp"}, {Expr: `"id"::text`, Type: "text"}}
+	after, afterArgs := p.Keyset(columns, 3)
+	query := `
+		SELECT "id"::text, "txHash", "marketAddress", action, outcome, amount::float8, "totalValue"::float8, "timestamp"
+		FROM "Activity"
+		WHERE LOWER("userAddress") = ANY($1) AND ` + after + `
+		ORDER BY ` + p.Order(columns) + `
+		LIMIT $2
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, append([]any{forms, p.Limit}, afterArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []UserTrade{}
+	for rows.Next() {
+		var t UserTrade
+		if err := rows.Scan(&t.ID, &t.TxHash, &t.MarketAddress, &t.Action, &t.Outcome, &t.Amount, &t.TotalValue, &t.Timestamp); err != nil {
+			return nil, err
+		}
+		t.Timestamp = t.Timestamp.UTC()
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+// addressForms returns the forms an account address may be stored in, the
+// short one (leading zeros trimmed) first
+func addressForms(address string) ([]string, bool) {
+	hex := strings.TrimPrefix(strings.ToLower(address), "0x")
+	if hex == "" || len(hex) > 64 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return nil, false
+	}
+	short := "0x" + strings.TrimLeft(hex, "0")
+	if short == "0x" {
+		short = "0x0"
+	}
+	long := "0x" + strings.Repeat("0", 64-len(hex)) + hex
+	return []string{short, long}, true
+}
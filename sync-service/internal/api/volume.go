@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
+)
+
+// Volume series intervals, all multiples of the hourly buckets
+var volumeIntervals = map[string]time.Duration{
+	"1h": time.Hour,
+	"4h": 4 * time.Hour,
+	"1d": 24 * time.Hour,
+	"1w": 7 * 24 * time.Hour,
+}
+
+// Points a volume series may hold
+const maxVolumePoints = 2000
+
+// VolumePoint is the volume traded in one interval, in exact APT
+type VolumePoint struct {
+	BucketStart time.Time `json:"bucketStart"`
+	Volume      string    `json:"volume"`
+	TradeCount  int64     `json:"tradeCount"`
+}
+
+// VolumeSeries is a market's volume per interval over a range
+type VolumeSeries struct {
+	Market    string        `json:"market"`
+	Interval  string        `json:"interval"`
+	Range     string        `json:"range"`
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Points    []VolumePoint `json:"points"`
+	UpdatedAt *time.Time    `json:"updatedAt"`
+}
+
+// marketVolume returns a market's volume and trade counts per interval over
+// range, read from the hourly buckets of the volume job. Intervals are UTC
+// (weeks start on Monday), the last one is in progress, and empty ones are
+// included, so the series can be charted as is.
+func (h *Handler) marketVolume(c *fiber.Ctx) error {
+	interval := c.Query("interval", "1h")
+	size, ok := volumeIntervals[interval]
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "interval must be 1h, 4h, 1d or 1w"})
+	}
+	span, err := parseRange(c.Query("range", "7d"))
+	if err != nil || span <= 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "range must be a positive duration such as 24h, 7d or 30d"})
+	}
+	if span/size > maxVolumePoints {
+		return c.Status(400).JSON(fiber.Map{"error": "range holds more than " + strconv.Itoa(maxVolumePoints) + " intervals, use a larger interval"})
+	}
+
+	address := c.Params("address")
+	var exists bool
+	err = h.db.Pool().QueryRow(c.Context(),
+		`SELECT EXISTS (SELECT 1 FROM "Market" WHERE "marketAddress" = $1)`, address,
+	).Scan(&exists)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load market")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load market"})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{"error": "market not found"})
+	}
+
+	to := time.Now().UTC().Truncate(size).Add(size)
+	from := to.Add(-span).Truncate(size)
+
+	query := `
+		SELECT
+			s.bucket_start,
+			COALESCE(SUM(b.volume), 0)::text,
+			COALESCE(SUM(b.trade_count), 0)
+		FROM generate_series($2::timestamp, $3::timestamp - $4::interval, $4::interval) AS s(bucket_start)
+		LEFT JOIN market_volume_buckets b
+			ON b.market_address = $1
+			AND b.bucket_start >= s.bucket_start AND b.bucket_start < s.bucket_start + $4::interval
+		GROUP BY s.bucket_start
+		ORDER BY s.bucket_start
+	`
+
+	rows, err := h.db.Pool().Query(c.Context(), query, address, from, to, size)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load volume series")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load volume series"})
+	}
+	defer rows.Close()
+
+	points := []VolumePoint{}
+	for rows.Next() {
+		var p VolumePoint
+		if err := rows.Scan(&p.BucketStart, &p.Volume, &p.TradeCount); err != nil {
+			log.Error().Err(err).Msg("Failed to scan volume point")
+			continue
+		}
+		p.BucketStart = p.BucketStart.UTC()
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to load volume series")
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load volume series"})
+	}
+
+	// Buckets are as recent as the last volume run
+	var updatedAt *time.Time
+	err = h.db.Pool().QueryRow(c.Context(),
+		`SELECT value::timestamptz FROM sync_state WHERE key = 'volume_buckets_through'`,
+	).Scan(&updatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Error().Err(err).Msg("Failed to load volume freshness")
+	}
+
+	return c.JSON(VolumeSeries{
+		Market:    address,
+		Interval:  interval,
+		Range:     c.Query("range", "7d"),
+		From:      timeconv.Format(from),
+		To:        timeconv.Format(to),
+		Points:    points,
+		UpdatedAt: updatedAt,
+	})
+}
+
+// parseRange is time.ParseDuration with a d (day) and w (week) unit
+func parseRange(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
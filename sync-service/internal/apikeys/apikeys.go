@@ -0,0 +1,337 @@
+// Package apikeys issues and checks the API keys of external consumers.
+// Keys are stored hashed in api_keys; each instance keeps the active hashes
+// in memory, so checking a request doesn't touch the database, and counts
+// requests per key in memory until they are added to api_key_usage.
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// Issued keys start with this, so they are recognizable in logs and secret
+// scanners
+const keyPrefix = "vfk_"
+
+// refreshInterval is how often usage is written and the active keys are
+// reloaded, which bounds how long a key revoked on another instance is
+// still accepted here
+const refreshInterval = 30 * time.Second
+
+var ErrNotFound = errors.New("api key not found")
+
+// Key is an issued key without its secret
+type Key struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+// Usage is a key's requests on one UTC day
+type Usage struct {
+	Day      string `json:"day"` // YYYY-MM-DD
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+}
+
+type usageKey struct {
+	id  int64
+	day string
+}
+
+type Store struct {
+	db *store.DB
+
+	mu     sync.RWMutex
+	active map[string]int64 // key hash -> id
+
+	countMu  sync.Mutex
+	counts   map[usageKey]*Usage
+	lastUsed map[int64]time.Time
+}
+
+func New(db *store.DB) *Store {
+	return &Store{
+		db:       db,
+		active:   make(map[string]int64),
+		counts:   make(map[usageKey]*Usage),
+		lastUsed: make(map[int64]time.Time),
+	}
+}
+
+// Run loads the active keys, then writes usage and reloads them every
+// refreshInterval until ctx is done. Flush writes what is left at shutdown.
+func (s *Store) Run(ctx context.Context) {
+	if err := s.Load(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load API keys")
+	}
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to write API key usage")
+			}
+			if err := s.Load(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to reload API keys")
+			}
+		}
+	}
+}
+
+// Load replaces the in-memory active keys with the unrevoked rows
+func (s *Store) Load(ctx context.Context) error {
+	rows, err := s.db.Pool().Query(ctx, `SELECT id, key_hash FROM api_keys WHERE revoked_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	active := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return err
+		}
+		active[hash] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.active = active
+	s.mu.Unlock()
+	return nil
+}
+
+// Issue creates a key named name and returns it with its secret, which is
+// not stored and can't be shown again
+func (s *Store) Issue(ctx context.Context, name string) (Key, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return Key{}, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	secret := keyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	hash := hashKey(secret)
+
+	key := Key{Name: name, Prefix: secret[:len(keyPrefix)+8]}
+	err := s.db.Pool().QueryRow(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, key.Name, key.Prefix, hash).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	s.mu.Lock()
+	s.active[hash] = key.ID
+	s.mu.Unlock()
+	return key, secret, nil
+}
+
+// Revoke stops a key from being accepted, on this instance right away and
+// on the others at their next reload. Revoking a revoked key keeps its
+// original revocation time.
+func (s *Store) Revoke(ctx context.Context, id int64) (Key, error) {
+	var hash string
+	var key Key
+	err := s.db.Pool().QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING key_hash, id, name, prefix, created_at, last_used_at, revoked_at
+	`, id).Scan(&hash, &key.ID, &key.Name, &key.Prefix, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Key{}, ErrNotFound
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	s.mu.Lock()
+	delete(s.active, hash)
+	s.mu.Unlock()
+	return key, nil
+}
+
+// List returns every key, revoked ones included, newest first
+func (s *Store) List(ctx context.Context) ([]Key, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, name, prefix, created_at, last_used_at, revoked_at
+		FROM api_keys
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		var k Key
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Usage returns a key and its usage over the last days UTC days, newest
+// first, with this instance's unwritten requests included
+func (s *Store) Usage(ctx context.Context, id int64, days int) (Key, []Usage, error) {
+	if err := s.Flush(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to write API key usage")
+	}
+
+	var key Key
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT id, name, prefix, created_at, last_used_at, revoked_at
+		FROM api_keys WHERE id = $1
+	`, id).Scan(&key.ID, &key.Name, &key.Prefix, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Key{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Key{}, nil, err
+	}
+
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), requests, errors
+		FROM api_key_usage
+		WHERE key_id = $1 AND day > (NOW() AT TIME ZONE 'UTC')::date - $2::int
+		ORDER BY day DESC
+	`, id, days)
+	if err != nil {
+		return Key{}, nil, err
+	}
+	defer rows.Close()
+
+	usage := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.Day, &u.Requests, &u.Errors); err != nil {
+			return Key{}, nil, err
+		}
+		usage = append(usage, u)
+	}
+	return key, usage, rows.Err()
+}
+
+// Lookup returns the ID of the active key token, for the auth middleware
+func (s *Store) Lookup(token string) (int64, bool) {
+	if len(token) <= len(keyPrefix) || token[:len(keyPrefix)] != keyPrefix {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.active[hashKey(token)]
+	return id, ok
+}
+
+// Record counts a request made with key id that was answered with status
+func (s *Store) Record(id int64, status int) {
+	now := time.Now().UTC()
+	k := usageKey{id: id, day: now.Format(time.DateOnly)}
+
+	s.countMu.Lock()
+	defer s.countMu.Unlock()
+	u, ok := s.counts[k]
+	if !ok {
+		u = &Usage{Day: k.day}
+		s.counts[k] = u
+	}
+	u.Requests++
+	if status >= 400 {
+		u.Errors++
+	}
+	s.lastUsed[id] = now
+}
+
+// Flush adds the counted requests to api_key_usage. On failure they are
+// kept for the next flush.
+func (s *Store) Flush(ctx context.Context) error {
+	s.countMu.Lock()
+	counts, lastUsed := s.counts, s.lastUsed
+	s.counts, s.lastUsed = make(map[usageKey]*Usage), make(map[int64]time.Time)
+	s.countMu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for k, u := range counts {
+		batch.Queue(`
+			INSERT INTO api_key_usage (key_id, day, requests, errors)
+			VALUES ($1, $2::date, $3, $4)
+			ON CONFLICT (key_id, day) DO UPDATE SET
+				requests = api_key_usage.requests + EXCLUDED.requests,
+				errors = api_key_usage.errors + EXCLUDED.errors
+		`, k.id, k.day, u.Requests, u.Errors)
+	}
+	for id, at := range lastUsed {
+		batch.Queue(`
+			UPDATE api_keys SET last_used_at = GREATEST(last_used_at, $2)
+			WHERE id = $1
+		`, id, at)
+	}
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err == nil {
+		err = tx.SendBatch(ctx, batch).Close()
+		if err == nil {
+			err = tx.Commit(ctx)
+		} else {
+			tx.Rollback(ctx)
+		}
+	}
+	if err != nil {
+		s.restore(counts, lastUsed)
+		return err
+	}
+	return nil
+}
+
+// restore adds counts that failed to flush back to the pending ones
+func (s *Store) restore(counts map[usageKey]*Usage, lastUsed map[int64]time.Time) {
+	s.countMu.Lock()
+	defer s.countMu.Unlock()
+	for k, u := range counts {
+		if pending, ok := s.counts[k]; ok {
+			pending.Requests += u.Requests
+			pending.Errors += u.Errors
+		} else {
+			s.counts[k] = u
+		}
+	}
+	for id, at := range lastUsed {
+		if at.After(s.lastUsed[id]) {
+			s.lastUsed[id] = at
+		}
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,222 @@
+// Package candles derives OHLCV candles and implied probability history from
+// the Activity table. Prices are the YES-equivalent APT paid per share: a YES
+// trade at p counts as p, a NO trade at p as 1-p.
+package candles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// Intervals maps the supported interval names to bucket sizes
+var Intervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// progressEvery is how many activities are scanned between progress logs
+const progressEvery = 10000
+
+type Candle struct {
+	BucketStart time.Time
+	Open        float64
+	High        float64
+	Low         float64
+	Close       float64
+	Volume      float64
+	TradeCount  int
+}
+
+// Result summarizes a rebuild
+type Result struct {
+	Market     string        `json:"market"`
+	Interval   string        `json:"interval"`
+	Activities int           `json:"activities"`
+	Skipped    int           `json:"skipped"`
+	Candles    int           `json:"candles"`
+	Replaced   int64         `json:"replaced"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// Rebuild recomputes every candle and probability point of market at
+// interval from its activities, then swaps the new series in within one
+// transaction so readers never see a partial series.
+func Rebuild(ctx context.Context, database *store.DB, market, interval string) (*Result, error) {
+	size, ok := Intervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	start := time.Now()
+	result := &Result{Market: market, Interval: interval}
+
+	// Activity before the retention cutoff has been compacted; the candles
+	// stored for it are kept. Every interval divides a day, so no bucket
+	// straddles the (midnight) cutoff.
+	var keepBefore time.Time
+	err := database.Pool().QueryRow(ctx,
+		`SELECT value::timestamptz FROM sync_state WHERE key = 'activity_compacted_before'`,
+	).Scan(&keepBefore)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load compaction cutoff: %w", err)
+	}
+	keepBefore = keepBefore.UTC()
+
+	var total int
+	err = database.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM "Activity"
+		WHERE "marketAddress" = $1 AND action IN ('BUY', 'SELL') AND "timestamp" >= $2
+	`, market, keepBefore).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count activities: %w", err)
+	}
+
+	log.Info().
+		Str("market", market).
+		Str("interval", interval).
+		Int("activities", total).
+		Msg("🕯️  Rebuilding candles")
+
+	rows, err := database.Pool().Query(ctx, `
+		SELECT "timestamp", "outcome", "amount", "totalValue"
+		FROM "Activity"
+		WHERE "marketAddress" = $1 AND action IN ('BUY', 'SELL') AND "timestamp" >= $2
+		ORDER BY "timestamp", "id"
+	`, market, keepBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load activities: %w", err)
+	}
+
+	var series []Candle
+	for rows.Next() {
+		var ts time.Time
+		var outcome string
+		var shares, apt float64
+		if err := rows.Scan(&ts, &outcome, &shares, &apt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		result.Activities++
+
+		if result.Activities%progressEvery == 0 {
+			log.Info().
+				Str("market", market).
+				Int("scanned", result.Activities).
+				Int("total", total).
+				Int("candles", len(series)).
+				Msg("🕯️  Candle rebuild progress")
+		}
+
+		if shares <= 0 {
+			result.Skipped++
+			continue
+		}
+		price := apt / shares
+		if outcome == "NO" {
+			price = 1 - price
+		}
+
+		bucket := ts.UTC().Truncate(size)
+		if n := len(series); n > 0 && series[n-1].BucketStart.Equal(bucket) {
+			c := &series[n-1]
+			c.High = max(c.High, price)
+			c.Low = min(c.Low, price)
+			c.Close = price
+			c.Volume += apt
+			c.TradeCount++
+			continue
+		}
+		series = append(series, Candle{
+			BucketStart: bucket,
+			Open:        price,
+			High:        price,
+			Low:         price,
+			Close:       price,
+			Volume:      apt,
+			TradeCount:  1,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load activities: %w", err)
+	}
+
+	replaced, err := swap(ctx, database, market, interval, keepBefore, series)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Candles = len(series)
+	result.Replaced = replaced
+	result.Duration = time.Since(start)
+
+	log.Info().
+		Str("market", market).
+		Str("interval", interval).
+		Int("candles", result.Candles).
+		Int64("replaced", replaced).
+		Int("skipped", result.Skipped).
+		Dur("duration", result.Duration).
+		Msg("✅ Candle rebuild complete")
+
+	return result, nil
+}
+
+// swap replaces the stored series from keepBefore on with the rebuilt one
+// atomically, returning how many old candles were removed
+func swap(ctx context.Context, database *store.DB, market, interval string, keepBefore time.Time, series []Candle) (int64, error) {
+	tx, err := database.Pool().Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`DELETE FROM market_candles WHERE market_address = $1 AND interval = $2 AND bucket_start >= $3`, market, interval, keepBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear candles: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM market_probability_history WHERE market_address = $1 AND interval = $2 AND bucket_start >= $3`, market, interval, keepBefore); err != nil {
+		return 0, fmt.Errorf("failed to clear probability history: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"market_candles"},
+		[]string{"market_address", "interval", "bucket_start", "open", "high", "low", "close", "volume", "trade_count"},
+		pgx.CopyFromSlice(len(series), func(i int) ([]any, error) {
+			c := series[i]
+			return []any{market, interval, c.BucketStart, c.Open, c.High, c.Low, c.Close, c.Volume, c.TradeCount}, nil
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write candles: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"market_probability_history"},
+		[]string{"market_address", "interval", "bucket_start", "yes_probability"},
+		pgx.CopyFromSlice(len(series), func(i int) ([]any, error) {
+			c := series[i]
+			return []any{market, interval, c.BucketStart, min(max(c.Close, 0), 1)}, nil
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write probability history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
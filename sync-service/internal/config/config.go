@@ -3,12 +3,84 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/eventmap"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/senders"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
 )
 
 type Config struct {
-	DatabaseURL string
-	Port        string
-	Environment string
+	DatabaseURL   string
+	Port          string
+	Environment   string
+	AptosNetwork  string
+	AptosRPCURL   string
+	ModuleAddress string
+
+	// Market description translation (optional)
+	TranslationURL     string
+	TranslationAPIKey  string
+	TranslationLocales []string
+
+	// Nightly Activity vs on-chain pool reconciliation
+	ReconcileViewFunction   string
+	ReconcileDriftThreshold float64
+	AlertWebhookURL         string
+
+	// On-chain vs database state reconciliation: view functions returning a
+	// market's [yes_supply, no_supply] and [resolved], how many random
+	// markets a run checks (0 checks all) and the largest tolerated
+	// difference between the stored and on-chain YES price
+	SupplyViewFunction           string
+	ResolutionViewFunction       string
+	StateReconcileSample         int
+	StateReconcilePriceTolerance float64
+
+	// View function returning a market pool's [yes_reserve, no_reserve],
+	// from which the current YES/NO prices are derived
+	PriceViewFunction string
+
+	// Job schedules set in the environment; empty keeps the persisted
+	// schedule (the built-in default on a fresh database)
+	MetricsCron    string
+	PoolsCron      string
+	ActivitiesCron string
+	PricesCron     string
+	ExpiryCron     string
+
+	// Per-job run timeouts by job name; jobs not listed keep the default
+	JobTimeouts map[string]time.Duration
+	// Consecutive failures of a job that trigger an alert (0 disables)
+	JobFailureAlertThreshold int
+
+	// Activity retention: days of raw rows kept (0 keeps everything) and
+	// whether compacted rows are archived or deleted
+	ActivityRetentionDays int
+	ActivityRetentionMode string
+
+	// Senders excluded from activities and volume metrics
+	Senders *senders.Filter
+
+	// Renames of older contract versions' events and fields
+	EventMappings *eventmap.Mapper
+
+	// Self-registration for service discovery (optional)
+	Registry     string
+	ConsulAddr   string
+	AdvertiseURL string
+
+	// Route authentication: tokens and per-route policy overrides
+	APITokens  []string
+	AdminToken string
+	AuthRules  []auth.Rule
+
+	// Per-client request budgets
+	RateLimit ratelimit.Config
 }
 
 func Load() (*Config, error) {
@@ -17,10 +89,137 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
 
+	var locales []string
+	for _, locale := range strings.Split(getEnv("TRANSLATION_LOCALES", "es,pt"), ",") {
+		if locale = strings.ToLower(strings.TrimSpace(locale)); locale != "" {
+			locales = append(locales, locale)
+		}
+	}
+
+	driftThreshold, err := strconv.ParseFloat(getEnv("RECONCILE_DRIFT_THRESHOLD", "0.001"), 64)
+	if err != nil || driftThreshold < 0 {
+		return nil, fmt.Errorf("RECONCILE_DRIFT_THRESHOLD must be a non-negative number")
+	}
+
+	stateSample, err := strconv.Atoi(getEnv("STATE_RECONCILE_SAMPLE", "0"))
+	if err != nil || stateSample < 0 {
+		return nil, fmt.Errorf("STATE_RECONCILE_SAMPLE must be a non-negative integer")
+	}
+	priceTolerance, err := strconv.ParseFloat(getEnv("STATE_RECONCILE_PRICE_TOLERANCE", "0.01"), 64)
+	if err != nil || priceTolerance < 0 {
+		return nil, fmt.Errorf("STATE_RECONCILE_PRICE_TOLERANCE must be a non-negative number")
+	}
+
+	// 7d metrics read raw rows, so at least 8 days must stay
+	retentionDays, err := strconv.Atoi(getEnv("ACTIVITY_RETENTION_DAYS", "0"))
+	if err != nil || retentionDays < 0 || (retentionDays > 0 && retentionDays < 8) {
+		return nil, fmt.Errorf("ACTIVITY_RETENTION_DAYS must be 0 (keep everything) or at least 8")
+	}
+	retentionMode := getEnv("ACTIVITY_RETENTION_MODE", "archive")
+	if retentionMode != "archive" && retentionMode != "delete" {
+		return nil, fmt.Errorf("ACTIVITY_RETENTION_MODE must be archive or delete")
+	}
+
+	schedules := map[string]string{}
+	for _, key := range []string{"SYNC_METRICS_CRON", "SYNC_POOLS_CRON", "SYNC_ACTIVITIES_CRON", "SYNC_PRICES_CRON", "SYNC_EXPIRY_CRON"} {
+		schedule := strings.TrimSpace(os.Getenv(key))
+		if schedule == "" {
+			continue
+		}
+		if err := scheduler.Validate(schedule); err != nil {
+			return nil, fmt.Errorf("%s: invalid cron expression %q: %w", key, schedule, err)
+		}
+		schedules[key] = schedule
+	}
+
+	// e.g. "metrics=45m,activities=5m"
+	jobTimeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(os.Getenv("SYNC_JOB_TIMEOUTS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("SYNC_JOB_TIMEOUTS entry %q must be job=duration with a positive duration, e.g. metrics=45m", entry)
+		}
+		jobTimeouts[strings.TrimSpace(name)] = timeout
+	}
+
+	failureThreshold, err := strconv.Atoi(getEnv("SYNC_JOB_FAILURE_ALERT_THRESHOLD", "3"))
+	if err != nil || failureThreshold < 0 {
+		return nil, fmt.Errorf("SYNC_JOB_FAILURE_ALERT_THRESHOLD must be a non-negative integer")
+	}
+
+	port := getEnv("PORT", "3001")
+	host, _ := os.Hostname()
+
+	apiTokens := []string{}
+	for _, token := range strings.Split(os.Getenv("API_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			apiTokens = append(apiTokens, token)
+		}
+	}
+	authRules, err := auth.ParseRules(os.Getenv("AUTH_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("AUTH_ROUTES: %w", err)
+	}
+	rateLimit, err := ratelimit.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	eventMappings, err := eventmap.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		DatabaseURL: databaseURL,
-		Port:        getEnv("PORT", "3001"),
-		Environment: getEnv("ENVIRONMENT", "development"),
+		DatabaseURL:   databaseURL,
+		Port:          port,
+		Environment:   getEnv("ENVIRONMENT", "development"),
+		AptosNetwork:  getEnv("NEXT_PUBLIC_APTOS_NETWORK", "testnet"),
+		AptosRPCURL:   os.Getenv("APTOS_RPC_URL"),
+		ModuleAddress: os.Getenv("NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS"),
+
+		TranslationURL:     os.Getenv("TRANSLATION_API_URL"),
+		TranslationAPIKey:  os.Getenv("TRANSLATION_API_KEY"),
+		TranslationLocales: locales,
+
+		ReconcileViewFunction:   getEnv("RECONCILE_VIEW_FUNCTION", "verifi_protocol::get_pool_totals"),
+		ReconcileDriftThreshold: driftThreshold,
+		AlertWebhookURL:         os.Getenv("ALERT_WEBHOOK_URL"),
+
+		SupplyViewFunction:           getEnv("SUPPLY_VIEW_FUNCTION", "verifi_protocol::get_outcome_supply"),
+		ResolutionViewFunction:       getEnv("RESOLUTION_VIEW_FUNCTION", "verifi_protocol::is_resolved"),
+		StateReconcileSample:         stateSample,
+		StateReconcilePriceTolerance: priceTolerance,
+
+		PriceViewFunction: getEnv("PRICE_VIEW_FUNCTION", "verifi_protocol::get_pool_reserves"),
+
+		MetricsCron:    schedules["SYNC_METRICS_CRON"],
+		PoolsCron:      schedules["SYNC_POOLS_CRON"],
+		ActivitiesCron: schedules["SYNC_ACTIVITIES_CRON"],
+		PricesCron:     schedules["SYNC_PRICES_CRON"],
+		ExpiryCron:     schedules["SYNC_EXPIRY_CRON"],
+		JobTimeouts:    jobTimeouts,
+
+		JobFailureAlertThreshold: failureThreshold,
+
+		ActivityRetentionDays: retentionDays,
+		ActivityRetentionMode: retentionMode,
+
+		Senders:       senders.FromEnv(),
+		EventMappings: eventMappings,
+
+		Registry:     os.Getenv("SERVICE_REGISTRY"),
+		ConsulAddr:   getEnv("CONSUL_HTTP_ADDR", "http://127.0.0.1:8500"),
+		AdvertiseURL: getEnv("SERVICE_ADVERTISE_URL", fmt.Sprintf("http://%s:%s", host, port)),
+
+		APITokens:  apiTokens,
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+		AuthRules:  authRules,
+
+		RateLimit: rateLimit,
 	}, nil
 }
 
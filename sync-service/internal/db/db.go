@@ -1,37 +0,0 @@
-package db
-
-import (
-	"context"
-	"time"
-
-	"github.com/jackc/pgx/v5/pgxpool"
-)
-
-type DB struct {
-	pool *pgxpool.Pool
-}
-
-func New(databaseURL string) (*DB, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	pool, err := pgxpool.New(ctx, databaseURL)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test connection
-	if err := pool.Ping(ctx); err != nil {
-		return nil, err
-	}
-
-	return &DB{pool: pool}, nil
-}
-
-func (db *DB) Close() {
-	db.pool.Close()
-}
-
-func (db *DB) Pool() *pgxpool.Pool {
-	return db.pool
-}
@@ -0,0 +1,127 @@
+// Package i18n translates market descriptions and negotiates the response
+// locale from Accept-Language.
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceLocale is the language markets are created in
+const SourceLocale = "en"
+
+// Translator is a pluggable machine-translation provider
+type Translator interface {
+	Translate(ctx context.Context, text, targetLocale string) (string, error)
+}
+
+// LibreTranslate talks to a LibreTranslate-compatible /translate endpoint
+type LibreTranslate struct {
+	URL        string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+func NewLibreTranslate(url, apiKey string) *LibreTranslate {
+	return &LibreTranslate{
+		URL:    url,
+		APIKey: apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+func (t *LibreTranslate) Translate(ctx context.Context, text, targetLocale string) (string, error) {
+	reqBody := map[string]string{
+		"q":      text,
+		"source": SourceLocale,
+		"target": targetLocale,
+		"format": "text",
+	}
+	if t.APIKey != "" {
+		reqBody["api_key"] = t.APIKey
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("translation error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.TranslatedText, nil
+}
+
+// Negotiate picks the best locale from an Accept-Language header among
+// available ones, matching on the primary language tag (es-MX → es). It
+// returns SourceLocale when nothing matches.
+func Negotiate(acceptLanguage string, available []string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{tag, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		primary, _, _ := strings.Cut(c.tag, "-")
+		for _, locale := range available {
+			if locale == c.tag || locale == primary {
+				return locale
+			}
+		}
+		if primary == SourceLocale {
+			return SourceLocale
+		}
+	}
+
+	return SourceLocale
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/page"
+)
+
+// What started a job run, as recorded in job_runs
+const (
+	// TriggerSchedule is a run at its scheduled time
+	TriggerSchedule = "schedule"
+	// TriggerCatchUp is a run replacing one missed during downtime
+	TriggerCatchUp = "catch_up"
+	// TriggerAdmin is a run started through POST /admin/jobs/:name/run
+	TriggerAdmin = "admin"
+	// TriggerManual is a run started through POST /sync/* or
+	// /jobs/:name/run
+	TriggerManual = "manual"
+	// TriggerStartup is the initial sync of a fresh process
+	TriggerStartup = "startup"
+)
+
+// Outcomes of a job run, as recorded in job_runs
+const (
+	RunRunning  = "running"
+	RunOK       = "ok"
+	RunFailed   = "failed"
+	RunTimedOut = "timeout"
+	// RunCancelled is a run stopped through Cancel (or by shutdown)
+	RunCancelled = "cancelled"
+	// RunSkipped is an occurrence or trigger refused because the job was
+	// still running
+	RunSkipped = "skipped"
+)
+
+// RunPage pages the run history: 50 runs by default, at most 500
+var RunPage = page.Options{DefaultLimit: 50, MaxLimit: 500, Keys: 2}
+
+// Counters are a job's runs by outcome since the process started, and its
+// latest error. ConsecutiveFailures counts failed and timed out runs since
+// the last successful one; cancelled and skipped runs leave it unchanged.
+type Counters struct {
+	Runs      int `json:"runs"`
+	Failed    int `json:"failed"`
+	TimedOut  int `json:"timedOut"`
+	Cancelled int `json:"cancelled"`
+	Skipped   int `json:"skipped"`
+
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           *string    `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// FailureAlert is called once a job's consecutive failures reach the
+// threshold given to SetFailureAlert, with the job's counters at that point
+type FailureAlert func(name string, counters Counters)
+
+// Run is one execution of a job
+type Run struct {
+	ID           int64      `json:"id"`
+	Job          string     `json:"job"`
+	Trigger      string     `json:"trigger"`
+	Instance     string     `json:"instance"`
+	Status       string     `json:"status"`
+	DryRun       bool       `json:"dry_run"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"` // unset while running
+	DurationMs   *int64     `json:"duration_ms,omitempty"`
+	RowsAffected *int64     `json:"rows_affected,omitempty"`
+	Error        *string    `json:"error,omitempty"`
+}
+
+type (
+	rowsKey   struct{}
+	dryRunKey struct{}
+)
+
+// AddRows adds n to the rows the job running on ctx reports in job_runs. It
+// does nothing when ctx doesn't belong to a job run (e.g. a direct call).
+func AddRows(ctx context.Context, n int) {
+	if rows, ok := ctx.Value(rowsKey{}).(*atomic.Int64); ok {
+		rows.Add(int64(n))
+	}
+}
+
+// IsDryRun reports whether ctx belongs to a dry run, in which the job must
+// compute as usual but not write
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// Counters returns every job's counters, by job name
+func (s *Scheduler) Counters() map[string]Counters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := make(map[string]Counters, len(s.jobs))
+	for name, j := range s.jobs {
+		counters[name] = j.counters
+	}
+	return counters
+}
+
+// Runs returns a page of a job's runs, newest first; p comes from
+// page.Parse with RunPage
+func (s *Scheduler) Runs(ctx context.Context, name string, p page.Params) ([]Run, error) {
+	s.mu.Lock()
+	_, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+
+	columns := []page.Column{{Expr: "started_at", Type: "timestamptz"}, {Expr: "id", Type: "bigint"}}
+	after, afterArgs := p.Keyset(columns, 3)
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT id, job_name, trigger, instance_id, status, dry_run, started_at, finished_at, duration_ms, rows_affected, error
+		FROM job_runs
+		WHERE job_name = $1 AND `+after+`
+		ORDER BY `+p.Order(columns)+`
+		LIMIT $2
+	`, append([]any{name, p.Limit}, afterArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		var r Run
+		if err := rows.Scan(&r.ID, &r.Job, &r.Trigger, &r.Instance, &r.Status, &r.DryRun, &r.StartedAt, &r.FinishedAt, &r.DurationMs, &r.RowsAffected, &r.Error); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// SetFailureAlert calls alert when a job fails threshold times in a row. It
+// fires once per streak: the count must drop back to zero (a successful run)
+// before the job can alert again. A threshold of 0 disables it.
+func (s *Scheduler) SetFailureAlert(threshold int, alert FailureAlert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failureThreshold = threshold
+	s.failureAlert = alert
+}
+
+// count adds a run with status to j's counters, err being the error of a
+// failed or timed out run, and fires the failure alert when the run
+// completes a streak
+func (s *Scheduler) count(j *job, status string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch status {
+	case RunSkipped:
+		j.counters.Skipped++
+		return
+	case RunOK:
+		j.counters.ConsecutiveFailures = 0
+	case RunFailed, RunTimedOut:
+		if status == RunFailed {
+			j.counters.Failed++
+		} else {
+			j.counters.TimedOut++
+		}
+		now := time.Now()
+		msg := err.Error()
+		j.counters.LastError, j.counters.LastErrorAt = &msg, &now
+		j.counters.ConsecutiveFailures++
+	case RunCancelled:
+		j.counters.Cancelled++
+	}
+	j.counters.Runs++
+
+	if s.failureAlert != nil && s.failureThreshold > 0 && j.counters.ConsecutiveFailures == s.failureThreshold {
+		// Outside the lock: the alert may post to a webhook
+		go s.failureAlert(j.name, j.counters)
+	}
+}
+
+// skip records a run of j that was refused for reason
+func (s *Scheduler) skip(ctx context.Context, j *job, trigger, reason string) {
+	log.Warn().Str("job", j.name).Str("trigger", trigger).Str("reason", reason).Msg("⏭️  Job run skipped")
+	s.count(j, RunSkipped, nil)
+
+	_, err := s.db.Pool().Exec(context.WithoutCancel(ctx), `
+		INSERT INTO job_runs (job_name, trigger, instance_id, status, started_at, finished_at, duration_ms, rows_affected, error)
+		VALUES ($1, $2, $3, $4, NOW(), NOW(), 0, 0, $5)
+	`, j.name, trigger, s.instance, RunSkipped, reason)
+	if err != nil {
+		log.Error().Err(err).Str("job", j.name).Msg("Failed to record skipped job run")
+	}
+}
+
+func (s *Scheduler) recordStart(ctx context.Context, name, trigger string, dryRun bool, started time.Time) (int64, error) {
+	var id int64
+	err := s.db.Pool().QueryRow(ctx, `
+		INSERT INTO job_runs (job_name, trigger, instance_id, status, dry_run, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, name, trigger, s.instance, RunRunning, dryRun, started).Scan(&id)
+	return id, err
+}
+
+func (s *Scheduler) recordFinish(ctx context.Context, id int64, status string, duration time.Duration, rows int64, lastError *string) error {
+	_, err := s.db.Pool().Exec(ctx, `
+		UPDATE job_runs
+		SET status = $2, finished_at = started_at + $3 * INTERVAL '1 millisecond',
+			duration_ms = $3, rows_affected = $4, error = $5
+		WHERE id = $1
+	`, id, status, duration.Milliseconds(), rows, lastError)
+	return err
+}
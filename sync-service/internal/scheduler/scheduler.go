@@ -0,0 +1,610 @@
+// Package scheduler runs the periodic sync jobs from a schedule persisted in
+// the scheduled_jobs table. Because next_run lives in the database, runs
+// missed while the service was down are detected on restart and handled
+// according to each job's catch-up policy, and several instances can share
+// one schedule without running a job twice.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/store"
+)
+
+// Catch-up policies for runs missed during downtime
+const (
+	// CatchUpSkip drops missed runs and waits for the next scheduled time
+	CatchUpSkip = "skip"
+	// CatchUpOnce runs a job once for any number of missed runs
+	CatchUpOnce = "once"
+	// CatchUpAll replays every missed run, one after another
+	CatchUpAll = "all"
+)
+
+const (
+	tickInterval = 5 * time.Second
+	// A due run older than this counts as missed rather than just late
+	missedAfter = time.Minute
+	// Upper bound on replayed runs for CatchUpAll after a long outage
+	maxCatchUpRuns = 100
+	// Runs are cancelled after this unless SetTimeout says otherwise
+	defaultTimeout = 30 * time.Minute
+	// The job lease outlives the run's timeout by this much, so a run that
+	// is slow to notice its cancellation keeps other instances out
+	leaseMargin = time.Minute
+)
+
+var ErrUnknownJob = errors.New("unknown job")
+
+// ErrJobRunning is returned when a run is refused because the job is still
+// running, in this process or on another instance
+var ErrJobRunning = errors.New("already running")
+
+// ErrJobNotRunning is returned when cancelling a job that has no run in
+// progress in this process
+var ErrJobNotRunning = errors.New("not running on this instance")
+
+// ErrDryRunUnsupported is returned for a dry run of a job that always writes
+var ErrDryRunUnsupported = errors.New("job does not support dry runs")
+
+// Schedules use cron expressions with a leading seconds field
+var parser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Validate checks a schedule the way Register and Update do
+func Validate(schedule string) error {
+	_, err := parser.Parse(schedule)
+	return err
+}
+
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	schedule string
+	catchUp  string
+	fn       Func
+	override bool
+	timeout  time.Duration
+	dryRun   bool // supports dry runs
+	running  bool
+	cancel   context.CancelFunc // set while running
+	replayed int
+	counters Counters
+}
+
+// Job is the persisted state of a job, as served by the admin API
+type Job struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	CatchUp        string     `json:"catch_up"`
+	Enabled        bool       `json:"enabled"`
+	Running        bool       `json:"running"`
+	NextRun        time.Time  `json:"next_run"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastDurationMs *int64     `json:"last_duration_ms,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+}
+
+// Update changes a job's schedule; nil fields are left as they are
+type Update struct {
+	Schedule *string `json:"schedule"`
+	CatchUp  *string `json:"catch_up"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+type Scheduler struct {
+	db       *store.DB
+	jobs     map[string]*job
+	instance string
+	ctx      context.Context
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+
+	failureThreshold int
+	failureAlert     FailureAlert
+}
+
+func New(database *store.DB) *Scheduler {
+	return &Scheduler{
+		db:       database,
+		jobs:     make(map[string]*job),
+		instance: store.InstanceID("sync-service"),
+	}
+}
+
+// Register adds a job with its default schedule. The defaults are only
+// written the first time; schedules edited through the admin API win.
+func (s *Scheduler) Register(name, schedule, catchUp string, fn Func) error {
+	if _, err := parser.Parse(schedule); err != nil {
+		return fmt.Errorf("job %s: invalid schedule %q: %w", name, schedule, err)
+	}
+	if err := validateCatchUp(catchUp); err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &job{name: name, schedule: schedule, catchUp: catchUp, fn: fn, timeout: defaultTimeout}
+	return nil
+}
+
+// SetTimeout cancels the context of a job's runs once they have taken
+// longer than timeout (default 30m)
+func (s *Scheduler) SetTimeout(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("job %s: timeout must be positive", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("job %s: %w", name, ErrUnknownJob)
+	}
+	j.timeout = timeout
+	return nil
+}
+
+// RegisterOverride is Register for a schedule set in the configuration: Start
+// writes it over the persisted schedule when they differ, moving next_run to
+// its next occurrence. Admin API edits still apply until the next start.
+func (s *Scheduler) RegisterOverride(name, schedule, catchUp string, fn Func) error {
+	if err := s.Register(name, schedule, catchUp, fn); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.jobs[name].override = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Start seeds the registered jobs and runs due jobs until ctx is cancelled.
+// Stop waits for jobs that are still running.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.ctx = ctx
+
+	for _, j := range s.jobs {
+		sched, _ := parser.Parse(j.schedule)
+		_, err := s.db.Pool().Exec(ctx, `
+			INSERT INTO scheduled_jobs (name, schedule, catch_up, next_run)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (name) DO UPDATE
+			SET schedule = EXCLUDED.schedule, next_run = EXCLUDED.next_run, updated_at = NOW()
+			WHERE $5 AND scheduled_jobs.schedule <> EXCLUDED.schedule
+		`, j.name, j.schedule, j.catchUp, sched.Next(time.Now()), j.override)
+		if err != nil {
+			return fmt.Errorf("failed to seed job %s: %w", j.name, err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			s.tick(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	s.wg.Wait()
+}
+
+// tick claims and starts every due job. A claim moves next_run forward with
+// a compare-and-set, so only one instance runs each occurrence.
+func (s *Scheduler) tick(ctx context.Context) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT name, schedule, catch_up, next_run
+		FROM scheduled_jobs
+		WHERE enabled AND next_run <= NOW()
+		ORDER BY next_run
+	`)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to load due jobs")
+		}
+		return
+	}
+
+	type due struct {
+		name, schedule, catchUp string
+		nextRun                 time.Time
+	}
+	var dueJobs []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.name, &d.schedule, &d.catchUp, &d.nextRun); err != nil {
+			log.Error().Err(err).Msg("Failed to scan due job")
+			continue
+		}
+		dueJobs = append(dueJobs, d)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, d := range dueJobs {
+		s.mu.Lock()
+		j, ok := s.jobs[d.name]
+		busy := ok && j.running
+		replayed := 0
+		if ok {
+			replayed = j.replayed
+		}
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		sched, err := parser.Parse(d.schedule)
+		if err != nil {
+			log.Error().Err(err).Str("job", d.name).Msg("Invalid schedule, job not run")
+			continue
+		}
+
+		missed := now.Sub(d.nextRun) > missedAfter
+		next := sched.Next(now)
+		run := true
+
+		if missed {
+			switch d.catchUp {
+			case CatchUpSkip:
+				run = false
+			case CatchUpAll:
+				// Replay one occurrence at a time; the rest stay due
+				if replayed < maxCatchUpRuns {
+					next = sched.Next(d.nextRun)
+				}
+			}
+		}
+
+		// Replays wait for the run in progress instead of being skipped
+		if busy && missed && d.catchUp == CatchUpAll {
+			continue
+		}
+
+		claimed, err := s.claim(ctx, d.name, d.nextRun, next)
+		if err != nil {
+			log.Error().Err(err).Str("job", d.name).Msg("Failed to claim job")
+			continue
+		}
+		if !claimed {
+			// Another instance got it
+			continue
+		}
+
+		if missed {
+			log.Warn().
+				Str("job", d.name).
+				Str("catch_up", d.catchUp).
+				Time("scheduled", d.nextRun).
+				Bool("run", run).
+				Msg("⏰ Missed scheduled run")
+		}
+
+		s.mu.Lock()
+		if missed && d.catchUp == CatchUpAll {
+			j.replayed++
+		} else {
+			j.replayed = 0
+		}
+		s.mu.Unlock()
+
+		if run {
+			trigger := TriggerSchedule
+			if missed {
+				trigger = TriggerCatchUp
+			}
+			// The occurrence is claimed either way, so an overrunning job
+			// skips the runs it overlaps instead of piling them up
+			if !s.acquire(j) {
+				s.skip(ctx, j, trigger, "previous run still in progress")
+				continue
+			}
+			s.start(ctx, j, trigger, false)
+		}
+	}
+}
+
+func (s *Scheduler) claim(ctx context.Context, name string, scheduled, next time.Time) (bool, error) {
+	tag, err := s.db.Pool().Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET next_run = $3, updated_at = NOW()
+		WHERE name = $1 AND next_run = $2
+	`, name, scheduled, next)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// acquire marks j running, or reports false when a run of it is still in
+// progress in this process
+func (s *Scheduler) acquire(j *job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.running {
+		return false
+	}
+	j.running = true
+	return true
+}
+
+// start runs j, which the caller acquired, in the background
+func (s *Scheduler) start(ctx context.Context, j *job, trigger string, dryRun bool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(ctx, j, trigger, dryRun)
+	}()
+}
+
+// Run runs a job now and waits for it, recording the run like a scheduled
+// one. It returns ErrJobRunning without running when the job is already
+// running.
+func (s *Scheduler) Run(ctx context.Context, name, trigger string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !s.acquire(j) {
+		s.skip(ctx, j, trigger, "previous run still in progress")
+		return fmt.Errorf("job %s is %w", name, ErrJobRunning)
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+	return s.execute(ctx, j, trigger, false)
+}
+
+// execute runs j, which the caller acquired, under the job's lease and
+// timeout, and records the outcome in job_runs and, unless it is a dry run,
+// scheduled_jobs. Cancel cancels the run's context.
+func (s *Scheduler) execute(ctx context.Context, j *job, trigger string, dryRun bool) error {
+	s.mu.Lock()
+	timeout := j.timeout
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	j.cancel = cancel
+	s.mu.Unlock()
+
+	defer func() {
+		cancel()
+		s.mu.Lock()
+		j.running = false
+		j.cancel = nil
+		s.mu.Unlock()
+	}()
+
+	// Other instances share the schedule but not the running flag
+	lease := "job:" + j.name
+	held, err := s.db.AcquireLease(ctx, lease, s.instance, timeout+leaseMargin)
+	if err != nil {
+		log.Error().Err(err).Str("job", j.name).Msg("Failed to take job lease, job not run")
+		return fmt.Errorf("failed to take job lease: %w", err)
+	}
+	if !held {
+		s.skip(ctx, j, trigger, "running on another instance")
+		return fmt.Errorf("job %s is %w on another instance", j.name, ErrJobRunning)
+	}
+	defer s.db.ReleaseLease(context.WithoutCancel(ctx), lease, s.instance)
+
+	log.Info().Str("job", j.name).Str("trigger", trigger).Bool("dry_run", dryRun).Msg("⏰ Running scheduled job")
+	started := time.Now()
+	runID, err := s.recordStart(ctx, j.name, trigger, dryRun, started)
+	if err != nil {
+		log.Error().Err(err).Str("job", j.name).Msg("Failed to record job start")
+	}
+
+	rows := new(atomic.Int64)
+	fnCtx := context.WithValue(runCtx, rowsKey{}, rows)
+	if dryRun {
+		fnCtx = context.WithValue(fnCtx, dryRunKey{}, true)
+	}
+	err = j.fn(fnCtx)
+	duration := time.Since(started)
+
+	status := RunOK
+	var lastError *string
+	if err != nil {
+		status = RunFailed
+		switch {
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			status = RunTimedOut
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		case errors.Is(runCtx.Err(), context.Canceled):
+			status = RunCancelled
+			err = fmt.Errorf("cancelled: %w", err)
+		}
+		msg := err.Error()
+		lastError = &msg
+		log.Error().Err(err).Str("job", j.name).Str("status", status).Msg("Scheduled job failed")
+	}
+	s.count(j, status, err)
+
+	record := context.WithoutCancel(ctx)
+	if !dryRun {
+		_, dbErr := s.db.Pool().Exec(record, `
+			UPDATE scheduled_jobs
+			SET last_run = $2, last_duration_ms = $3, last_error = $4, updated_at = NOW()
+			WHERE name = $1
+		`, j.name, started, duration.Milliseconds(), lastError)
+		if dbErr != nil {
+			log.Error().Err(dbErr).Str("job", j.name).Msg("Failed to record job run")
+		}
+	}
+	if runID != 0 {
+		if dbErr := s.recordFinish(record, runID, status, duration, rows.Load(), lastError); dbErr != nil {
+			log.Error().Err(dbErr).Str("job", j.name).Msg("Failed to record job run")
+		}
+	}
+
+	return err
+}
+
+// Jobs returns the persisted state of every job
+func (s *Scheduler) Jobs(ctx context.Context) ([]Job, error) {
+	rows, err := s.db.Pool().Query(ctx, `
+		SELECT name, schedule, catch_up, enabled, next_run, last_run, last_duration_ms, last_error
+		FROM scheduled_jobs
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		var j Job
+		if err := rows.Scan(&j.Name, &j.Schedule, &j.CatchUp, &j.Enabled, &j.NextRun, &j.LastRun, &j.LastDurationMs, &j.LastError); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if registered, ok := s.jobs[j.Name]; ok {
+			j.Running = registered.running
+		}
+		s.mu.Unlock()
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Update edits a job's schedule, catch-up policy or enabled flag. A new
+// schedule takes effect from now, so it never triggers catch-up runs.
+func (s *Scheduler) Update(ctx context.Context, name string, u Update) (*Job, error) {
+	if u.Schedule != nil {
+		if _, err := parser.Parse(*u.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", *u.Schedule, err)
+		}
+	}
+	if u.CatchUp != nil {
+		if err := validateCatchUp(*u.CatchUp); err != nil {
+			return nil, err
+		}
+	}
+
+	var nextRun *time.Time
+	if u.Schedule != nil {
+		sched, _ := parser.Parse(*u.Schedule)
+		next := sched.Next(time.Now())
+		nextRun = &next
+	}
+
+	tag, err := s.db.Pool().Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET schedule = COALESCE($2, schedule),
+			catch_up = COALESCE($3, catch_up),
+			enabled = COALESCE($4, enabled),
+			next_run = COALESCE($5, next_run),
+			updated_at = NOW()
+		WHERE name = $1
+	`, name, u.Schedule, u.CatchUp, u.Enabled, nextRun)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrUnknownJob
+	}
+
+	return s.job(ctx, name)
+}
+
+// Trigger runs a job now without changing its schedule, recording trigger
+// as what started it. A dry run computes without writing; only jobs marked
+// with AllowDryRun support it. It returns ErrJobRunning when the job is
+// already running in this process.
+func (s *Scheduler) Trigger(name, trigger string, dryRun bool) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	supported := ok && j.dryRun
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	if dryRun && !supported {
+		return fmt.Errorf("job %s: %w", name, ErrDryRunUnsupported)
+	}
+	if !s.acquire(j) {
+		s.skip(s.ctx, j, trigger, "previous run still in progress")
+		return fmt.Errorf("job %s is %w", name, ErrJobRunning)
+	}
+
+	s.start(s.ctx, j, trigger, dryRun)
+	return nil
+}
+
+// AllowDryRun marks a job as supporting dry runs: its function checks
+// IsDryRun and skips its writes
+func (s *Scheduler) AllowDryRun(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("job %s: %w", name, ErrUnknownJob)
+	}
+	j.dryRun = true
+	return nil
+}
+
+// Cancel cancels the context of the job's run in progress in this process.
+// The job stops at its next query or check of the context, and the run is
+// recorded as cancelled.
+func (s *Scheduler) Cancel(name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	var cancel context.CancelFunc
+	if ok {
+		cancel = j.cancel
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownJob
+	}
+	if cancel == nil {
+		return fmt.Errorf("job %s is %w", name, ErrJobNotRunning)
+	}
+	log.Warn().Str("job", name).Msg("🛑 Cancelling job run")
+	cancel()
+	return nil
+}
+
+func (s *Scheduler) job(ctx context.Context, name string) (*Job, error) {
+	jobs, err := s.Jobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if j.Name == name {
+			return &j, nil
+		}
+	}
+	return nil, ErrUnknownJob
+}
+
+func validateCatchUp(policy string) error {
+	switch policy {
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+		return nil
+	default:
+		return fmt.Errorf("invalid catch-up policy %q (want %s, %s or %s)", policy, CatchUpSkip, CatchUpOnce, CatchUpAll)
+	}
+}
@@ -0,0 +1,318 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/amount"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/store"
+	"github.com/verifi-protocol/pkg/timeconv"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+const (
+	// How far the first SyncActivities run looks back from the indexer
+	// checkpoint
+	activitiesSyncWindow = uint64(2000)
+	activitiesBatchSize  = uint64(100)
+
+	// How long a SyncActivities run scans before saving its progress, well
+	// within the 5 minute schedule. A run that falls behind catches up over
+	// the next runs instead of a fixed window per run.
+	activitiesSyncBudget = 4 * time.Minute
+
+	// Only one sync-service instance reconciles at a time. The reconciler
+	// never touches versions above the indexer checkpoint, which belong to
+	// the indexer's writer:Activity lease.
+	reconcileLease    = "reconcile:Activity"
+	reconcileLeaseTTL = 10 * time.Minute
+)
+
+// activity is a BUY/SELL row decoded from a shares event
+type activity struct {
+	Version       int64
+	EventIndex    int
+	EventName     string
+	TxHash        string
+	MarketAddress string
+	UserAddress   string
+	Action        string
+	Outcome       string
+	Amount        string // exact share decimal
+	TotalValue    string // exact APT decimal
+	Timestamp     time.Time
+}
+
+// SyncActivities re-scans the versions the indexer has already processed and
+// inserts any Activity rows that are missing, so indexer or webhook outages
+// don't leave permanent gaps in the trade history.
+func (s *Service) SyncActivities(ctx context.Context) error {
+	start := time.Now()
+	log.Info().Msg("📝 Starting activities sync...")
+
+	if s.config.ModuleAddress == "" {
+		log.Warn().Msg("⚠️  NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS not set, skipping activities sync")
+		s.updateStats(ctx, "activities")
+		return nil
+	}
+
+	owned, err := s.db.AcquireLease(ctx, reconcileLease, s.owner, reconcileLeaseTTL)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	if !owned {
+		log.Info().Msg("⏸️  Another instance is reconciling activities, skipping")
+		return nil
+	}
+	defer s.db.ReleaseLease(context.Background(), reconcileLease, s.owner)
+
+	indexed, err := s.loadVersion(ctx, "last_indexed_version")
+	if err != nil {
+		s.incrementErrors(ctx)
+		return fmt.Errorf("failed to load indexer checkpoint: %w", err)
+	}
+
+	reconciled, err := s.loadVersion(ctx, "last_reconciled_version")
+	if errors.Is(err, pgx.ErrNoRows) {
+		// First run: only look back one window from the indexer checkpoint
+		reconciled = 0
+		if indexed > activitiesSyncWindow {
+			reconciled = indexed - activitiesSyncWindow
+		}
+	} else if err != nil {
+		s.incrementErrors(ctx)
+		return fmt.Errorf("failed to load reconciliation checkpoint: %w", err)
+	}
+
+	// Scan up to the indexer checkpoint or until the budget runs out. to is
+	// the last version scanned without a failed insert; the checkpoint never
+	// moves past a failure, so the next run retries it.
+	from, to := reconciled+1, reconciled
+	deadline := time.Now().Add(activitiesSyncBudget)
+	inserted := 0
+	var insertErr error
+scan:
+	for to < indexed && time.Now().Before(deadline) {
+		// Cancelled or timed out: stop before the checkpoint moves
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batchStart := to + 1
+		limit := min(activitiesBatchSize, indexed-to)
+		txs, err := s.client.GetTransactionsByVersionRange(ctx, batchStart, limit)
+		if err != nil {
+			s.incrementErrors(ctx)
+			return fmt.Errorf("failed to fetch transactions: %w", err)
+		}
+
+		scanned := to
+		for _, tx := range txs {
+			version, err := strconv.ParseUint(tx.Version, 10, 64)
+			if err != nil || version <= to {
+				continue
+			}
+			for _, a := range s.activitiesFromTx(tx) {
+				ok, err := s.insertActivity(ctx, a)
+				if err != nil {
+					log.Error().Err(err).Str("tx", a.TxHash).Msg("Failed to insert activity")
+					insertErr = fmt.Errorf("failed to insert activity at version %d: %w", version, err)
+					break scan
+				}
+				if ok {
+					inserted++
+				}
+			}
+			to = version
+		}
+		if to == scanned {
+			// Nothing new from the fullnode; the next run retries
+			break
+		}
+	}
+
+	if to > reconciled {
+		if err := s.saveVersion(ctx, "last_reconciled_version", to); err != nil {
+			s.incrementErrors(ctx)
+			return fmt.Errorf("failed to save reconciliation checkpoint: %w", err)
+		}
+	}
+	if insertErr != nil {
+		s.incrementErrors(ctx)
+		scheduler.AddRows(ctx, inserted)
+		return insertErr
+	}
+
+	s.updateStats(ctx, "activities")
+	scheduler.AddRows(ctx, inserted)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Uint64("from", from).
+		Uint64("to", to).
+		Uint64("behind", indexed-to).
+		Int("inserted", inserted).
+		Msg("✅ Activities sync completed")
+
+	return nil
+}
+
+// activitiesFromTx decodes the BUY/SELL activities emitted by our module in tx
+func (s *Service) activitiesFromTx(tx aptos.TransactionEvent) []activity {
+	if !tx.Success || tx.Type != "user_transaction" {
+		return nil
+	}
+
+	// The indexer archives these without recording activity; don't backfill them
+	if !s.config.Senders.Includes(tx.Sender) {
+		return nil
+	}
+
+	// Same event names and fields for every contract version
+	tx = s.config.EventMappings.Normalize(tx)
+
+	timestamp, err := timeconv.Parse(tx.Timestamp)
+	if err != nil {
+		log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping transaction with invalid timestamp")
+		return nil
+	}
+	version, err := strconv.ParseInt(tx.Version, 10, 64)
+	if err != nil {
+		log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping transaction with invalid version")
+		return nil
+	}
+
+	var activities []activity
+	for i, event := range tx.Events {
+		tag, ok := aptos.MatchModule(event.Type, s.config.ModuleAddress)
+		if !ok {
+			continue
+		}
+		eventName := tag.Name
+
+		var action, marketAddress, user, sharesRaw, aptRaw string
+		var isYes bool
+		switch eventName {
+		case "SharesMintedEvent":
+			e, err := aptos.DecodeSharesMinted(event.Data)
+			if err != nil {
+				log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping malformed event")
+				continue
+			}
+			action, marketAddress, user, isYes = "BUY", e.MarketAddress, e.User, e.IsYes
+			sharesRaw, aptRaw = e.SharesOut, e.AptAmountIn
+		case "SharesBurnedEvent":
+			e, err := aptos.DecodeSharesBurned(event.Data)
+			if err != nil {
+				log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping malformed event")
+				continue
+			}
+			action, marketAddress, user, isYes = "SELL", e.MarketAddress, e.User, e.IsYes
+			sharesRaw, aptRaw = e.SharesIn, e.AptAmountOut
+		default:
+			continue
+		}
+
+		shares, err := amount.SharesToUnits(sharesRaw)
+		if err != nil {
+			log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping malformed event")
+			continue
+		}
+		apt, err := amount.OctasToAPT(aptRaw)
+		if err != nil {
+			log.Warn().Err(err).Str("tx", tx.Hash).Msg("Skipping malformed event")
+			continue
+		}
+
+		outcome := "NO"
+		if isYes {
+			outcome = "YES"
+		}
+
+		activities = append(activities, activity{
+			Version:       version,
+			EventIndex:    tx.EventIndex(i),
+			EventName:     eventName,
+			TxHash:        tx.Hash,
+			MarketAddress: marketAddress,
+			UserAddress:   user,
+			Action:        action,
+			Outcome:       outcome,
+			Amount:        shares,
+			TotalValue:    apt,
+			Timestamp:     timestamp,
+		})
+	}
+
+	return activities
+}
+
+// insertActivity inserts a unless its event was already processed by the
+// indexer or an earlier run, reporting whether a new row was written
+func (s *Service) insertActivity(ctx context.Context, a activity) (bool, error) {
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	claimed, err := store.ClaimEvent(ctx, tx, s.config.AptosNetwork, a.Version, a.EventIndex, a.EventName, a.TxHash)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO "Activity" (
+			"id", "txHash", "marketAddress", "userAddress",
+			"action", "outcome", "amount", "totalValue", "timestamp", "network"
+		) VALUES (
+			gen_random_uuid(), $1, $2, $3, $4, $5, $6::numeric, $7::numeric, $8, $9
+		)
+	`
+
+	_, err = tx.Exec(ctx, query,
+		a.TxHash,
+		a.MarketAddress,
+		a.UserAddress,
+		a.Action,
+		a.Outcome,
+		a.Amount,
+		a.TotalValue,
+		a.Timestamp,
+		s.config.AptosNetwork,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (s *Service) loadVersion(ctx context.Context, key string) (uint64, error) {
+	var value string
+	err := s.db.Pool().QueryRow(ctx,
+		`SELECT value FROM sync_state WHERE key = $1`, key,
+	).Scan(&value)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func (s *Service) saveVersion(ctx context.Context, key string, version uint64) error {
+	query := `
+		INSERT INTO sync_state (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = NOW()
+	`
+
+	_, err := s.db.Pool().Exec(ctx, query, key, strconv.FormatUint(version, 10))
+	return err
+}
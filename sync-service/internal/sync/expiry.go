@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// MarketPendingResolution is the status of a market whose resolution time
+// has passed but whose resolution hasn't been indexed yet. The indexer
+// replaces it with 'resolved' when the resolution event arrives.
+const MarketPendingResolution = "pending_resolution"
+
+// ExpiredMarket is a market moved to pending_resolution, as sent in the
+// market_expired alert
+type ExpiredMarket struct {
+	MarketAddress       string    `json:"market_address"`
+	Description         string    `json:"description"`
+	ResolutionTimestamp time.Time `json:"resolution_timestamp"`
+}
+
+// SyncExpiry marks active markets whose resolution time has passed as
+// pending_resolution, so the UI stops offering trades on them before the
+// on-chain resolution is indexed, and alerts once per market.
+func (s *Service) SyncExpiry(ctx context.Context) error {
+	start := time.Now()
+	log.Info().Msg("⌛ Starting market expiry sync...")
+
+	// A dry run only lists the markets the update would move
+	query := `
+		UPDATE "Market"
+		SET status = '` + MarketPendingResolution + `', "updatedAt" = NOW()
+		WHERE status = 'active' AND "resolutionTimestamp" <= NOW()
+		RETURNING "marketAddress", "description", "resolutionTimestamp"
+	`
+	if scheduler.IsDryRun(ctx) {
+		query = `
+			SELECT "marketAddress", "description", "resolutionTimestamp"
+			FROM "Market"
+			WHERE status = 'active' AND "resolutionTimestamp" <= NOW()
+		`
+	}
+
+	rows, err := s.db.Pool().Query(ctx, query)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	var expired []ExpiredMarket
+	for rows.Next() {
+		var m ExpiredMarket
+		if err := rows.Scan(&m.MarketAddress, &m.Description, &m.ResolutionTimestamp); err != nil {
+			log.Error().Err(err).Msg("Failed to scan expired market")
+			continue
+		}
+		expired = append(expired, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	for _, m := range expired {
+		if scheduler.IsDryRun(ctx) {
+			log.Info().
+				Str("market", m.MarketAddress).
+				Time("resolution_timestamp", m.ResolutionTimestamp).
+				Msg("🧪 Dry run: market expired, not marked")
+			continue
+		}
+		log.Warn().
+			Str("market", m.MarketAddress).
+			Time("resolution_timestamp", m.ResolutionTimestamp).
+			Msg("⌛ Market expired, pending resolution")
+		s.SendAlert(ctx, "market_expired", m)
+	}
+
+	scheduler.AddRows(ctx, len(expired))
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("expired", len(expired)).
+		Msg("✅ Market expiry sync completed")
+
+	return nil
+}
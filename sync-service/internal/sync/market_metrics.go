@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/verifi-protocol/pkg/amount"
+)
+
+// positionMetrics are the Market columns derived from every trade,
+// whoever the sender
+type positionMetrics struct {
+	OpenInterestYes string   // outstanding YES shares
+	OpenInterestNo  string   // outstanding NO shares
+	PriceChange24h  *float64 // nil without trades
+}
+
+// marketPositions computes open interest as shares bought minus shares sold
+// per outcome, and the change of the YES price (as in the candles: a NO
+// trade at p counts as 1-p) between the last trade before since and the
+// latest trade. A market younger than since is measured from its first
+// trade. Open interest includes the days compacted into activity_rollups.
+func (s *Service) marketPositions(ctx context.Context, marketAddress string, since time.Time) (positionMetrics, error) {
+	query := `
+		WITH trades AS (
+			SELECT
+				"timestamp",
+				CASE WHEN outcome = 'NO' THEN 1 - "totalValue" / amount ELSE "totalValue" / amount END AS price
+			FROM "Activity"
+			WHERE "marketAddress" = $1
+				AND action IN ('BUY', 'SELL')
+				AND amount > 0 AND "totalValue" IS NOT NULL
+		), positions AS (
+			SELECT action, outcome, amount
+			FROM "Activity"
+			WHERE "marketAddress" = $1 AND action IN ('BUY', 'SELL')
+			UNION ALL
+			SELECT action, outcome, amount
+			FROM activity_rollups
+			WHERE market_address = $1 AND action IN ('BUY', 'SELL')
+		)
+		SELECT
+			(
+				SELECT COALESCE(SUM(CASE WHEN action = 'BUY' THEN amount ELSE -amount END), 0)::text
+				FROM positions
+				WHERE outcome = 'YES'
+			),
+			(
+				SELECT COALESCE(SUM(CASE WHEN action = 'BUY' THEN amount ELSE -amount END), 0)::text
+				FROM positions
+				WHERE outcome = 'NO'
+			),
+			(SELECT price FROM trades ORDER BY "timestamp" DESC LIMIT 1)::float8,
+			COALESCE(
+				(SELECT price FROM trades WHERE "timestamp" <= $2 ORDER BY "timestamp" DESC LIMIT 1),
+				(SELECT price FROM trades WHERE "timestamp" > $2 ORDER BY "timestamp" LIMIT 1)
+			)::float8
+	`
+
+	var m positionMetrics
+	var latest, baseline *float64
+	err := s.db.Pool().QueryRow(ctx, query, marketAddress, since).
+		Scan(&m.OpenInterestYes, &m.OpenInterestNo, &latest, &baseline)
+	if err != nil {
+		return positionMetrics{}, err
+	}
+
+	if latest != nil && baseline != nil {
+		change := *latest - *baseline
+		m.PriceChange24h = &change
+	}
+	return m, nil
+}
+
+// poolLiquidity is the APT the market's pool holds, from the cumulative
+// totals of the RECONCILE_VIEW_FUNCTION view. It returns nil without a
+// module address, so the stored value is kept.
+func (s *Service) poolLiquidity(ctx context.Context, marketAddress string) (*string, error) {
+	if s.config.ModuleAddress == "" {
+		return nil, nil
+	}
+
+	function := s.config.ModuleAddress + "::" + s.config.ReconcileViewFunction
+	totalIn, totalOut, err := s.poolTotals(ctx, function, marketAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	liquidity, err := amount.OctasToAPT(strconv.FormatInt(max(totalIn-totalOut, 0), 10))
+	if err != nil {
+		return nil, err
+	}
+	return &liquidity, nil
+}
@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// SyncPrices reads the YES and NO reserves of every active market's pool
+// through a view call and stores them and the implied prices on the Market
+// row, so frontends don't make the view calls themselves. In a constant-product
+// pool the YES price is the NO reserve's share of both reserves.
+func (s *Service) SyncPrices(ctx context.Context) error {
+	if s.config.ModuleAddress == "" {
+		log.Warn().Msg("⚠️  NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS not set, skipping prices sync")
+		return nil
+	}
+
+	start := time.Now()
+	log.Info().Msg("💹 Starting prices sync...")
+
+	rows, err := s.db.Pool().Query(ctx, `SELECT "marketAddress" FROM "Market" WHERE status = 'active'`)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	var markets []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			log.Error().Err(err).Msg("Failed to scan market")
+			continue
+		}
+		markets = append(markets, address)
+	}
+	rows.Close()
+
+	function := s.config.ModuleAddress + "::" + s.config.PriceViewFunction
+	updated := 0
+	for _, market := range markets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		yesReserve, noReserve, err := s.poolReserves(ctx, function, market)
+		if err != nil {
+			log.Warn().Err(err).Str("market", market).Msg("Failed to read pool reserves")
+			continue
+		}
+		priceYes := impliedPriceYes(yesReserve, noReserve)
+
+		if scheduler.IsDryRun(ctx) {
+			log.Info().
+				Str("market", market).
+				Float64("currentPriceYes", priceYes).
+				Msg("🧪 Dry run: price computed, not written")
+			updated++
+			continue
+		}
+
+		_, err = s.db.Pool().Exec(ctx, `
+			UPDATE "Market"
+			SET "currentPriceYes" = $2, "currentPriceNo" = $3, "reserveYes" = $4, "reserveNo" = $5, "pricesUpdatedAt" = NOW()
+			WHERE "marketAddress" = $1
+		`, market, priceYes, 1-priceYes, yesReserve, noReserve)
+		if err != nil {
+			log.Error().Err(err).Str("market", market).Msg("Failed to store prices")
+			continue
+		}
+		updated++
+	}
+
+	scheduler.AddRows(ctx, updated)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("markets", len(markets)).
+		Int("updated", updated).
+		Msg("✅ Prices sync completed")
+
+	return nil
+}
+
+// poolReserves reads [yes_reserve, no_reserve] from the view function
+func (s *Service) poolReserves(ctx context.Context, function, marketAddress string) (int64, int64, error) {
+	result, err := s.client.View(ctx, function, []string{}, []string{marketAddress})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result) < 2 {
+		return 0, 0, fmt.Errorf("%s returned %d values, expected 2", function, len(result))
+	}
+
+	yesReserve, err := parseU64(result[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("yes_reserve: %w", err)
+	}
+	noReserve, err := parseU64(result[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("no_reserve: %w", err)
+	}
+	return yesReserve, noReserve, nil
+}
+
+// impliedPriceYes is the YES price of a pool. An empty pool has no price
+// information and counts as even odds.
+func impliedPriceYes(yesReserve, noReserve int64) float64 {
+	if yesReserve+noReserve == 0 {
+		return 0.5
+	}
+	return float64(noReserve) / float64(yesReserve+noReserve)
+}
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// SyncProtocolStats recomputes the protocol-wide totals in ProtocolStats.
+// Volumes and traders are summed from Activity and its rollups with the same
+// rules as the Market columns (BUY, SELL and SWAP, sender lists applied);
+// TVL is the liquidity SyncMetrics last read for each market, and fees come
+// from ProtocolFee.
+func (s *Service) SyncProtocolStats(ctx context.Context) error {
+	start := time.Now()
+	log.Info().Msg("🌐 Starting protocol stats sync...")
+
+	query := `
+		WITH trades AS (
+			SELECT "userAddress", action, "timestamp", "totalValue"
+			FROM "Activity"
+			UNION ALL
+			SELECT user_address, action, day::timestamp, total_value
+			FROM activity_rollups
+		), volume AS (
+			SELECT
+				COALESCE(SUM("totalValue"), 0) AS total,
+				COALESCE(SUM("totalValue") FILTER (WHERE "timestamp" >= $1), 0) AS last24h,
+				COUNT(DISTINCT "userAddress") AS traders
+			FROM trades
+			WHERE action IN ('BUY', 'SELL', 'SWAP')
+				AND (cardinality($2::text[]) = 0 OR LOWER("userAddress") = ANY($2))
+				AND NOT (LOWER("userAddress") = ANY($3))
+		), markets AS (
+			SELECT
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE status = 'active') AS active,
+				COALESCE(SUM("liquidity"), 0) AS tvl
+			FROM "Market"
+		), fees AS (
+			SELECT
+				COALESCE(SUM("amount"), 0) AS total,
+				COALESCE(SUM("amount") FILTER (WHERE "timestamp" >= $1), 0) AS last24h
+			FROM "ProtocolFee"
+		)
+		SELECT
+			markets.total, markets.active,
+			volume.total::text, volume.last24h::text, volume.traders,
+			markets.tvl::text, fees.total::text, fees.last24h::text
+		FROM volume, markets, fees
+	`
+	// Amounts stay exact decimal strings from SUM to the upsert
+	var markets, active, traders int
+	var totalVolume, volume24h, tvl, totalFees, fees24h string
+	allowed, denied := s.senderLists()
+	err := s.db.Pool().QueryRow(ctx, query, time.Now().Add(-24*time.Hour), allowed, denied).
+		Scan(&markets, &active, &totalVolume, &volume24h, &traders, &tvl, &totalFees, &fees24h)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	if !scheduler.IsDryRun(ctx) {
+		_, err = s.db.Pool().Exec(ctx, `
+			INSERT INTO "ProtocolStats" (
+				"id", "totalMarkets", "activeMarkets", "totalVolume", "volume24h",
+				"uniqueTraders", "tvl", "totalFees", "fees24h", "updatedAt"
+			)
+			VALUES (1, $1, $2, $3::numeric, $4::numeric, $5, $6::numeric, $7::numeric, $8::numeric, NOW())
+			ON CONFLICT ("id") DO UPDATE SET
+				"totalMarkets" = EXCLUDED."totalMarkets",
+				"activeMarkets" = EXCLUDED."activeMarkets",
+				"totalVolume" = EXCLUDED."totalVolume",
+				"volume24h" = EXCLUDED."volume24h",
+				"uniqueTraders" = EXCLUDED."uniqueTraders",
+				"tvl" = EXCLUDED."tvl",
+				"totalFees" = EXCLUDED."totalFees",
+				"fees24h" = EXCLUDED."fees24h",
+				"updatedAt" = EXCLUDED."updatedAt"
+		`, markets, active, totalVolume, volume24h, traders, tvl, totalFees, fees24h)
+		if err != nil {
+			s.incrementErrors(ctx)
+			return err
+		}
+	}
+
+	scheduler.AddRows(ctx, 1)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("markets", markets).
+		Int("active", active).
+		Str("total_volume", totalVolume).
+		Str("volume24h", volume24h).
+		Int("traders", traders).
+		Str("tvl", tvl).
+		Str("total_fees", totalFees).
+		Bool("dry_run", scheduler.IsDryRun(ctx)).
+		Msg("✅ Protocol stats sync completed")
+
+	return nil
+}
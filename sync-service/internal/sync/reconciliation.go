@@ -0,0 +1,260 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/timeconv"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// UnitDrift is one market's comparison of summed Activity amounts against
+// the pool's on-chain cumulative counters, in octas
+type UnitDrift struct {
+	MarketAddress string  `json:"market_address"`
+	ActivityIn    int64   `json:"activity_in"`
+	ActivityOut   int64   `json:"activity_out"`
+	OnchainIn     int64   `json:"onchain_in"`
+	OnchainOut    int64   `json:"onchain_out"`
+	DriftRatio    float64 `json:"drift_ratio"`
+}
+
+// SyncUnitReconciliation compares, for every market, the APT recorded in
+// Activity against the pool's cumulative in/out counters read through a
+// view call. Each result is stored in unit_reconciliations; markets drifting
+// above the configured threshold are alerted, which catches decimal and
+// parsing bugs long before the totals are visibly wrong.
+func (s *Service) SyncUnitReconciliation(ctx context.Context) error {
+	if s.config.ModuleAddress == "" {
+		log.Warn().Msg("⚠️  NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS not set, skipping unit reconciliation")
+		return nil
+	}
+
+	start := time.Now()
+	log.Info().Msg("⚖️  Starting unit reconciliation...")
+
+	// Compacted days count through their activity_rollups totals
+	query := `
+		SELECT
+			"marketAddress",
+			ROUND(COALESCE(SUM(CASE WHEN action = 'BUY' THEN "totalValue"::numeric ELSE 0 END), 0) * 100000000)::bigint,
+			ROUND(COALESCE(SUM(CASE WHEN action = 'SELL' THEN "totalValue"::numeric ELSE 0 END), 0) * 100000000)::bigint
+		FROM (
+			SELECT "marketAddress", action, "totalValue"
+			FROM "Activity"
+			WHERE action IN ('BUY', 'SELL')
+			UNION ALL
+			SELECT market_address, action, total_value
+			FROM activity_rollups
+			WHERE action IN ('BUY', 'SELL')
+		) a
+		GROUP BY "marketAddress"
+	`
+
+	rows, err := s.db.Pool().Query(ctx, query)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	var drifts []UnitDrift
+	for rows.Next() {
+		var address string
+		var octasIn, octasOut int64
+		if err := rows.Scan(&address, &octasIn, &octasOut); err != nil {
+			log.Error().Err(err).Msg("Failed to scan activity totals")
+			continue
+		}
+		drifts = append(drifts, UnitDrift{
+			MarketAddress: address,
+			ActivityIn:    octasIn,
+			ActivityOut:   octasOut,
+		})
+	}
+	rows.Close()
+
+	// Trades from excluded senders are archived in raw_events instead of
+	// Activity but still move the pool; count them so they aren't drift
+	excluded, err := s.excludedSenderTotals(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load excluded sender totals, drift may include them")
+	}
+	for i := range drifts {
+		if t, ok := excluded[drifts[i].MarketAddress]; ok {
+			drifts[i].ActivityIn += t[0]
+			drifts[i].ActivityOut += t[1]
+		}
+	}
+
+	function := s.config.ModuleAddress + "::" + s.config.ReconcileViewFunction
+
+	checked, alerted := 0, 0
+	for _, d := range drifts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		onchainIn, onchainOut, err := s.poolTotals(ctx, function, d.MarketAddress)
+		if err != nil {
+			log.Warn().Err(err).Str("market", d.MarketAddress).Msg("Failed to read on-chain pool totals")
+			continue
+		}
+		d.OnchainIn, d.OnchainOut = onchainIn, onchainOut
+		d.DriftRatio = math.Max(
+			driftRatio(d.ActivityIn, d.OnchainIn),
+			driftRatio(d.ActivityOut, d.OnchainOut),
+		)
+
+		alert := d.DriftRatio > s.config.ReconcileDriftThreshold
+		if alert {
+			alerted++
+			log.Error().
+				Str("market", d.MarketAddress).
+				Int64("activity_in", d.ActivityIn).
+				Int64("onchain_in", d.OnchainIn).
+				Int64("activity_out", d.ActivityOut).
+				Int64("onchain_out", d.OnchainOut).
+				Float64("drift_ratio", d.DriftRatio).
+				Msg("🚨 Activity totals drift from on-chain pool")
+			s.SendAlert(ctx, "unit_drift", d)
+		}
+
+		_, err = s.db.Pool().Exec(ctx, `
+			INSERT INTO unit_reconciliations (
+				market_address, activity_in, activity_out, onchain_in, onchain_out, drift_ratio, alerted
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, d.MarketAddress, d.ActivityIn, d.ActivityOut, d.OnchainIn, d.OnchainOut, d.DriftRatio, alert)
+		if err != nil {
+			log.Error().Err(err).Str("market", d.MarketAddress).Msg("Failed to record reconciliation")
+			continue
+		}
+		checked++
+	}
+
+	scheduler.AddRows(ctx, checked)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("markets", checked).
+		Int("drifting", alerted).
+		Msg("✅ Unit reconciliation completed")
+
+	return nil
+}
+
+// excludedSenderTotals sums the octas in/out of archived trades from
+// excluded senders, per market
+func (s *Service) excludedSenderTotals(ctx context.Context) (map[string][2]int64, error) {
+	query := `
+		SELECT
+			data->>'market_address',
+			COALESCE(SUM((data->>'apt_amount_in')::numeric), 0)::bigint,
+			COALESCE(SUM((data->>'apt_amount_out')::numeric), 0)::bigint
+		FROM raw_events
+		WHERE source = 'excluded_sender'
+		GROUP BY data->>'market_address'
+	`
+
+	rows, err := s.db.Pool().Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string][2]int64)
+	for rows.Next() {
+		var address string
+		var in, out int64
+		if err := rows.Scan(&address, &in, &out); err != nil {
+			return nil, err
+		}
+		totals[address] = [2]int64{in, out}
+	}
+	return totals, rows.Err()
+}
+
+// poolTotals returns the cumulative APT (octas) paid into and out of a
+// market's pool. The view function returns [total_in, total_out] as u64s.
+func (s *Service) poolTotals(ctx context.Context, function, marketAddress string) (int64, int64, error) {
+	result, err := s.client.View(ctx, function, []string{}, []string{marketAddress})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result) < 2 {
+		return 0, 0, fmt.Errorf("%s returned %d values, expected 2", function, len(result))
+	}
+
+	totalIn, err := parseU64(result[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("total_in: %w", err)
+	}
+	totalOut, err := parseU64(result[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("total_out: %w", err)
+	}
+	return totalIn, totalOut, nil
+}
+
+// parseU64 decodes a Move u64, which the REST API returns as a string
+func parseU64(v interface{}) (int64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected type %T", v)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || !n.IsInt64() {
+		return 0, fmt.Errorf("invalid u64 %q", s)
+	}
+	return n.Int64(), nil
+}
+
+// driftRatio is |recorded - onchain| relative to onchain. Any recorded
+// amount against an empty pool counts as full drift.
+func driftRatio(recorded, onchain int64) float64 {
+	diff := math.Abs(float64(recorded - onchain))
+	if onchain == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return 1
+	}
+	return diff / float64(onchain)
+}
+
+// SendAlert posts an alert to ALERT_WEBHOOK_URL when one is configured
+func (s *Service) SendAlert(ctx context.Context, kind string, details interface{}) {
+	if s.config.AlertWebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"service": "verifi-sync-service",
+		"alert":   kind,
+		"details": details,
+		"time":    timeconv.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to create alert request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Alert delivery failed")
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// compactedBeforeKey is the sync_state key holding the time before which
+// Activity rows have been rolled up; candle rebuilds keep older candles
+const compactedBeforeKey = "activity_compacted_before"
+
+// Activity retention modes
+const (
+	RetentionArchive = "archive"
+	RetentionDelete  = "delete"
+)
+
+// SyncActivityRetention rolls Activity rows older than
+// ACTIVITY_RETENTION_DAYS up into activity_rollups, one UTC day at a time,
+// and archives or deletes them. Each day is compacted by a single statement,
+// so a cancelled run leaves no row both rolled up and still in Activity.
+func (s *Service) SyncActivityRetention(ctx context.Context) error {
+	days := s.config.ActivityRetentionDays
+	if days == 0 {
+		log.Debug().Msg("Activity retention disabled")
+		return nil
+	}
+
+	start := time.Now()
+	log.Info().Int("days", days).Str("mode", s.config.ActivityRetentionMode).Msg("🗜️  Starting activity retention...")
+
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+
+	if scheduler.IsDryRun(ctx) {
+		var expired int
+		err := s.db.Pool().QueryRow(ctx, `SELECT COUNT(*) FROM "Activity" WHERE "timestamp" < $1`, cutoff).Scan(&expired)
+		if err != nil {
+			return err
+		}
+		scheduler.AddRows(ctx, expired)
+		log.Info().
+			Time("cutoff", cutoff).
+			Int("activities", expired).
+			Msg("🧪 Dry run: activities past retention, not compacted")
+		return nil
+	}
+
+	compacted, compactedDays := 0, 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var oldest *time.Time
+		err := s.db.Pool().QueryRow(ctx, `SELECT MIN("timestamp") FROM "Activity" WHERE "timestamp" < $1`, cutoff).Scan(&oldest)
+		if err != nil {
+			s.incrementErrors(ctx)
+			return err
+		}
+		if oldest == nil {
+			break
+		}
+
+		day := oldest.UTC().Truncate(24 * time.Hour)
+		n, err := s.compactDay(ctx, day)
+		if err != nil {
+			s.incrementErrors(ctx)
+			return err
+		}
+		if n == 0 {
+			// Nothing moved though MIN found a row: stop rather than spin
+			log.Warn().Time("oldest", *oldest).Msg("Activity day compacted no rows, stopping")
+			break
+		}
+		scheduler.AddRows(ctx, n)
+		compacted += n
+		compactedDays++
+
+		log.Debug().
+			Str("day", day.Format("2006-01-02")).
+			Int("activities", n).
+			Msg("Activity day compacted")
+	}
+
+	// Only ever moves forward: a shorter retention later doesn't bring
+	// compacted rows back
+	_, err := s.db.Pool().Exec(ctx, `
+		INSERT INTO sync_state (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET value = GREATEST(sync_state.value::timestamptz, EXCLUDED.value::timestamptz)::text, updated_at = NOW()
+	`, compactedBeforeKey, cutoff.Format(time.RFC3339))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to record activity compaction cutoff")
+	}
+
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Time("cutoff", cutoff).
+		Int("days", compactedDays).
+		Int("activities", compacted).
+		Msg("✅ Activity retention completed")
+
+	return nil
+}
+
+// compactDay moves the Activity rows of one UTC day into activity_rollups,
+// copying them to activity_archive first in archive mode, and returns how
+// many rows it moved
+func (s *Service) compactDay(ctx context.Context, day time.Time) (int, error) {
+	query := `
+		WITH moved AS (
+			DELETE FROM "Activity"
+			WHERE "timestamp" >= $1 AND "timestamp" < $2
+			RETURNING *
+		), archived AS (
+			INSERT INTO activity_archive (id, market_address, "timestamp", row)
+			SELECT moved."id"::text, moved."marketAddress", moved."timestamp", to_jsonb(moved)
+			FROM moved
+			WHERE $4::boolean
+			ON CONFLICT (id) DO NOTHING
+		), rolled AS (
+			INSERT INTO activity_rollups (
+				market_address, day, user_address, action, outcome,
+				trade_count, amount, total_value, value_count
+			)
+			SELECT
+				"marketAddress", $3::date, "userAddress", action, COALESCE(outcome, ''),
+				COUNT(*), COALESCE(SUM(amount), 0), COALESCE(SUM("totalValue"), 0), COUNT("totalValue")
+			FROM moved
+			GROUP BY "marketAddress", "userAddress", action, COALESCE(outcome, '')
+			ON CONFLICT (market_address, day, user_address, action, outcome) DO UPDATE
+			SET trade_count = activity_rollups.trade_count + EXCLUDED.trade_count,
+				amount = activity_rollups.amount + EXCLUDED.amount,
+				total_value = activity_rollups.total_value + EXCLUDED.total_value,
+				value_count = activity_rollups.value_count + EXCLUDED.value_count
+		)
+		SELECT COUNT(*) FROM moved
+	`
+
+	var moved int
+	err := s.db.Pool().QueryRow(ctx, query,
+		day, day.Add(24*time.Hour), day.Format("2006-01-02"), s.config.ActivityRetentionMode == RetentionArchive,
+	).Scan(&moved)
+	return moved, err
+}
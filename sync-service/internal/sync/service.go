@@ -2,19 +2,28 @@ package sync
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/pkg/aptos"
+	"github.com/verifi-protocol/pkg/store"
 	"github.com/verifi-protocol/sync-service/internal/config"
-	"github.com/verifi-protocol/sync-service/internal/db"
+	"github.com/verifi-protocol/sync-service/internal/i18n"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
 )
 
 type Service struct {
-	db     *db.DB
+	db     *store.DB
+	client *aptos.Client
 	config *config.Config
 	stats  *Stats
+	owner  string
 	mu     sync.RWMutex
+
+	translator i18n.Translator
 }
 
 type Stats struct {
@@ -27,24 +36,66 @@ type Stats struct {
 	Errors             int       `json:"errors"`
 }
 
-func NewService(database *db.DB, cfg *config.Config) *Service {
+func NewService(database *store.DB, client *aptos.Client, cfg *config.Config) *Service {
 	return &Service{
 		db:     database,
+		client: client,
 		config: cfg,
 		stats:  &Stats{},
+		owner:  store.InstanceID("sync-service"),
 	}
 }
 
-func (s *Service) GetStats() *Stats {
+// SetTranslator enables machine translation of market descriptions
+func (s *Service) SetTranslator(t i18n.Translator) {
+	s.translator = t
+}
+
+// GetStats returns a copy of this process's counters, safe to read while
+// syncs keep updating them
+func (s *Service) GetStats() Stats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.stats
+	return *s.stats
 }
 
-func (s *Service) updateStats(syncType string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// LifetimeStats returns the counters of every instance since the sync_stats
+// table was created, which survive restarts and deploys
+func (s *Service) LifetimeStats(ctx context.Context) (Stats, error) {
+	rows, err := s.db.Pool().Query(ctx, `SELECT name, count, last_at FROM sync_stats`)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer rows.Close()
+
+	var stats Stats
+	for rows.Next() {
+		var name string
+		var count int
+		var lastAt *time.Time
+		if err := rows.Scan(&name, &count, &lastAt); err != nil {
+			return Stats{}, err
+		}
+		var last time.Time
+		if lastAt != nil {
+			last = *lastAt
+		}
+		switch name {
+		case "metrics":
+			stats.LastMetricsSync, stats.MetricsSyncCount = last, count
+		case "pools":
+			stats.LastPoolsSync, stats.PoolsSyncCount = last, count
+		case "activities":
+			stats.LastActivitiesSync, stats.ActivitiesSyncCount = last, count
+		case "errors":
+			stats.Errors = count
+		}
+	}
+	return stats, rows.Err()
+}
 
+func (s *Service) updateStats(ctx context.Context, syncType string) {
+	s.mu.Lock()
 	switch syncType {
 	case "metrics":
 		s.stats.LastMetricsSync = time.Now()
@@ -56,12 +107,31 @@ func (s *Service) updateStats(syncType string) {
 		s.stats.LastActivitiesSync = time.Now()
 		s.stats.ActivitiesSyncCount++
 	}
+	s.mu.Unlock()
+
+	s.persistStat(ctx, syncType)
 }
 
-func (s *Service) incrementErrors() {
+func (s *Service) incrementErrors(ctx context.Context) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.stats.Errors++
+	s.mu.Unlock()
+
+	s.persistStat(ctx, "errors")
+}
+
+// persistStat adds one to a lifetime counter. A failure is only logged: the
+// in-memory counters are already updated.
+func (s *Service) persistStat(ctx context.Context, name string) {
+	_, err := s.db.Pool().Exec(context.WithoutCancel(ctx), `
+		INSERT INTO sync_stats (name, count, last_at, updated_at)
+		VALUES ($1, 1, NOW(), NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET count = sync_stats.count + 1, last_at = NOW(), updated_at = NOW()
+	`, name)
+	if err != nil {
+		log.Warn().Err(err).Str("stat", name).Msg("Failed to persist sync stats")
+	}
 }
 
 func (s *Service) SyncMetrics(ctx context.Context) error {
@@ -72,12 +142,12 @@ func (s *Service) SyncMetrics(ctx context.Context) error {
 	query := `
 		SELECT "marketAddress", "description"
 		FROM "Market"
-		WHERE status = 'active'
+		WHERE status IN ('active', 'pending_resolution')
 	`
 
 	rows, err := s.db.Pool().Query(ctx, query)
 	if err != nil {
-		s.incrementErrors()
+		s.incrementErrors(ctx)
 		return err
 	}
 	defer rows.Close()
@@ -101,19 +171,28 @@ func (s *Service) SyncMetrics(ctx context.Context) error {
 
 	// Calculate volume for each market
 	for _, market := range markets {
+		// Cancelled or timed out: the remaining markets would only fail
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := s.calculateMarketMetrics(ctx, market.Address); err != nil {
 			log.Error().
 				Err(err).
-				Str("market", market.Address[:10]+"...").
+				Str("market", aptos.ShortAddress(market.Address)).
 				Msg("Failed to calculate metrics")
 			continue
 		}
+		scheduler.AddRows(ctx, 1)
 	}
 
-	s.updateStats("metrics")
+	// A dry run leaves the sync stats alone, like the Market rows
+	if !scheduler.IsDryRun(ctx) {
+		s.updateStats(ctx, "metrics")
+	}
 	log.Info().
 		Dur("duration", time.Since(start)).
 		Int("markets", len(markets)).
+		Bool("dry_run", scheduler.IsDryRun(ctx)).
 		Msg("✅ Metrics sync completed")
 
 	return nil
@@ -124,51 +203,126 @@ func (s *Service) calculateMarketMetrics(ctx context.Context, marketAddress stri
 	time24hAgo := time.Now().Add(-24 * time.Hour)
 	time7dAgo := time.Now().Add(-7 * 24 * time.Hour)
 
+	// Compacted days (activity_rollups) count towards the all-time figures;
+	// retention keeps at least 8 days, so never towards 24h or 7d
 	query := `
 		SELECT
-			COALESCE(SUM(CASE WHEN timestamp >= $1 THEN "totalValue" ELSE 0 END), 0) as volume24h,
-			COALESCE(SUM(CASE WHEN timestamp >= $2 THEN "totalValue" ELSE 0 END), 0) as volume7d,
-			COALESCE(SUM("totalValue"), 0) as totalVolume,
-			COUNT(DISTINCT "userAddress") as uniqueTraders
-		FROM "Activity"
-		WHERE "marketAddress" = $3
-			AND action IN ('BUY', 'SELL', 'SWAP')
+			COALESCE(SUM(CASE WHEN timestamp >= $1 THEN "totalValue"::numeric ELSE 0 END), 0)::text as volume24h,
+			COALESCE(SUM(CASE WHEN timestamp >= $2 THEN "totalValue"::numeric ELSE 0 END), 0)::text as volume7d,
+			COALESCE(SUM("totalValue"::numeric), 0)::text as totalVolume,
+			COUNT(DISTINCT "userAddress") as uniqueTraders,
+			COUNT(DISTINCT "userAddress") FILTER (WHERE timestamp >= $1) as uniqueTraders24h,
+			COUNT(DISTINCT "userAddress") FILTER (WHERE timestamp >= $2) as uniqueTraders7d,
+			COALESCE(SUM(trades) FILTER (WHERE timestamp >= $1), 0) as tradeCount24h,
+			COALESCE(ROUND(SUM("totalValue"::numeric) / NULLIF(SUM(valued), 0), 8), 0)::text as avgTradeSize
+		FROM (
+			SELECT "userAddress", action, timestamp, "totalValue", 1 AS trades, CASE WHEN "totalValue" IS NULL THEN 0 ELSE 1 END AS valued
+			FROM "Activity"
+			WHERE "marketAddress" = $3
+			UNION ALL
+			SELECT user_address, action, day::timestamp, total_value, trade_count, value_count
+			FROM activity_rollups
+			WHERE market_address = $3
+		) a
+		WHERE action IN ('BUY', 'SELL', 'SWAP')
+			AND (cardinality($4::text[]) = 0 OR LOWER("userAddress") = ANY($4))
+			AND NOT (LOWER("userAddress") = ANY($5))
 	`
 
-	var volume24h, volume7d, totalVolume float64
-	var uniqueTraders int
+	// Volumes stay exact decimal strings from SUM to UPDATE
+	var volume24h, volume7d, totalVolume, avgTradeSize string
+	var uniqueTraders, uniqueTraders24h, uniqueTraders7d, tradeCount24h int
 
-	err := s.db.Pool().QueryRow(ctx, query, time24hAgo, time7dAgo, marketAddress).
-		Scan(&volume24h, &volume7d, &totalVolume, &uniqueTraders)
+	// Rows recorded before a sender was denied are still excluded here
+	allow, deny := s.senderLists()
+	err := s.db.Pool().QueryRow(ctx, query, time24hAgo, time7dAgo, marketAddress, allow, deny).
+		Scan(&volume24h, &volume7d, &totalVolume, &uniqueTraders, &uniqueTraders24h, &uniqueTraders7d, &tradeCount24h, &avgTradeSize)
 	if err != nil {
 		return err
 	}
 
+	// Open interest and price change count every trade, like the candles
+	positions, err := s.marketPositions(ctx, marketAddress, time24hAgo)
+	if err != nil {
+		return fmt.Errorf("failed to compute open interest: %w", err)
+	}
+
+	// A failed view call keeps the stored liquidity
+	liquidity, err := s.poolLiquidity(ctx, marketAddress)
+	if err != nil {
+		log.Warn().Err(err).Str("market", marketAddress).Msg("Failed to read pool liquidity")
+	}
+
+	if scheduler.IsDryRun(ctx) {
+		log.Info().
+			Str("market", marketAddress).
+			Str("volume24h", volume24h).
+			Str("volume7d", volume7d).
+			Str("totalVolume", totalVolume).
+			Int("uniqueTraders", uniqueTraders).
+			Int("uniqueTraders24h", uniqueTraders24h).
+			Int("uniqueTraders7d", uniqueTraders7d).
+			Int("tradeCount24h", tradeCount24h).
+			Str("avgTradeSize", avgTradeSize).
+			Str("openInterestYes", positions.OpenInterestYes).
+			Str("openInterestNo", positions.OpenInterestNo).
+			Interface("liquidity", liquidity).
+			Interface("priceChange24h", positions.PriceChange24h).
+			Msg("🧪 Dry run: metrics computed, not written")
+		return nil
+	}
+
 	// Update market record
 	updateQuery := `
 		UPDATE "Market"
 		SET
-			"volume24h" = $1,
-			"volume7d" = $2,
-			"totalVolume" = $3,
+			"volume24h" = $1::numeric,
+			"volume7d" = $2::numeric,
+			"totalVolume" = $3::numeric,
 			"uniqueTraders" = $4,
+			"tradeCount24h" = $6,
+			"openInterestYes" = $7::numeric,
+			"openInterestNo" = $8::numeric,
+			"liquidity" = COALESCE($9::numeric, "liquidity"),
+			"priceChange24h" = $10,
+			"uniqueTraders24h" = $11,
+			"uniqueTraders7d" = $12,
+			"avgTradeSize" = $13::numeric,
 			"updatedAt" = NOW()
 		WHERE "marketAddress" = $5
 	`
 
 	_, err = s.db.Pool().Exec(ctx, updateQuery,
-		volume24h, volume7d, totalVolume, uniqueTraders, marketAddress)
+		volume24h, volume7d, totalVolume, uniqueTraders, marketAddress,
+		tradeCount24h, positions.OpenInterestYes, positions.OpenInterestNo, liquidity, positions.PriceChange24h,
+		uniqueTraders24h, uniqueTraders7d, avgTradeSize)
 
 	if err == nil {
 		log.Debug().
-			Str("market", marketAddress[:10]+"...").
-			Float64("volume24h", volume24h).
+			Str("market", aptos.ShortAddress(marketAddress)).
+			Str("volume24h", volume24h).
 			Msg("Metrics updated")
 	}
 
 	return err
 }
 
+// senderLists returns the sender allow/deny lists in the forms stored in
+// Activity: both the long and the short (leading zeros trimmed) address
+func (s *Service) senderLists() ([]string, []string) {
+	expand := func(addrs []string) []string {
+		out := make([]string, 0, 2*len(addrs))
+		for _, addr := range addrs {
+			out = append(out, addr)
+			if short := "0x" + strings.TrimLeft(strings.TrimPrefix(addr, "0x"), "0"); short != addr {
+				out = append(out, short)
+			}
+		}
+		return out
+	}
+	return expand(s.config.Senders.Allowed()), expand(s.config.Senders.Denied())
+}
+
 func (s *Service) SyncPools(ctx context.Context) error {
 	start := time.Now()
 	log.Info().Msg("💧 Starting pools sync...")
@@ -176,25 +330,10 @@ func (s *Service) SyncPools(ctx context.Context) error {
 	// TODO: Implement pool sync logic
 	// This would sync pool reserves, LP positions, etc.
 
-	s.updateStats("pools")
+	s.updateStats(ctx, "pools")
 	log.Info().
 		Dur("duration", time.Since(start)).
 		Msg("✅ Pools sync completed")
 
 	return nil
 }
-
-func (s *Service) SyncActivities(ctx context.Context) error {
-	start := time.Now()
-	log.Info().Msg("📝 Starting activities sync...")
-
-	// TODO: Implement activities sync from Nodit
-	// This would be a backup for webhook data
-
-	s.updateStats("activities")
-	log.Info().
-		Dur("duration", time.Since(start)).
-		Msg("✅ Activities sync completed")
-
-	return nil
-}
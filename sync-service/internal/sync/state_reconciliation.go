@@ -0,0 +1,246 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// Checks compared by SyncStateReconciliation, as recorded in
+// reconciliation_reports
+const (
+	CheckSupplyYes  = "supply_yes"
+	CheckSupplyNo   = "supply_no"
+	CheckPrice      = "price"
+	CheckResolution = "resolution"
+)
+
+// StateDiscrepancy is one check on which a market's database row disagrees
+// with the chain. Supplies are in raw share units, prices are YES
+// probabilities and resolution is "true" or "false".
+type StateDiscrepancy struct {
+	MarketAddress string  `json:"market_address"`
+	Check         string  `json:"check"`
+	DBValue       string  `json:"db_value"`
+	OnchainValue  string  `json:"onchain_value"`
+	Drift         float64 `json:"drift"`
+}
+
+// marketState is what the database says about a market, in the units the
+// view functions return. Open interest is in raw share units.
+type marketState struct {
+	address         string
+	openInterestYes int64
+	openInterestNo  int64
+	currentPriceYes *float64
+	resolved        bool
+}
+
+// SyncStateReconciliation compares each market's on-chain state against its
+// Market row and the Activity-derived aggregates: outcome token supply
+// against open interest, the price implied by the pool reserves against
+// currentPriceYes, and the on-chain resolution against the status. Every
+// disagreement beyond tolerance is stored in reconciliation_reports, and the
+// markets with any are alerted. STATE_RECONCILE_SAMPLE limits a run to that
+// many random markets.
+func (s *Service) SyncStateReconciliation(ctx context.Context) error {
+	if s.config.ModuleAddress == "" {
+		log.Warn().Msg("⚠️  NEXT_PUBLIC_PUBLISHER_ACCOUNT_ADDRESS not set, skipping state reconciliation")
+		return nil
+	}
+
+	start := time.Now()
+	log.Info().Msg("🔎 Starting state reconciliation...")
+
+	markets, err := s.marketStates(ctx, s.config.StateReconcileSample)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	checked, drifting := 0, 0
+	for _, m := range markets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		discrepancies := s.reconcileMarketState(ctx, m)
+		checked++
+		if len(discrepancies) == 0 {
+			continue
+		}
+		drifting++
+
+		for _, d := range discrepancies {
+			log.Error().
+				Str("market", d.MarketAddress).
+				Str("check", d.Check).
+				Str("db_value", d.DBValue).
+				Str("onchain_value", d.OnchainValue).
+				Float64("drift", d.Drift).
+				Msg("🚨 Database drifts from on-chain state")
+
+			_, err := s.db.Pool().Exec(ctx, `
+				INSERT INTO reconciliation_reports (market_address, check_name, db_value, onchain_value, drift)
+				VALUES ($1, $2, $3, $4, $5)
+			`, d.MarketAddress, d.Check, d.DBValue, d.OnchainValue, d.Drift)
+			if err != nil {
+				log.Error().Err(err).Str("market", d.MarketAddress).Msg("Failed to record reconciliation report")
+			}
+		}
+		s.SendAlert(ctx, "state_drift", discrepancies)
+	}
+
+	scheduler.AddRows(ctx, checked)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("markets", checked).
+		Int("drifting", drifting).
+		Msg("✅ State reconciliation completed")
+
+	return nil
+}
+
+// marketStates loads the markets to reconcile: all of them, or sample
+// random ones when sample is positive
+func (s *Service) marketStates(ctx context.Context, sample int) ([]marketState, error) {
+	// Open interest is summed from Activity (and its rollups) now rather than
+	// read from the Market row, which is only as recent as the last metrics
+	// run
+	query := `
+		SELECT
+			m."marketAddress",
+			ROUND(COALESCE(SUM(a.amount) FILTER (WHERE a.outcome = 'YES'), 0) * 1000000)::bigint,
+			ROUND(COALESCE(SUM(a.amount) FILTER (WHERE a.outcome = 'NO'), 0) * 1000000)::bigint,
+			m."currentPriceYes",
+			m.status = 'resolved'
+		FROM "Market" m
+		LEFT JOIN (
+			SELECT "marketAddress", outcome, CASE WHEN action = 'BUY' THEN amount ELSE -amount END AS amount
+			FROM "Activity"
+			WHERE action IN ('BUY', 'SELL')
+			UNION ALL
+			SELECT market_address, outcome, CASE WHEN action = 'BUY' THEN amount ELSE -amount END
+			FROM activity_rollups
+			WHERE action IN ('BUY', 'SELL')
+		) a ON a."marketAddress" = m."marketAddress"
+		GROUP BY m."marketAddress", m."currentPriceYes", m.status
+		ORDER BY CASE WHEN $1 > 0 THEN random() END
+		LIMIT NULLIF($1, 0)
+	`
+
+	rows, err := s.db.Pool().Query(ctx, query, sample)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var markets []marketState
+	for rows.Next() {
+		var m marketState
+		if err := rows.Scan(&m.address, &m.openInterestYes, &m.openInterestNo, &m.currentPriceYes, &m.resolved); err != nil {
+			return nil, err
+		}
+		markets = append(markets, m)
+	}
+	return markets, rows.Err()
+}
+
+// reconcileMarketState runs every check on one market. A check whose view
+// call fails is logged and left out, not reported as drift.
+func (s *Service) reconcileMarketState(ctx context.Context, m marketState) []StateDiscrepancy {
+	var discrepancies []StateDiscrepancy
+	report := func(check, dbValue, onchainValue string, drift float64) {
+		discrepancies = append(discrepancies, StateDiscrepancy{
+			MarketAddress: m.address,
+			Check:         check,
+			DBValue:       dbValue,
+			OnchainValue:  onchainValue,
+			Drift:         drift,
+		})
+	}
+	prefix := s.config.ModuleAddress + "::"
+
+	// Supply: every outstanding share was bought and not sold back
+	if supplyYes, supplyNo, err := s.outcomeSupply(ctx, prefix+s.config.SupplyViewFunction, m.address); err != nil {
+		log.Warn().Err(err).Str("market", m.address).Msg("Failed to read outcome supply")
+	} else {
+		for _, c := range []struct {
+			check       string
+			db, onchain int64
+		}{
+			{CheckSupplyYes, m.openInterestYes, supplyYes},
+			{CheckSupplyNo, m.openInterestNo, supplyNo},
+		} {
+			if drift := driftRatio(c.db, c.onchain); drift > s.config.ReconcileDriftThreshold {
+				report(c.check, strconv.FormatInt(c.db, 10), strconv.FormatInt(c.onchain, 10), drift)
+			}
+		}
+	}
+
+	// Reserves: the stored price is at most one prices run behind the pool
+	if yesReserve, noReserve, err := s.poolReserves(ctx, prefix+s.config.PriceViewFunction, m.address); err != nil {
+		log.Warn().Err(err).Str("market", m.address).Msg("Failed to read pool reserves")
+	} else if priceYes := impliedPriceYes(yesReserve, noReserve); m.currentPriceYes != nil {
+		if drift := math.Abs(*m.currentPriceYes - priceYes); drift > s.config.StateReconcilePriceTolerance {
+			report(CheckPrice, formatPrice(*m.currentPriceYes), formatPrice(priceYes), drift)
+		}
+	}
+
+	// Resolution: a resolved market whose event the indexer missed
+	if resolved, err := s.onchainResolved(ctx, prefix+s.config.ResolutionViewFunction, m.address); err != nil {
+		log.Warn().Err(err).Str("market", m.address).Msg("Failed to read resolution status")
+	} else if resolved != m.resolved {
+		report(CheckResolution, strconv.FormatBool(m.resolved), strconv.FormatBool(resolved), 1)
+	}
+
+	return discrepancies
+}
+
+// outcomeSupply reads the YES and NO token supply, in raw share units, from
+// a view function returning [yes_supply, no_supply]
+func (s *Service) outcomeSupply(ctx context.Context, function, marketAddress string) (int64, int64, error) {
+	result, err := s.client.View(ctx, function, []string{}, []string{marketAddress})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result) < 2 {
+		return 0, 0, fmt.Errorf("%s returned %d values, expected 2", function, len(result))
+	}
+
+	yesSupply, err := parseU64(result[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("yes_supply: %w", err)
+	}
+	noSupply, err := parseU64(result[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("no_supply: %w", err)
+	}
+	return yesSupply, noSupply, nil
+}
+
+// onchainResolved reads a market's resolution flag from a view function
+// returning [bool]
+func (s *Service) onchainResolved(ctx context.Context, function, marketAddress string) (bool, error) {
+	result, err := s.client.View(ctx, function, []string{}, []string{marketAddress})
+	if err != nil {
+		return false, err
+	}
+	if len(result) < 1 {
+		return false, fmt.Errorf("%s returned no values", function)
+	}
+	resolved, ok := result[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("%s returned %T, expected bool", function, result[0])
+	}
+	return resolved, nil
+}
+
+func formatPrice(p float64) string {
+	return strconv.FormatFloat(p, 'f', 6, 64)
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// SyncTranslations machine-translates the description of every market that
+// has no translation yet for a configured locale. Manual translations
+// uploaded through the admin API are never overwritten.
+func (s *Service) SyncTranslations(ctx context.Context) error {
+	if s.translator == nil || len(s.config.TranslationLocales) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	log.Info().Msg("🌐 Starting translations sync...")
+
+	query := `
+		SELECT m."marketAddress", m."description"
+		FROM "Market" m
+		WHERE NOT EXISTS (
+			SELECT 1 FROM market_translations t
+			WHERE t.market_address = m."marketAddress" AND t.locale = $1
+		)
+	`
+
+	upsert := `
+		INSERT INTO market_translations (market_address, locale, description, source, updated_at)
+		VALUES ($1, $2, $3, 'provider', NOW())
+		ON CONFLICT (market_address, locale) DO NOTHING
+	`
+
+	translated := 0
+	for _, locale := range s.config.TranslationLocales {
+		rows, err := s.db.Pool().Query(ctx, query, locale)
+		if err != nil {
+			s.incrementErrors(ctx)
+			return err
+		}
+
+		type pending struct{ address, description string }
+		var markets []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.address, &p.description); err != nil {
+				log.Error().Err(err).Msg("Failed to scan market")
+				continue
+			}
+			markets = append(markets, p)
+		}
+		rows.Close()
+
+		for _, m := range markets {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			text, err := s.translator.Translate(ctx, m.description, locale)
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("market", m.address).
+					Str("locale", locale).
+					Msg("Translation failed")
+				continue
+			}
+
+			if _, err := s.db.Pool().Exec(ctx, upsert, m.address, locale, text); err != nil {
+				log.Error().Err(err).Str("market", m.address).Msg("Failed to store translation")
+				continue
+			}
+			translated++
+		}
+	}
+
+	scheduler.AddRows(ctx, translated)
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Int("translated", translated).
+		Msg("✅ Translations sync completed")
+
+	return nil
+}
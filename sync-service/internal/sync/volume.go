@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/verifi-protocol/sync-service/internal/scheduler"
+)
+
+// volumeThroughKey is the sync_state key holding the start of the last
+// volume run; the API reports it as the series' freshness
+const volumeThroughKey = "volume_buckets_through"
+
+// volumeLookback is how far before the last run each run re-aggregates, so
+// activities indexed late (or removed by a rollback) are picked up
+const volumeLookback = 24 * time.Hour
+
+// SyncVolume re-aggregates the hourly market_volume_buckets from Activity,
+// from volumeLookback before the previous run on; the first run backfills
+// every hour. Buckets before the activity retention cutoff are left as they
+// are, since their rows are gone. The window is replaced in one transaction.
+func (s *Service) SyncVolume(ctx context.Context) error {
+	start := time.Now()
+	log.Info().Msg("📈 Starting volume buckets sync...")
+
+	var through *time.Time
+	var compactedBefore time.Time
+	err := s.db.Pool().QueryRow(ctx, `
+		SELECT
+			(SELECT value::timestamptz FROM sync_state WHERE key = $1),
+			COALESCE((SELECT value::timestamptz FROM sync_state WHERE key = $2), 'epoch')
+	`, volumeThroughKey, compactedBeforeKey).Scan(&through, &compactedBefore)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	from := compactedBefore.UTC()
+	if through != nil {
+		if since := through.UTC().Add(-volumeLookback).Truncate(time.Hour); since.After(from) {
+			from = since
+		}
+	}
+	allowed, denied := s.senderLists()
+
+	aggregate := `
+		SELECT "marketAddress", date_trunc('hour', "timestamp"), COALESCE(SUM("totalValue"), 0), COUNT(*)
+		FROM "Activity"
+		WHERE "timestamp" >= $1
+			AND action IN ('BUY', 'SELL', 'SWAP')
+			AND (cardinality($2::text[]) = 0 OR LOWER("userAddress") = ANY($2))
+			AND NOT (LOWER("userAddress") = ANY($3))
+		GROUP BY 1, 2
+	`
+
+	if scheduler.IsDryRun(ctx) {
+		var buckets int
+		err := s.db.Pool().QueryRow(ctx, `SELECT COUNT(*) FROM (`+aggregate+`) b`, from, allowed, denied).Scan(&buckets)
+		if err != nil {
+			return err
+		}
+		scheduler.AddRows(ctx, buckets)
+		log.Info().
+			Time("from", from).
+			Int("buckets", buckets).
+			Msg("🧪 Dry run: volume buckets not written")
+		return nil
+	}
+
+	tx, err := s.db.Pool().Begin(ctx)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM market_volume_buckets WHERE bucket_start >= $1`, from); err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO market_volume_buckets (market_address, bucket_start, volume, trade_count)
+	`+aggregate, from, allowed, denied)
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO sync_state (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = NOW()
+	`, volumeThroughKey, start.UTC().Format(time.RFC3339))
+	if err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.incrementErrors(ctx)
+		return err
+	}
+
+	scheduler.AddRows(ctx, int(tag.RowsAffected()))
+	log.Info().
+		Dur("duration", time.Since(start)).
+		Time("from", from).
+		Int64("buckets", tag.RowsAffected()).
+		Msg("✅ Volume buckets sync completed")
+
+	return nil
+}
@@ -0,0 +1,9 @@
+-- Translated market descriptions, served by Accept-Language on the markets API
+CREATE TABLE IF NOT EXISTS market_translations (
+    market_address VARCHAR(66) NOT NULL,
+    locale VARCHAR(16) NOT NULL,
+    description TEXT NOT NULL,
+    source VARCHAR(16) NOT NULL, -- 'provider' or 'manual'
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (market_address, locale)
+);
@@ -0,0 +1,13 @@
+-- Durable schedule for the periodic sync jobs. next_run survives restarts so
+-- runs missed during downtime are detected and caught up per catch_up policy.
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(64) PRIMARY KEY,
+    schedule VARCHAR(128) NOT NULL, -- cron expression with seconds
+    catch_up VARCHAR(16) NOT NULL DEFAULT 'once', -- 'skip', 'once' or 'all'
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run TIMESTAMPTZ NOT NULL,
+    last_run TIMESTAMPTZ,
+    last_duration_ms BIGINT,
+    last_error TEXT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
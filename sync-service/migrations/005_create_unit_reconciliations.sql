@@ -0,0 +1,17 @@
+-- Nightly comparison of summed Activity amounts against on-chain cumulative
+-- pool counters. Amounts are in octas; drift_ratio is the worst relative
+-- difference of the two directions.
+CREATE TABLE IF NOT EXISTS unit_reconciliations (
+    id BIGSERIAL PRIMARY KEY,
+    market_address VARCHAR(66) NOT NULL,
+    activity_in NUMERIC(40, 0) NOT NULL,
+    activity_out NUMERIC(40, 0) NOT NULL,
+    onchain_in NUMERIC(40, 0) NOT NULL,
+    onchain_out NUMERIC(40, 0) NOT NULL,
+    drift_ratio DOUBLE PRECISION NOT NULL,
+    alerted BOOLEAN NOT NULL DEFAULT FALSE,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_unit_reconciliations_market
+    ON unit_reconciliations (market_address, checked_at DESC);
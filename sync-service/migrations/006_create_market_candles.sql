@@ -0,0 +1,22 @@
+-- OHLCV candles of the YES price (APT per share) per market and interval
+CREATE TABLE IF NOT EXISTS market_candles (
+    market_address VARCHAR(66) NOT NULL,
+    interval VARCHAR(8) NOT NULL, -- e.g. '5m', '1h', '1d'
+    bucket_start TIMESTAMPTZ NOT NULL,
+    open DOUBLE PRECISION NOT NULL,
+    high DOUBLE PRECISION NOT NULL,
+    low DOUBLE PRECISION NOT NULL,
+    close DOUBLE PRECISION NOT NULL,
+    volume DOUBLE PRECISION NOT NULL, -- APT traded
+    trade_count INT NOT NULL,
+    PRIMARY KEY (market_address, interval, bucket_start)
+);
+
+-- Implied YES probability at the close of each bucket
+CREATE TABLE IF NOT EXISTS market_probability_history (
+    market_address VARCHAR(66) NOT NULL,
+    interval VARCHAR(8) NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    yes_probability DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (market_address, interval, bucket_start)
+);
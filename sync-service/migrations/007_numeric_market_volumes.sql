@@ -0,0 +1,18 @@
+-- Keep Market volume totals as exact decimals, matching the NUMERIC Activity
+-- amounts they are summed from. Skipped when already converted.
+DO $$
+DECLARE
+    col TEXT;
+BEGIN
+    FOREACH col IN ARRAY ARRAY['volume24h', 'volume7d', 'totalVolume'] LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_name = 'Market' AND column_name = col AND data_type <> 'numeric'
+        ) THEN
+            EXECUTE format(
+                'ALTER TABLE "Market" ALTER COLUMN %I TYPE NUMERIC(38, 8) USING ROUND(%I::numeric, 8)',
+                col, col
+            );
+        END IF;
+    END LOOP;
+END $$;
@@ -0,0 +1,12 @@
+-- Running service instances, for discovery by the ops tooling. Rows are
+-- removed on clean shutdown; a stale heartbeat_at marks a crashed instance.
+CREATE TABLE IF NOT EXISTS service_registry (
+    instance_id VARCHAR(255) PRIMARY KEY,
+    service VARCHAR(64) NOT NULL,
+    version VARCHAR(64) NOT NULL,
+    commit VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    endpoints JSONB NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+-- Ledger of applied module events, shared with the indexer; an activity is
+-- only backfilled if its (version, event index) can be claimed here
+CREATE TABLE IF NOT EXISTS processed_events (
+    transaction_version BIGINT NOT NULL,
+    event_index INT NOT NULL,
+    event_type TEXT NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_version, event_index)
+);
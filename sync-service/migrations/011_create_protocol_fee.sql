@@ -0,0 +1,14 @@
+-- Create the ProtocolFee table the indexer writes when it hasn't run its
+-- migrations yet, so GET /stats/revenue works against a fresh database.
+-- Same definition as the indexer's 017_create_protocol_fee.
+CREATE TABLE IF NOT EXISTS "ProtocolFee" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "txHash" TEXT NOT NULL,
+    "marketAddress" TEXT NOT NULL,
+    "amount" NUMERIC(38, 8) NOT NULL, -- APT
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ProtocolFee_txHash_idx" ON "ProtocolFee" ("txHash");
+CREATE INDEX IF NOT EXISTS "ProtocolFee_timestamp_idx" ON "ProtocolFee" ("timestamp");
+CREATE INDEX IF NOT EXISTS "ProtocolFee_marketAddress_timestamp_idx" ON "ProtocolFee" ("marketAddress", "timestamp");
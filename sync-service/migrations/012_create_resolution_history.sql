@@ -0,0 +1,16 @@
+-- Create the indexer's ResolutionHistory table when its migrations haven't
+-- run yet, so GET /markets/:address/resolution-history works against a fresh
+-- database. Same definition as the indexer's 019_create_resolution_history.
+CREATE TABLE IF NOT EXISTS "ResolutionHistory" (
+    "id" TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    "marketAddress" TEXT NOT NULL,
+    "txHash" TEXT NOT NULL,
+    "state" TEXT NOT NULL CHECK ("state" IN ('PROPOSED', 'DISPUTED', 'DISPUTE_RESOLVED')),
+    "outcome" TEXT,
+    "actor" TEXT NOT NULL,
+    "data" JSONB NOT NULL DEFAULT '{}',
+    "timestamp" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ResolutionHistory_marketAddress_timestamp_idx" ON "ResolutionHistory" ("marketAddress", "timestamp");
+CREATE INDEX IF NOT EXISTS "ResolutionHistory_txHash_idx" ON "ResolutionHistory" ("txHash");
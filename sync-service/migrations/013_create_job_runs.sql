@@ -0,0 +1,16 @@
+-- One row per sync job execution, scheduled or triggered, so run history
+-- survives deploys. finished_at stays NULL while a run is in progress (or
+-- when its instance died mid-run).
+CREATE TABLE IF NOT EXISTS job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    job_name VARCHAR(64) NOT NULL,
+    trigger VARCHAR(16) NOT NULL, -- 'schedule', 'catch_up', 'admin', 'manual' or 'startup'
+    instance_id TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    duration_ms BIGINT,
+    rows_affected BIGINT,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job_name_started_at_idx ON job_runs (job_name, started_at DESC);
@@ -0,0 +1,7 @@
+-- Outcome of each job run: 'running', 'ok', 'failed', 'timeout' or 'skipped'
+-- (refused because the job was still running here or on another instance)
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'running';
+
+UPDATE job_runs
+SET status = CASE WHEN error IS NULL THEN 'ok' ELSE 'failed' END
+WHERE finished_at IS NOT NULL AND status = 'running';
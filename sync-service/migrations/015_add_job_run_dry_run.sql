@@ -0,0 +1,3 @@
+-- Dry runs compute without writing and are kept apart in the run history.
+-- Runs stopped through POST /jobs/:name/cancel get status 'cancelled'.
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+-- Market list metrics written by SyncMetrics next to the volumes: trades in
+-- the last 24h, outstanding shares per outcome, the APT held by the pool and
+-- the YES price change over 24h (NULL until the market has traded).
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "tradeCount24h" INT NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "openInterestYes" NUMERIC(38, 6) NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "openInterestNo" NUMERIC(38, 6) NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "liquidity" NUMERIC(38, 8) NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "priceChange24h" DOUBLE PRECISION;
@@ -0,0 +1,6 @@
+-- Recent engagement on Market for trending sorts, written by SyncMetrics:
+-- distinct traders over 24h and 7d and the average APT per trade. Trades in
+-- the last 24h are in "tradeCount24h" (016).
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "uniqueTraders24h" INT NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "uniqueTraders7d" INT NOT NULL DEFAULT 0;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "avgTradeSize" NUMERIC(38, 8) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Current implied prices on Market, written by SyncPrices from the pool
+-- reserves (PRICE_VIEW_FUNCTION). NULL until the first sync reads the pool.
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "currentPriceYes" DOUBLE PRECISION;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "currentPriceNo" DOUBLE PRECISION;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "pricesUpdatedAt" TIMESTAMP(3);
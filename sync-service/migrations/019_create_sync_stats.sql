@@ -0,0 +1,9 @@
+-- Lifetime sync counters, shared by every instance and kept across restarts.
+-- One row per sync type ('metrics', 'pools', 'activities') plus 'errors';
+-- last_at is the latest sync or error.
+CREATE TABLE IF NOT EXISTS sync_stats (
+    name VARCHAR(64) PRIMARY KEY,
+    count BIGINT NOT NULL DEFAULT 0,
+    last_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
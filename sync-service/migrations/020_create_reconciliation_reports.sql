@@ -0,0 +1,15 @@
+-- Disagreements between a market's database state and the chain, found by
+-- the state reconciliation job: one row per market and check (supply_yes,
+-- supply_no, price, resolution) that drifted beyond tolerance.
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id BIGSERIAL PRIMARY KEY,
+    market_address VARCHAR(66) NOT NULL,
+    check_name VARCHAR(32) NOT NULL,
+    db_value TEXT NOT NULL,
+    onchain_value TEXT NOT NULL,
+    drift DOUBLE PRECISION NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_reports_market
+    ON reconciliation_reports (market_address, checked_at DESC);
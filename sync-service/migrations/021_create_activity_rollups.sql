@@ -0,0 +1,33 @@
+-- Activity retention: rows older than ACTIVITY_RETENTION_DAYS are rolled up
+-- here per market, UTC day, trader, action and outcome, then archived or
+-- deleted. All-time aggregates (total volume, traders, open interest,
+-- reconciliations) add the rollups to the remaining Activity rows.
+-- value_count counts the rows that had a "totalValue".
+CREATE TABLE IF NOT EXISTS activity_rollups (
+    market_address TEXT NOT NULL,
+    day DATE NOT NULL,
+    user_address TEXT NOT NULL,
+    action TEXT NOT NULL,
+    outcome TEXT NOT NULL DEFAULT '',
+    trade_count BIGINT NOT NULL DEFAULT 0,
+    amount NUMERIC(38, 6) NOT NULL DEFAULT 0,
+    total_value NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    value_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (market_address, day, user_address, action, outcome)
+);
+
+-- Compacted rows kept as JSON with ACTIVITY_RETENTION_MODE=archive, so a
+-- later Activity schema change doesn't break the archive
+CREATE TABLE IF NOT EXISTS activity_archive (
+    id TEXT PRIMARY KEY,
+    market_address TEXT NOT NULL,
+    "timestamp" TIMESTAMP(3) NOT NULL,
+    row JSONB NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_archive_market
+    ON activity_archive (market_address, "timestamp");
+
+-- Compaction finds the oldest rows by time alone
+CREATE INDEX IF NOT EXISTS "Activity_timestamp_idx" ON "Activity" ("timestamp");
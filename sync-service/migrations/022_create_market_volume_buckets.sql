@@ -0,0 +1,10 @@
+-- Hourly traded volume per market, maintained by the volume job from
+-- Activity (BUY, SELL and SWAP, respecting the sender lists) for the volume
+-- time series. bucket_start is UTC, like Activity."timestamp".
+CREATE TABLE IF NOT EXISTS market_volume_buckets (
+    market_address TEXT NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    volume NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    trade_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (market_address, bucket_start)
+);
@@ -0,0 +1,14 @@
+-- Protocol-wide totals for GET /stats, recomputed by the protocol_stats job.
+-- A single row (id = 1), so the endpoint is one primary key read.
+CREATE TABLE IF NOT EXISTS "ProtocolStats" (
+    "id" INT PRIMARY KEY DEFAULT 1 CHECK ("id" = 1),
+    "totalMarkets" INT NOT NULL DEFAULT 0,
+    "activeMarkets" INT NOT NULL DEFAULT 0,
+    "totalVolume" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "volume24h" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "uniqueTraders" INT NOT NULL DEFAULT 0,
+    "tvl" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "totalFees" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "fees24h" NUMERIC(38, 8) NOT NULL DEFAULT 0,
+    "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
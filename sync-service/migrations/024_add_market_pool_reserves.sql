@@ -0,0 +1,4 @@
+-- Pool reserves on Market, in raw share units, written by SyncPrices with the
+-- prices derived from them, so the market summary needs no view call
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "reserveYes" BIGINT;
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "reserveNo" BIGINT;
@@ -0,0 +1,14 @@
+-- Full-text search over markets (GET /markets/search). category is optional
+-- and set through PUT /admin/markets/:address/category. The search vector is
+-- a generated column, so every writer of description or category keeps it
+-- current; the description weighs more than the category.
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "category" TEXT;
+
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "searchVector" tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE("description", '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE("category", '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS "Market_searchVector_idx" ON "Market" USING GIN ("searchVector");
+CREATE INDEX IF NOT EXISTS "Market_category_idx" ON "Market" ("category");
@@ -0,0 +1,22 @@
+-- API keys issued to external consumers through /admin/api-keys. Only the
+-- SHA-256 of a key is stored; prefix is its first characters, so keys can be
+-- told apart in listings.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Requests per key and UTC day, added to by every instance. errors counts
+-- responses with status 400 and above.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    errors BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
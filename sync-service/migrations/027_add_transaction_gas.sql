@@ -0,0 +1,16 @@
+-- Gas per indexed transaction, read by GET /users/:address. Creates the
+-- indexer's indexed_transactions when its migrations haven't run yet; same
+-- definition as its 007, 021 and 024.
+CREATE TABLE IF NOT EXISTS indexed_transactions (
+    version BIGINT NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    indexed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    network TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (network, version)
+);
+
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS sender TEXT;
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS gas_used BIGINT;
+ALTER TABLE indexed_transactions ADD COLUMN IF NOT EXISTS gas_unit_price BIGINT; -- octas per gas unit
+
+CREATE INDEX IF NOT EXISTS idx_indexed_transactions_tx_hash ON indexed_transactions (tx_hash);
@@ -0,0 +1,21 @@
+-- Network of Activity rows and processed_events claims, which the sync
+-- service writes with its APTOS_NETWORK. Same columns and keys as the
+-- indexer's 021, for databases where its migrations haven't run yet.
+ALTER TABLE "Activity" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "Market" ADD COLUMN IF NOT EXISTS "network" TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE processed_events ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '';
+
+-- Re-key per network unless the indexer already has
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM pg_index i
+        JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey)
+        WHERE i.indrelid = 'processed_events'::regclass AND i.indisprimary AND a.attname = 'network'
+    ) THEN
+        ALTER TABLE processed_events DROP CONSTRAINT IF EXISTS processed_events_pkey;
+        ALTER TABLE processed_events ADD PRIMARY KEY (network, transaction_version, event_index);
+    END IF;
+END $$;
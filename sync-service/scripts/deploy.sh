@@ -14,7 +14,11 @@ echo "🚀 Deploying VeriFi Sync Service to $SSH_USER@$VPS_IP"
 
 # Build binary locally
 echo "📦 Building Go binary..."
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o sync-service ./cmd/server
+BUILDINFO="github.com/verifi-protocol/pkg/buildinfo"
+LDFLAGS="-X ${BUILDINFO}.Version=$(git describe --tags --always 2>/dev/null || echo dev)"
+LDFLAGS="$LDFLAGS -X ${BUILDINFO}.Commit=$(git rev-parse HEAD 2>/dev/null || echo unknown)"
+LDFLAGS="$LDFLAGS -X ${BUILDINFO}.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o sync-service ./cmd/server
 
 # Create deployment package
 echo "📁 Creating deployment package..."
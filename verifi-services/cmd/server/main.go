@@ -0,0 +1,369 @@
+// verifi-services runs the indexer and the sync service in one process, on
+// one database pool and one HTTP server, for deployments too small to need
+// them split:
+//
+//	verifi-services serve [--indexer] [--sync] [--port 3000]
+//
+// Without --indexer or --sync both run. Every route keeps the path it has in
+// the split services; /health, /healthz, /readyz, /version and /status
+// combine both.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+
+	indexerapp "github.com/verifi-protocol/indexer-service/app"
+	"github.com/verifi-protocol/pkg/auth"
+	"github.com/verifi-protocol/pkg/buildinfo"
+	"github.com/verifi-protocol/pkg/logging"
+	"github.com/verifi-protocol/pkg/openapi"
+	"github.com/verifi-protocol/pkg/ratelimit"
+	"github.com/verifi-protocol/pkg/store"
+	syncapp "github.com/verifi-protocol/sync-service/app"
+)
+
+const usage = "usage: verifi-services serve [--indexer] [--sync] [--port 3000]"
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "serve" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	runIndexer := flags.Bool("indexer", false, "Run the event indexer")
+	runSync := flags.Bool("sync", false, "Run the sync jobs and markets API")
+	port := flags.String("port", getEnv("PORT", "3000"), "HTTP port for both services")
+	flags.Parse(os.Args[2:])
+	if !*runIndexer && !*runSync {
+		*runIndexer, *runSync = true, true
+	}
+
+	// One .env for both services, also read by indexer config reloads
+	indexerapp.EnvFiles = []string{".env"}
+	envErr := indexerapp.LoadEnv(godotenv.Load)
+
+	// Setup logger; the indexer's writer backs GET /logs and LOG_DB
+	logFormat := os.Getenv("LOG_FORMAT")
+	var writers []io.Writer
+	if *runIndexer {
+		writers = append(writers, indexerapp.LogWriter())
+	}
+	err := logging.Setup(logFormat, logging.Meta{
+		Service:  "verifi-services",
+		Instance: store.InstanceID("verifi-services"),
+		Version:  buildinfo.Version,
+	}, writers...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid log format")
+	}
+	if envErr != nil {
+		log.Warn().Msg("No .env file found, using system environment variables")
+	}
+
+	log.Info().Bool("indexer", *runIndexer).Bool("sync", *runSync).Msg("🚀 VeriFi Services Starting...")
+
+	// One pool for both services
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL is required")
+	}
+	database, err := store.New(databaseURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer database.Close()
+
+	log.Info().Msg("✅ Database connected")
+
+	var indexer *indexerapp.App
+	if *runIndexer {
+		if err := indexerapp.Migrate(context.Background(), database); err != nil {
+			log.Fatal().Err(err).Msg("Failed to run indexer migrations")
+		}
+		if indexer, err = indexerapp.Load(database); err != nil {
+			log.Fatal().Err(err).Msg("Failed to start indexer")
+		}
+	}
+
+	var syncer *syncapp.App
+	if *runSync {
+		if err := syncapp.Migrate(context.Background(), database); err != nil {
+			log.Fatal().Err(err).Msg("Failed to run sync migrations")
+		}
+		if syncer, err = syncapp.Load(database); err != nil {
+			log.Fatal().Err(err).Msg("Failed to start sync service")
+		}
+	}
+
+	// Setup Fiber app
+	server := fiber.New(fiber.Config{
+		AppName:      "VeriFi Services",
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	})
+
+	// Middleware
+	server.Use(indexerapp.Recover())
+	server.Use(logging.Requests(logFormat))
+	server.Use(cors.New(cors.Config{
+		AllowOrigins: "*",
+		AllowMethods: "GET,POST,PUT,DELETE",
+	}))
+	server.Use(ratelimit.New(rateLimitConfig(indexer, syncer)))
+	authCfg := authConfig(indexer, syncer)
+	server.Use(auth.New(authCfg))
+	if indexer != nil && indexer.DebugEndpoints() {
+		server.Use(pprof.New())
+		log.Warn().Msg("🔬 pprof enabled under /debug/pprof")
+	}
+
+	probes(server, indexer, syncer)
+	ops := probeOperations()
+	if indexer != nil {
+		indexer.Routes(server)
+		ops = append(ops, indexer.Operations()...)
+	}
+	if syncer != nil {
+		syncer.Routes(server)
+		ops = append(ops, syncer.Operations()...)
+		if err := syncer.Start(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to start scheduler")
+		}
+	}
+
+	// OpenAPI document of the routes this process serves
+	ops = append(ops, openapi.Operation{Method: "GET", Path: "/openapi.json", Tag: "service", Summary: "This document"})
+	server.Get("/openapi.json", openapi.Handler(openapi.Build("verifi-services", buildinfo.Version, ops, authCfg.Policy)))
+
+	// Start server in goroutine
+	go func() {
+		log.Info().Msgf("🌐 Server listening on :%s", *port)
+		if err := server.Listen(":" + *port); err != nil {
+			log.Fatal().Err(err).Msg("Failed to start server")
+		}
+	}()
+
+	// Announce each service to the registry (optional)
+	var deregister []func()
+	if indexer != nil {
+		indexer.Start()
+		stop, err := indexer.Announce()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid SERVICE_REGISTRY")
+		}
+		deregister = append(deregister, stop)
+
+		// SIGHUP reloads the indexer settings, as POST /admin/reload does
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if _, err := indexer.Reload(); err != nil {
+					log.Error().Err(err).Msg("Config reload failed, keeping current settings")
+				}
+			}
+		}()
+	}
+	if syncer != nil {
+		stop, err := syncer.Announce()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid SERVICE_REGISTRY")
+		}
+		deregister = append(deregister, stop)
+
+		go syncer.InitialSync(context.Background())
+	}
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info().Msg("🛑 Shutting down services...")
+	for _, stop := range deregister {
+		stop()
+	}
+	if syncer != nil {
+		syncer.Close()
+	}
+	if err := server.Shutdown(); err != nil {
+		log.Error().Err(err).Msg("Server shutdown error")
+	}
+	if indexer != nil {
+		indexer.Close()
+	}
+
+	log.Info().Msg("✅ Services stopped")
+}
+
+// authConfig merges the services' route policies. Both read API_TOKENS,
+// ADMIN_TOKEN and AUTH_ROUTES, and each rule list ends with the AUTH_ROUTES
+// entries, so configured rules still come after every built-in one. Issued
+// consumer keys come from the sync service and only pass its consumer
+// routes, never the indexer's.
+func authConfig(indexer *indexerapp.App, syncer *syncapp.App) auth.Config {
+	cfg := auth.Config{
+		Rules: []auth.Rule{
+			{Prefix: "/healthz", Policy: auth.Public},
+			{Prefix: "/readyz", Policy: auth.Public},
+			{Prefix: "/openapi.json", Policy: auth.Public},
+		},
+	}
+	var services []auth.Config
+	if indexer != nil {
+		services = append(services, indexer.Auth())
+	}
+	if syncer != nil {
+		services = append(services, syncer.Auth())
+	}
+	for _, service := range services {
+		cfg.APITokens, cfg.AdminToken = service.APITokens, service.AdminToken
+		cfg.Rules = append(cfg.Rules, service.Rules...)
+		if service.Keys != nil {
+			cfg.Keys = service.Keys
+		}
+	}
+	return cfg
+}
+
+// rateLimitConfig merges the services' rate limit classes. Both read the
+// same RATE_LIMIT_* variables and tokens, so the budgets are taken from
+// either, and one limiter covers both services' routes.
+func rateLimitConfig(indexer *indexerapp.App, syncer *syncapp.App) ratelimit.Config {
+	cfg := ratelimit.Config{
+		Rules: []ratelimit.Rule{
+			{Prefix: "/healthz", Class: ratelimit.Unlimited},
+			{Prefix: "/readyz", Class: ratelimit.Unlimited},
+		},
+	}
+	var services []ratelimit.Config
+	if indexer != nil {
+		services = append(services, indexer.RateLimit())
+	}
+	if syncer != nil {
+		services = append(services, syncer.RateLimit())
+	}
+	for _, service := range services {
+		rules := append(cfg.Rules, service.Rules...)
+		cfg, cfg.Rules = service, rules
+	}
+	return cfg
+}
+
+// Bodies of the combined probes. Each service's section is left out when it
+// isn't running.
+type (
+	probeStatus struct {
+		Status string `json:"status"`
+	}
+	combinedHealth struct {
+		Status   string `json:"status"`
+		Service  string `json:"service"`
+		Time     int64  `json:"time"`
+		Services struct {
+			Indexer *indexerapp.HealthReport `json:"indexer,omitempty"`
+			Sync    *probeStatus             `json:"sync,omitempty"`
+		} `json:"services"`
+	}
+	combinedVersion struct {
+		Indexer *buildinfo.Info `json:"indexer,omitempty"`
+		Sync    *buildinfo.Info `json:"sync,omitempty"`
+	}
+	combinedStatus struct {
+		Indexer *indexerapp.StatusReport `json:"indexer,omitempty"`
+		Sync    *syncapp.StatusReport    `json:"sync,omitempty"`
+	}
+)
+
+// probeOperations documents the routes probes mounts
+func probeOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: "GET", Path: "/health", Tag: "service", Summary: "Health of both services; 503 when the indexer is degraded", Response: combinedHealth{}},
+		{Method: "GET", Path: "/healthz", Tag: "service", Summary: "Liveness", Response: indexerapp.Liveness{}},
+		{Method: "GET", Path: "/readyz", Tag: "service", Summary: "Indexer readiness; 503 while the database or listener is down", Response: indexerapp.HealthReport{}},
+		{Method: "GET", Path: "/version", Tag: "service", Summary: "Build metadata of both services", Response: combinedVersion{}},
+		{Method: "GET", Path: "/status", Tag: "service", Summary: "Status of both services", Response: combinedStatus{}},
+	}
+}
+
+// probes serves the combined health, version and status routes. /health is
+// 503 when the indexer reports degraded.
+func probes(r fiber.Router, indexer *indexerapp.App, syncer *syncapp.App) {
+	r.Get("/health", func(c *fiber.Ctx) error {
+		body := combinedHealth{Status: "healthy", Service: "verifi-services", Time: time.Now().Unix()}
+		code := 200
+		if indexer != nil {
+			report := indexer.Health(c.Context())
+			body.Services.Indexer = &report
+			if !report.Healthy() {
+				body.Status, code = "degraded", 503
+			}
+		}
+		if syncer != nil {
+			body.Services.Sync = &probeStatus{Status: "healthy"}
+		}
+		return c.Status(code).JSON(body)
+	})
+
+	// /healthz only says the process is up; /readyz follows the indexer
+	r.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(indexerapp.Liveness{Status: "healthy", Service: "verifi-services", Time: time.Now().Unix()})
+	})
+	r.Get("/readyz", func(c *fiber.Ctx) error {
+		if indexer == nil {
+			return c.JSON(probeStatus{Status: "healthy"})
+		}
+		report := indexer.Ready(c.Context())
+		if !report.Healthy() {
+			return c.Status(503).JSON(report)
+		}
+		return c.JSON(report)
+	})
+
+	r.Get("/version", func(c *fiber.Ctx) error {
+		var versions combinedVersion
+		if indexer != nil {
+			info := indexer.BuildInfo()
+			versions.Indexer = &info
+		}
+		if syncer != nil {
+			info := syncer.BuildInfo()
+			versions.Sync = &info
+		}
+		return c.JSON(versions)
+	})
+
+	r.Get("/status", func(c *fiber.Ctx) error {
+		var status combinedStatus
+		if indexer != nil {
+			report := indexer.Status(c.Context())
+			status.Indexer = &report
+		}
+		if syncer != nil {
+			report := syncer.Status(c.Context())
+			status.Sync = &report
+		}
+		return c.JSON(status)
+	})
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}